@@ -0,0 +1,160 @@
+// Package check inspects a configuration and the host it runs on, and plans
+// the network changes that starting TunGo would make, without changing
+// anything.
+package check
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+)
+
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusWarning Status = "warning"
+	StatusError   Status = "error"
+)
+
+// Finding is the result of one check.
+type Finding struct {
+	Status  Status `json:"Status"`
+	Check   string `json:"Check"`
+	Message string `json:"Message"`
+}
+
+// Report lists the findings and the planned network changes, in the order
+// they would be applied.
+type Report struct {
+	Path     string    `json:"Path"`
+	Findings []Finding `json:"Findings"`
+	Plan     []string  `json:"Plan"`
+}
+
+// Errors counts the findings that would stop TunGo from starting.
+func (r Report) Errors() int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Status == StatusError {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *Report) add(status Status, check, format string, args ...any) {
+	r.Findings = append(r.Findings, Finding{Status: status, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) plan(format string, args ...any) {
+	r.Plan = append(r.Plan, fmt.Sprintf(format, args...))
+}
+
+// Checker runs the checks against a Host.
+type Checker struct {
+	host Host
+}
+
+func NewChecker() *Checker {
+	return &Checker{host: systemHost{}}
+}
+
+// tunnel is a subnet that a TUN interface of TunGo takes.
+type tunnel struct {
+	tunName string
+	subnet  netip.Prefix
+}
+
+// checkOverlaps reports tunnel subnets that collide with addresses of other
+// interfaces, which breaks routing, or with routes of other interfaces,
+// which the tunnel shadows. Interfaces in own, left over by an earlier run,
+// are skipped.
+func (c *Checker) checkOverlaps(r *Report, tunnels []tunnel, own []string) {
+	interfaces, err := c.host.Interfaces()
+	if err != nil {
+		r.add(StatusWarning, "subnets", "cannot list network interfaces: %v", err)
+		return
+	}
+	routes, err := c.host.Routes()
+	if err != nil {
+		r.add(StatusWarning, "subnets", "cannot list routes: %v", err)
+	}
+	clean := true
+	for _, t := range tunnels {
+		for _, iface := range interfaces {
+			if slices.Contains(own, iface.Name) {
+				continue
+			}
+			for _, prefix := range iface.Prefixes {
+				if prefix.Overlaps(t.subnet) {
+					clean = false
+					r.add(StatusError, "subnets", "%s subnet %s overlaps %s on %s", t.tunName, t.subnet, prefix, iface.Name)
+				}
+			}
+		}
+		for _, route := range routes {
+			if route.Prefix.Bits() == 0 || slices.Contains(own, route.Device) {
+				continue
+			}
+			if route.Prefix.Overlaps(t.subnet) && !slices.ContainsFunc(interfaces, func(i Interface) bool {
+				return i.Name == route.Device && slices.Contains(i.Prefixes, route.Prefix)
+			}) {
+				clean = false
+				r.add(StatusWarning, "subnets", "%s subnet %s overlaps route %s via %s", t.tunName, t.subnet, route.Prefix, route.Device)
+			}
+		}
+	}
+	if clean {
+		r.add(StatusOK, "subnets", "tunnel subnets do not overlap host interfaces or routes")
+	}
+}
+
+// checkListen reports whether address can be bound. A taken address is an
+// error: the listener would fail at start.
+func (c *Checker) checkListen(r *Report, check, network, address string) {
+	if err := c.host.Listen(network, address); err != nil {
+		r.add(StatusError, check, "%s %s is not available: %v", network, address, err)
+		return
+	}
+	r.add(StatusOK, check, "%s %s is free", network, address)
+}
+
+// checkTools reports the tools that are missing from PATH.
+func (c *Checker) checkTools(r *Report, tools []tool) {
+	for _, t := range tools {
+		found := slices.IndexFunc(t.names, func(name string) bool {
+			return c.host.LookPath(name) == nil
+		})
+		switch {
+		case found >= 0:
+			r.add(StatusOK, "tools", "%s found for %s", t.names[found], t.purpose)
+		case t.optional:
+			r.add(StatusWarning, "tools", "%s not found for %s", joinOr(t.names), t.purpose)
+		default:
+			r.add(StatusError, "tools", "%s not found for %s", joinOr(t.names), t.purpose)
+		}
+	}
+}
+
+// tool is an external command that TunGo runs. Any of names will do.
+type tool struct {
+	names    []string
+	purpose  string
+	optional bool
+}
+
+func joinOr(names []string) string {
+	switch len(names) {
+	case 0:
+		return ""
+	case 1:
+		return names[0]
+	default:
+		s := names[0]
+		for _, name := range names[1 : len(names)-1] {
+			s += ", " + name
+		}
+		return s + " or " + names[len(names)-1]
+	}
+}
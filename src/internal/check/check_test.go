@@ -0,0 +1,285 @@
+package check
+
+import (
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	clientconfig "tungo/internal/config/client"
+	serverconfig "tungo/internal/config/server"
+	"tungo/internal/config/settings"
+	"tungo/internal/platform"
+	"tungo/internal/protocol/keys"
+)
+
+type fakeHost struct {
+	interfaces []Interface
+	routes     []Route
+	busy       []string
+	missing    []string
+}
+
+func (h fakeHost) Interfaces() ([]Interface, error) { return h.interfaces, nil }
+func (h fakeHost) Routes() ([]Route, error)         { return h.routes, nil }
+
+func (h fakeHost) Listen(network, address string) error {
+	if slices.Contains(h.busy, network+" "+address) {
+		return errors.New("address already in use")
+	}
+	return nil
+}
+
+func (h fakeHost) LookPath(name string) error {
+	if slices.Contains(h.missing, name) {
+		return errors.New("not found")
+	}
+	return nil
+}
+
+func defaultHost() fakeHost {
+	return fakeHost{
+		interfaces: []Interface{{Name: "eth0", Prefixes: []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}}},
+		routes: []Route{
+			{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Device: "eth0"},
+			{Prefix: netip.MustParsePrefix("192.168.1.0/24"), Device: "eth0"},
+		},
+	}
+}
+
+func findings(r Report, status Status) []string {
+	var messages []string
+	for _, f := range r.Findings {
+		if f.Status == status {
+			messages = append(messages, f.Check+": "+f.Message)
+		}
+	}
+	return messages
+}
+
+func hasFinding(r Report, status Status, substr string) bool {
+	return slices.ContainsFunc(findings(r, status), func(m string) bool { return strings.Contains(m, substr) })
+}
+
+func writeServerConfiguration(t *testing.T, conf *serverconfig.Configuration) string {
+	t.Helper()
+	t.Setenv("Host", "")
+	t.Setenv("ServerIP", "")
+	t.Setenv("EnableUDP", "")
+	t.Setenv("EnableTCP", "")
+	t.Setenv("EnableWS", "")
+	path := filepath.Join(t.TempDir(), "server_configuration.json")
+	data, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func serverWithKeys(t *testing.T) *serverconfig.Configuration {
+	t.Helper()
+	public, private, err := (&keys.DefaultKeyDeriver{}).GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := serverconfig.New()
+	conf.X25519PublicKey, conf.X25519PrivateKey = public, private[:]
+	return conf
+}
+
+func TestServer_OK(t *testing.T) {
+	if !platform.ServerModeSupported() {
+		t.Skip("server mode is not supported on this platform")
+	}
+	path := writeServerConfiguration(t, serverWithKeys(t))
+	before, _ := os.ReadFile(path)
+
+	r := (&Checker{host: defaultHost()}).Server(path)
+	if r.Errors() != 0 || len(findings(r, StatusWarning)) != 0 {
+		t.Fatalf("unexpected findings: %v %v", findings(r, StatusError), findings(r, StatusWarning))
+	}
+	for _, step := range []string{"create TUN", "masquerade 10.0.1.0/24 out of eth0", "listen for UDP clients on udp port 9090"} {
+		if !slices.ContainsFunc(r.Plan, func(p string) bool { return strings.Contains(p, step) }) {
+			t.Fatalf("plan lacks %q: %v", step, r.Plan)
+		}
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Fatal("check changed the configuration file")
+	}
+}
+
+func TestServer_Problems(t *testing.T) {
+	if !platform.ServerModeSupported() {
+		t.Skip("server mode is not supported on this platform")
+	}
+	conf := serverWithKeys(t)
+	conf.X25519PublicKey = make([]byte, 32)
+	conf.EnableTCP = true
+	path := writeServerConfiguration(t, conf)
+	host := fakeHost{
+		interfaces: []Interface{{Name: "eth0", Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16")}}},
+		routes:     []Route{{Prefix: netip.MustParsePrefix("10.0.1.0/24"), Device: "wg0"}},
+		busy:       []string{"udp :9090"},
+		missing:    []string{"iptables"},
+	}
+
+	r := (&Checker{host: host}).Server(path)
+	for _, want := range []string{
+		"keys: X25519PrivateKey does not match",
+		"subnets: s_tcptun0 subnet 10.0.0.0/24 overlaps 10.0.0.0/16 on eth0",
+		"ports: udp :9090 is not available",
+		"tools: iptables not found",
+		"uplink: no IPv4 default route",
+	} {
+		if !hasFinding(r, StatusError, want) {
+			t.Fatalf("missing error %q in %v", want, findings(r, StatusError))
+		}
+	}
+	if !hasFinding(r, StatusWarning, "overlaps route 10.0.1.0/24 via wg0") {
+		t.Fatalf("missing route warning in %v", findings(r, StatusWarning))
+	}
+	if hasFinding(r, StatusError, "iptables or nft") {
+		t.Fatal("nft is an alternative for MSS clamping")
+	}
+}
+
+func TestServer_InvalidAndMissingConfiguration(t *testing.T) {
+	if !platform.ServerModeSupported() {
+		t.Skip("server mode is not supported on this platform")
+	}
+	conf := serverconfig.New()
+	conf.UDPSettings.Port = 0
+	conf.UDPSettings.MTU = 100
+	r := (&Checker{host: defaultHost()}).Server(writeServerConfiguration(t, conf))
+	if !hasFinding(r, StatusError, "invalid 'MTU'") || len(r.Plan) != 0 {
+		t.Fatalf("invalid configuration: %v, plan %v", r.Findings, r.Plan)
+	}
+
+	missing := filepath.Join(t.TempDir(), "server_configuration.json")
+	r = (&Checker{host: defaultHost()}).Server(missing)
+	if r.Errors() != 0 || !hasFinding(r, StatusWarning, "does not exist") {
+		t.Fatalf("missing configuration: %v", r.Findings)
+	}
+	if _, err := os.Stat(missing); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("check wrote the default configuration")
+	}
+}
+
+func TestServer_ReportsPendingMigration(t *testing.T) {
+	if !platform.ServerModeSupported() {
+		t.Skip("server mode is not supported on this platform")
+	}
+	conf := serverWithKeys(t)
+	conf.Version = 0
+	path := writeServerConfiguration(t, conf)
+	r := (&Checker{host: defaultHost()}).Server(path)
+	if !hasFinding(r, StatusWarning, "version 0 is upgraded") {
+		t.Fatalf("missing migration warning: %v", r.Findings)
+	}
+	if _, err := os.Stat(path + ".v0.bak"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("check migrated the configuration file")
+	}
+}
+
+func writeClientConfiguration(t *testing.T, conf clientconfig.Configuration) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "client_configuration.json")
+	data, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func validClient(t *testing.T) clientconfig.Configuration {
+	t.Helper()
+	public, private, err := (&keys.DefaultKeyDeriver{}).GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientconfig.Configuration{
+		Version:  clientconfig.Migrations.Current(),
+		ClientID: 2,
+		UDPSettings: settings.Settings{
+			Addressing: settings.Addressing{
+				TunName:    "tun0",
+				Server:     settings.Host{IPv4: "203.0.113.1"},
+				Port:       9090,
+				IPv4Subnet: netip.MustParsePrefix("10.0.1.0/24"),
+			},
+			Routing:  settings.Routing{ExcludeRoutes: []netip.Prefix{netip.MustParsePrefix("198.51.100.0/24")}},
+			MTU:      1400,
+			Protocol: settings.UDP,
+		},
+		X25519PublicKey:  make([]byte, 32),
+		ClientPublicKey:  public,
+		ClientPrivateKey: private[:],
+		Protocol:         settings.UDP,
+		KillSwitch:       true,
+	}
+}
+
+func TestClient_OK(t *testing.T) {
+	r := (&Checker{host: defaultHost()}).Client(writeClientConfiguration(t, validClient(t)))
+	if r.Errors() != 0 {
+		t.Fatalf("unexpected errors: %v", findings(r, StatusError))
+	}
+	for _, step := range []string{
+		"create TUN tun0 with 10.0.1.3/24, MTU 1400",
+		"route 203.0.113.1 through the current uplink",
+		"route 198.51.100.0/24 around the tunnel",
+		"route 0.0.0.0/1 and 128.0.0.0/1 into tun0",
+		"kill switch",
+	} {
+		if !slices.ContainsFunc(r.Plan, func(p string) bool { return strings.Contains(p, step) }) {
+			t.Fatalf("plan lacks %q: %v", step, r.Plan)
+		}
+	}
+}
+
+func TestClient_Problems(t *testing.T) {
+	conf := validClient(t)
+	conf.ClientPublicKey = make([]byte, 32)
+	conf.MetricsAddress = "127.0.0.1:9100"
+	host := defaultHost()
+	host.interfaces = append(host.interfaces, Interface{Name: "docker0", Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}})
+	host.interfaces = append(host.interfaces, Interface{Name: "tun0", Prefixes: []netip.Prefix{netip.MustParsePrefix("10.0.1.0/24")}})
+	host.busy = []string{"tcp 127.0.0.1:9100"}
+
+	r := (&Checker{host: host}).Client(writeClientConfiguration(t, conf))
+	for _, want := range []string{
+		"keys: ClientPrivateKey does not match",
+		"subnets: tun0 subnet 10.0.1.0/24 overlaps 10.0.0.0/8 on docker0",
+		"metrics: tcp 127.0.0.1:9100 is not available",
+	} {
+		if !hasFinding(r, StatusError, want) {
+			t.Fatalf("missing error %q in %v", want, findings(r, StatusError))
+		}
+	}
+	if hasFinding(r, StatusError, "on tun0") {
+		t.Fatal("the tunnel's own interface is not a conflict")
+	}
+
+	r = (&Checker{host: host}).Client(filepath.Join(t.TempDir(), "missing.json"))
+	if !hasFinding(r, StatusError, "configuration") {
+		t.Fatalf("missing configuration: %v", r.Findings)
+	}
+}
+
+func TestJoinOr(t *testing.T) {
+	for names, want := range map[string]string{"a": "a", "a,b": "a or b", "a,b,c": "a, b or c"} {
+		if got := joinOr(strings.Split(names, ",")); got != want {
+			t.Fatalf("joinOr(%s) = %q, want %q", names, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package check
+
+import (
+	"bytes"
+	"net/netip"
+	"os"
+	"slices"
+	"strings"
+
+	clientconfig "tungo/internal/config/client"
+	"tungo/internal/config/settings"
+	"tungo/internal/protocol/keys"
+)
+
+// Client checks the client configuration at path.
+func (c *Checker) Client(path string) Report {
+	r := Report{Path: path}
+	conf, ok := loadClient(&r, path)
+	if !ok {
+		return r
+	}
+	if public, err := keys.PublicKey(conf.ClientPrivateKey); err != nil || !bytes.Equal(public, conf.ClientPublicKey) {
+		r.add(StatusError, "keys", "ClientPrivateKey does not match ClientPublicKey")
+	} else {
+		r.add(StatusOK, "keys", "client key pair matches")
+	}
+	candidates, err := conf.CandidateSettings()
+	if err != nil {
+		r.add(StatusError, "configuration", "%v", err)
+		return r
+	}
+
+	var tunnels []tunnel
+	var own []string
+	for _, s := range candidates {
+		if !slices.Contains(own, s.TunName) {
+			own = append(own, s.TunName)
+		}
+		for _, subnet := range []netip.Prefix{s.IPv4Subnet, s.IPv6Subnet} {
+			t := tunnel{tunName: s.TunName, subnet: subnet}
+			if subnet.IsValid() && !slices.Contains(tunnels, t) {
+				tunnels = append(tunnels, t)
+			}
+		}
+	}
+	c.checkOverlaps(&r, tunnels, own)
+	if conf.MetricsAddress != "" {
+		c.checkListen(&r, "metrics", "tcp", conf.MetricsAddress)
+	}
+	c.checkTools(&r, clientTools(conf))
+	planClient(&r, conf, candidates[0])
+	return r
+}
+
+// loadClient reads the configuration as the client would, including the
+// private key of an enrolled configuration.
+func loadClient(r *Report, path string) (*clientconfig.Configuration, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		r.add(StatusError, "configuration", "%v", err)
+		return nil, false
+	}
+	conf, err := clientconfig.Load(path)
+	if err != nil {
+		r.add(StatusError, "configuration", "%v", err)
+		return nil, false
+	}
+	if !clientconfig.IsURI(strings.TrimSpace(string(data))) {
+		if _, from, err := clientconfig.Migrations.Migrate(data); err == nil && from < clientconfig.Migrations.Current() {
+			r.add(StatusWarning, "configuration", "version %d is upgraded to %d at start; the original is kept as a backup",
+				from, clientconfig.Migrations.Current())
+		}
+	}
+	r.add(StatusOK, "configuration", "configuration is valid")
+	return conf, true
+}
+
+// planClient plans the session with the first endpoint; later endpoints
+// only replace the server and protocol.
+func planClient(r *Report, conf *clientconfig.Configuration, s settings.Settings) {
+	r.plan("create TUN %s with %s, MTU %d", s.TunName, strings.Join(tunAddresses(s), " and "), s.MTU)
+	r.plan("route %s through the current uplink", serverHost(s.Server))
+	for _, prefix := range s.BypassRoutes() {
+		r.plan("route %s around the tunnel", prefix)
+	}
+	if s.FullTunnel() {
+		r.plan("route 0.0.0.0/1 and 128.0.0.0/1 into %s", s.TunName)
+		if s.IPv6.IsValid() {
+			r.plan("route ::/1 and 8000::/1 into %s", s.TunName)
+		}
+	} else {
+		for _, prefix := range s.IncludeRoutes {
+			r.plan("route %s into %s", prefix, s.TunName)
+		}
+	}
+	resolvers := s.DNSv4Resolvers()
+	if s.IPv6.IsValid() {
+		resolvers = append(resolvers, s.DNSv6Resolvers()...)
+	}
+	r.plan("resolve DNS through %s on %s", strings.Join(resolvers, ", "), s.TunName)
+	if conf.KillSwitch {
+		r.plan("block traffic outside the tunnel (kill switch)")
+	}
+	if conf.MetricsAddress != "" {
+		r.plan("serve metrics on %s", conf.MetricsAddress)
+	}
+}
+
+func serverHost(h settings.Host) string {
+	switch {
+	case h.Domain != "":
+		return h.Domain
+	case h.IPv4 != "":
+		return h.IPv4
+	default:
+		return h.IPv6
+	}
+}
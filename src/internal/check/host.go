@@ -0,0 +1,94 @@
+package check
+
+import (
+	"net"
+	"net/netip"
+	"os/exec"
+)
+
+// Host is the read-only view of the system that the checks inspect.
+type Host interface {
+	Interfaces() ([]Interface, error)
+	Routes() ([]Route, error)
+	// Listen binds address on network ("tcp" or "udp") and releases it.
+	Listen(network, address string) error
+	LookPath(name string) error
+}
+
+type Interface struct {
+	Name     string
+	Prefixes []netip.Prefix
+}
+
+type Route struct {
+	Prefix netip.Prefix
+	Device string
+}
+
+type systemHost struct{}
+
+func (systemHost) Interfaces() ([]Interface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Interface, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		entry := Interface{Name: iface.Name}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip, ok := netip.AddrFromSlice(ipNet.IP)
+			if !ok || ip.IsLinkLocalUnicast() {
+				continue
+			}
+			bits, _ := ipNet.Mask.Size()
+			entry.Prefixes = append(entry.Prefixes, netip.PrefixFrom(ip.Unmap(), bits).Masked())
+		}
+		result = append(result, entry)
+	}
+	return result, nil
+}
+
+func (systemHost) Routes() ([]Route, error) {
+	return systemRoutes()
+}
+
+func (systemHost) Listen(network, address string) error {
+	if network == "udp" {
+		conn, err := net.ListenPacket(network, address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	return listener.Close()
+}
+
+func (systemHost) LookPath(name string) error {
+	_, err := exec.LookPath(name)
+	return err
+}
+
+// defaultDevice returns the device of the IPv4 default route.
+func defaultDevice(routes []Route) string {
+	for _, route := range routes {
+		if route.Prefix.Bits() == 0 && route.Prefix.Addr().Is4() {
+			return route.Device
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package check
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math/bits"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func systemRoutes() ([]Route, error) {
+	routes, err := readRoutes("/proc/net/route", parseRoutes)
+	if err != nil {
+		return nil, err
+	}
+	routes6, err := readRoutes("/proc/net/ipv6_route", parseRoutes6)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return append(routes, routes6...), nil
+}
+
+func readRoutes(path string, parse func(io.Reader) ([]Route, error)) ([]Route, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return parse(f)
+}
+
+// parseRoutes reads /proc/net/route, whose addresses are hex in host byte
+// order.
+func parseRoutes(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 || fields[0] == "Iface" {
+			continue
+		}
+		destination, err1 := strconv.ParseUint(fields[1], 16, 32)
+		mask, err2 := strconv.ParseUint(fields[7], 16, 32)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		var addr [4]byte
+		binary.LittleEndian.PutUint32(addr[:], uint32(destination))
+		prefix := netip.PrefixFrom(netip.AddrFrom4(addr), bits.OnesCount32(uint32(mask)))
+		routes = append(routes, Route{Prefix: prefix.Masked(), Device: fields[0]})
+	}
+	return routes, scanner.Err()
+}
+
+// parseRoutes6 reads /proc/net/ipv6_route. Loopback, link-local and
+// multicast routes are skipped.
+func parseRoutes6(r io.Reader) ([]Route, error) {
+	var routes []Route
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[9] == "lo" {
+			continue
+		}
+		raw, err := hex.DecodeString(fields[0])
+		if err != nil || len(raw) != 16 {
+			continue
+		}
+		length, err := strconv.ParseUint(fields[1], 16, 8)
+		if err != nil || length > 128 {
+			continue
+		}
+		addr := netip.AddrFrom16([16]byte(raw))
+		if addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+			continue
+		}
+		routes = append(routes, Route{Prefix: netip.PrefixFrom(addr, int(length)).Masked(), Device: fields[9]})
+	}
+	return routes, scanner.Err()
+}
@@ -0,0 +1,40 @@
+package check
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestParseRoutes(t *testing.T) {
+	input := "Iface\tDestination\tGateway \tFlags\tRefCnt\tUse\tMetric\tMask\t\tMTU\tWindow\tIRTT\n" +
+		"eth0\t00000000\t010200C0\t0003\t0\t0\t0\t00000000\t0\t0\t0\n" +
+		"eth0\t000200C0\t00000000\t0001\t0\t0\t0\t00FFFFFF\t0\t0\t0\n"
+	routes, err := parseRoutes(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Route{
+		{Prefix: netip.MustParsePrefix("0.0.0.0/0"), Device: "eth0"},
+		{Prefix: netip.MustParsePrefix("192.0.2.0/24"), Device: "eth0"},
+	}
+	if len(routes) != len(want) || routes[0] != want[0] || routes[1] != want[1] {
+		t.Fatalf("routes = %v, want %v", routes, want)
+	}
+	if defaultDevice(routes) != "eth0" {
+		t.Fatalf("defaultDevice = %q", defaultDevice(routes))
+	}
+}
+
+func TestParseRoutes6(t *testing.T) {
+	input := "fd000000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000001 00000000 00000001     eth0\n" +
+		"fe800000000000000000000000000000 40 00000000000000000000000000000000 00 00000000000000000000000000000000 00000100 00000002 00000000 00000001     eth0\n" +
+		"00000000000000000000000000000001 80 00000000000000000000000000000000 00 00000000000000000000000000000000 00000000 00000001 00000000 00200001       lo\n"
+	routes, err := parseRoutes6(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0] != (Route{Prefix: netip.MustParsePrefix("fd00::/64"), Device: "eth0"}) {
+		t.Fatalf("routes = %v", routes)
+	}
+}
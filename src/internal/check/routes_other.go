@@ -0,0 +1,9 @@
+//go:build !linux
+
+package check
+
+// systemRoutes is not implemented here; subnets are checked against
+// interface addresses only.
+func systemRoutes() ([]Route, error) {
+	return nil, nil
+}
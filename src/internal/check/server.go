@@ -0,0 +1,160 @@
+package check
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	serverconfig "tungo/internal/config/server"
+	"tungo/internal/config/settings"
+	"tungo/internal/platform"
+	"tungo/internal/protocol/keys"
+)
+
+// Server checks the server configuration at path.
+func (c *Checker) Server(path string) Report {
+	r := Report{Path: path}
+	if !platform.ServerModeSupported() {
+		r.add(StatusError, "platform", "server mode is not supported on this platform")
+		return r
+	}
+	conf, ok := loadServer(&r, path)
+	if !ok {
+		return r
+	}
+	checkServerKeys(&r, conf)
+
+	var tunnels []tunnel
+	var own []string
+	ipv6 := false
+	for _, profile := range conf.Profiles() {
+		s := profile.Settings
+		own = append(own, s.TunName)
+		if !profile.Enabled {
+			continue
+		}
+		if s.IPv4Subnet.IsValid() {
+			tunnels = append(tunnels, tunnel{tunName: s.TunName, subnet: s.IPv4Subnet})
+		}
+		if s.IPv6Subnet.IsValid() {
+			tunnels = append(tunnels, tunnel{tunName: s.TunName, subnet: s.IPv6Subnet})
+			ipv6 = true
+		}
+	}
+	if len(tunnels) == 0 {
+		r.add(StatusError, "protocols", "no protocol is enabled")
+		return r
+	}
+	c.checkOverlaps(&r, tunnels, own)
+	for _, profile := range conf.Profiles() {
+		if profile.Enabled {
+			c.checkListen(&r, "ports", listenNetwork(profile.Settings.Protocol), fmt.Sprintf(":%d", profile.Settings.Port))
+		}
+	}
+	if conf.MetricsAddress != "" {
+		c.checkListen(&r, "metrics", "tcp", conf.MetricsAddress)
+	}
+	c.checkTools(&r, serverTools(ipv6))
+	c.planServer(&r, conf)
+	return r
+}
+
+// loadServer reads the configuration as the server would. A missing file is
+// not an error: the server writes the default configuration at start.
+func loadServer(r *Report, path string) (*serverconfig.Configuration, bool) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		r.add(StatusWarning, "configuration", "%s does not exist; the server writes a default configuration at start", path)
+		return serverconfig.New(), true
+	}
+	if err != nil {
+		r.add(StatusError, "configuration", "%v", err)
+		return nil, false
+	}
+	conf, err := serverconfig.Load(path)
+	if err != nil {
+		r.add(StatusError, "configuration", "%v", err)
+		return nil, false
+	}
+	if _, from, err := serverconfig.Migrations.Migrate(data); err == nil && from < serverconfig.Migrations.Current() {
+		r.add(StatusWarning, "configuration", "version %d is upgraded to %d at start; the original is kept as a backup",
+			from, serverconfig.Migrations.Current())
+	}
+	r.add(StatusOK, "configuration", "configuration is valid")
+	return conf, true
+}
+
+func checkServerKeys(r *Report, conf *serverconfig.Configuration) {
+	if len(conf.X25519PublicKey) == 0 && len(conf.X25519PrivateKey) == 0 {
+		r.add(StatusOK, "keys", "no server key pair yet; one is generated at start")
+	} else if public, err := keys.PublicKey(conf.X25519PrivateKey); err != nil || !bytes.Equal(public, conf.X25519PublicKey) {
+		r.add(StatusError, "keys", "X25519PrivateKey does not match X25519PublicKey")
+	} else {
+		r.add(StatusOK, "keys", "server key pair matches")
+	}
+}
+
+func (c *Checker) planServer(r *Report, conf *serverconfig.Configuration) {
+	routes, _ := c.host.Routes()
+	uplink := defaultDevice(routes)
+	if uplink == "" {
+		r.add(StatusError, "uplink", "no IPv4 default route; clients are masqueraded out of its interface")
+		uplink = "<default interface>"
+	} else {
+		r.add(StatusOK, "uplink", "clients are masqueraded out of %s", uplink)
+	}
+
+	ipv4, ipv6 := false, false
+	for _, profile := range conf.Profiles() {
+		if profile.Enabled {
+			ipv4 = ipv4 || profile.Settings.IPv4Subnet.IsValid()
+			ipv6 = ipv6 || profile.Settings.IPv6Subnet.IsValid()
+		}
+	}
+	if ipv4 {
+		r.plan("enable IPv4 forwarding (net.ipv4.ip_forward = 1)")
+	}
+	if ipv6 {
+		r.plan("enable IPv6 forwarding (net.ipv6.conf.all.forwarding = 1)")
+	}
+	for _, profile := range conf.Profiles() {
+		if !profile.Enabled {
+			continue
+		}
+		s := profile.Settings
+		r.plan("create TUN %s with %s, MTU %d", s.TunName, strings.Join(tunAddresses(s), " and "), s.MTU)
+		if s.IPv4Subnet.IsValid() {
+			r.plan("masquerade %s out of %s", s.IPv4Subnet.Masked(), uplink)
+		}
+		if s.IPv6Subnet.IsValid() {
+			r.plan("masquerade %s out of %s", s.IPv6Subnet.Masked(), uplink)
+		}
+		r.plan("forward between %s and %s, and between clients on %s", s.TunName, uplink, s.TunName)
+		r.plan("clamp TCP MSS on %s", s.TunName)
+		r.plan("listen for %s clients on %s port %d", s.Protocol, listenNetwork(s.Protocol), s.Port)
+	}
+	if conf.MetricsAddress != "" {
+		r.plan("serve metrics on %s", conf.MetricsAddress)
+	}
+}
+
+// tunAddresses lists the addresses of a TUN with their prefix length.
+func tunAddresses(s settings.Settings) []string {
+	var addresses []string
+	if cidr, err := s.IPv4CIDR(); err == nil {
+		addresses = append(addresses, cidr)
+	}
+	if cidr, err := s.IPv6CIDR(); err == nil {
+		addresses = append(addresses, cidr)
+	}
+	return addresses
+}
+
+func listenNetwork(protocol settings.Protocol) string {
+	if protocol == settings.UDP {
+		return "udp"
+	}
+	return "tcp"
+}
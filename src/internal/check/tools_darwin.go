@@ -0,0 +1,14 @@
+package check
+
+import clientconfig "tungo/internal/config/client"
+
+func serverTools(bool) []tool {
+	return nil
+}
+
+func clientTools(*clientconfig.Configuration) []tool {
+	return []tool{
+		{names: []string{"ifconfig"}, purpose: "TUN interface"},
+		{names: []string{"route"}, purpose: "routes"},
+	}
+}
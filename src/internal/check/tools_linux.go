@@ -0,0 +1,27 @@
+package check
+
+import clientconfig "tungo/internal/config/client"
+
+func serverTools(ipv6 bool) []tool {
+	tools := []tool{
+		{names: []string{"ip"}, purpose: "TUN interfaces"},
+		{names: []string{"sysctl"}, purpose: "packet forwarding"},
+		{names: []string{"iptables"}, purpose: "NAT and forwarding rules"},
+	}
+	if ipv6 {
+		tools = append(tools, tool{names: []string{"ip6tables"}, purpose: "IPv6 NAT and forwarding rules"})
+	}
+	return append(tools, tool{names: []string{"iptables", "nft"}, purpose: "TCP MSS clamping"})
+}
+
+func clientTools(conf *clientconfig.Configuration) []tool {
+	tools := []tool{
+		{names: []string{"ip"}, purpose: "TUN interface and routes"},
+		{names: []string{"iptables", "nft"}, purpose: "TCP MSS clamping"},
+		{names: []string{"resolvectl"}, purpose: "DNS through systemd-resolved; /etc/resolv.conf is rewritten without it", optional: true},
+	}
+	if conf.KillSwitch {
+		tools = append(tools, tool{names: []string{"nft", "iptables"}, purpose: "the kill switch"})
+	}
+	return tools
+}
@@ -0,0 +1,13 @@
+package check
+
+import clientconfig "tungo/internal/config/client"
+
+// Windows configures interfaces through the system API and runs no tools.
+
+func serverTools(bool) []tool {
+	return nil
+}
+
+func clientTools(*clientconfig.Configuration) []tool {
+	return nil
+}
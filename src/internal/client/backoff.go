@@ -0,0 +1,97 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"syscall"
+	"time"
+
+	"tungo/internal/client/state"
+	"tungo/internal/protocol/noise"
+)
+
+// ErrHandshakeRejected marks a handshake the server answered with a rejection
+// reply: it read msg1 and refused the client key, e.g. because the key is
+// unknown, disabled or lacks its pre-shared key. A mismatched server key gets
+// no reply and fails like an unreachable server.
+var ErrHandshakeRejected = errors.New("handshake rejected by server")
+
+var errResolve = errors.New("failed to resolve server")
+
+// backoff computes exponentially growing reconnect delays with jitter.
+type backoff struct {
+	initial time.Duration
+	max     time.Duration
+	attempt int
+	jitter  func() float64
+}
+
+func newBackoff(initial, maximum time.Duration) *backoff {
+	return &backoff{initial: initial, max: maximum, jitter: rand.Float64}
+}
+
+// next returns the delay before the next attempt. Rejected handshakes wait
+// the full maximum and a loaded server skips two steps ahead. The delay is
+// drawn from the upper half of the step so that it never drops below half
+// of the exponential value.
+func (b *backoff) next(reason state.Reason) time.Duration {
+	if reason == state.ReasonCookieRequired {
+		b.attempt += 2
+	}
+	delay := b.initial
+	for i := 0; i < b.attempt && delay < b.max; i++ {
+		delay *= 2
+	}
+	delay = min(delay, b.max)
+	if reason == state.ReasonAuthRejected {
+		delay = b.max
+	}
+	b.attempt++
+	half := delay / 2
+	return half + time.Duration(b.jitter()*float64(delay-half))
+}
+
+func (b *backoff) reset() {
+	b.attempt = 0
+}
+
+// classify maps a session error to the reason published with Backoff.
+func classify(err error) state.Reason {
+	var (
+		netErr net.Error
+		dnsErr *net.DNSError
+	)
+	switch {
+	case err == nil:
+		return state.ReasonNone
+	case errors.Is(err, noise.ErrCookieRequired):
+		return state.ReasonCookieRequired
+	case errors.Is(err, ErrHandshakeRejected):
+		return state.ReasonAuthRejected
+	case errors.As(err, &dnsErr),
+		errors.As(err, &netErr),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ENETUNREACH),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, errResolve):
+		return state.ReasonNetwork
+	default:
+		return state.ReasonOther
+	}
+}
+
+// handshakeError marks explicit rejections by the server. Everything else,
+// including a closed or reset connection, may be transient and keeps its
+// own meaning.
+func handshakeError(err error) error {
+	if errors.Is(err, noise.ErrRejected) {
+		return fmt.Errorf("%w: %w", ErrHandshakeRejected, err)
+	}
+	return err
+}
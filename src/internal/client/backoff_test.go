@@ -0,0 +1,200 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"tungo/internal/client/state"
+	clientconfig "tungo/internal/config/client"
+	"tungo/internal/config/settings"
+	"tungo/internal/protocol/keys"
+	"tungo/internal/protocol/noise"
+	tcptransport "tungo/internal/transport/tcp"
+)
+
+func TestBackoffGrowsAndCaps(t *testing.T) {
+	b := newBackoff(100*time.Millisecond, time.Second)
+	b.jitter = func() float64 { return 1 }
+
+	want := []time.Duration{100, 200, 400, 800, 1000, 1000}
+	for i, w := range want {
+		if got := b.next(state.ReasonNetwork); got != w*time.Millisecond {
+			t.Fatalf("step %d: delay = %v, want %v", i, got, w*time.Millisecond)
+		}
+	}
+	b.reset()
+	if got := b.next(state.ReasonNetwork); got != 100*time.Millisecond {
+		t.Fatalf("after reset delay = %v, want 100ms", got)
+	}
+}
+
+func TestBackoffJitterStaysInUpperHalf(t *testing.T) {
+	b := newBackoff(time.Second, time.Minute)
+	b.jitter = func() float64 { return 0 }
+	if got := b.next(state.ReasonNetwork); got != 500*time.Millisecond {
+		t.Fatalf("delay = %v, want 500ms", got)
+	}
+}
+
+func TestBackoffReasons(t *testing.T) {
+	b := newBackoff(100*time.Millisecond, 10*time.Second)
+	b.jitter = func() float64 { return 1 }
+
+	if got := b.next(state.ReasonCookieRequired); got != 400*time.Millisecond {
+		t.Fatalf("cookie delay = %v, want 400ms", got)
+	}
+	if got := b.next(state.ReasonAuthRejected); got != 10*time.Second {
+		t.Fatalf("rejected delay = %v, want the maximum", got)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want state.Reason
+	}{
+		{"nil", nil, state.ReasonNone},
+		{"cookie", fmt.Errorf("noise: %w", noise.ErrCookieRequired), state.ReasonCookieRequired},
+		{"rejected", handshakeError(noise.ErrRejected), state.ReasonAuthRejected},
+		{"eof", handshakeError(fmt.Errorf("noise: read response: %w", io.EOF)), state.ReasonNetwork},
+		{"unexpected eof", handshakeError(io.ErrUnexpectedEOF), state.ReasonNetwork},
+		{"reset", handshakeError(&net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}), state.ReasonNetwork},
+		{"refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), state.ReasonNetwork},
+		{"dns", &net.DNSError{Err: "no such host", Name: "vpn.example.com"}, state.ReasonNetwork},
+		{"resolve", fmt.Errorf("%w vpn.example.com: %w", errResolve, context.DeadlineExceeded), state.ReasonNetwork},
+		{"joined", errors.Join(errors.New("other"), handshakeError(noise.ErrRejected)), state.ReasonAuthRejected},
+		{"other", errors.New("unsupported protocol"), state.ReasonOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.err); got != tt.want {
+				t.Fatalf("classify(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandshakeErrorKeepsNetworkFailures(t *testing.T) {
+	for _, err := range []error{
+		syscall.ECONNREFUSED,
+		syscall.ECONNRESET,
+		io.EOF,
+		io.ErrUnexpectedEOF,
+		noise.ErrCookieRequired,
+		errors.New("noise: read msg2: chacha20poly1305: message authentication failed"),
+	} {
+		if wrapped := handshakeError(err); errors.Is(wrapped, ErrHandshakeRejected) {
+			t.Fatalf("%v must not count as rejection", err)
+		}
+	}
+}
+
+// rejectingServer accepts TCP handshakes and refuses every client key.
+func rejectingServer(t *testing.T, serverPublicKey, serverPrivateKey []byte) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			framed, _ := tcptransport.NewFramedConn(conn, 2048)
+			h := noise.NewIKHandshakeServer(serverPublicKey, serverPrivateKey, noPeers{}, nil, nil, nil)
+			_, _ = h.ServerSideHandshake(framed)
+			_ = conn.Close()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+type noPeers struct{}
+
+func (noPeers) Lookup([]byte) (noise.PeerAccess, bool) { return noise.PeerAccess{}, false }
+
+func rejectionTestClient(port int, serverPublicKey []byte, reconnect clientconfig.Reconnect) *Client {
+	deriver := &keys.DefaultKeyDeriver{}
+	clientPublicKey, clientPrivateKey, _ := deriver.GenerateX25519KeyPair()
+	return &Client{
+		configuration: &clientconfig.Configuration{
+			ClientID:         1,
+			Protocol:         settings.TCP,
+			TCPSettings:      mkTCPSettings(port),
+			ClientPublicKey:  clientPublicKey,
+			ClientPrivateKey: clientPrivateKey[:],
+			X25519PublicKey:  serverPublicKey,
+			Reconnect:        reconnect,
+		},
+		tunManager: &clientTestTunManager{},
+	}
+}
+
+func TestClientStopsAfterRejectedHandshakes(t *testing.T) {
+	deriver := &keys.DefaultKeyDeriver{}
+	serverPublicKey, serverPrivateKey, _ := deriver.GenerateX25519KeyPair()
+	port := rejectingServer(t, serverPublicKey, serverPrivateKey[:])
+	client := rejectionTestClient(port, serverPublicKey, clientconfig.Reconnect{InitialDelayMs: 1, MaxDelayMs: 1, RejectionWindowMs: 20})
+	states, cancel := client.Subscribe()
+	defer cancel()
+
+	start := time.Now()
+	err := client.Run(t.Context())
+	if !errors.Is(err, ErrHandshakeRejected) || !strings.Contains(err.Error(), "giving up after") {
+		t.Fatalf("Run() error = %v, want rejection give-up", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Fatalf("gave up after %v, before the rejection window", elapsed)
+	}
+	final := client.states.Current()
+	if final.State != state.Disconnected || final.Reason != state.ReasonAuthRejected {
+		t.Fatalf("final status = %+v, want disconnected with auth rejected", final)
+	}
+	if s := <-states; s.State != state.Disconnected {
+		t.Fatalf("subscriber status = %v, want the latest", s.State)
+	}
+}
+
+func TestClientKeepsRetryingDroppedHandshakes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// read msg1, then drop the client without a rejection reply
+			_, _ = conn.Read(make([]byte, 2048))
+			_ = conn.Close()
+		}
+	}()
+
+	deriver := &keys.DefaultKeyDeriver{}
+	serverPublicKey, _, _ := deriver.GenerateX25519KeyPair()
+	client := rejectionTestClient(listener.Addr().(*net.TCPAddr).Port, serverPublicKey,
+		clientconfig.Reconnect{InitialDelayMs: 1, MaxDelayMs: 1, RejectionWindowMs: 1})
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v, want retries until stopped", err)
+	}
+	if client.reconnects.Load() < 2 {
+		t.Fatalf("reconnects = %d, want retries", client.reconnects.Load())
+	}
+}
@@ -10,18 +10,26 @@ import (
 	"sync/atomic"
 	"time"
 
+	"tungo/internal/client/instance"
+	"tungo/internal/client/netwatch"
+	"tungo/internal/client/proxy"
 	"tungo/internal/client/resume"
+	"tungo/internal/client/state"
 	"tungo/internal/client/tcp"
 	"tungo/internal/client/udp"
-	"tungo/internal/config"
 	clientconfig "tungo/internal/config/client"
 	"tungo/internal/config/settings"
 	"tungo/internal/trafficstats"
 	clienttun "tungo/internal/tun/client"
+	"tungo/internal/tun/userspace"
 )
 
 var (
 	ErrResumeDetected = errors.New("resume detected")
+
+	errNetworkChanged = errors.New("network changed")
+
+	errKillSwitchUnsupported = errors.New("kill switch is not supported on this platform")
 )
 
 type tunManager interface {
@@ -30,6 +38,24 @@ type tunManager interface {
 	SetRouteEndpoint(netip.AddrPort)
 }
 
+// killSwitch is implemented by TUN managers that can block non-tunnel egress.
+type killSwitch interface {
+	EnableKillSwitch() error
+	DisableKillSwitch() error
+}
+
+// uplinkRefresher is implemented by TUN managers that can move the routes
+// around the tunnel to a new uplink.
+type uplinkRefresher interface {
+	RefreshUplink() error
+}
+
+// rebinder is implemented by tunnels that can move their session to a new
+// local address without a new handshake.
+type rebinder interface {
+	Rebind() error
+}
+
 type crypto interface {
 	Encrypt([]byte) ([]byte, error)
 	Decrypt([]byte) ([]byte, error)
@@ -48,109 +74,429 @@ type Client struct {
 	configuration *clientconfig.Configuration
 	tunManager    tunManager
 	ready         atomic.Bool
+	reconnects    atomic.Uint64
+	// tunnel is the packet loop of the current session, nil while connecting.
+	tunnel   atomic.Pointer[activeTunnel]
+	failover failover
+	states   state.Hub
+	// device outlives sessions; it is only touched by the session running
+	// at the time, or by run between sessions.
+	device *persistentTun
+	// proxies is set for the rootless client, which has no TUN device.
+	proxies *proxy.Server
+	// lock marks the instance as running until Run returns.
+	lock *instance.Lock
 }
 
-// New builds a client that owns the transport and TUN lifecycles.
+type protocolTunnel interface {
+	Run() error
+	RTT() time.Duration
+}
+
+// activeTunnel boxes the protocol client so that it can be swapped atomically.
+type activeTunnel struct {
+	protocolTunnel
+}
+
+// New builds a client for the active configuration.
 func New() (*Client, error) {
-	control := config.NewClientControl()
-	slog.Info("starting client")
+	return NewInstance(clientconfig.NewResolver(), "")
+}
+
+// NewInstance builds a client for the named configuration next to the one
+// resolver points to, that owns the transport and TUN lifecycles. It refuses
+// to start while the instance is already running or its TUN or routes clash
+// with another running instance.
+func NewInstance(resolver clientconfig.Resolver, name string) (*Client, error) {
+	if name == "" {
+		slog.Info("starting client")
+	} else {
+		slog.Info("starting client", "instance", name)
+	}
 
-	conf, err := control.Configuration()
+	conf, err := clientconfig.NewInstanceManager(resolver, name).Configuration()
 	if err != nil {
 		return nil, fmt.Errorf("init error: failed to read client configuration: %w", err)
 	}
+	registry := instance.NewRegistry()
+	lock, err := registry.Lock(name)
+	if err != nil {
+		return nil, fmt.Errorf("init error: %w", err)
+	}
+	if err := clientconfig.CheckInstanceConflicts(conf, runningConfigurations(registry, resolver, name)); err != nil {
+		_ = lock.Release()
+		return nil, fmt.Errorf("init error: %w", err)
+	}
 	tunManager, err := clienttun.New(conf)
 	if err != nil {
+		_ = lock.Release()
 		return nil, fmt.Errorf("init error: failed to configure tun: %w", err)
 	}
 
 	return &Client{
 		configuration: conf,
 		tunManager:    tunManager,
+		lock:          lock,
+	}, nil
+}
+
+// runningConfigurations reads the configurations of the running instances
+// other than self.
+func runningConfigurations(registry *instance.Registry, resolver clientconfig.Resolver, self string) map[string]*clientconfig.Configuration {
+	running, err := registry.Running()
+	if err != nil {
+		slog.Warn("failed to list running client instances", "err", err)
+		return nil
+	}
+	confs := make(map[string]*clientconfig.Configuration, len(running))
+	for _, r := range running {
+		if r.Name == self {
+			continue
+		}
+		conf, err := clientconfig.NewInstanceManager(resolver, r.Name).Configuration()
+		if err != nil {
+			slog.Warn("failed to read configuration of a running instance", "instance", r.Name, "err", err)
+			continue
+		}
+		confs[r.Name] = conf
+	}
+	return confs
+}
+
+// NewUserspace builds a client that needs no privileges: the tunnel ends in
+// a userspace network stack that local programs reach through SOCKS5 and
+// HTTP proxies. The proxies require the credentials of the configuration.
+func NewUserspace(resolver clientconfig.Resolver) (*Client, error) {
+	slog.Info("starting rootless client")
+
+	conf, err := clientconfig.NewInstanceManager(resolver, "").Configuration()
+	if err != nil {
+		return nil, fmt.Errorf("init error: failed to read client configuration: %w", err)
+	}
+	if conf.KillSwitch {
+		slog.Warn("kill switch does not apply to the rootless client, ignoring it")
+		conf.KillSwitch = false
+	}
+	if conf.Proxy.Username == "" {
+		return nil, fmt.Errorf("init error: the rootless client requires Proxy.Username and Proxy.Password in the client configuration")
+	}
+	network := userspace.NewManager()
+	socks5Address, httpAddress := conf.Proxy.Addresses()
+	credentials := proxy.Credentials{Username: conf.Proxy.Username, Password: conf.Proxy.Password}
+
+	return &Client{
+		configuration: conf,
+		tunManager:    network,
+		proxies:       proxy.NewServer(network, socks5Address, httpAddress, credentials),
 	}, nil
 }
 
 // Run reconnects until the client is stopped. Context cancellation is a clean
 // stop.
 func (c *Client) Run(ctx context.Context) error {
-	err := c.run(ctx)
+	defer func() { _ = c.lock.Release() }()
+
+	proxiesDone := make(chan struct{})
+	proxiesCtx, stopProxies := context.WithCancel(ctx)
+	defer func() {
+		stopProxies()
+		<-proxiesDone
+	}()
+	if c.proxies != nil {
+		if err := c.proxies.Listen(); err != nil {
+			close(proxiesDone)
+			return err
+		}
+		go func() {
+			defer close(proxiesDone)
+			c.proxies.Serve(proxiesCtx)
+		}()
+	} else {
+		close(proxiesDone)
+	}
+
+	metricsDone := make(chan struct{})
+	metricsCtx, stopMetrics := context.WithCancel(ctx)
+	if c.configuration.MetricsAddress != "" {
+		registry := c.metricsRegistry()
+		go func() {
+			defer close(metricsDone)
+			c.serveMetrics(metricsCtx, registry)
+		}()
+	} else {
+		close(metricsDone)
+	}
+	err := c.runProtected(ctx)
+	stopMetrics()
+	<-metricsDone
 	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
 		return nil
 	}
 	return err
 }
 
+// runProtected wraps run in the kill switch when it is enabled. The kill
+// switch stays up across reconnects and is removed only on a clean stop, so
+// a crash keeps blocking until the next start.
+func (c *Client) runProtected(ctx context.Context) error {
+	ks, supported := c.tunManager.(killSwitch)
+	if !c.configuration.KillSwitch {
+		if supported {
+			// Clears rules left by a crashed run that had the kill switch on.
+			_ = ks.DisableKillSwitch()
+		}
+		return c.run(ctx)
+	}
+	if !supported {
+		return errKillSwitchUnsupported
+	}
+	if err := ks.EnableKillSwitch(); err != nil {
+		return fmt.Errorf("failed to enable kill switch: %w", err)
+	}
+	err := c.run(ctx)
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		if disableErr := ks.DisableKillSwitch(); disableErr != nil {
+			slog.Warn("failed to disable kill switch", "err", disableErr)
+		} else {
+			slog.Info("kill switch disabled")
+		}
+	}
+	return err
+}
+
+// run reconnects with exponential backoff until the context is canceled or
+// the server keeps rejecting the handshake. A network change cuts the wait
+// short.
 func (c *Client) run(ctx context.Context) error {
-	defer c.disposeDevices()
+	defer c.releaseDevice()
 
+	changes := netwatch.Watch(ctx, c.tunNames())
+
+	retry := newBackoff(c.configuration.Reconnect.Delays())
+	rejectionWindow := c.configuration.Reconnect.RejectionWindow()
+	failures := 0
+	// rejectedSince is when the server first rejected the client since the
+	// last session. Other failures in between do not end the streak.
+	var rejectedSince time.Time
 	for ctx.Err() == nil {
-		err := c.runSession(ctx)
+		err := c.runSession(ctx, changes)
 		switch {
 		case err == nil:
+			c.states.Publish(state.Status{State: state.Disconnected})
 			return nil
 		case errors.Is(err, context.Canceled):
+			c.states.Publish(state.Status{State: state.Disconnected})
 			return context.Canceled
-		default:
-			slog.Warn("session error, reconnecting", "err", err)
-			timer := time.NewTimer(500 * time.Millisecond)
-			select {
-			case <-ctx.Done():
-				timer.Stop()
-				return context.Canceled
-			case <-timer.C:
+		}
+
+		// A session that got connected starts the backoff over.
+		if c.states.Current().State == state.Connected {
+			retry.reset()
+			failures = 0
+			rejectedSince = time.Time{}
+		}
+		if errors.Is(err, errNetworkChanged) {
+			slog.Info("network changed, reconnecting")
+			c.reconnects.Add(1)
+			c.refreshUplink()
+			continue
+		}
+		failures++
+		reason := classify(err)
+		if reason == state.ReasonAuthRejected {
+			now := time.Now()
+			if rejectedSince.IsZero() {
+				rejectedSince = now
+			}
+			if rejected := now.Sub(rejectedSince); rejectionWindow > 0 && rejected >= rejectionWindow {
+				err = fmt.Errorf("giving up after handshakes were rejected for %s: %w", rejected.Round(time.Millisecond), err)
+				c.states.Publish(state.Status{State: state.Disconnected, Reason: reason, Err: err, Attempt: failures})
+				return err
 			}
 		}
+
+		delay := retry.next(reason)
+		slog.Warn("session error, reconnecting", "err", err, "reason", reason, "delay", delay)
+		c.reconnects.Add(1)
+		c.states.Publish(state.Status{
+			State:   state.Backoff,
+			Reason:  reason,
+			Err:     err,
+			Attempt: failures,
+			RetryIn: delay,
+		})
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.states.Publish(state.Status{State: state.Disconnected})
+			return context.Canceled
+		case <-changes:
+			timer.Stop()
+			slog.Info("network changed, retrying now")
+			c.refreshUplink()
+		case <-timer.C:
+		}
 	}
+	c.states.Publish(state.Status{State: state.Disconnected})
 	return context.Canceled
 }
 
-func (c *Client) runSession(parentCtx context.Context) error {
+// runSession runs one transport session. On a network change a connected
+// session that can roam moves to the new uplink; any other session is
+// stopped with errNetworkChanged so that it is redialed at once.
+func (c *Client) runSession(parentCtx context.Context, changes <-chan struct{}) error {
 	ctx, cancel := context.WithCancel(parentCtx)
 	defer cancel()
 
-	c.disposeDevices()
+	if c.device == nil {
+		c.disposeDevices()
+	}
 	forward := make(chan error)
 	go func() {
 		forward <- c.forward(ctx)
 	}()
-	select {
-	case <-ctx.Done():
-		cancel()
-		<-forward
-		return ctx.Err()
-	case <-resume.Watch(ctx):
-		cancel()
-		<-forward
-		return ErrResumeDetected
-	case err := <-forward:
-		return err
+	resumed := resume.Watch(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-forward
+			return ctx.Err()
+		case <-resumed:
+			cancel()
+			<-forward
+			return ErrResumeDetected
+		case <-changes:
+			if c.roam() {
+				continue
+			}
+			cancel()
+			<-forward
+			return errNetworkChanged
+		case err := <-forward:
+			return err
+		}
 	}
 }
 
+// roam moves a connected session that supports it, UDP, to the new uplink:
+// the server follows the session to its new source address.
+func (c *Client) roam() bool {
+	active := c.tunnel.Load()
+	if active == nil {
+		return false
+	}
+	tunnel, ok := active.protocolTunnel.(rebinder)
+	if !ok || !c.refreshUplink() {
+		return false
+	}
+	if err := tunnel.Rebind(); err != nil {
+		slog.Warn("failed to move session to the new uplink", "err", err)
+		return false
+	}
+	slog.Info("network changed, moved session to the new uplink")
+	return true
+}
+
+// refreshUplink moves the routes around the kept TUN device to the new
+// uplink. It reports false when they could not be moved.
+func (c *Client) refreshUplink() bool {
+	refresher, ok := c.tunManager.(uplinkRefresher)
+	if !ok || c.device == nil {
+		return true
+	}
+	if err := refresher.RefreshUplink(); err != nil {
+		slog.Warn("failed to move routes to the new uplink", "err", err)
+		return false
+	}
+	return true
+}
+
+// tunNames lists the TUN devices of every profile, which the network monitor
+// must not take for an uplink.
+func (c *Client) tunNames() []string {
+	var names []string
+	for _, s := range []settings.Settings{
+		c.configuration.TCPSettings,
+		c.configuration.UDPSettings,
+		c.configuration.WSSettings,
+	} {
+		if s.TunName != "" {
+			names = append(names, s.TunName)
+		}
+	}
+	return names
+}
+
 func (c *Client) forward(ctx context.Context) error {
-	c.tunManager.SetRouteEndpoint(netip.AddrPort{})
+	if c.device == nil {
+		c.tunManager.SetRouteEndpoint(netip.AddrPort{})
+	}
 
 	transport, crypto, rekey, err := c.establishConnection(ctx)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = transport.Close() }()
-	attachRouteEndpoint(transport, c.tunManager)
+	selected := c.failover.settings()
 
-	device, err := c.tunManager.CreateDevice()
+	device, err := c.sessionDevice(transport, selected)
 	if err != nil {
 		slog.Error("failed to create TUN device", "err", err)
 		return err
 	}
 	defer func() { _ = device.Close() }()
 
-	return c.runTunnel(ctx, transport, trafficstats.WrapTun(device), crypto, rekey)
+	return c.runTunnel(ctx, transport, trafficstats.WrapTun(device), selected, crypto, rekey)
+}
+
+// sessionDevice returns a view of the TUN device for the new session. The
+// device of the previous session is kept when the session uses the same
+// profile and server address, so that a reconnect is invisible to
+// applications; otherwise it is rebuilt with routes for the new server.
+func (c *Client) sessionDevice(transport io.ReadWriteCloser, selected settings.Settings) (io.ReadWriteCloser, error) {
+	endpoint := remoteAddr(transport)
+	if c.device != nil {
+		if c.device.serves(selected, endpoint) {
+			slog.Info("reusing TUN device", "name", selected.TunName)
+			return c.device.session(), nil
+		}
+		c.releaseDevice()
+	}
+
+	if switcher, ok := c.tunManager.(settingsSwitcher); ok {
+		switcher.UseSettings(selected)
+	}
+	attachRouteEndpoint(transport, c.tunManager)
+	device, err := c.tunManager.CreateDevice()
+	if err != nil {
+		return nil, err
+	}
+	c.device = newPersistentTun(device, selected, endpoint)
+	return c.device.session(), nil
+}
+
+// releaseDevice closes the kept TUN device and removes its configuration.
+func (c *Client) releaseDevice() {
+	if c.device != nil {
+		_ = c.device.Close()
+		c.device = nil
+	}
+	c.disposeDevices()
+	c.tunManager.SetRouteEndpoint(netip.AddrPort{})
 }
 
 func (c *Client) Ready() bool {
 	return c.ready.Load()
 }
 
+// Subscribe streams connection state changes, starting with the current one.
+// Call the returned function to stop receiving them.
+func (c *Client) Subscribe() (<-chan state.Status, func()) {
+	return c.states.Subscribe()
+}
+
 func (c *Client) disposeDevices() {
 	if err := c.tunManager.DisposeDevices(); err != nil {
 		slog.Warn("failed to dispose TUN devices", "err", err)
@@ -161,13 +507,10 @@ func (c *Client) runTunnel(
 	ctx context.Context,
 	transport io.ReadWriteCloser,
 	tun io.ReadWriteCloser,
+	selected settings.Settings,
 	crypto crypto,
 	rekey clientRekey,
 ) error {
-	selected, err := c.configuration.ActiveSettings()
-	if err != nil {
-		return err
-	}
 	allowed := allowedSources(selected)
 	switch selected.Protocol {
 	case settings.UDP:
@@ -175,19 +518,28 @@ func (c *Client) runTunnel(
 		if err != nil {
 			return err
 		}
-		c.ready.Store(true)
-		slog.Info("tunneling traffic via TUN device")
-		return tunnel.Run()
+		return c.runActive(tunnel, selected)
 	case settings.TCP, settings.WS, settings.WSS:
 		tunnel := tcp.New(ctx, transport, tun, crypto, rekey, allowed)
-		c.ready.Store(true)
-		slog.Info("tunneling traffic via TUN device")
-		return tunnel.Run()
+		return c.runActive(tunnel, selected)
 	default:
 		return fmt.Errorf("unsupported protocol %q", selected.Protocol)
 	}
 }
 
+func (c *Client) runActive(tunnel protocolTunnel, selected settings.Settings) error {
+	c.tunnel.Store(&activeTunnel{protocolTunnel: tunnel})
+	defer c.tunnel.Store(nil)
+	c.ready.Store(true)
+	c.states.Publish(state.Status{
+		State:    state.Connected,
+		Endpoint: endpointAddress(selected),
+		Protocol: selected.Protocol,
+	})
+	slog.Info("tunneling traffic via TUN device")
+	return tunnel.Run()
+}
+
 func allowedSources(s settings.Settings) map[netip.Addr]struct{} {
 	allowed := make(map[netip.Addr]struct{}, 2)
 	if s.IPv4.IsValid() {
@@ -209,3 +561,10 @@ func attachRouteEndpoint(transport io.ReadWriteCloser, tunManager tunManager) {
 		tunManager.SetRouteEndpoint(addrPort)
 	}
 }
+
+func remoteAddr(transport io.ReadWriteCloser) netip.Addr {
+	if remoteProvider, ok := transport.(interface{ RemoteAddrPort() netip.AddrPort }); ok {
+		return remoteProvider.RemoteAddrPort().Addr().Unmap()
+	}
+	return netip.Addr{}
+}
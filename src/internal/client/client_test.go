@@ -31,6 +31,84 @@ func (m *clientTestTunManager) DisposeDevices() error {
 
 func (*clientTestTunManager) SetRouteEndpoint(netip.AddrPort) {}
 
+type clientTestKillSwitchManager struct {
+	clientTestTunManager
+	enableErr error
+	enabled   atomic.Int32
+	disabled  atomic.Int32
+}
+
+func (m *clientTestKillSwitchManager) EnableKillSwitch() error {
+	m.enabled.Add(1)
+	return m.enableErr
+}
+
+func (m *clientTestKillSwitchManager) DisableKillSwitch() error {
+	m.disabled.Add(1)
+	return nil
+}
+
+func TestClientKillSwitchEnabledAndRemovedOnStop(t *testing.T) {
+	manager := &clientTestKillSwitchManager{}
+	client := &Client{
+		configuration: &clientconfig.Configuration{Protocol: settings.UNKNOWN, KillSwitch: true},
+		tunManager:    manager,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if manager.enabled.Load() != 1 || manager.disabled.Load() != 1 {
+		t.Fatalf("enable=%d disable=%d, want 1/1", manager.enabled.Load(), manager.disabled.Load())
+	}
+}
+
+func TestClientKillSwitchEnableError(t *testing.T) {
+	manager := &clientTestKillSwitchManager{enableErr: errors.New("nft failed")}
+	client := &Client{
+		configuration: &clientconfig.Configuration{Protocol: settings.UNKNOWN, KillSwitch: true},
+		tunManager:    manager,
+	}
+
+	err := client.Run(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "failed to enable kill switch") {
+		t.Fatalf("Run() error = %v, want kill switch error", err)
+	}
+	if manager.disposeCalls.Load() != 0 {
+		t.Fatal("no session must start when the kill switch fails")
+	}
+}
+
+func TestClientKillSwitchUnsupported(t *testing.T) {
+	client := &Client{
+		configuration: &clientconfig.Configuration{Protocol: settings.UNKNOWN, KillSwitch: true},
+		tunManager:    &clientTestTunManager{},
+	}
+
+	if err := client.Run(t.Context()); !errors.Is(err, errKillSwitchUnsupported) {
+		t.Fatalf("Run() error = %v, want %v", err, errKillSwitchUnsupported)
+	}
+}
+
+func TestClientWithoutKillSwitchClearsStaleRules(t *testing.T) {
+	manager := &clientTestKillSwitchManager{}
+	client := &Client{
+		configuration: &clientconfig.Configuration{Protocol: settings.UNKNOWN},
+		tunManager:    manager,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := client.Run(ctx); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if manager.enabled.Load() != 0 || manager.disabled.Load() != 1 {
+		t.Fatalf("enable=%d disable=%d, want 0/1", manager.enabled.Load(), manager.disabled.Load())
+	}
+}
+
 func TestClientStopsDuringReconnectDelay(t *testing.T) {
 	manager := &clientTestTunManager{}
 	client := &Client{
@@ -89,7 +167,7 @@ func TestRunSessionReturnsForwardError(t *testing.T) {
 		tunManager:    manager,
 	}
 
-	err := client.runSession(t.Context())
+	err := client.runSession(t.Context(), nil)
 	if err == nil || !strings.Contains(err.Error(), "unsupported protocol") {
 		t.Fatalf("runSession() error = %v, want unsupported protocol", err)
 	}
@@ -141,7 +219,7 @@ func TestRunSessionCancellationStopsForward(t *testing.T) {
 	ctx, cancel := context.WithCancel(t.Context())
 	t.Cleanup(cancel)
 	done := make(chan error, 1)
-	go func() { done <- client.runSession(ctx) }()
+	go func() { done <- client.runSession(ctx, nil) }()
 
 	var serverConn net.Conn
 	select {
@@ -180,3 +258,107 @@ func TestRunSessionCancellationStopsForward(t *testing.T) {
 		t.Fatal("connection remained open after cancellation")
 	}
 }
+
+func TestRunSessionRedialsOnNetworkChange(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	deriver := &keys.DefaultKeyDeriver{}
+	serverPublicKey, _, _ := deriver.GenerateX25519KeyPair()
+	clientPublicKey, clientPrivateKey, _ := deriver.GenerateX25519KeyPair()
+	client := &Client{
+		configuration: &clientconfig.Configuration{
+			ClientID:         1,
+			Protocol:         settings.TCP,
+			TCPSettings:      mkTCPSettings(listener.Addr().(*net.TCPAddr).Port),
+			ClientPublicKey:  clientPublicKey,
+			ClientPrivateKey: clientPrivateKey[:],
+			X25519PublicKey:  serverPublicKey,
+		},
+		tunManager: &clientTestTunManager{},
+	}
+
+	changes := make(chan struct{}, 1)
+	done := make(chan error, 1)
+	go func() { done <- client.runSession(t.Context(), changes) }()
+
+	select {
+	case conn := <-accepted:
+		t.Cleanup(func() { _ = conn.Close() })
+	case <-time.After(2 * time.Second):
+		t.Fatal("runSession() did not dial")
+	}
+	changes <- struct{}{}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, errNetworkChanged) {
+			t.Fatalf("runSession() error = %v, want errNetworkChanged", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("runSession() did not stop on a network change")
+	}
+}
+
+type roamTestTunnel struct {
+	rttTunnel
+	rebindErr error
+	rebinds   int
+}
+
+func (r *roamTestTunnel) Rebind() error {
+	r.rebinds++
+	return r.rebindErr
+}
+
+type roamTestTunManager struct {
+	clientTestTunManager
+	refreshErr error
+	refreshes  int
+}
+
+func (m *roamTestTunManager) RefreshUplink() error {
+	m.refreshes++
+	return m.refreshErr
+}
+
+func TestRoamRebindsConnectedTunnel(t *testing.T) {
+	manager := &roamTestTunManager{}
+	client := &Client{tunManager: manager, device: &persistentTun{}}
+	if client.roam() {
+		t.Fatal("a session that is still connecting must be redialed")
+	}
+
+	tunnel := &roamTestTunnel{}
+	client.tunnel.Store(&activeTunnel{protocolTunnel: tunnel})
+	if !client.roam() {
+		t.Fatal("expected the connected session to roam")
+	}
+	if manager.refreshes != 1 || tunnel.rebinds != 1 {
+		t.Fatalf("refreshes=%d rebinds=%d, want routes moved before the socket", manager.refreshes, tunnel.rebinds)
+	}
+
+	tunnel.rebindErr = errors.New("network is unreachable")
+	if client.roam() {
+		t.Fatal("a failed rebind must fall back to a redial")
+	}
+	manager.refreshErr = errors.New("no default route")
+	if client.roam() || tunnel.rebinds != 2 {
+		t.Fatal("the socket must not move while the routes could not")
+	}
+
+	client.tunnel.Store(&activeTunnel{protocolTunnel: rttTunnel{}})
+	manager.refreshErr = nil
+	if client.roam() {
+		t.Fatal("a tunnel without Rebind must be redialed")
+	}
+}
@@ -2,8 +2,10 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"net"
 	"net/http"
@@ -11,6 +13,7 @@ import (
 	"strconv"
 	"sync"
 	"time"
+	"tungo/internal/client/state"
 	"tungo/internal/config/settings"
 	"tungo/internal/protocol/chacha20/tcp"
 	"tungo/internal/protocol/chacha20/udp"
@@ -38,24 +41,124 @@ type rekeyV2Handshake interface {
 	FinishRekeyV2(msg2 []byte) (c2s, s2c []byte, err error)
 }
 
+// establishConnection tries the configured endpoints in order, starting
+// with the one that worked last, and records the settings of the endpoint
+// it connected to.
 func (c *Client) establishConnection(
 	ctx context.Context,
 ) (io.ReadWriteCloser, crypto, clientRekey, error) {
-	connSettings, err := c.configuration.ActiveSettings()
+	candidates, err := c.candidateSettings()
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	var errs []error
+	for _, i := range c.failover.order(len(candidates)) {
+		candidate := candidates[i]
+		if candidate.Protocol == settings.UDP && c.failover.fallBack(i, c.configuration.UDPFallbackAfter) {
+			if fallback, ok := c.udpFallback(candidate); ok {
+				candidate = fallback
+			}
+		}
+		if c.device != nil && !c.device.routes(candidate) {
+			// The kept device routes everything but its own server into the
+			// tunnel, DNS included. Release it so that another endpoint, or a
+			// server that must be resolved again, is reached over the uplink;
+			// its own server address stays reachable through the host route.
+			slog.Info("releasing TUN device for another endpoint", "server", preferredHost(candidate.Server))
+			c.releaseDevice()
+		}
+		transport, cr, rekey, err := c.establishEndpoint(ctx, candidate)
+		if err == nil {
+			c.failover.succeeded(i, candidate)
+			return transport, cr, rekey, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil, nil, err
+		}
+		if candidate.Protocol == settings.UDP {
+			failures := c.failover.udpFailed(i)
+			if failures == c.configuration.UDPFallbackAfter {
+				slog.Warn("UDP handshakes keep failing, falling back", "server", preferredHost(candidate.Server), "failures", failures)
+			}
+		}
+		if len(candidates) > 1 {
+			slog.Warn("endpoint failed", "server", preferredHost(candidate.Server), "protocol", candidate.Protocol, "err", err)
+		}
+		errs = append(errs, err)
+	}
+	return nil, nil, nil, errors.Join(errs...)
+}
+
+// candidateSettings returns the endpoints this client can use. Without a TUN
+// manager that can switch profiles, only endpoints sharing the active
+// profile are kept.
+func (c *Client) candidateSettings() ([]settings.Settings, error) {
+	candidates, err := c.configuration.CandidateSettings()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := c.tunManager.(settingsSwitcher); ok || len(c.configuration.Endpoints) == 0 {
+		return candidates, nil
+	}
+	active, err := c.configuration.ActiveSettings()
+	if err != nil {
+		return nil, err
+	}
+	usable := candidates[:0]
+	for _, candidate := range candidates {
+		if candidate.TunName == active.TunName {
+			usable = append(usable, candidate)
+		}
+	}
+	if len(usable) == 0 {
+		return nil, fmt.Errorf("no endpoint uses the active %s profile", active.Protocol)
+	}
+	return usable, nil
+}
+
+func (c *Client) udpFallback(udp settings.Settings) (settings.Settings, bool) {
+	fallback, err := c.configuration.UDPFallbackSettings(udp)
+	if err != nil {
+		return settings.Settings{}, false
+	}
+	if _, ok := c.tunManager.(settingsSwitcher); !ok && fallback.TunName != udp.TunName {
+		return settings.Settings{}, false
+	}
+	return fallback, true
+}
+
+func (c *Client) establishEndpoint(
+	ctx context.Context,
+	connSettings settings.Settings,
+) (io.ReadWriteCloser, crypto, clientRekey, error) {
 	deadline := time.Now().Add(time.Duration(math.Max(float64(connSettings.DialTimeoutMs), 5000)) * time.Millisecond)
 	establishCtx, establishCancel := context.WithDeadline(ctx, deadline)
 	defer establishCancel()
 
+	status := state.Status{Endpoint: endpointAddress(connSettings), Protocol: connSettings.Protocol}
+	if connSettings.Server.Domain != "" {
+		status.State = state.Resolving
+		c.states.Publish(status)
+		if _, err := host.ResolveIP(establishCtx, connSettings.Server); err != nil {
+			return nil, nil, nil, fmt.Errorf("%w %s: %w", errResolve, connSettings.Server.Domain, err)
+		}
+	}
+	status.State = state.Dialing
+	c.states.Publish(status)
 	adapter, err := dial(establishCtx, ctx, connSettings)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("unable to establish %s connection: %w", connSettings.Protocol, err)
 	}
 
-	return c.establishSecuredConnection(establishCtx, adapter, connSettings.Protocol)
+	status.State = state.Handshaking
+	c.states.Publish(status)
+	return c.establishSecuredConnection(establishCtx, adapter, connSettings)
+}
+
+// endpointAddress formats the server of s as host:port for status reports.
+func endpointAddress(s settings.Settings) string {
+	return net.JoinHostPort(preferredHost(s.Server), strconv.Itoa(s.Port))
 }
 
 func dial(
@@ -75,7 +178,7 @@ func dial(
 func (c *Client) establishSecuredConnection(
 	ctx context.Context,
 	adapter io.ReadWriteCloser,
-	protocol settings.Protocol,
+	connSettings settings.Settings,
 ) (io.ReadWriteCloser, crypto, clientRekey, error) {
 	// IK handshake requires client keys
 	if len(c.configuration.ClientPublicKey) != 32 || len(c.configuration.ClientPrivateKey) != 32 {
@@ -87,11 +190,15 @@ func (c *Client) establishSecuredConnection(
 		return nil, nil, nil, fmt.Errorf("server public key not configured (required for IK handshake)")
 	}
 
-	handshake := noise.NewIKHandshakeClient(
+	handshake := noise.NewIKHandshakeClientWithPresharedKey(
 		c.configuration.ClientPublicKey,
 		c.configuration.ClientPrivateKey,
 		c.configuration.X25519PublicKey,
+		c.configuration.PresharedKey,
 	)
+	if connSettings.Encryption == settings.AES256GCM {
+		handshake.OfferAES256GCM()
+	}
 
 	var closeOnce sync.Once
 	closeAdapter := func() {
@@ -105,7 +212,7 @@ func (c *Client) establishSecuredConnection(
 		if ctxErr := ctx.Err(); ctxErr != nil {
 			return nil, nil, nil, ctxErr
 		}
-		return nil, nil, nil, err
+		return nil, nil, nil, handshakeError(err)
 	}
 
 	var (
@@ -113,13 +220,13 @@ func (c *Client) establishSecuredConnection(
 		epochController epochController
 		err             error
 	)
-	switch protocol {
+	switch connSettings.Protocol {
 	case settings.UDP:
 		cr, epochController, err = udp.NewFromHandshake(handshake, false)
 	case settings.TCP, settings.WS, settings.WSS:
 		cr, epochController, err = tcp.NewFromHandshake(handshake, false)
 	default:
-		err = fmt.Errorf("unsupported protocol: %v", protocol)
+		err = fmt.Errorf("unsupported protocol: %v", connSettings.Protocol)
 	}
 	cancelCloseOnContextDone()
 	if ctxErr := ctx.Err(); ctxErr != nil {
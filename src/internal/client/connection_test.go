@@ -41,9 +41,9 @@ func newTestAllowedPeers(peers []testPeer) testPeers {
 	return lookup
 }
 
-func (p testPeers) Lookup(publicKey []byte) (int, bool, bool) {
+func (p testPeers) Lookup(publicKey []byte) (noise.PeerAccess, bool) {
 	peer, found := p[string(publicKey)]
-	return peer.ClientID, peer.Enabled, found
+	return noise.PeerAccess{ClientID: peer.ClientID, Enabled: peer.Enabled}, found
 }
 
 func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
@@ -443,7 +443,7 @@ func TestEstablishSecuredConnection_MissingClientKeys_ClosesAdapter(t *testing.T
 	_, _, _, err := client.establishSecuredConnection(
 		context.Background(),
 		tr,
-		settings.TCP,
+		settings.Settings{Protocol: settings.TCP},
 	)
 	if err == nil || !strings.Contains(err.Error(), "client keys not configured") {
 		t.Fatalf("expected client keys error, got %v", err)
@@ -469,7 +469,7 @@ func TestEstablishSecuredConnection_MissingServerPublicKey_ClosesAdapter(t *test
 	_, _, _, err := client.establishSecuredConnection(
 		context.Background(),
 		tr,
-		settings.TCP,
+		settings.Settings{Protocol: settings.TCP},
 	)
 	if err == nil || !strings.Contains(err.Error(), "server public key not configured") {
 		t.Fatalf("expected server public key error, got %v", err)
@@ -497,7 +497,7 @@ func TestEstablishSecuredConnection_HandshakeError_ClosesAdapter(t *testing.T) {
 	_, _, _, err := client.establishSecuredConnection(
 		context.Background(),
 		tr,
-		settings.TCP,
+		settings.Settings{Protocol: settings.TCP},
 	)
 	if err == nil {
 		t.Fatal("expected handshake error")
@@ -525,7 +525,7 @@ func TestEstablishSecuredConnection_CancelClosesAdapter(t *testing.T) {
 
 	errCh := make(chan error, 1)
 	go func() {
-		_, _, _, err := client.establishSecuredConnection(ctx, transport, settings.TCP)
+		_, _, _, err := client.establishSecuredConnection(ctx, transport, settings.Settings{Protocol: settings.TCP})
 		errCh <- err
 	}()
 
@@ -683,6 +683,7 @@ func TestEstablishSecuredConnection_Success(t *testing.T) {
 		newTestAllowedPeers(allowedPeers),
 		cookieManager,
 		noise.NewLoadMonitor(10000),
+		nil,
 	)
 
 	serverErrCh := make(chan error, 1)
@@ -694,7 +695,7 @@ func TestEstablishSecuredConnection_Success(t *testing.T) {
 	adapter, crypto, coordinator, err := client.establishSecuredConnection(
 		context.Background(),
 		clientAdapter,
-		settings.TCP,
+		settings.Settings{Protocol: settings.TCP},
 	)
 	if err != nil {
 		t.Fatalf("establishSecuredConnection failed: %v", err)
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"io"
+	"net/netip"
+	"sync"
+	"time"
+
+	"tungo/internal/config/settings"
+)
+
+const (
+	// tunQueueSize bounds the packets read from the TUN while no session
+	// drains them. Beyond that the kernel queue fills up and drops.
+	tunQueueSize = 128
+	// tunPacketMaxAge drops queued packets that waited too long for a
+	// session; senders retransmit anything that matters.
+	tunPacketMaxAge = 3 * time.Second
+)
+
+type tunPacket struct {
+	buf [settings.DefaultEthernetMTU]byte
+	n   int
+	at  time.Time
+}
+
+// persistentTun keeps one TUN device open across transport sessions so that
+// its addresses, routes and DNS survive reconnects. A single goroutine reads
+// the device into a bounded queue that the current session drains.
+type persistentTun struct {
+	device   io.ReadWriteCloser
+	settings settings.Settings
+	endpoint netip.Addr
+
+	writeMu   sync.Mutex
+	queue     chan *tunPacket
+	free      chan *tunPacket
+	closed    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
+	readErr   error
+}
+
+func newPersistentTun(device io.ReadWriteCloser, s settings.Settings, endpoint netip.Addr) *persistentTun {
+	t := &persistentTun{
+		device:   device,
+		settings: s,
+		endpoint: endpoint,
+		queue:    make(chan *tunPacket, tunQueueSize),
+		free:     make(chan *tunPacket, tunQueueSize),
+		closed:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for range tunQueueSize {
+		t.free <- &tunPacket{}
+	}
+	go t.pump()
+	return t
+}
+
+func (t *persistentTun) pump() {
+	defer close(t.done)
+	for {
+		var pkt *tunPacket
+		select {
+		case pkt = <-t.free:
+		case <-t.closed:
+			return
+		}
+		n, err := t.device.Read(pkt.buf[:])
+		if err != nil {
+			t.readErr = err
+			return
+		}
+		pkt.n, pkt.at = n, time.Now()
+		select {
+		case t.queue <- pkt:
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+// serves reports whether the device was built for s and endpoint.
+func (t *persistentTun) serves(s settings.Settings, endpoint netip.Addr) bool {
+	return t.settings.TunName == s.TunName &&
+		t.settings.Protocol == s.Protocol &&
+		t.endpoint == endpoint
+}
+
+// routes reports whether the device was built for the profile and server of
+// s, whose host route then keeps the server reachable outside the tunnel.
+// A server known only by its domain is never routed: resolving it goes
+// through the host resolver, which the device points into the tunnel.
+func (t *persistentTun) routes(s settings.Settings) bool {
+	return t.settings.TunName == s.TunName &&
+		t.settings.Protocol == s.Protocol &&
+		t.settings.Server == s.Server &&
+		(s.Server.IPv4 != "" || s.Server.IPv6 != "")
+}
+
+// session returns a view of the device for one transport session. Closing
+// the view detaches the session and leaves the device open.
+func (t *persistentTun) session() io.ReadWriteCloser {
+	return &tunSession{tun: t, closed: make(chan struct{})}
+}
+
+func (t *persistentTun) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		err = t.device.Close()
+		<-t.done
+	})
+	return err
+}
+
+type tunSession struct {
+	tun       *persistentTun
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *tunSession) Read(p []byte) (int, error) {
+	for {
+		// A detached session must not take packets meant for the next one.
+		select {
+		case <-s.closed:
+			return 0, io.ErrClosedPipe
+		default:
+		}
+		select {
+		case <-s.closed:
+			return 0, io.ErrClosedPipe
+		case <-s.tun.done:
+			if s.tun.readErr != nil {
+				return 0, s.tun.readErr
+			}
+			return 0, io.ErrClosedPipe
+		case pkt := <-s.tun.queue:
+			if time.Since(pkt.at) > tunPacketMaxAge {
+				s.tun.free <- pkt
+				continue
+			}
+			n := copy(p, pkt.buf[:pkt.n])
+			s.tun.free <- pkt
+			return n, nil
+		}
+	}
+}
+
+// Write serializes writers: a session that is shutting down may still be
+// writing when the next one starts.
+func (s *tunSession) Write(p []byte) (int, error) {
+	select {
+	case <-s.closed:
+		return 0, io.ErrClosedPipe
+	default:
+	}
+	s.tun.writeMu.Lock()
+	defer s.tun.writeMu.Unlock()
+	return s.tun.device.Write(p)
+}
+
+func (s *tunSession) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
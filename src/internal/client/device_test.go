@@ -0,0 +1,226 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"tungo/internal/config/settings"
+)
+
+// deviceTestTun is a TUN stand-in fed through a channel.
+type deviceTestTun struct {
+	packets chan []byte
+	closed  chan struct{}
+	once    sync.Once
+	mu      sync.Mutex
+	written [][]byte
+}
+
+func newDeviceTestTun() *deviceTestTun {
+	return &deviceTestTun{packets: make(chan []byte, 16), closed: make(chan struct{})}
+}
+
+func (d *deviceTestTun) Read(p []byte) (int, error) {
+	select {
+	case pkt := <-d.packets:
+		return copy(p, pkt), nil
+	case <-d.closed:
+		return 0, io.EOF
+	}
+}
+
+func (d *deviceTestTun) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.written = append(d.written, bytes.Clone(p))
+	return len(p), nil
+}
+
+func (d *deviceTestTun) Close() error {
+	d.once.Do(func() { close(d.closed) })
+	return nil
+}
+
+func (d *deviceTestTun) isClosed() bool {
+	select {
+	case <-d.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+func readWithin(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		buf := make([]byte, settings.DefaultEthernetMTU)
+		n, err := r.Read(buf)
+		done <- result{buf[:n], err}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			t.Fatalf("Read() error = %v", res.err)
+		}
+		return res.data
+	case <-time.After(time.Second):
+		t.Fatal("Read() did not return")
+		return nil
+	}
+}
+
+func TestPersistentTunQueuesPacketsBetweenSessions(t *testing.T) {
+	device := newDeviceTestTun()
+	tun := newPersistentTun(device, settings.Settings{}, netip.Addr{})
+	defer func() { _ = tun.Close() }()
+
+	first := tun.session()
+	device.packets <- []byte("one")
+	if got := readWithin(t, first); string(got) != "one" {
+		t.Fatalf("first session read %q, want one", got)
+	}
+	_ = first.Close()
+	if _, err := first.Read(make([]byte, 8)); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("closed session Read() error = %v, want ErrClosedPipe", err)
+	}
+	if _, err := first.Write([]byte("x")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Fatalf("closed session Write() error = %v, want ErrClosedPipe", err)
+	}
+
+	// read while no session is attached, delivered to the next one
+	device.packets <- []byte("two")
+	second := tun.session()
+	if got := readWithin(t, second); string(got) != "two" {
+		t.Fatalf("second session read %q, want two", got)
+	}
+	if _, err := second.Write([]byte("reply")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if device.isClosed() {
+		t.Fatal("closing a session must keep the device open")
+	}
+	if len(device.written) != 1 || string(device.written[0]) != "reply" {
+		t.Fatalf("device writes = %q", device.written)
+	}
+}
+
+func TestPersistentTunDropsStalePackets(t *testing.T) {
+	device := newDeviceTestTun()
+	tun := newPersistentTun(device, settings.Settings{}, netip.Addr{})
+	defer func() { _ = tun.Close() }()
+
+	stale := <-tun.free
+	stale.n = copy(stale.buf[:], "stale")
+	stale.at = time.Now().Add(-2 * tunPacketMaxAge)
+	tun.queue <- stale
+	device.packets <- []byte("fresh")
+
+	if got := readWithin(t, tun.session()); string(got) != "fresh" {
+		t.Fatalf("read %q, want fresh", got)
+	}
+}
+
+func TestPersistentTunCloseStopsSessions(t *testing.T) {
+	device := newDeviceTestTun()
+	tun := newPersistentTun(device, settings.Settings{}, netip.Addr{})
+	session := tun.session()
+
+	if err := tun.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !device.isClosed() {
+		t.Fatal("Close() must close the device")
+	}
+	if _, err := session.Read(make([]byte, 8)); err == nil {
+		t.Fatal("expected Read() to fail after Close()")
+	}
+}
+
+func TestPersistentTunServes(t *testing.T) {
+	udp := settings.Settings{Addressing: settings.Addressing{TunName: "tun0"}, Protocol: settings.UDP}
+	server := netip.MustParseAddr("198.51.100.1")
+	tun := &persistentTun{settings: udp, endpoint: server}
+
+	if !tun.serves(udp, server) {
+		t.Fatal("expected the same profile and server to be served")
+	}
+	if tun.serves(udp, netip.MustParseAddr("198.51.100.2")) {
+		t.Fatal("a new server address needs new routes")
+	}
+	ws := settings.Settings{Addressing: settings.Addressing{TunName: "tun2"}, Protocol: settings.WS}
+	if tun.serves(ws, server) {
+		t.Fatal("a new profile needs a new device")
+	}
+}
+
+// deviceTestTunManager hands out deviceTestTun devices.
+type deviceTestTunManager struct {
+	created  int
+	disposed int
+	devices  []*deviceTestTun
+}
+
+func (m *deviceTestTunManager) CreateDevice() (io.ReadWriteCloser, error) {
+	m.created++
+	device := newDeviceTestTun()
+	m.devices = append(m.devices, device)
+	return device, nil
+}
+
+func (m *deviceTestTunManager) DisposeDevices() error {
+	m.disposed++
+	return nil
+}
+
+func (*deviceTestTunManager) SetRouteEndpoint(netip.AddrPort) {}
+
+type deviceTestTransport struct {
+	io.ReadWriteCloser
+	remote netip.AddrPort
+}
+
+func (t deviceTestTransport) RemoteAddrPort() netip.AddrPort { return t.remote }
+
+func TestSessionDeviceReusedAcrossReconnects(t *testing.T) {
+	manager := &deviceTestTunManager{}
+	client := &Client{tunManager: manager}
+	selected := settings.Settings{Addressing: settings.Addressing{TunName: "tun0"}, Protocol: settings.UDP}
+	transport := deviceTestTransport{remote: netip.MustParseAddrPort("198.51.100.1:9090")}
+
+	first, err := client.sessionDevice(transport, selected)
+	if err != nil {
+		t.Fatalf("sessionDevice() error = %v", err)
+	}
+	_ = first.Close()
+	second, err := client.sessionDevice(transport, selected)
+	if err != nil {
+		t.Fatalf("sessionDevice() error = %v", err)
+	}
+	_ = second.Close()
+	if manager.created != 1 || manager.disposed != 0 {
+		t.Fatalf("created=%d disposed=%d, want the device kept", manager.created, manager.disposed)
+	}
+
+	moved := deviceTestTransport{remote: netip.MustParseAddrPort("198.51.100.2:9090")}
+	if _, err := client.sessionDevice(moved, selected); err != nil {
+		t.Fatalf("sessionDevice() error = %v", err)
+	}
+	if manager.created != 2 || manager.disposed != 1 || !manager.devices[0].isClosed() {
+		t.Fatalf("created=%d disposed=%d, want the device rebuilt for the new server", manager.created, manager.disposed)
+	}
+
+	client.releaseDevice()
+	if client.device != nil || !manager.devices[1].isClosed() {
+		t.Fatal("releaseDevice() must close the kept device")
+	}
+}
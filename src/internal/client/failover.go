@@ -0,0 +1,83 @@
+package client
+
+import (
+	"sync"
+
+	"tungo/internal/config/settings"
+)
+
+// settingsSwitcher is implemented by TUN managers that can rebuild the device
+// for the profile of another endpoint.
+type settingsSwitcher interface {
+	UseSettings(settings.Settings)
+}
+
+// failover orders endpoint attempts and keeps what it learned across
+// sessions: the endpoint that worked last and the failed UDP handshakes per
+// endpoint. The zero value is ready to use.
+type failover struct {
+	mu          sync.Mutex
+	preferred   int
+	udpFailures map[int]int
+	active      settings.Settings
+}
+
+// order returns the endpoint indexes to try: the last working one first,
+// then the rest in configured order.
+func (f *failover) order(n int) []int {
+	f.mu.Lock()
+	preferred := f.preferred
+	f.mu.Unlock()
+	if preferred >= n {
+		preferred = 0
+	}
+	order := make([]int, 0, n)
+	order = append(order, preferred)
+	for i := 0; i < n; i++ {
+		if i != preferred {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// fallBack reports whether the UDP endpoint i failed at least after times in
+// a row.
+func (f *failover) fallBack(i, after int) bool {
+	if after <= 0 {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.udpFailures[i] >= after
+}
+
+func (f *failover) udpFailed(i int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.udpFailures == nil {
+		f.udpFailures = make(map[int]int)
+	}
+	f.udpFailures[i]++
+	return f.udpFailures[i]
+}
+
+// succeeded remembers endpoint i as the preferred one. A UDP success clears
+// its failure count; a fallback success keeps it, so the endpoint stays on
+// the fallback for the rest of the run.
+func (f *failover) succeeded(i int, s settings.Settings) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.preferred = i
+	f.active = s
+	if s.Protocol == settings.UDP {
+		delete(f.udpFailures, i)
+	}
+}
+
+// settings returns the settings of the last established connection.
+func (f *failover) settings() settings.Settings {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+
+	clientconfig "tungo/internal/config/client"
+	"tungo/internal/config/settings"
+)
+
+// failoverTestTunManager can switch profiles, so every endpoint is usable.
+type failoverTestTunManager struct {
+	clientTestTunManager
+	used []settings.Settings
+}
+
+func (m *failoverTestTunManager) UseSettings(s settings.Settings) {
+	m.used = append(m.used, s)
+}
+
+func TestFailoverOrderStartsWithPreferred(t *testing.T) {
+	var f failover
+	if got := f.order(3); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Fatalf("order() = %v, want [0 1 2]", got)
+	}
+	f.succeeded(2, settings.Settings{Protocol: settings.TCP})
+	if got := f.order(3); !slices.Equal(got, []int{2, 0, 1}) {
+		t.Fatalf("order() = %v, want [2 0 1]", got)
+	}
+	// a shorter endpoint list after reload falls back to the first entry
+	if got := f.order(2); !slices.Equal(got, []int{0, 1}) {
+		t.Fatalf("order() = %v, want [0 1]", got)
+	}
+}
+
+func TestFailoverUDPFailures(t *testing.T) {
+	var f failover
+	f.udpFailed(0)
+	if f.fallBack(0, 2) {
+		t.Fatal("fallback after one failure, want two")
+	}
+	f.udpFailed(0)
+	if !f.fallBack(0, 2) {
+		t.Fatal("expected fallback after two failures")
+	}
+	if f.fallBack(0, 0) {
+		t.Fatal("zero threshold must disable the fallback")
+	}
+	f.succeeded(0, settings.Settings{Protocol: settings.UDP})
+	if f.fallBack(0, 2) {
+		t.Fatal("a UDP success must reset the failures")
+	}
+}
+
+func TestEstablishConnectionTriesEndpointsInOrder(t *testing.T) {
+	conf := &clientconfig.Configuration{
+		ClientID:    1,
+		Protocol:    settings.TCP,
+		TCPSettings: mkTCPSettings(1),
+		WSSettings:  mkWSSettings("127.0.0.1", 9, settings.WS),
+		Endpoints: []clientconfig.Endpoint{
+			{Protocol: settings.TCP},
+			{Protocol: settings.WS},
+		},
+	}
+	client := &Client{configuration: conf, tunManager: &failoverTestTunManager{}}
+
+	_, _, _, err := client.establishConnection(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	msg := err.Error()
+	tcpAt, wsAt := strings.Index(msg, "unable to establish TCP"), strings.Index(msg, "unable to establish WS")
+	if tcpAt < 0 || wsAt < 0 || tcpAt > wsAt {
+		t.Fatalf("expected TCP then WS attempts, got %v", err)
+	}
+}
+
+func TestEstablishConnectionSkipsOtherProfilesWithoutSwitcher(t *testing.T) {
+	conf := &clientconfig.Configuration{
+		ClientID:    1,
+		Protocol:    settings.TCP,
+		TCPSettings: mkTCPSettings(1),
+		WSSettings:  mkWSSettings("127.0.0.1", 9, settings.WS),
+		Endpoints: []clientconfig.Endpoint{
+			{Protocol: settings.WS},
+			{Protocol: settings.TCP},
+		},
+	}
+	conf.TCPSettings.TunName = "tcp0"
+	conf.WSSettings.TunName = "ws0"
+	client := &Client{configuration: conf, tunManager: &clientTestTunManager{}}
+
+	_, _, _, err := client.establishConnection(context.Background())
+	if err == nil || strings.Contains(err.Error(), "WS") {
+		t.Fatalf("expected only the TCP endpoint to be tried, got %v", err)
+	}
+}
+
+func TestEstablishConnectionFallsBackFromUDP(t *testing.T) {
+	conf := &clientconfig.Configuration{
+		ClientID:         1,
+		Protocol:         settings.UDP,
+		UDPSettings:      mkUDPSettings(70000),
+		WSSettings:       mkWSSettings("127.0.0.1", 9, settings.WS),
+		UDPFallbackAfter: 2,
+	}
+	client := &Client{configuration: conf, tunManager: &failoverTestTunManager{}}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		_, _, _, err := client.establishConnection(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "unable to establish UDP") {
+			t.Fatalf("attempt %d: expected UDP error, got %v", attempt, err)
+		}
+	}
+	_, _, _, err := client.establishConnection(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unable to establish WS") {
+		t.Fatalf("expected WS fallback attempt, got %v", err)
+	}
+}
+
+func TestEstablishConnectionKeepsDeviceAcrossFailedAttempts(t *testing.T) {
+	conf := &clientconfig.Configuration{
+		ClientID:    1,
+		Protocol:    settings.TCP,
+		TCPSettings: mkTCPSettings(1),
+		WSSettings:  mkWSSettings("127.0.0.1", 9, settings.WS),
+	}
+	client := &Client{configuration: conf, tunManager: &failoverTestTunManager{}}
+	tun := newDeviceTestTun()
+	client.device = newPersistentTun(tun, conf.TCPSettings, netip.MustParseAddr("127.0.0.1"))
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if _, _, _, err := client.establishConnection(context.Background()); err == nil {
+			t.Fatalf("attempt %d: expected error", attempt)
+		}
+		if client.device == nil || tun.isClosed() {
+			t.Fatalf("attempt %d: the device was released", attempt)
+		}
+	}
+
+	conf.Endpoints = []clientconfig.Endpoint{{Protocol: settings.WS}}
+	if _, _, _, err := client.establishConnection(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	if client.device != nil || !tun.isClosed() {
+		t.Fatal("the device must be released before dialing another endpoint")
+	}
+}
+
+func TestEstablishConnectionReleasesDeviceBeforeResolving(t *testing.T) {
+	tcpSettings := mkTCPSettings(1)
+	tcpSettings.Server = mustHost("localhost")
+	conf := &clientconfig.Configuration{
+		ClientID:    1,
+		Protocol:    settings.TCP,
+		TCPSettings: tcpSettings,
+	}
+	client := &Client{configuration: conf, tunManager: &failoverTestTunManager{}}
+	tun := newDeviceTestTun()
+	client.device = newPersistentTun(tun, conf.TCPSettings, netip.MustParseAddr("127.0.0.1"))
+
+	if _, _, _, err := client.establishConnection(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+	if client.device != nil || !tun.isClosed() {
+		t.Fatal("the device must be released before resolving the server domain, whose DNS it routes into the tunnel")
+	}
+}
@@ -0,0 +1,5 @@
+package instance
+
+func runtimeDir() string {
+	return "/var/run/tungo"
+}
@@ -0,0 +1,14 @@
+package instance
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func runtimeDir() string {
+	programData := os.Getenv("ProgramData")
+	if programData == "" {
+		programData = `C:\ProgramData`
+	}
+	return filepath.Join(programData, "TunGo", "run")
+}
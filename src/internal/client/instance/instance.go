@@ -0,0 +1,161 @@
+// Package instance keeps track of the client instances running on this host.
+// Every running client holds an exclusive lock on a file in the runtime
+// directory that records its PID; the lock goes away with the process, so a
+// crash never leaves an instance that looks alive.
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrRunning is returned by Lock when the instance is already running.
+var ErrRunning = errors.New("instance is already running")
+
+const (
+	lockPrefix = "client"
+	lockSuffix = ".lock"
+)
+
+// Running describes a running instance. Name is empty for the instance that
+// runs the active configuration.
+type Running struct {
+	Name string
+	PID  int
+}
+
+// Registry locks and finds instances in a runtime directory.
+type Registry struct {
+	dir string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{dir: runtimeDir()}
+}
+
+// Lock is held for as long as the instance runs.
+type Lock struct {
+	file *os.File
+}
+
+// Lock claims the named instance for this process.
+func (r *Registry) Lock(name string) (*Lock, error) {
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create runtime directory: %w", err)
+	}
+	file, err := os.OpenFile(r.lockPath(name), os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open instance lock: %w", err)
+	}
+	if err := tryLock(file); err != nil {
+		_ = file.Close()
+		if errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("%w: %s", ErrRunning, displayName(name))
+		}
+		return nil, fmt.Errorf("failed to lock instance: %w", err)
+	}
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+	return &Lock{file: file}, nil
+}
+
+// Release lets the instance be started again. The file is left in place:
+// removing it could let two processes lock different files of the same name.
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Running lists the running instances, ordered by name.
+func (r *Registry) Running() ([]Running, error) {
+	paths, err := filepath.Glob(filepath.Join(r.dir, lockPrefix+"*"+lockSuffix))
+	if err != nil {
+		return nil, err
+	}
+	var running []Running
+	for _, path := range paths {
+		name, ok := lockName(filepath.Base(path))
+		if !ok {
+			continue
+		}
+		pid, held, err := probe(path)
+		if err != nil {
+			return nil, err
+		}
+		if held {
+			running = append(running, Running{Name: name, PID: pid})
+		}
+	}
+	slices.SortFunc(running, func(a, b Running) int { return strings.Compare(a.Name, b.Name) })
+	return running, nil
+}
+
+// Stop asks the named instance to shut down cleanly.
+func (r *Registry) Stop(name string) error {
+	pid, held, err := probe(r.lockPath(name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if !held {
+		return fmt.Errorf("instance %s is not running", displayName(name))
+	}
+	if pid <= 0 {
+		return fmt.Errorf("instance %s did not record its PID", displayName(name))
+	}
+	return terminate(pid)
+}
+
+// probe reports whether the lock at path is held and by which PID.
+func probe(path string) (int, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() { _ = file.Close() }()
+	switch err := tryLock(file); {
+	case err == nil:
+		return 0, false, nil
+	case !errors.Is(err, errLocked):
+		return 0, false, fmt.Errorf("failed to probe %s: %w", path, err)
+	}
+	data := make([]byte, 32)
+	n, _ := file.ReadAt(data, 0)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data[:n])))
+	return pid, true, nil
+}
+
+func (r *Registry) lockPath(name string) string {
+	if name == "" {
+		return filepath.Join(r.dir, lockPrefix+lockSuffix)
+	}
+	return filepath.Join(r.dir, lockPrefix+"."+name+lockSuffix)
+}
+
+func lockName(base string) (string, bool) {
+	rest, ok := strings.CutPrefix(base, lockPrefix)
+	if !ok {
+		return "", false
+	}
+	if rest, ok = strings.CutSuffix(rest, lockSuffix); !ok {
+		return "", false
+	}
+	if rest == "" {
+		return "", true
+	}
+	return strings.CutPrefix(rest, ".")
+}
+
+func displayName(name string) string {
+	if name == "" {
+		return "(active)"
+	}
+	return name
+}
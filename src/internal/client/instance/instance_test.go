@@ -0,0 +1,90 @@
+package instance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLockExcludesSecondRun(t *testing.T) {
+	r := &Registry{dir: t.TempDir()}
+	lock, err := r.Lock("prod")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	if _, err := r.Lock("prod"); !errors.Is(err, ErrRunning) {
+		t.Fatalf("second Lock() error = %v, want %v", err, ErrRunning)
+	}
+	other, err := r.Lock("")
+	if err != nil {
+		t.Fatalf("Lock(\"\") error = %v", err)
+	}
+	defer func() { _ = other.Release() }()
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	again, err := r.Lock("prod")
+	if err != nil {
+		t.Fatalf("Lock() after release error = %v", err)
+	}
+	_ = again.Release()
+}
+
+func TestRunningListsHeldLocks(t *testing.T) {
+	dir := t.TempDir()
+	r := &Registry{dir: dir}
+	for _, name := range []string{"staging", "", "prod"} {
+		lock, err := r.Lock(name)
+		if err != nil {
+			t.Fatalf("Lock(%q) error = %v", name, err)
+		}
+		t.Cleanup(func() { _ = lock.Release() })
+	}
+	stale, err := r.Lock("stale")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	_ = stale.Release()
+	_ = os.WriteFile(filepath.Join(dir, "clientlock"), nil, 0o644)
+
+	running, err := r.Running()
+	if err != nil {
+		t.Fatalf("Running() error = %v", err)
+	}
+	pid := os.Getpid()
+	want := []Running{{Name: "", PID: pid}, {Name: "prod", PID: pid}, {Name: "staging", PID: pid}}
+	if !reflect.DeepEqual(running, want) {
+		t.Fatalf("Running() = %+v, want %+v", running, want)
+	}
+}
+
+func TestStopRequiresRunningInstance(t *testing.T) {
+	r := &Registry{dir: t.TempDir()}
+	if err := r.Stop("prod"); err == nil {
+		t.Fatal("expected error for an instance that never ran")
+	}
+	lock, err := r.Lock("prod")
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	_ = lock.Release()
+	if err := r.Stop("prod"); err == nil {
+		t.Fatal("expected error for a stopped instance")
+	}
+}
+
+func TestLockName(t *testing.T) {
+	for base, want := range map[string]string{"client.lock": "", "client.prod.lock": "prod"} {
+		if got, ok := lockName(base); !ok || got != want {
+			t.Errorf("lockName(%q) = %q, %v", base, got, ok)
+		}
+	}
+	for _, base := range []string{"clientlock", "client.json", "server.lock"} {
+		if _, ok := lockName(base); ok {
+			t.Errorf("lockName(%q) accepted", base)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package instance
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var errLocked = errors.New("locked")
+
+// lockOffset places the lock past the PID, since Windows locks are
+// mandatory and would otherwise keep probe from reading it.
+const lockOffset = 1 << 20
+
+func tryLock(file *os.File) error {
+	overlapped := windows.Overlapped{Offset: lockOffset}
+	err := windows.LockFileEx(
+		windows.Handle(file.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, &overlapped,
+	)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLocked
+	}
+	return err
+}
+
+func terminate(int) error {
+	return errors.New("stopping an instance is not supported on Windows; stop it where it was started")
+}
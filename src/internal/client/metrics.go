@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"tungo/internal/client/state"
+	"tungo/internal/metrics"
+	"tungo/internal/trafficstats"
+)
+
+// serveMetrics runs the metrics listener. Failing to open it is logged but
+// does not stop the tunnel.
+func (c *Client) serveMetrics(ctx context.Context, registry *metrics.Registry) {
+	if err := metrics.Serve(ctx, c.configuration.MetricsAddress, c.configuration.MetricsAllowRemote, registry); err != nil {
+		slog.Warn("metrics listener unavailable", "err", err)
+	}
+}
+
+// metricsRegistry describes the client counters. It installs a global traffic
+// collector when none is set, so it must be called before the TUN is wrapped.
+func (c *Client) metricsRegistry() *metrics.Registry {
+	if trafficstats.Global() == nil {
+		trafficstats.SetGlobal(trafficstats.NewCollector(time.Second, 0.35))
+	}
+
+	registry := metrics.NewRegistry()
+	registry.Gauge("tungo_client_connected",
+		"Whether a tunnel session is established.",
+		func() float64 {
+			if c.tunnel.Load() != nil {
+				return 1
+			}
+			return 0
+		})
+	registry.GaugeVec("tungo_client_state",
+		"Connection lifecycle state: 1 for the current state, 0 for the others.",
+		"state",
+		func() map[string]float64 {
+			current := c.states.Current().State
+			values := make(map[string]float64, len(state.States()))
+			for _, s := range state.States() {
+				values[s.String()] = 0
+			}
+			values[current.String()] = 1
+			return values
+		})
+	registry.Counter("tungo_client_reconnects_total",
+		"Sessions restarted after an error.",
+		c.reconnects.Load)
+	registry.Gauge("tungo_client_rtt_seconds",
+		"Round trip of the last answered keepalive ping.",
+		func() float64 {
+			tunnel := c.tunnel.Load()
+			if tunnel == nil {
+				return 0
+			}
+			return tunnel.RTT().Seconds()
+		})
+	metrics.RegisterTraffic(registry)
+	return registry
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"tungo/internal/client/state"
+	"tungo/internal/trafficstats"
+)
+
+type rttTunnel struct{ rtt time.Duration }
+
+func (rttTunnel) Run() error           { return nil }
+func (t rttTunnel) RTT() time.Duration { return t.rtt }
+
+func TestClientMetricsExportSessionState(t *testing.T) {
+	t.Cleanup(func() { trafficstats.SetGlobal(nil) })
+	trafficstats.SetGlobal(nil)
+
+	client := &Client{}
+	registry := client.metricsRegistry()
+	if trafficstats.Global() == nil {
+		t.Fatal("metrics did not install a traffic collector")
+	}
+	client.reconnects.Add(2)
+	client.tunnel.Store(&activeTunnel{protocolTunnel: rttTunnel{rtt: 25 * time.Millisecond}})
+	client.states.Publish(state.Status{State: state.Connected})
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"tungo_client_connected 1",
+		`tungo_client_state{state="connected"} 1`,
+		`tungo_client_state{state="backoff"} 0`,
+		"tungo_client_reconnects_total 2",
+		"tungo_client_rtt_seconds 0.025",
+		"tungo_tx_bytes_total 0",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, out.String())
+		}
+	}
+
+	client.tunnel.Store(nil)
+	client.states.Publish(state.Status{State: state.Backoff})
+	out.Reset()
+	_, _ = registry.WriteTo(&out)
+	for _, line := range []string{
+		"tungo_client_connected 0",
+		`tungo_client_state{state="connected"} 0`,
+		`tungo_client_state{state="backoff"} 1`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, out.String())
+		}
+	}
+}
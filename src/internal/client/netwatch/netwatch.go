@@ -0,0 +1,56 @@
+// Package netwatch reports changes of the host network, such as a switch
+// from Wi-Fi to Ethernet or a new DHCP lease, so that the client can move its
+// session to the new uplink right away instead of waiting for keepalives to
+// time out.
+package netwatch
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// settle is how long the network has to stay quiet before a change is
+// reported. Joining a network emits a burst of link, address and route
+// events.
+const settle = 300 * time.Millisecond
+
+// Watch reports network changes until ctx is done. Interfaces named in
+// ignore, such as the client's own TUN devices, are not watched. A change
+// that arrives while the previous one is unread is merged into it. Where no
+// monitor is available the channel never fires.
+func Watch(ctx context.Context, ignore []string) <-chan struct{} {
+	out := make(chan struct{}, 1)
+	events, err := monitor(ctx, ignore)
+	if err != nil {
+		slog.Warn("network change detection is unavailable", "err", err)
+		return out
+	}
+	if events != nil {
+		go debounce(ctx, events, out, settle)
+	}
+	return out
+}
+
+// debounce signals out once events stayed quiet for the given duration.
+func debounce(ctx context.Context, events <-chan struct{}, out chan<- struct{}, quiet time.Duration) {
+	timer := time.NewTimer(quiet)
+	timer.Stop()
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+			timer.Reset(quiet)
+		case <-timer.C:
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
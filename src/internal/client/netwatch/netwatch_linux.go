@@ -0,0 +1,302 @@
+package netwatch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"slices"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// monitor subscribes to rtnetlink link, address and route notifications and
+// sends on the returned channel whenever the uplink may have moved.
+func monitor(ctx context.Context, ignore []string) (<-chan struct{}, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netlink socket: %w", err)
+	}
+	groups := unix.RTMGRP_LINK |
+		unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR |
+		unix.RTMGRP_IPV4_ROUTE | unix.RTMGRP_IPV6_ROUTE
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: uint32(groups)}); err != nil {
+		_ = unix.Close(fd)
+		return nil, fmt.Errorf("failed to subscribe to netlink groups: %w", err)
+	}
+	file := os.NewFile(uintptr(fd), "netlink")
+	conn, err := file.SyscallConn()
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	// The snapshot is taken after subscribing so that nothing in between is
+	// missed; replayed notifications are absorbed by the state.
+	state := newNetState(ignore)
+	if err := state.load(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	events := make(chan struct{}, 1)
+	go func() {
+		<-ctx.Done()
+		_ = file.Close()
+	}()
+	go func() {
+		defer close(events)
+		buf := make([]byte, 1<<16)
+		for {
+			var (
+				n       int
+				recvErr error
+			)
+			err := conn.Read(func(fd uintptr) bool {
+				n, _, recvErr = unix.Recvfrom(int(fd), buf, 0)
+				return !errors.Is(recvErr, unix.EAGAIN)
+			})
+			if err == nil {
+				err = recvErr
+			}
+			changed := false
+			switch {
+			case errors.Is(err, unix.ENOBUFS):
+				// Notifications were dropped; start over from a fresh snapshot.
+				state = newNetState(ignore)
+				if loadErr := state.load(); loadErr != nil {
+					slog.Warn("failed to reload network state", "err", loadErr)
+				}
+				changed = true
+			case err != nil:
+				if ctx.Err() == nil {
+					slog.Warn("network change detection stopped", "err", err)
+				}
+				return
+			default:
+				msgs, parseErr := syscall.ParseNetlinkMessage(buf[:n])
+				if parseErr != nil {
+					continue
+				}
+				for _, msg := range msgs {
+					if state.apply(msg) {
+						changed = true
+					}
+				}
+			}
+			if changed {
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+type link struct {
+	name    string
+	running bool
+	ignored bool
+}
+
+type addrKey struct {
+	index uint32
+	addr  netip.Addr
+}
+
+type routeKey struct {
+	family   uint8
+	oif      uint32
+	gateway  netip.Addr
+	priority uint32
+}
+
+// netState tracks what the uplink depends on: running links, global
+// addresses and main-table default routes. Notifications that do not change
+// it, such as lease renewals and router advertisements, are not changes.
+type netState struct {
+	ignore   []string
+	links    map[uint32]link
+	addrs    map[addrKey]struct{}
+	defaults map[routeKey]struct{}
+}
+
+func newNetState(ignore []string) *netState {
+	return &netState{
+		ignore:   ignore,
+		links:    make(map[uint32]link),
+		addrs:    make(map[addrKey]struct{}),
+		defaults: make(map[routeKey]struct{}),
+	}
+}
+
+// load fills the state from dumps of the current links, addresses and
+// routes.
+func (s *netState) load() error {
+	for _, proto := range []int{unix.RTM_GETLINK, unix.RTM_GETADDR, unix.RTM_GETROUTE} {
+		rib, err := syscall.NetlinkRIB(proto, unix.AF_UNSPEC)
+		if err != nil {
+			return fmt.Errorf("failed to dump network state: %w", err)
+		}
+		msgs, err := syscall.ParseNetlinkMessage(rib)
+		if err != nil {
+			return fmt.Errorf("failed to parse network state: %w", err)
+		}
+		for _, msg := range msgs {
+			s.apply(msg)
+		}
+	}
+	return nil
+}
+
+// apply records msg and reports whether it changed the state.
+func (s *netState) apply(msg syscall.NetlinkMessage) bool {
+	switch msg.Header.Type {
+	case unix.RTM_NEWLINK, unix.RTM_DELLINK:
+		return s.applyLink(msg)
+	case unix.RTM_NEWADDR, unix.RTM_DELADDR:
+		return s.applyAddr(msg)
+	case unix.RTM_NEWROUTE, unix.RTM_DELROUTE:
+		return s.applyRoute(msg)
+	default:
+		return false
+	}
+}
+
+func (s *netState) applyLink(msg syscall.NetlinkMessage) bool {
+	if len(msg.Data) < unix.SizeofIfInfomsg {
+		return false
+	}
+	linkType := binary.NativeEndian.Uint16(msg.Data[2:4])
+	index := binary.NativeEndian.Uint32(msg.Data[4:8])
+	flags := binary.NativeEndian.Uint32(msg.Data[8:12])
+	prev, known := s.links[index]
+
+	if msg.Header.Type == unix.RTM_DELLINK {
+		delete(s.links, index)
+		return known && prev.running && !prev.ignored
+	}
+
+	next := link{name: prev.name, running: flags&unix.IFF_RUNNING != 0}
+	attrs, _ := syscall.ParseNetlinkRouteAttr(&msg)
+	for _, attr := range attrs {
+		if attr.Attr.Type == unix.IFLA_IFNAME {
+			next.name = cString(attr.Value)
+		}
+	}
+	// TUN devices, ours or those of other client instances, are never an
+	// uplink.
+	next.ignored = flags&unix.IFF_LOOPBACK != 0 || linkType == unix.ARPHRD_NONE || slices.Contains(s.ignore, next.name)
+	s.links[index] = next
+	// A new interface matters once it gets an address or a route.
+	return known && prev.running != next.running && !next.ignored
+}
+
+func (s *netState) applyAddr(msg syscall.NetlinkMessage) bool {
+	if len(msg.Data) < unix.SizeofIfAddrmsg {
+		return false
+	}
+	flags := uint32(msg.Data[2])
+	scope := msg.Data[3]
+	index := binary.NativeEndian.Uint32(msg.Data[4:8])
+	if scope != unix.RT_SCOPE_UNIVERSE || s.ignored(index) {
+		return false
+	}
+
+	var addr, local netip.Addr
+	attrs, _ := syscall.ParseNetlinkRouteAttr(&msg)
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case unix.IFA_ADDRESS:
+			addr, _ = netip.AddrFromSlice(attr.Value)
+		case unix.IFA_LOCAL:
+			local, _ = netip.AddrFromSlice(attr.Value)
+		case unix.IFA_FLAGS:
+			if len(attr.Value) >= 4 {
+				flags |= binary.NativeEndian.Uint32(attr.Value)
+			}
+		}
+	}
+	// Privacy addresses rotate and tentative ones are not usable yet.
+	if flags&(unix.IFA_F_TEMPORARY|unix.IFA_F_TENTATIVE) != 0 {
+		return false
+	}
+	if local.IsValid() {
+		addr = local
+	}
+	if !addr.IsValid() {
+		return false
+	}
+
+	key := addrKey{index: index, addr: addr.Unmap()}
+	_, known := s.addrs[key]
+	if msg.Header.Type == unix.RTM_DELADDR {
+		delete(s.addrs, key)
+		return known
+	}
+	s.addrs[key] = struct{}{}
+	return !known
+}
+
+func (s *netState) applyRoute(msg syscall.NetlinkMessage) bool {
+	if len(msg.Data) < unix.SizeofRtMsg {
+		return false
+	}
+	family, dstLen := msg.Data[0], msg.Data[1]
+	table, routeType := uint32(msg.Data[4]), msg.Data[7]
+	if dstLen != 0 || routeType != unix.RTN_UNICAST {
+		return false
+	}
+
+	key := routeKey{family: family}
+	attrs, _ := syscall.ParseNetlinkRouteAttr(&msg)
+	for _, attr := range attrs {
+		switch attr.Attr.Type {
+		case unix.RTA_TABLE:
+			if len(attr.Value) >= 4 {
+				table = binary.NativeEndian.Uint32(attr.Value)
+			}
+		case unix.RTA_OIF:
+			if len(attr.Value) >= 4 {
+				key.oif = binary.NativeEndian.Uint32(attr.Value)
+			}
+		case unix.RTA_GATEWAY:
+			key.gateway, _ = netip.AddrFromSlice(attr.Value)
+		case unix.RTA_PRIORITY:
+			if len(attr.Value) >= 4 {
+				key.priority = binary.NativeEndian.Uint32(attr.Value)
+			}
+		}
+	}
+	if table != unix.RT_TABLE_MAIN || s.ignored(key.oif) {
+		return false
+	}
+
+	_, known := s.defaults[key]
+	if msg.Header.Type == unix.RTM_DELROUTE {
+		delete(s.defaults, key)
+		return known
+	}
+	s.defaults[key] = struct{}{}
+	return !known
+}
+
+func (s *netState) ignored(index uint32) bool {
+	l, ok := s.links[index]
+	return ok && l.ignored
+}
+
+func cString(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,115 @@
+package netwatch
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func rtAttr(typ uint16, value []byte) []byte {
+	length := unix.SizeofRtAttr + len(value)
+	b := make([]byte, (length+3)&^3)
+	binary.NativeEndian.PutUint16(b[0:2], uint16(length))
+	binary.NativeEndian.PutUint16(b[2:4], typ)
+	copy(b[unix.SizeofRtAttr:], value)
+	return b
+}
+
+func u32(v uint32) []byte {
+	return binary.NativeEndian.AppendUint32(nil, v)
+}
+
+func linkMsg(typ uint16, index, flags uint32, name string) syscall.NetlinkMessage {
+	data := make([]byte, unix.SizeofIfInfomsg)
+	binary.NativeEndian.PutUint32(data[4:8], index)
+	binary.NativeEndian.PutUint32(data[8:12], flags)
+	data = append(data, rtAttr(unix.IFLA_IFNAME, append([]byte(name), 0))...)
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: data}
+}
+
+func tunLinkMsg(typ uint16, index, flags uint32, name string) syscall.NetlinkMessage {
+	msg := linkMsg(typ, index, flags, name)
+	binary.NativeEndian.PutUint16(msg.Data[2:4], unix.ARPHRD_NONE)
+	return msg
+}
+
+func addrMsg(typ uint16, index uint32, scope uint8, flags uint8, addr string) syscall.NetlinkMessage {
+	data := make([]byte, unix.SizeofIfAddrmsg)
+	a := netip.MustParseAddr(addr)
+	data[0] = unix.AF_INET
+	if a.Is6() {
+		data[0] = unix.AF_INET6
+	}
+	data[2], data[3] = flags, scope
+	binary.NativeEndian.PutUint32(data[4:8], index)
+	data = append(data, rtAttr(unix.IFA_ADDRESS, a.AsSlice())...)
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: data}
+}
+
+func routeMsg(typ uint16, dstLen uint8, oif uint32, gateway string) syscall.NetlinkMessage {
+	data := make([]byte, unix.SizeofRtMsg)
+	data[0], data[1] = unix.AF_INET, dstLen
+	data[4], data[7] = unix.RT_TABLE_MAIN, unix.RTN_UNICAST
+	data = append(data, rtAttr(unix.RTA_OIF, u32(oif))...)
+	data = append(data, rtAttr(unix.RTA_GATEWAY, netip.MustParseAddr(gateway).AsSlice())...)
+	return syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: data}
+}
+
+func newTestState() *netState {
+	s := newNetState([]string{"tun0"})
+	s.apply(linkMsg(unix.RTM_NEWLINK, 1, unix.IFF_UP|unix.IFF_LOOPBACK|unix.IFF_RUNNING, "lo"))
+	s.apply(linkMsg(unix.RTM_NEWLINK, 2, unix.IFF_UP|unix.IFF_RUNNING, "eth0"))
+	s.apply(linkMsg(unix.RTM_NEWLINK, 3, unix.IFF_UP|unix.IFF_RUNNING, "tun0"))
+	// the device of another client instance
+	s.apply(tunLinkMsg(unix.RTM_NEWLINK, 5, unix.IFF_UP|unix.IFF_RUNNING, "tun1"))
+	s.apply(addrMsg(unix.RTM_NEWADDR, 2, unix.RT_SCOPE_UNIVERSE, 0, "192.168.1.10"))
+	s.apply(routeMsg(unix.RTM_NEWROUTE, 0, 2, "192.168.1.1"))
+	return s
+}
+
+func TestNetStateReportsUplinkChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  syscall.NetlinkMessage
+		want bool
+	}{
+		{"new lease", addrMsg(unix.RTM_NEWADDR, 2, unix.RT_SCOPE_UNIVERSE, 0, "192.168.1.20"), true},
+		{"lease renewal", addrMsg(unix.RTM_NEWADDR, 2, unix.RT_SCOPE_UNIVERSE, 0, "192.168.1.10"), false},
+		{"address removed", addrMsg(unix.RTM_DELADDR, 2, unix.RT_SCOPE_UNIVERSE, 0, "192.168.1.10"), true},
+		{"link-local address", addrMsg(unix.RTM_NEWADDR, 2, unix.RT_SCOPE_LINK, 0, "fe80::1"), false},
+		{"privacy address", addrMsg(unix.RTM_NEWADDR, 2, unix.RT_SCOPE_UNIVERSE, unix.IFA_F_TEMPORARY, "2001:db8::1"), false},
+		{"tunnel address", addrMsg(unix.RTM_NEWADDR, 3, unix.RT_SCOPE_UNIVERSE, 0, "10.0.0.2"), false},
+		{"new default route", routeMsg(unix.RTM_NEWROUTE, 0, 2, "192.168.1.254"), true},
+		{"same default route", routeMsg(unix.RTM_NEWROUTE, 0, 2, "192.168.1.1"), false},
+		{"default route removed", routeMsg(unix.RTM_DELROUTE, 0, 2, "192.168.1.1"), true},
+		{"host route", routeMsg(unix.RTM_NEWROUTE, 32, 2, "192.168.1.1"), false},
+		{"tunnel default route", routeMsg(unix.RTM_NEWROUTE, 0, 3, "10.0.0.1"), false},
+		{"carrier lost", linkMsg(unix.RTM_NEWLINK, 2, unix.IFF_UP, "eth0"), true},
+		{"link unchanged", linkMsg(unix.RTM_NEWLINK, 2, unix.IFF_UP|unix.IFF_RUNNING, "eth0"), false},
+		{"uplink removed", linkMsg(unix.RTM_DELLINK, 2, 0, "eth0"), true},
+		{"tunnel removed", linkMsg(unix.RTM_DELLINK, 3, 0, "tun0"), false},
+		{"new interface", linkMsg(unix.RTM_NEWLINK, 4, unix.IFF_UP|unix.IFF_RUNNING, "wlan0"), false},
+		{"other tunnel address", addrMsg(unix.RTM_NEWADDR, 5, unix.RT_SCOPE_UNIVERSE, 0, "10.1.0.2"), false},
+		{"other tunnel default route", routeMsg(unix.RTM_NEWROUTE, 0, 5, "10.1.0.1"), false},
+		{"other tunnel removed", tunLinkMsg(unix.RTM_DELLINK, 5, 0, "tun1"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTestState().apply(tt.msg); got != tt.want {
+				t.Fatalf("apply() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetStateIgnoresTruncatedMessages(t *testing.T) {
+	s := newTestState()
+	for _, typ := range []uint16{unix.RTM_NEWLINK, unix.RTM_NEWADDR, unix.RTM_NEWROUTE} {
+		if s.apply(syscall.NetlinkMessage{Header: syscall.NlMsghdr{Type: typ}, Data: []byte{1}}) {
+			t.Fatalf("truncated message of type %d reported as a change", typ)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package netwatch
+
+import "context"
+
+func monitor(context.Context, []string) (<-chan struct{}, error) {
+	return nil, nil
+}
@@ -0,0 +1,63 @@
+package netwatch
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+	"time"
+)
+
+func TestDebounceReportsBurstOnce(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		defer cancel()
+		events := make(chan struct{})
+		out := make(chan struct{}, 1)
+		go debounce(ctx, events, out, settle)
+
+		for range 3 {
+			events <- struct{}{}
+			time.Sleep(settle / 2)
+		}
+		synctest.Wait()
+		select {
+		case <-out:
+			t.Fatal("change reported before the network settled")
+		default:
+		}
+
+		time.Sleep(settle)
+		synctest.Wait()
+		select {
+		case <-out:
+		default:
+			t.Fatal("change not reported after the network settled")
+		}
+		select {
+		case <-out:
+			t.Fatal("burst reported more than once")
+		default:
+		}
+	})
+}
+
+func TestDebounceStopsWithContext(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(t.Context())
+		events := make(chan struct{})
+		out := make(chan struct{}, 1)
+		done := make(chan struct{})
+		go func() {
+			debounce(ctx, events, out, settle)
+			close(done)
+		}()
+
+		cancel()
+		synctest.Wait()
+		select {
+		case <-done:
+		default:
+			t.Fatal("debounce did not stop after cancellation")
+		}
+	})
+}
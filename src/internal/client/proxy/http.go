@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+)
+
+// serveHTTP tunnels CONNECT requests and forwards plain requests in the
+// absolute form a client sends to a proxy.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorizedHTTP(r) {
+		w.Header().Set("Proxy-Authenticate", `Basic realm="tungo"`)
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+	if r.Method == http.MethodConnect {
+		s.httpConnect(w, r)
+		return
+	}
+	if r.URL.Scheme != "http" || r.URL.Host == "" {
+		http.Error(w, "not a proxy request", http.StatusBadRequest)
+		return
+	}
+	forwarder := &httputil.ReverseProxy{
+		// A proxy request already names its absolute target.
+		Rewrite:   func(*httputil.ProxyRequest) {},
+		Transport: s.transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			slog.Debug("HTTP proxy request failed", "url", r.URL, "err", err)
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		},
+	}
+	forwarder.ServeHTTP(w, r)
+}
+
+func (s *Server) httpConnect(w http.ResponseWriter, r *http.Request) {
+	upstream, err := s.dial(r.Context(), r.Host)
+	if err != nil {
+		slog.Debug("HTTP proxy connect failed", "target", r.Host, "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = upstream.Close()
+		http.Error(w, "connection cannot be hijacked", http.StatusInternalServerError)
+		return
+	}
+	conn, buffered, err := hijacker.Hijack()
+	if err != nil {
+		_ = upstream.Close()
+		return
+	}
+	untrack := s.track(conn)
+	defer untrack()
+
+	if _, err := conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		_ = conn.Close()
+		_ = upstream.Close()
+		return
+	}
+	// A client may send its first bytes before reading the response.
+	if n := buffered.Reader.Buffered(); n > 0 {
+		early, _ := buffered.Reader.Peek(n)
+		if _, err := upstream.Write(early); err != nil {
+			_ = conn.Close()
+			_ = upstream.Close()
+			return
+		}
+	}
+	relay(conn, upstream)
+}
+
+// authorizedHTTP checks the Basic credentials in Proxy-Authorization, which
+// the forwarder drops as a hop-by-hop header.
+func (s *Server) authorizedHTTP(r *http.Request) bool {
+	if !s.credentials.required() {
+		return true
+	}
+	scheme, encoded, ok := strings.Cut(r.Header.Get("Proxy-Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Basic") {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	return ok && s.credentials.match(username, password)
+}
+
+// newTransport returns an HTTP transport that dials through the tunnel.
+func (s *Server) newTransport() *http.Transport {
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, address string) (net.Conn, error) {
+			return s.dial(ctx, address)
+		},
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
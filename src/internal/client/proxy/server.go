@@ -0,0 +1,181 @@
+// Package proxy serves local SOCKS5 and HTTP proxies whose connections leave
+// through the tunnel of the rootless client.
+package proxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	// handshakeTimeout bounds the proxy negotiation of a new connection.
+	handshakeTimeout = 10 * time.Second
+	// dialTimeout bounds opening a connection through the tunnel.
+	dialTimeout = 15 * time.Second
+)
+
+// Network opens connections through the tunnel.
+type Network interface {
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+	// ListenPacket opens an unconnected UDP socket, network is "udp4" or
+	// "udp6".
+	ListenPacket(network string) (net.PacketConn, error)
+	LookupNetIP(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+// Credentials are the username and password that proxy clients must
+// present. Zero Credentials accept every client that reaches the listeners.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+func (c Credentials) required() bool {
+	return c != Credentials{}
+}
+
+// match compares in constant time, so that the time taken does not tell how
+// much of a guess was right.
+func (c Credentials) match(username, password string) bool {
+	usernameOK := subtle.ConstantTimeCompare([]byte(username), []byte(c.Username))
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(c.Password))
+	return usernameOK&passwordOK == 1
+}
+
+// Server runs the SOCKS5 and HTTP proxies.
+type Server struct {
+	network     Network
+	socksAddr   string
+	httpAddr    string
+	credentials Credentials
+	socks       net.Listener
+	http        net.Listener
+	transport   *http.Transport
+	handlers    sync.WaitGroup
+	mu          sync.Mutex
+	connections map[io.Closer]struct{}
+}
+
+func NewServer(network Network, socksAddr, httpAddr string, credentials Credentials) *Server {
+	s := &Server{
+		network:     network,
+		socksAddr:   socksAddr,
+		httpAddr:    httpAddr,
+		credentials: credentials,
+		connections: make(map[io.Closer]struct{}),
+	}
+	s.transport = s.newTransport()
+	return s
+}
+
+// Listen opens both listeners, so that an address in use fails the start
+// rather than the first connection.
+func (s *Server) Listen() error {
+	socks, err := net.Listen("tcp", s.socksAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for SOCKS5 on %s: %w", s.socksAddr, err)
+	}
+	httpListener, err := net.Listen("tcp", s.httpAddr)
+	if err != nil {
+		_ = socks.Close()
+		return fmt.Errorf("failed to listen for HTTP on %s: %w", s.httpAddr, err)
+	}
+	s.socks, s.http = socks, httpListener
+	slog.Info("SOCKS5 proxy listening", "address", socks.Addr())
+	slog.Info("HTTP proxy listening", "address", httpListener.Addr())
+	return nil
+}
+
+// Serve accepts proxy connections until ctx is done, then closes all of
+// them. Listen must have succeeded.
+func (s *Server) Serve(ctx context.Context) {
+	httpServer := &http.Server{
+		Handler:           http.HandlerFunc(s.serveHTTP),
+		ReadHeaderTimeout: handshakeTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelDebug),
+	}
+	var listeners sync.WaitGroup
+	listeners.Go(func() { _ = httpServer.Serve(s.http) })
+	listeners.Go(s.acceptSOCKS)
+
+	<-ctx.Done()
+	_ = httpServer.Close()
+	_ = s.socks.Close()
+	listeners.Wait()
+	s.transport.CloseIdleConnections()
+	s.closeConnections()
+	s.handlers.Wait()
+}
+
+func (s *Server) acceptSOCKS() {
+	for {
+		conn, err := s.socks.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Warn("SOCKS5 proxy stopped accepting", "err", err)
+			}
+			return
+		}
+		untrack := s.track(conn)
+		s.handlers.Go(func() {
+			defer untrack()
+			s.serveSOCKS(conn)
+		})
+	}
+}
+
+// track registers a connection to be closed on shutdown.
+func (s *Server) track(c io.Closer) (untrack func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connections[c] = struct{}{}
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.connections, c)
+	}
+}
+
+func (s *Server) closeConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.connections {
+		_ = c.Close()
+	}
+}
+
+func (s *Server) dial(ctx context.Context, address string) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	return s.network.DialContext(ctx, "tcp", address)
+}
+
+// relay copies both ways until both directions finished, then closes both
+// connections.
+func relay(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	halfCopy := func(dst, src net.Conn) {
+		_, _ = io.Copy(dst, src)
+		if closer, ok := dst.(interface{ CloseWrite() error }); ok {
+			_ = closer.CloseWrite()
+		} else {
+			_ = dst.Close()
+		}
+		done <- struct{}{}
+	}
+	go halfCopy(a, b)
+	go halfCopy(b, a)
+	<-done
+	<-done
+	_ = a.Close()
+	_ = b.Close()
+}
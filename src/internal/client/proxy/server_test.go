@@ -0,0 +1,399 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// loopbackNetwork stands in for the tunnel with the host network, and
+// resolves "echo.test" to the loopback address.
+type loopbackNetwork struct{}
+
+func (loopbackNetwork) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if host == "echo.test" {
+		address = net.JoinHostPort("127.0.0.1", port)
+	}
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+func (loopbackNetwork) ListenPacket(network string) (net.PacketConn, error) {
+	return net.ListenPacket(network, "127.0.0.1:0")
+}
+
+func (loopbackNetwork) LookupNetIP(_ context.Context, host string) ([]netip.Addr, error) {
+	if host == "echo.test" {
+		return []netip.Addr{netip.MustParseAddr("127.0.0.1")}, nil
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return nil, err
+	}
+	return []netip.Addr{addr}, nil
+}
+
+func startServer(t *testing.T, credentials Credentials) *Server {
+	t.Helper()
+	s := NewServer(loopbackNetwork{}, "127.0.0.1:0", "127.0.0.1:0", credentials)
+	if err := s.Listen(); err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Serve(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return s
+}
+
+func tcpEcho(t *testing.T) netip.AddrPort {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).AddrPort()
+}
+
+func socksDial(t *testing.T, s *Server) net.Conn {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.socks.Addr().String())
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func socksHandshake(t *testing.T, conn net.Conn, command byte, target []byte) (byte, netip.AddrPort) {
+	t.Helper()
+	if _, err := conn.Write([]byte{socksVersion, 1, socksNoAuth}); err != nil {
+		t.Fatalf("write greeting: %v", err)
+	}
+	var method [2]byte
+	if _, err := io.ReadFull(conn, method[:]); err != nil || method != [2]byte{socksVersion, socksNoAuth} {
+		t.Fatalf("method = %v, %v", method, err)
+	}
+	request := append([]byte{socksVersion, command, 0}, target...)
+	if _, err := conn.Write(request); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+	var header [3]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	var addrType [1]byte
+	if _, err := io.ReadFull(conn, addrType[:]); err != nil {
+		t.Fatalf("read reply: %v", err)
+	}
+	bound, err := readSOCKSAddr(conn, addrType[0])
+	if err != nil {
+		t.Fatalf("read bound address: %v", err)
+	}
+	return header[1], netip.MustParseAddrPort(bound.String())
+}
+
+func domainTarget(host string, port uint16) []byte {
+	target := append([]byte{socksDomain, byte(len(host))}, host...)
+	return binary.BigEndian.AppendUint16(target, port)
+}
+
+func expectEcho(t *testing.T, conn net.Conn, message string) {
+	t.Helper()
+	if _, err := conn.Write([]byte(message)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	buf := make([]byte, len(message))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(buf) != message {
+		t.Fatalf("echo = %q, want %q", buf, message)
+	}
+}
+
+func TestSOCKSConnect(t *testing.T) {
+	s := startServer(t, Credentials{})
+	echo := tcpEcho(t)
+
+	for name, target := range map[string][]byte{
+		"ipv4":   appendSOCKSAddr(nil, echo),
+		"domain": domainTarget("echo.test", echo.Port()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			conn := socksDial(t, s)
+			if reply, _ := socksHandshake(t, conn, socksConnect, target); reply != socksSucceeded {
+				t.Fatalf("reply = %d, want %d", reply, socksSucceeded)
+			}
+			expectEcho(t, conn, "through the tunnel")
+		})
+	}
+}
+
+func TestSOCKSRejects(t *testing.T) {
+	s := startServer(t, Credentials{})
+
+	t.Run("bind", func(t *testing.T) {
+		conn := socksDial(t, s)
+		target := appendSOCKSAddr(nil, netip.MustParseAddrPort("127.0.0.1:1"))
+		if reply, _ := socksHandshake(t, conn, 2, target); reply != socksCommandUnsupported {
+			t.Fatalf("reply = %d, want %d", reply, socksCommandUnsupported)
+		}
+	})
+	t.Run("unreachable", func(t *testing.T) {
+		conn := socksDial(t, s)
+		if reply, _ := socksHandshake(t, conn, socksConnect, domainTarget("unknown.test", 80)); reply != socksHostUnreachable {
+			t.Fatalf("reply = %d, want %d", reply, socksHostUnreachable)
+		}
+	})
+	t.Run("authentication only", func(t *testing.T) {
+		conn := socksDial(t, s)
+		_, _ = conn.Write([]byte{socksVersion, 1, 0x02})
+		var method [2]byte
+		if _, err := io.ReadFull(conn, method[:]); err != nil || method[1] != socksNoAcceptable {
+			t.Fatalf("method = %v, %v; want no acceptable method", method, err)
+		}
+	})
+}
+
+func TestSOCKSAuthentication(t *testing.T) {
+	s := startServer(t, Credentials{Username: "user", Password: "secret"})
+	echo := tcpEcho(t)
+
+	authenticate := func(conn net.Conn, username, password string) byte {
+		t.Helper()
+		_, _ = conn.Write([]byte{socksVersion, 2, socksNoAuth, socksUserPass})
+		var method [2]byte
+		if _, err := io.ReadFull(conn, method[:]); err != nil || method != [2]byte{socksVersion, socksUserPass} {
+			t.Fatalf("method = %v, %v", method, err)
+		}
+		request := append([]byte{socksAuthVersion, byte(len(username))}, username...)
+		request = append(append(request, byte(len(password))), password...)
+		_, _ = conn.Write(request)
+		var status [2]byte
+		if _, err := io.ReadFull(conn, status[:]); err != nil || status[0] != socksAuthVersion {
+			t.Fatalf("status = %v, %v", status, err)
+		}
+		return status[1]
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		conn := socksDial(t, s)
+		if status := authenticate(conn, "user", "secret"); status != socksAuthSucceeded {
+			t.Fatalf("status = %d, want success", status)
+		}
+		request := append([]byte{socksVersion, socksConnect, 0}, appendSOCKSAddr(nil, echo)...)
+		_, _ = conn.Write(request)
+		reply := make([]byte, 10)
+		if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != socksSucceeded {
+			t.Fatalf("reply = %v, %v", reply, err)
+		}
+		expectEcho(t, conn, "authenticated")
+	})
+	t.Run("wrong password", func(t *testing.T) {
+		conn := socksDial(t, s)
+		if status := authenticate(conn, "user", "guess"); status != socksAuthFailed {
+			t.Fatalf("status = %d, want failure", status)
+		}
+		if _, err := conn.Read(make([]byte, 1)); err == nil {
+			t.Fatal("connection stayed open after a failed authentication")
+		}
+	})
+	t.Run("no authentication", func(t *testing.T) {
+		conn := socksDial(t, s)
+		_, _ = conn.Write([]byte{socksVersion, 1, socksNoAuth})
+		var method [2]byte
+		if _, err := io.ReadFull(conn, method[:]); err != nil || method[1] != socksNoAcceptable {
+			t.Fatalf("method = %v, %v; want no acceptable method", method, err)
+		}
+	})
+}
+
+func TestSOCKSUDPAssociate(t *testing.T) {
+	s := startServer(t, Credentials{})
+	echo, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = echo.Close() }()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := echo.ReadFromUDPAddrPort(buf)
+			if err != nil {
+				return
+			}
+			_, _ = echo.WriteToUDPAddrPort(buf[:n], from)
+		}
+	}()
+	echoAddr := echo.LocalAddr().(*net.UDPAddr).AddrPort()
+
+	control := socksDial(t, s)
+	reply, relayAddr := socksHandshake(t, control, socksUDPAssociate, appendSOCKSAddr(nil, netip.AddrPortFrom(netip.IPv4Unspecified(), 0)))
+	if reply != socksSucceeded {
+		t.Fatalf("reply = %d, want %d", reply, socksSucceeded)
+	}
+	client, err := net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(relayAddr))
+	if err != nil {
+		t.Fatalf("dial relay: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	datagram := append([]byte{0, 0, 0}, domainTarget("echo.test", echoAddr.Port())...)
+	if _, err := client.Write(append(datagram, "ping"...)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// Fragments are dropped.
+	fragment := append([]byte{0, 0, 1}, appendSOCKSAddr(nil, echoAddr)...)
+	_, _ = client.Write(append(fragment, "fragment"...))
+
+	_ = client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 1500)
+	n, err := client.Read(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	want := append(append([]byte{0, 0, 0}, appendSOCKSAddr(nil, echoAddr)...), "ping"...)
+	if string(buf[:n]) != string(want) {
+		t.Fatalf("reply = %v, want %v", buf[:n], want)
+	}
+	_ = client.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := client.Read(buf); err == nil {
+		t.Fatalf("unexpected datagram %q", buf[:n])
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	s := startServer(t, Credentials{})
+	echo := tcpEcho(t)
+
+	conn, err := net.Dial("tcp", s.http.Addr().String())
+	if err != nil {
+		t.Fatalf("dial proxy: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	target := fmt.Sprintf("echo.test:%d", echo.Port())
+	_, _ = fmt.Fprintf(conn, "CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target)
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("CONNECT response = %v, %v", resp, err)
+	}
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(reader, buf); err != nil || string(buf) != "hello" {
+		t.Fatalf("echo = %q, %v", buf, err)
+	}
+}
+
+func TestHTTPForward(t *testing.T) {
+	s := startServer(t, Credentials{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	origin := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s %s", r.Host, r.URL.Path)
+	})}
+	go func() { _ = origin.Serve(listener) }()
+	defer func() { _ = origin.Close() }()
+
+	proxyURL, _ := url.Parse("http://" + s.http.Addr().String())
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}, Timeout: 5 * time.Second}
+	target := fmt.Sprintf("http://echo.test:%d/path", listener.Addr().(*net.TCPAddr).Port)
+	resp, err := client.Get(target)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	want := fmt.Sprintf("echo.test:%d /path", listener.Addr().(*net.TCPAddr).Port)
+	if string(body) != want {
+		t.Fatalf("body = %q, want %q", body, want)
+	}
+
+	// Requests that do not name an absolute target are not proxied.
+	direct, err := http.Get("http://" + s.http.Addr().String() + "/")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	_ = direct.Body.Close()
+	if direct.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", direct.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestHTTPProxyAuthentication(t *testing.T) {
+	s := startServer(t, Credentials{Username: "user", Password: "secret"})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	origin := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%q", r.Header.Get("Proxy-Authorization"))
+	})}
+	go func() { _ = origin.Serve(listener) }()
+	defer func() { _ = origin.Close() }()
+	target := fmt.Sprintf("http://echo.test:%d/", listener.Addr().(*net.TCPAddr).Port)
+
+	for name, tc := range map[string]struct {
+		userinfo *url.Userinfo
+		status   int
+	}{
+		"valid":          {userinfo: url.UserPassword("user", "secret"), status: http.StatusOK},
+		"wrong password": {userinfo: url.UserPassword("user", "guess"), status: http.StatusProxyAuthRequired},
+		"missing":        {status: http.StatusProxyAuthRequired},
+	} {
+		t.Run(name, func(t *testing.T) {
+			proxyURL := &url.URL{Scheme: "http", Host: s.http.Addr().String(), User: tc.userinfo}
+			client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}, Timeout: 5 * time.Second}
+			resp, err := client.Get(target)
+			if err != nil {
+				t.Fatalf("GET error = %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tc.status)
+			}
+			if body, _ := io.ReadAll(resp.Body); tc.status == http.StatusOK && string(body) != `""` {
+				t.Fatalf("origin received Proxy-Authorization %s", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SOCKS5 constants from RFC 1928.
+const (
+	socksVersion = 5
+
+	socksNoAuth       = 0x00
+	socksUserPass     = 0x02
+	socksNoAcceptable = 0xff
+
+	socksConnect      = 1
+	socksUDPAssociate = 3
+
+	socksIPv4   = 1
+	socksDomain = 3
+	socksIPv6   = 4
+
+	socksSucceeded          = 0
+	socksGeneralFailure     = 1
+	socksHostUnreachable    = 4
+	socksCommandUnsupported = 7
+	socksAddressUnsupported = 8
+)
+
+// Username/password authentication constants from RFC 1929.
+const (
+	socksAuthVersion   = 1
+	socksAuthSucceeded = 0
+	socksAuthFailed    = 1
+)
+
+var errSOCKSAddressType = errors.New("unsupported SOCKS5 address type")
+
+// socksAddr is a SOCKS5 destination: an IP address or a domain name.
+type socksAddr struct {
+	host string
+	port uint16
+}
+
+func (a socksAddr) String() string {
+	return net.JoinHostPort(a.host, strconv.Itoa(int(a.port)))
+}
+
+func (s *Server) serveSOCKS(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+
+	if err := socksNegotiate(conn, s.credentials); err != nil {
+		slog.Debug("SOCKS5 negotiation failed", "client", conn.RemoteAddr(), "err", err)
+		return
+	}
+	command, target, err := readSOCKSRequest(conn)
+	if err != nil {
+		if errors.Is(err, errSOCKSAddressType) {
+			_ = writeSOCKSReply(conn, socksAddressUnsupported, netip.AddrPort{})
+		}
+		slog.Debug("invalid SOCKS5 request", "client", conn.RemoteAddr(), "err", err)
+		return
+	}
+
+	switch command {
+	case socksConnect:
+		s.socksConnect(conn, target)
+	case socksUDPAssociate:
+		s.socksAssociate(conn)
+	default:
+		_ = writeSOCKSReply(conn, socksCommandUnsupported, netip.AddrPort{})
+	}
+}
+
+// socksNegotiate requires username/password authentication with credentials,
+// or accepts clients that offer "no authentication" when none are set.
+func socksNegotiate(conn net.Conn, credentials Credentials) error {
+	var header [2]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return err
+	}
+	if header[0] != socksVersion {
+		return fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return err
+	}
+	method := byte(socksNoAuth)
+	if credentials.required() {
+		method = socksUserPass
+	}
+	if !bytes.Contains(methods, []byte{method}) {
+		_, _ = conn.Write([]byte{socksVersion, socksNoAcceptable})
+		return fmt.Errorf("client does not offer authentication method %d", method)
+	}
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return err
+	}
+	if method == socksUserPass {
+		return socksAuthenticate(conn, credentials)
+	}
+	return nil
+}
+
+// socksAuthenticate runs the username/password subnegotiation of RFC 1929.
+func socksAuthenticate(conn net.Conn, credentials Credentials) error {
+	var version [1]byte
+	if _, err := io.ReadFull(conn, version[:]); err != nil {
+		return err
+	}
+	if version[0] != socksAuthVersion {
+		return fmt.Errorf("unsupported SOCKS5 authentication version %d", version[0])
+	}
+	username, err := readSOCKSString(conn)
+	if err != nil {
+		return err
+	}
+	password, err := readSOCKSString(conn)
+	if err != nil {
+		return err
+	}
+	if !credentials.match(username, password) {
+		_, _ = conn.Write([]byte{socksAuthVersion, socksAuthFailed})
+		return errors.New("wrong username or password")
+	}
+	_, err = conn.Write([]byte{socksAuthVersion, socksAuthSucceeded})
+	return err
+}
+
+// readSOCKSString reads a string prefixed with its one-byte length.
+func readSOCKSString(r io.Reader) (string, error) {
+	var length [1]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return "", err
+	}
+	s := make([]byte, length[0])
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+func readSOCKSRequest(r io.Reader) (byte, socksAddr, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, socksAddr{}, err
+	}
+	if header[0] != socksVersion {
+		return 0, socksAddr{}, fmt.Errorf("unsupported SOCKS version %d", header[0])
+	}
+	target, err := readSOCKSAddr(r, header[3])
+	return header[1], target, err
+}
+
+func readSOCKSAddr(r io.Reader, addrType byte) (socksAddr, error) {
+	var host string
+	switch addrType {
+	case socksIPv4, socksIPv6:
+		raw := make([]byte, 4)
+		if addrType == socksIPv6 {
+			raw = make([]byte, 16)
+		}
+		if _, err := io.ReadFull(r, raw); err != nil {
+			return socksAddr{}, err
+		}
+		addr, _ := netip.AddrFromSlice(raw)
+		host = addr.String()
+	case socksDomain:
+		name, err := readSOCKSString(r)
+		if err != nil {
+			return socksAddr{}, err
+		}
+		host = name
+	default:
+		return socksAddr{}, errSOCKSAddressType
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(r, port[:]); err != nil {
+		return socksAddr{}, err
+	}
+	return socksAddr{host: host, port: binary.BigEndian.Uint16(port[:])}, nil
+}
+
+// appendSOCKSAddr appends addr in the SOCKS5 address format.
+func appendSOCKSAddr(b []byte, addr netip.AddrPort) []byte {
+	ip := addr.Addr().Unmap()
+	if ip.Is4() {
+		b = append(b, socksIPv4)
+	} else {
+		b = append(b, socksIPv6)
+	}
+	b = append(b, ip.AsSlice()...)
+	return binary.BigEndian.AppendUint16(b, addr.Port())
+}
+
+func writeSOCKSReply(w io.Writer, reply byte, bound netip.AddrPort) error {
+	if !bound.IsValid() {
+		bound = netip.AddrPortFrom(netip.IPv4Unspecified(), 0)
+	}
+	_, err := w.Write(appendSOCKSAddr([]byte{socksVersion, reply, 0}, bound))
+	return err
+}
+
+func (s *Server) socksConnect(conn net.Conn, target socksAddr) {
+	upstream, err := s.dial(context.Background(), target.String())
+	if err != nil {
+		slog.Debug("SOCKS5 connect failed", "target", target, "err", err)
+		_ = writeSOCKSReply(conn, socksHostUnreachable, netip.AddrPort{})
+		return
+	}
+	if err := writeSOCKSReply(conn, socksSucceeded, addrPort(upstream.LocalAddr())); err != nil {
+		_ = upstream.Close()
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	relay(conn, upstream)
+}
+
+// socksAssociate relays UDP for the client until it closes the control
+// connection.
+func (s *Server) socksAssociate(conn net.Conn) {
+	local := addrPort(conn.LocalAddr())
+	relayConn, err := net.ListenUDP("udp", net.UDPAddrFromAddrPort(netip.AddrPortFrom(local.Addr(), 0)))
+	if err != nil {
+		slog.Warn("failed to open SOCKS5 UDP relay", "err", err)
+		_ = writeSOCKSReply(conn, socksGeneralFailure, netip.AddrPort{})
+		return
+	}
+	association := &udpAssociation{
+		network: s.network,
+		relay:   relayConn,
+		client:  addrPort(conn.RemoteAddr()).Addr(),
+		sockets: make(map[string]net.PacketConn),
+	}
+	defer association.close()
+	if err := writeSOCKSReply(conn, socksSucceeded, addrPort(relayConn.LocalAddr())); err != nil {
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	go association.run()
+	_, _ = io.Copy(io.Discard, conn)
+}
+
+// udpAssociation moves datagrams between a SOCKS5 client and the tunnel.
+// Only the host of the control connection may use it.
+type udpAssociation struct {
+	network Network
+	relay   *net.UDPConn
+	client  netip.Addr
+
+	mu         sync.Mutex
+	closed     bool
+	clientAddr netip.AddrPort
+	sockets    map[string]net.PacketConn
+}
+
+func (a *udpAssociation) run() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, from, err := a.relay.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			return
+		}
+		if from.Addr().Unmap() != a.client.Unmap() {
+			continue
+		}
+		if err := a.forward(from, buf[:n]); err != nil {
+			slog.Debug("dropped SOCKS5 datagram", "err", err)
+		}
+	}
+}
+
+// forward sends one client datagram: RSV(2) FRAG(1) ATYP DST.ADDR DST.PORT
+// DATA. Fragments are not supported and dropped.
+func (a *udpAssociation) forward(from netip.AddrPort, datagram []byte) error {
+	if len(datagram) < 4 || datagram[2] != 0 {
+		return errors.New("fragmented or short datagram")
+	}
+	r := bytes.NewReader(datagram[3:])
+	var addrType [1]byte
+	_, _ = r.Read(addrType[:])
+	target, err := readSOCKSAddr(r, addrType[0])
+	if err != nil {
+		return err
+	}
+	payload := datagram[len(datagram)-r.Len():]
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	addrs, err := a.network.LookupNetIP(ctx, target.host)
+	cancel()
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("failed to resolve %s: %w", target.host, err)
+	}
+	destination := netip.AddrPortFrom(addrs[0], target.port)
+
+	socket, err := a.socket(from, destination.Addr())
+	if err != nil {
+		return err
+	}
+	_, err = socket.WriteTo(payload, net.UDPAddrFromAddrPort(destination))
+	return err
+}
+
+// socket returns the tunnel socket for the family of destination and
+// remembers the client port that replies go to.
+func (a *udpAssociation) socket(from netip.AddrPort, destination netip.Addr) (net.PacketConn, error) {
+	network := "udp4"
+	if !destination.Is4() {
+		network = "udp6"
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil, net.ErrClosed
+	}
+	a.clientAddr = from
+	if socket, ok := a.sockets[network]; ok {
+		return socket, nil
+	}
+	socket, err := a.network.ListenPacket(network)
+	if err != nil {
+		return nil, err
+	}
+	a.sockets[network] = socket
+	go a.replies(socket)
+	return socket, nil
+}
+
+func (a *udpAssociation) replies(socket net.PacketConn) {
+	buf := make([]byte, 64*1024)
+	for {
+		n, from, err := socket.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		source := addrPort(from)
+		if !source.IsValid() {
+			continue
+		}
+		packet := appendSOCKSAddr(make([]byte, 0, 22+n), source)
+		packet = append([]byte{0, 0, 0}, packet...)
+		packet = append(packet, buf[:n]...)
+
+		a.mu.Lock()
+		client := a.clientAddr
+		a.mu.Unlock()
+		_, _ = a.relay.WriteToUDPAddrPort(packet, client)
+	}
+}
+
+func (a *udpAssociation) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+	_ = a.relay.Close()
+	for _, socket := range a.sockets {
+		_ = socket.Close()
+	}
+}
+
+func addrPort(addr net.Addr) netip.AddrPort {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.AddrPort()
+	case *net.UDPAddr:
+		return a.AddrPort()
+	default:
+		return netip.AddrPort{}
+	}
+}
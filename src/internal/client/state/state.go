@@ -0,0 +1,165 @@
+// Package state describes the client connection lifecycle and publishes its
+// changes to subscribers such as the TUI.
+package state
+
+import (
+	"sync"
+	"time"
+
+	"tungo/internal/config/settings"
+)
+
+// State is a phase of the client connection lifecycle.
+type State int
+
+const (
+	Disconnected State = iota
+	Resolving
+	Dialing
+	Handshaking
+	Connected
+	Backoff
+)
+
+// States returns every State in lifecycle order.
+func States() []State {
+	return []State{Disconnected, Resolving, Dialing, Handshaking, Connected, Backoff}
+}
+
+func (s State) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Resolving:
+		return "resolving"
+	case Dialing:
+		return "dialing"
+	case Handshaking:
+		return "handshaking"
+	case Connected:
+		return "connected"
+	case Backoff:
+		return "backoff"
+	default:
+		return "unknown"
+	}
+}
+
+// Reason classifies why a connection attempt or session failed.
+type Reason int
+
+const (
+	ReasonNone Reason = iota
+	// ReasonNetwork covers resolution, dial and timeout failures.
+	ReasonNetwork
+	// ReasonAuthRejected means the server dropped the handshake: the client
+	// key is unknown or disabled, or the server key does not match.
+	ReasonAuthRejected
+	// ReasonCookieRequired means the server is under load.
+	ReasonCookieRequired
+	// ReasonOther covers dropped sessions and local failures.
+	ReasonOther
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonNone:
+		return "none"
+	case ReasonNetwork:
+		return "network unreachable"
+	case ReasonAuthRejected:
+		return "auth rejected"
+	case ReasonCookieRequired:
+		return "cookie required"
+	case ReasonOther:
+		return "session error"
+	default:
+		return "unknown"
+	}
+}
+
+// Status is one published state with its context.
+type Status struct {
+	State State
+	// Endpoint is host:port of the server being contacted or used.
+	Endpoint string
+	Protocol settings.Protocol
+	// Reason and Err describe the last failure in Backoff and Disconnected.
+	Reason Reason
+	Err    error
+	// Attempt counts consecutive failed sessions.
+	Attempt int
+	// RetryIn is the delay before the next attempt in Backoff.
+	RetryIn time.Duration
+	Since   time.Time
+}
+
+// Hub keeps the current Status and fans changes out to subscribers. A slow
+// subscriber misses intermediate states but always receives the latest one.
+// The zero value is ready to use.
+type Hub struct {
+	mu          sync.Mutex
+	current     Status
+	subscribers map[chan Status]struct{}
+}
+
+// Publish makes s the current status.
+func (h *Hub) Publish(s Status) {
+	if s.Since.IsZero() {
+		s.Since = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.current = s
+	for ch := range h.subscribers {
+		deliver(ch, s)
+	}
+}
+
+// Current returns the last published status.
+func (h *Hub) Current() Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.current
+}
+
+// Subscribe returns a channel that receives the current status and every
+// later change. The returned function unsubscribes and closes the channel.
+func (h *Hub) Subscribe() (<-chan Status, func()) {
+	ch := make(chan Status, 1)
+	h.mu.Lock()
+	if h.subscribers == nil {
+		h.subscribers = make(map[chan Status]struct{})
+	}
+	h.subscribers[ch] = struct{}{}
+	ch <- h.current
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			close(ch)
+			h.mu.Unlock()
+		})
+	}
+}
+
+// deliver replaces an unread status with s. Only Publish sends, under the
+// hub lock, so the channel cannot fill up between the two selects.
+func deliver(ch chan Status, s Status) {
+	select {
+	case ch <- s:
+		return
+	default:
+	}
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- s:
+	default:
+	}
+}
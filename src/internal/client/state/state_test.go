@@ -0,0 +1,69 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHubSubscribeReceivesCurrentAndChanges(t *testing.T) {
+	var hub Hub
+	hub.Publish(Status{State: Dialing})
+
+	ch, cancel := hub.Subscribe()
+	defer cancel()
+	if got := (<-ch).State; got != Dialing {
+		t.Fatalf("first status = %v, want dialing", got)
+	}
+
+	hub.Publish(Status{State: Handshaking})
+	if got := (<-ch).State; got != Handshaking {
+		t.Fatalf("status = %v, want handshaking", got)
+	}
+}
+
+func TestHubSlowSubscriberGetsLatest(t *testing.T) {
+	var hub Hub
+	ch, cancel := hub.Subscribe()
+	defer cancel()
+
+	hub.Publish(Status{State: Resolving})
+	hub.Publish(Status{State: Dialing})
+	hub.Publish(Status{State: Connected})
+
+	select {
+	case s := <-ch:
+		if s.State != Connected {
+			t.Fatalf("status = %v, want connected", s.State)
+		}
+		if s.Since.IsZero() {
+			t.Fatal("Publish must stamp Since")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no status delivered")
+	}
+	if got := hub.Current().State; got != Connected {
+		t.Fatalf("Current() = %v, want connected", got)
+	}
+}
+
+func TestHubCancelClosesChannel(t *testing.T) {
+	var hub Hub
+	ch, cancel := hub.Subscribe()
+	<-ch
+	cancel()
+	cancel()
+
+	if _, ok := <-ch; ok {
+		t.Fatal("expected closed channel")
+	}
+	hub.Publish(Status{State: Backoff})
+}
+
+func TestStrings(t *testing.T) {
+	if Backoff.String() != "backoff" || State(99).String() != "unknown" {
+		t.Fatal("unexpected State strings")
+	}
+	if ReasonAuthRejected.String() != "auth rejected" || Reason(99).String() != "unknown" {
+		t.Fatal("unexpected Reason strings")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"net/netip"
+	"time"
 )
 
 type sender interface {
@@ -47,6 +48,12 @@ func New(
 	}
 }
 
+// RTT returns the round trip of the last answered keepalive ping, or zero
+// before the first one.
+func (c *Client) RTT() time.Duration {
+	return c.transport.RTT()
+}
+
 // Run moves packets in both directions until the context is cancelled or one
 // direction fails.
 func (c *Client) Run() error {
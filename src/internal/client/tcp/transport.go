@@ -34,7 +34,11 @@ type transportHandler struct {
 	rekey               transportRekey
 	egress              sender
 	lastRecvNano        atomic.Int64
-	pingBuf             []byte
+	// pingSentNano is set while a ping awaits its Pong.
+	pingSentNano atomic.Int64
+	// rttNano is the round trip of the last answered ping.
+	rttNano atomic.Int64
+	pingBuf []byte
 }
 
 func newTransportHandler(
@@ -104,6 +108,9 @@ func (t *transportHandler) HandleTransport() error {
 					}
 					continue
 				case servicepacket.Pong:
+					if sent := t.pingSentNano.Swap(0); sent != 0 {
+						t.rttNano.Store(time.Now().UnixNano() - sent)
+					}
 					continue
 				}
 			}
@@ -137,9 +144,17 @@ func (t *transportHandler) sendPing() {
 		slog.Warn("keepalive failed to encode ping", "err", err)
 		return
 	}
+	sentAt := time.Now().UnixNano()
 	if err := t.egress.Send(t.pingBuf[:]); err != nil {
 		slog.Warn("keepalive failed to send ping", "err", err)
+		return
 	}
+	t.pingSentNano.Store(sentAt)
+}
+
+// RTT returns the round trip of the last answered ping, or zero.
+func (t *transportHandler) RTT() time.Duration {
+	return time.Duration(t.rttNano.Load())
 }
 
 func (t *transportHandler) handleRekeyAck(carrierEpoch uint16, payload []byte) error {
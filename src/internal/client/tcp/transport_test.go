@@ -567,3 +567,33 @@ func TestTransportHandler_Pong_Consumed(t *testing.T) {
 		t.Fatalf("want io.EOF after Pong, got %v", err)
 	}
 }
+
+func TestTransportHandler_PongRecordsRTT(t *testing.T) {
+	pong := make([]byte, 3)
+	_ = servicepacket.Encode(servicepacket.Pong, pong)
+	cipher := make([]byte, chacha20poly1305.Overhead+len(pong))
+
+	eg := &TransportHandlerMockEgress{}
+	h := newTestTransportHandler(context.Background(),
+		rdr(struct {
+			data []byte
+			err  error
+		}{cipher, nil}, struct {
+			data []byte
+			err  error
+		}{nil, io.EOF}),
+		io.Discard,
+		&TransportHandlerMockCrypto{decOut: pong}, nil, nil, eg)
+
+	if h.RTT() != 0 {
+		t.Fatalf("RTT before ping = %v, want 0", h.RTT())
+	}
+	h.sendPing()
+	time.Sleep(time.Millisecond)
+	if err := h.HandleTransport(); !errors.Is(err, io.EOF) {
+		t.Fatalf("want EOF, got %v", err)
+	}
+	if h.RTT() <= 0 {
+		t.Fatalf("RTT after pong = %v, want > 0", h.RTT())
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"net/netip"
 	"time"
 
-	udptransport "tungo/internal/transport/udp"
+	"tungo/internal/protocol/servicepacket"
+
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
 type sender interface {
@@ -28,6 +30,8 @@ type rekeySession interface {
 // Client moves packets between a TUN device and a UDP transport.
 type Client struct {
 	ctx       context.Context
+	conn      *rebindableConn
+	outbound  *packetSender
 	tun       *tunHandler
 	transport *transportHandler
 }
@@ -47,21 +51,30 @@ func New(
 	}
 
 	const deadline = time.Second
-	udpTransport := udptransport.NewClientConn(udpConn, deadline, deadline)
-	outbound := newPacketSender(udpTransport, crypto)
+	conn := newRebindableConn(udpConn, deadline)
+	outbound := newPacketSender(conn, crypto)
 	tunHandler := newTunHandler(ctx, tun, outbound, rekey, allowedSources)
-	transportHandler := newTransportHandler(ctx, udpTransport, tun, crypto, rekey, outbound)
+	transportHandler := newTransportHandler(ctx, conn, tun, crypto, rekey, outbound)
 
 	return &Client{
 		ctx:       ctx,
+		conn:      conn,
+		outbound:  outbound,
 		tun:       tunHandler,
 		transport: transportHandler,
 	}, nil
 }
 
+// RTT returns the round trip of the last answered keepalive ping, or zero
+// before the first one.
+func (c *Client) RTT() time.Duration {
+	return c.transport.RTT()
+}
+
 // Run moves packets in both directions until the context is cancelled or one
 // direction fails.
 func (c *Client) Run() error {
+	defer func() { _ = c.conn.Close() }()
 	errCh := make(chan error, 2)
 	go func() { errCh <- c.tun.HandleTun() }()
 	go func() { errCh <- c.transport.HandleTransport() }()
@@ -74,6 +87,20 @@ func (c *Client) Run() error {
 	}
 }
 
+// Rebind moves the session to a new socket after the host changed networks
+// and sends a keepalive from it right away, so that the server follows the
+// session without waiting for traffic.
+func (c *Client) Rebind() error {
+	if err := c.conn.rebind(); err != nil {
+		return fmt.Errorf("failed to rebind UDP socket: %w", err)
+	}
+	ping := make([]byte, udpPayloadOffset+3, udpPayloadOffset+3+chacha20poly1305.Overhead)
+	if err := servicepacket.Encode(servicepacket.Ping, ping[udpPayloadOffset:]); err != nil {
+		return err
+	}
+	return c.outbound.Send(ping)
+}
+
 func unwrapUDPConn(transport io.ReadWriteCloser) (*net.UDPConn, error) {
 	current := transport
 	for range 8 {
@@ -0,0 +1,103 @@
+package udp
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	udptransport "tungo/internal/transport/udp"
+)
+
+// rebindableConn carries the session over a connected UDP socket that can be
+// replaced after the host changed networks. The server moves the session to
+// the new source address once a packet from it authenticates.
+//
+// The caller keeps owning the socket it passed in; rebind and Close only
+// close the sockets opened by rebind.
+type rebindableConn struct {
+	remote   *net.UDPAddr
+	deadline time.Duration
+	original *net.UDPConn
+
+	mu      sync.Mutex
+	closed  bool
+	socket  *net.UDPConn
+	current atomic.Pointer[io.ReadWriteCloser]
+}
+
+func newRebindableConn(conn *net.UDPConn, deadline time.Duration) *rebindableConn {
+	c := &rebindableConn{deadline: deadline, original: conn, socket: conn}
+	c.remote, _ = conn.RemoteAddr().(*net.UDPAddr)
+	c.store(conn)
+	return c
+}
+
+func (c *rebindableConn) store(conn *net.UDPConn) {
+	rw := udptransport.NewClientConn(conn, c.deadline, c.deadline)
+	c.current.Store(&rw)
+}
+
+// Read and Write retry on the new socket when the old one was closed by a
+// rebind under them.
+func (c *rebindableConn) Read(p []byte) (int, error) {
+	for {
+		current := c.current.Load()
+		n, err := (*current).Read(p)
+		if err != nil && c.current.Load() != current {
+			continue
+		}
+		return n, err
+	}
+}
+
+func (c *rebindableConn) Write(p []byte) (int, error) {
+	for {
+		current := c.current.Load()
+		n, err := (*current).Write(p)
+		if err != nil && c.current.Load() != current {
+			continue
+		}
+		return n, err
+	}
+}
+
+// rebind replaces the socket with a new one that takes its source address
+// from the current route to the server.
+func (c *rebindableConn) rebind() error {
+	if c.remote == nil {
+		return net.ErrClosed
+	}
+	conn, err := net.DialUDP("udp", nil, c.remote)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		_ = conn.Close()
+		return net.ErrClosed
+	}
+	old := c.socket
+	c.socket = conn
+	c.store(conn)
+	if old == c.original {
+		// Wake a Read blocked on the caller's socket without closing it.
+		return old.SetReadDeadline(time.Now())
+	}
+	return old.Close()
+}
+
+func (c *rebindableConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.socket == c.original {
+		return nil
+	}
+	return c.socket.Close()
+}
@@ -0,0 +1,131 @@
+package udp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"tungo/internal/protocol/servicepacket"
+)
+
+func rebindTestSockets(t *testing.T) (server, client *net.UDPConn) {
+	t.Helper()
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+	client, err = net.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return server, client
+}
+
+func receiveFrom(t *testing.T, server *net.UDPConn) ([]byte, netip.AddrPort) {
+	t.Helper()
+	_ = server.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 64)
+	n, from, err := server.ReadFromUDPAddrPort(buf)
+	if err != nil {
+		t.Fatalf("server read: %v", err)
+	}
+	return buf[:n], from
+}
+
+func TestRebindableConnMovesToNewSocket(t *testing.T) {
+	server, original := rebindTestSockets(t)
+	conn := newRebindableConn(original, time.Second)
+
+	if _, err := conn.Write([]byte("before")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	_, oldAddr := receiveFrom(t, server)
+
+	// A read blocked on the old socket continues on the new one.
+	read := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		if err != nil {
+			read <- err.Error()
+			return
+		}
+		read <- string(buf[:n])
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	if err := conn.rebind(); err != nil {
+		t.Fatalf("rebind() error = %v", err)
+	}
+	if _, err := conn.Write([]byte("after")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	payload, newAddr := receiveFrom(t, server)
+	if string(payload) != "after" || newAddr == oldAddr {
+		t.Fatalf("got %q from %v, want a packet from a new source than %v", payload, newAddr, oldAddr)
+	}
+
+	if _, err := server.WriteToUDPAddrPort([]byte("reply"), newAddr); err != nil {
+		t.Fatalf("server write: %v", err)
+	}
+	select {
+	case got := <-read:
+		if got != "reply" {
+			t.Fatalf("Read() = %q, want reply", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Read() did not move to the new socket")
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := conn.rebind(); err == nil {
+		t.Fatal("rebind() after Close() must fail")
+	}
+}
+
+func TestRebindableConnKeepsCallerSocket(t *testing.T) {
+	server, original := rebindTestSockets(t)
+	conn := newRebindableConn(original, time.Second)
+
+	if err := conn.rebind(); err != nil {
+		t.Fatalf("rebind() error = %v", err)
+	}
+	if err := conn.rebind(); err != nil {
+		t.Fatalf("second rebind() error = %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := original.Write([]byte("still open")); err != nil {
+		t.Fatalf("caller socket closed: %v", err)
+	}
+	if payload, _ := receiveFrom(t, server); string(payload) != "still open" {
+		t.Fatalf("server got %q", payload)
+	}
+}
+
+func TestClientRebindSendsKeepalive(t *testing.T) {
+	server, original := rebindTestSockets(t)
+	client, err := New(context.Background(), original, nil, thAckCrypto{}, nil, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = client.conn.Close() })
+
+	if err := client.Rebind(); err != nil {
+		t.Fatalf("Rebind() error = %v", err)
+	}
+	payload, from := receiveFrom(t, server)
+	if from == original.LocalAddr().(*net.UDPAddr).AddrPort() {
+		t.Fatal("keepalive sent from the old socket")
+	}
+	if spType, ok := servicepacket.Parse(payload[udpPayloadOffset:]); !ok || spType != servicepacket.Ping {
+		t.Fatalf("sent %v, want a ping", payload)
+	}
+}
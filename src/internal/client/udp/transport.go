@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"tungo/internal/config/settings"
@@ -33,7 +34,10 @@ type transportHandler struct {
 	egress              sender
 	lastRecvAt          time.Time
 	lastPingSentAt      time.Time
-	pingBuf             []byte
+	pingPending         bool
+	// rttNano is the round trip of the last answered ping.
+	rttNano atomic.Int64
+	pingBuf []byte
 }
 
 func newTransportHandler(
@@ -153,8 +157,14 @@ func (t *transportHandler) handleControlplane(
 			}
 		}
 		return true, nil
+	case servicepacket.Pong:
+		if t.pingPending {
+			t.pingPending = false
+			t.rttNano.Store(int64(time.Since(t.lastPingSentAt)))
+		}
+		return true, nil
 	default:
-		// ignore unknown service_packet packets (including Pong — recv timer already reset above)
+		// ignore unknown service_packet packets (recv timer already reset above)
 		return true, nil
 	}
 }
@@ -178,4 +188,10 @@ func (t *transportHandler) sendPing() {
 		return
 	}
 	t.lastPingSentAt = time.Now()
+	t.pingPending = true
+}
+
+// RTT returns the round trip of the last answered ping, or zero.
+func (t *transportHandler) RTT() time.Duration {
+	return time.Duration(t.rttNano.Load())
 }
@@ -944,3 +944,23 @@ func TestHandleTransport_WriteErrorAfterCancel(t *testing.T) {
 		t.Fatalf("expected nil after cancel, got %v", err)
 	}
 }
+
+func TestHandleDatagram_PongRecordsRTT(t *testing.T) {
+	pongSP := []byte{0xFF, 0x01, byte(servicepacket.Pong)}
+	cipher := buildTestUDPPacket(0, pongSP)
+	eg := &capturingEgress{}
+	h := newTestTransportHandler(context.Background(), &thTestReader{}, &thTestWriter{}, &thTestCrypto{output: pongSP}, nil, nil, eg)
+
+	// An unsolicited Pong carries no timing.
+	if _, err := h.handleDatagram(cipher); err != nil || h.RTT() != 0 {
+		t.Fatalf("err=%v RTT=%v, want nil and 0", err, h.RTT())
+	}
+	h.sendPing()
+	time.Sleep(time.Millisecond)
+	if _, err := h.handleDatagram(cipher); err != nil {
+		t.Fatal(err)
+	}
+	if h.RTT() <= 0 {
+		t.Fatalf("RTT after pong = %v, want > 0", h.RTT())
+	}
+}
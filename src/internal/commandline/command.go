@@ -14,16 +14,45 @@ const (
 	CommandRuntime
 	CommandVersion
 	CommandServerConfigGenerate
+	CommandClientProxy
+	CommandClientDown
+	CommandClientList
+	CommandServerPeerList
+	CommandServerPeerShow
+	CommandServerPeerEnable
+	CommandServerPeerDisable
+	CommandServerPeerRemove
+	CommandServerPeerRename
+	CommandClientKeygen
+	CommandKeygen
+	CommandPubkey
+	CommandServerCheck
+	CommandClientCheck
+	CommandServerKeyFile
+	CommandClientKeyFile
+	CommandServerSessionList
+	CommandServerSessionTerminate
 )
 
+// maxOperands is the most operands a command takes.
+const maxOperands = 2
+
 type Command struct {
 	Kind              CommandKind
 	RuntimeMode       config.Mode
 	RequiresElevation bool
+	// Operands hold the arguments after the command words, such as the
+	// instance name of "c up <name>", in order.
+	Operands [maxOperands]string
 }
 
 type commandSpec struct {
-	args        []string
+	args []string
+	// operands name the arguments, such as "<name>", that the spec takes
+	// after args.
+	operands []string
+	// flags are the flags that only this command accepts.
+	flags       []flagSpec
 	description string
 	command     Command
 }
@@ -39,11 +68,127 @@ var commands = []commandSpec{
 		description: "Start client runtime",
 		command:     Command{Kind: CommandRuntime, RuntimeMode: config.ModeClient, RequiresElevation: true},
 	},
+	{
+		args:        []string{"c", "proxy"},
+		description: "Start rootless client with SOCKS5 and HTTP proxies",
+		command:     Command{Kind: CommandClientProxy},
+	},
+	{
+		args:        []string{"c", "up"},
+		operands:    []string{"<name>"},
+		description: "Start the named client instance",
+		command:     Command{Kind: CommandRuntime, RuntimeMode: config.ModeClient, RequiresElevation: true},
+	},
+	{
+		args:        []string{"c", "down"},
+		operands:    []string{"<name>"},
+		description: "Stop the named client instance",
+		command:     Command{Kind: CommandClientDown, RequiresElevation: true},
+	},
+	{
+		args:        []string{"c", "list"},
+		description: "List client instances",
+		command:     Command{Kind: CommandClientList, RequiresElevation: true},
+	},
+	{
+		args:        []string{"c", "keygen"},
+		description: "Make the client key pair and print the public key to enroll",
+		command:     Command{Kind: CommandClientKeygen, RequiresElevation: true},
+	},
+	{
+		args:        []string{"c", "check"},
+		description: "Check the client configuration and host without changing them",
+		command:     Command{Kind: CommandClientCheck, RequiresElevation: true},
+	},
+	{
+		args:        []string{"c", "check"},
+		operands:    []string{"<name>"},
+		description: "Check the named client instance without changing anything",
+		command:     Command{Kind: CommandClientCheck, RequiresElevation: true},
+	},
+	{
+		args:        []string{"c", "keyfile"},
+		description: "Move the client private key into a key file next to the configuration",
+		command:     Command{Kind: CommandClientKeyFile, RequiresElevation: true},
+	},
+	{
+		args:        []string{"c", "keyfile"},
+		operands:    []string{"<name>"},
+		description: "Move the private key of the named client instance into a key file",
+		command:     Command{Kind: CommandClientKeyFile, RequiresElevation: true},
+	},
 	{
 		args:        []string{"s", "gen"},
+		flags:       generateFlags,
 		description: "Generate server configuration",
 		command:     Command{Kind: CommandServerConfigGenerate, RequiresElevation: true},
 	},
+	{
+		args:        []string{"s", "check"},
+		description: "Check the server configuration and host without changing them",
+		command:     Command{Kind: CommandServerCheck, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "keyfile"},
+		description: "Move the server private key into a key file next to the configuration",
+		command:     Command{Kind: CommandServerKeyFile, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "peers", "list"},
+		description: "List peers",
+		command:     Command{Kind: CommandServerPeerList, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "peers", "show"},
+		operands:    []string{"<id>"},
+		description: "Show a peer",
+		command:     Command{Kind: CommandServerPeerShow, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "peers", "enable"},
+		operands:    []string{"<id>"},
+		description: "Allow a peer to connect",
+		command:     Command{Kind: CommandServerPeerEnable, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "peers", "disable"},
+		operands:    []string{"<id>"},
+		description: "Revoke a peer and close its sessions",
+		command:     Command{Kind: CommandServerPeerDisable, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "peers", "remove"},
+		operands:    []string{"<id>"},
+		description: "Remove a peer and close its sessions",
+		command:     Command{Kind: CommandServerPeerRemove, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "peers", "rename"},
+		operands:    []string{"<id>", "<name>"},
+		description: "Rename a peer",
+		command:     Command{Kind: CommandServerPeerRename, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "admin", "sessions"},
+		description: "List the live sessions of the running server",
+		command:     Command{Kind: CommandServerSessionList, RequiresElevation: true},
+	},
+	{
+		args:        []string{"s", "admin", "terminate"},
+		operands:    []string{"<ip|key>"},
+		description: "Close the live sessions of a tunnel address or public key",
+		command:     Command{Kind: CommandServerSessionTerminate, RequiresElevation: true},
+	},
+	{
+		args:        []string{"keygen"},
+		description: "Print a new private key",
+		command:     Command{Kind: CommandKeygen},
+	},
+	{
+		args:        []string{"pubkey"},
+		description: "Print the public key of the private key read from stdin",
+		command:     Command{Kind: CommandPubkey},
+	},
 	{
 		args:        []string{"version"},
 		description: "Show version",
@@ -51,24 +196,45 @@ var commands = []commandSpec{
 	},
 }
 
+// ParseCommand matches the command words of a command line; Parse also
+// handles flags.
 func ParseCommand(args []string) (Command, error) {
-	for _, spec := range commands {
-		if matches(args, spec.args) {
-			return spec.command, nil
+	command, _, err := parseCommand(args)
+	return command, err
+}
+
+func parseCommand(args []string) (Command, *commandSpec, error) {
+	for i, spec := range commands {
+		if len(args) != len(spec.args)+len(spec.operands) || !matches(args[:len(spec.args)], spec.args) {
+			continue
+		}
+		command := spec.command
+		for i, name := range spec.operands {
+			operand := strings.TrimSpace(args[len(spec.args)+i])
+			if operand == "" {
+				return Command{}, nil, fmt.Errorf("missing %s", name)
+			}
+			command.Operands[i] = operand
 		}
+		return command, &commands[i], nil
 	}
-	return Command{}, errors.New("invalid arguments")
+	return Command{}, nil, errors.New("invalid arguments")
 }
 
 func RuntimeModeArgs(mode config.Mode) ([]string, error) {
 	for _, spec := range commands {
-		if spec.command.Kind == CommandRuntime && spec.command.RuntimeMode == mode {
+		if spec.command.Kind == CommandRuntime && spec.command.RuntimeMode == mode && len(spec.operands) == 0 {
 			return append([]string(nil), spec.args...), nil
 		}
 	}
 	return nil, fmt.Errorf("unsupported runtime mode: %v", mode)
 }
 
+// InstanceArgs returns the arguments that start the named client instance.
+func InstanceArgs(name string) []string {
+	return []string{"c", "up", name}
+}
+
 func matches(got, want []string) bool {
 	if len(got) != len(want) {
 		return false
@@ -83,9 +249,14 @@ func matches(got, want []string) bool {
 
 func CommandUsage(commandName string) string {
 	var b strings.Builder
-	_, _ = fmt.Fprintf(&b, "Usage: %s <command>\nCommands:\n", commandName)
+	_, _ = fmt.Fprintf(&b, "Usage: %s <command> [flags]\nCommands:\n", commandName)
 	for _, spec := range commands {
-		_, _ = fmt.Fprintf(&b, "  %s  - %s\n", strings.Join(spec.args, " "), spec.description)
+		_, _ = fmt.Fprintf(&b, "  %s  - %s\n", spec.usage(), spec.description)
 	}
+	writeFlags(&b, nil)
 	return b.String()
 }
+
+func (s commandSpec) usage() string {
+	return strings.Join(append(append([]string(nil), s.args...), s.operands...), " ")
+}
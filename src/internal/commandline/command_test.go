@@ -15,6 +15,23 @@ func TestParseCommandOK(t *testing.T) {
 		{[]string{"s"}, Command{Kind: CommandRuntime, RuntimeMode: config.ModeServer, RequiresElevation: true}},
 		{[]string{"  c  "}, Command{Kind: CommandRuntime, RuntimeMode: config.ModeClient, RequiresElevation: true}},
 		{[]string{"s", "gen"}, Command{Kind: CommandServerConfigGenerate, RequiresElevation: true}},
+		{[]string{"c", "proxy"}, Command{Kind: CommandClientProxy}},
+		{[]string{"c", "up", "prod"}, Command{Kind: CommandRuntime, RuntimeMode: config.ModeClient, RequiresElevation: true, Operands: [maxOperands]string{"prod"}}},
+		{[]string{"c", "down", " prod "}, Command{Kind: CommandClientDown, RequiresElevation: true, Operands: [maxOperands]string{"prod"}}},
+		{[]string{"c", "list"}, Command{Kind: CommandClientList, RequiresElevation: true}},
+		{[]string{"s", "peers", "list"}, Command{Kind: CommandServerPeerList, RequiresElevation: true}},
+		{[]string{"s", "peers", "disable", "7"}, Command{Kind: CommandServerPeerDisable, RequiresElevation: true, Operands: [maxOperands]string{"7"}}},
+		{[]string{"s", "admin", "sessions"}, Command{Kind: CommandServerSessionList, RequiresElevation: true}},
+		{[]string{"s", "admin", "terminate", "10.0.0.2"}, Command{Kind: CommandServerSessionTerminate, RequiresElevation: true, Operands: [maxOperands]string{"10.0.0.2"}}},
+		{[]string{"c", "keygen"}, Command{Kind: CommandClientKeygen, RequiresElevation: true}},
+		{[]string{"s", "check"}, Command{Kind: CommandServerCheck, RequiresElevation: true}},
+		{[]string{"c", "check"}, Command{Kind: CommandClientCheck, RequiresElevation: true}},
+		{[]string{"c", "check", "prod"}, Command{Kind: CommandClientCheck, RequiresElevation: true, Operands: [maxOperands]string{"prod"}}},
+		{[]string{"s", "keyfile"}, Command{Kind: CommandServerKeyFile, RequiresElevation: true}},
+		{[]string{"c", "keyfile"}, Command{Kind: CommandClientKeyFile, RequiresElevation: true}},
+		{[]string{"c", "keyfile", "prod"}, Command{Kind: CommandClientKeyFile, RequiresElevation: true, Operands: [maxOperands]string{"prod"}}},
+		{[]string{"keygen"}, Command{Kind: CommandKeygen}},
+		{[]string{"pubkey"}, Command{Kind: CommandPubkey}},
 		{[]string{" version "}, Command{Kind: CommandVersion}},
 	}
 
@@ -36,6 +53,12 @@ func TestParseCommandErrors(t *testing.T) {
 	if err == nil || got.Kind != CommandUnknown {
 		t.Fatalf("expected unknown command error for invalid args, got %+v err=%v", got, err)
 	}
+
+	for _, args := range [][]string{{"c", "up"}, {"c", "up", " "}, {"c", "down", "a", "b"}, {"s", "peers", "rename", "3"}} {
+		if got, err := ParseCommand(args); err == nil || got.Kind != CommandUnknown {
+			t.Fatalf("expected error for args=%q, got %+v", args, got)
+		}
+	}
 }
 
 func TestRuntimeModeArgs(t *testing.T) {
@@ -67,6 +90,14 @@ func TestRuntimeModeArgs(t *testing.T) {
 	if _, err := RuntimeModeArgs(0); err == nil {
 		t.Fatal("expected error for invalid runtime mode")
 	}
+
+	if got := strings.Join(InstanceArgs("prod"), " "); got != "c up prod" {
+		t.Fatalf("expected instance args, got %q", got)
+	}
+	parsed, err := ParseCommand(InstanceArgs("prod"))
+	if err != nil || parsed.Operands[0] != "prod" {
+		t.Fatalf("instance args do not parse back: %+v err=%v", parsed, err)
+	}
 }
 
 func TestCommandUsage(t *testing.T) {
@@ -75,6 +106,13 @@ func TestCommandUsage(t *testing.T) {
 		!strings.Contains(got, "s  - Start server runtime") ||
 		!strings.Contains(got, "c  - Start client runtime") ||
 		!strings.Contains(got, "s gen  - Generate server configuration") ||
+		!strings.Contains(got, "c proxy  - Start rootless client with SOCKS5 and HTTP proxies") ||
+		!strings.Contains(got, "c up <name>  - Start the named client instance") ||
+		!strings.Contains(got, "c list  - List client instances") ||
+		!strings.Contains(got, "s peers rename <id> <name>  - Rename a peer") ||
+		!strings.Contains(got, "s admin terminate <ip|key>  - Close the live sessions of a tunnel address or public key") ||
+		!strings.Contains(got, "c keygen  - Make the client key pair and print the public key to enroll") ||
+		!strings.Contains(got, "pubkey  - Print the public key of the private key read from stdin") ||
 		!strings.Contains(got, "version  - Show version") {
 		t.Fatalf("unexpected usage: %q", got)
 	}
@@ -0,0 +1,199 @@
+package commandline
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"tungo/internal/logging"
+)
+
+// Options are the flags that every command accepts. A flag that is not given
+// falls back to its environment variable.
+type Options struct {
+	// ConfigPath replaces the default configuration file of the command.
+	ConfigPath string
+	LogLevel   slog.Level
+	LogFormat  logging.Format
+	Output     Output
+}
+
+// Output selects how commands that report data print it.
+type Output string
+
+const (
+	OutputTable Output = "table"
+	OutputJSON  Output = "json"
+)
+
+// Invocation is a parsed command line.
+type Invocation struct {
+	Command Command
+	Options Options
+	// Generate holds the flags of "s gen".
+	Generate Generate
+	// Help is set by --help; CommandHelp renders the help it asks for.
+	Help  bool
+	topic *commandSpec
+}
+
+type flagSpec struct {
+	name string
+	// value names the argument of the flag. A flag without one is a switch,
+	// which reads as "true" when given.
+	value       string
+	env         string
+	description string
+}
+
+const (
+	flagConfig = iota
+	flagLogLevel
+	flagLogFormat
+	flagOutput
+)
+
+var flags = [...]flagSpec{
+	flagConfig:    {name: "config", value: "<path>", env: "TUNGO_CONFIG", description: "Use this configuration file instead of the default"},
+	flagLogLevel:  {name: "log-level", value: "<level>", env: "TUNGO_LOG_LEVEL", description: "Log level: debug, info, warn or error (default info)"},
+	flagLogFormat: {name: "log-format", value: "<format>", env: "TUNGO_LOG_FORMAT", description: "Log format: text or json (default text)"},
+	flagOutput:    {name: "output", value: "<format>", description: "Report format: table or json (default table)"},
+}
+
+// Parse splits args into a command and its flags. Flags go before, between
+// or after the command words, as --name value or --name=value.
+func Parse(args []string) (Invocation, error) {
+	set := flag.NewFlagSet("", flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	var values [len(flags)]string
+	for i, f := range flags {
+		set.StringVar(&values[i], f.name, "", f.description)
+	}
+	commandValues := make(map[string]string)
+	for _, spec := range commands {
+		for _, f := range spec.flags {
+			if set.Lookup(f.name) != nil {
+				continue
+			}
+			parse := set.Func
+			if f.value == "" {
+				parse = set.BoolFunc
+			}
+			parse(f.name, f.description, func(value string) error {
+				commandValues[f.name] = value
+				return nil
+			})
+		}
+	}
+
+	var positional []string
+	rest := args
+	for {
+		if err := set.Parse(rest); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return Invocation{Help: true, topic: findSpec(positional)}, nil
+			}
+			return Invocation{}, err
+		}
+		rest = set.Args()
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		rest = rest[1:]
+	}
+
+	for i, f := range flags {
+		if values[i] == "" && f.env != "" {
+			values[i] = strings.TrimSpace(os.Getenv(f.env))
+		}
+	}
+	options := Options{ConfigPath: values[flagConfig], LogFormat: logging.FormatText, Output: OutputTable}
+	if values[flagLogLevel] != "" {
+		level, err := logging.ParseLevel(values[flagLogLevel])
+		if err != nil {
+			return Invocation{}, err
+		}
+		options.LogLevel = level
+	}
+	if values[flagLogFormat] != "" {
+		format, err := logging.ParseFormat(values[flagLogFormat])
+		if err != nil {
+			return Invocation{}, err
+		}
+		options.LogFormat = format
+	}
+	switch output := Output(strings.ToLower(values[flagOutput])); output {
+	case "":
+	case OutputTable, OutputJSON:
+		options.Output = output
+	default:
+		return Invocation{}, fmt.Errorf("unknown output format %q: use table or json", values[flagOutput])
+	}
+
+	command, spec, err := parseCommand(positional)
+	if err != nil {
+		return Invocation{}, err
+	}
+	for _, name := range slices.Sorted(maps.Keys(commandValues)) {
+		if !slices.ContainsFunc(spec.flags, func(f flagSpec) bool { return f.name == name }) {
+			return Invocation{}, fmt.Errorf("flag --%s does not apply to %q", name, spec.usage())
+		}
+	}
+	invocation := Invocation{Command: command, Options: options}
+	if command.Kind == CommandServerConfigGenerate {
+		if invocation.Generate, err = parseGenerate(commandValues, time.Now()); err != nil {
+			return Invocation{}, err
+		}
+	}
+	return invocation, nil
+}
+
+// findSpec returns the command that args name, with or without its operand.
+func findSpec(args []string) *commandSpec {
+	for i, spec := range commands {
+		if len(args) >= len(spec.args) && len(args) <= len(spec.args)+len(spec.operands) &&
+			matches(args[:len(spec.args)], spec.args) {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// CommandHelp returns the help that invocation asks for: the usage of one
+// command, or of all of them.
+func CommandHelp(commandName string, invocation Invocation) string {
+	spec := invocation.topic
+	if spec == nil {
+		return CommandUsage(commandName)
+	}
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "Usage: %s %s [flags]\n\n%s\n", commandName, spec.usage(), spec.description)
+	writeFlags(&b, spec.flags)
+	return b.String()
+}
+
+// writeFlags lists the flags of a command before the flags that every
+// command accepts.
+func writeFlags(b *strings.Builder, commandFlags []flagSpec) {
+	b.WriteString("Flags:\n")
+	for _, f := range slices.Concat(commandFlags, flags[:]) {
+		usage := "--" + f.name
+		if f.value != "" {
+			usage += " " + f.value
+		}
+		if f.env == "" {
+			_, _ = fmt.Fprintf(b, "  %s  - %s\n", usage, f.description)
+			continue
+		}
+		_, _ = fmt.Fprintf(b, "  %s  - %s (env %s)\n", usage, f.description, f.env)
+	}
+	b.WriteString("  --help  - Show help for a command\n")
+}
@@ -0,0 +1,107 @@
+package commandline
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"tungo/internal/config"
+	"tungo/internal/logging"
+)
+
+func TestParseFlagsAroundCommand(t *testing.T) {
+	cases := []struct {
+		in      []string
+		command Command
+		options Options
+	}{
+		{
+			in:      []string{"c"},
+			command: Command{Kind: CommandRuntime, RuntimeMode: config.ModeClient, RequiresElevation: true},
+			options: Options{LogLevel: slog.LevelInfo, LogFormat: logging.FormatText, Output: OutputTable},
+		},
+		{
+			in:      []string{"--config", "/tmp/client.json", "c", "--log-level=debug"},
+			command: Command{Kind: CommandRuntime, RuntimeMode: config.ModeClient, RequiresElevation: true},
+			options: Options{ConfigPath: "/tmp/client.json", LogLevel: slog.LevelDebug, LogFormat: logging.FormatText, Output: OutputTable},
+		},
+		{
+			in:      []string{"s", "--log-format", "json", "gen"},
+			command: Command{Kind: CommandServerConfigGenerate, RequiresElevation: true},
+			options: Options{LogLevel: slog.LevelInfo, LogFormat: logging.FormatJSON, Output: OutputTable},
+		},
+		{
+			in:      []string{"c", "up", "prod", "-config=/tmp/c.json"},
+			command: Command{Kind: CommandRuntime, RuntimeMode: config.ModeClient, RequiresElevation: true, Operands: [maxOperands]string{"prod"}},
+			options: Options{ConfigPath: "/tmp/c.json", LogLevel: slog.LevelInfo, LogFormat: logging.FormatText, Output: OutputTable},
+		},
+		{
+			in:      []string{"s", "peers", "rename", "3", "--output=json", "alice"},
+			command: Command{Kind: CommandServerPeerRename, RequiresElevation: true, Operands: [maxOperands]string{"3", "alice"}},
+			options: Options{LogLevel: slog.LevelInfo, LogFormat: logging.FormatText, Output: OutputJSON},
+		},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.in)
+		if err != nil || got.Help || got.Command != c.command || got.Options != c.options {
+			t.Fatalf("args=%q got=%+v err=%v", c.in, got, err)
+		}
+	}
+}
+
+func TestParseEnvironmentOverrides(t *testing.T) {
+	t.Setenv("TUNGO_CONFIG", "/srv/server.json")
+	t.Setenv("TUNGO_LOG_LEVEL", "warn")
+	t.Setenv("TUNGO_LOG_FORMAT", "json")
+
+	got, err := Parse([]string{"s"})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := Options{ConfigPath: "/srv/server.json", LogLevel: slog.LevelWarn, LogFormat: logging.FormatJSON, Output: OutputTable}
+	if got.Options != want {
+		t.Fatalf("options = %+v, want %+v", got.Options, want)
+	}
+
+	got, err = Parse([]string{"s", "--log-level", "error", "--config", "/etc/other.json"})
+	if err != nil || got.Options.LogLevel != slog.LevelError || got.Options.ConfigPath != "/etc/other.json" {
+		t.Fatalf("flags should win over the environment, got %+v err=%v", got.Options, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"c", "--unknown"},
+		{"c", "--config"},
+		{"c", "--log-level", "loud"},
+		{"c", "--log-format=xml"},
+		{"s", "peers", "list", "--output", "yaml"},
+		{"--log-level", "debug"},
+	} {
+		if _, err := Parse(args); err == nil {
+			t.Fatalf("expected error for args=%q", args)
+		}
+	}
+}
+
+func TestCommandHelp(t *testing.T) {
+	got, err := Parse([]string{"c", "up", "--help"})
+	if err != nil || !got.Help {
+		t.Fatalf("Parse() = %+v, %v", got, err)
+	}
+	help := CommandHelp("tungo", got)
+	if !strings.Contains(help, "Usage: tungo c up <name> [flags]") ||
+		!strings.Contains(help, "Start the named client instance") ||
+		!strings.Contains(help, "--config <path>") ||
+		strings.Contains(help, "s gen") {
+		t.Fatalf("unexpected command help: %q", help)
+	}
+
+	got, err = Parse([]string{"-h"})
+	if err != nil || !got.Help {
+		t.Fatalf("Parse() = %+v, %v", got, err)
+	}
+	if help := CommandHelp("tungo", got); help != CommandUsage("tungo") {
+		t.Fatalf("expected general usage, got %q", help)
+	}
+}
@@ -0,0 +1,121 @@
+package commandline
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"tungo/internal/config"
+	"tungo/internal/config/settings"
+	"tungo/internal/protocol/keys"
+)
+
+// OutStdout is the --out value that prints the configurations without
+// saving them anywhere.
+const OutStdout = "-"
+
+// Generate holds the flags of "s gen".
+type Generate struct {
+	Options config.GenerateOptions
+	// Out is a file, a directory or a .zip archive to write the
+	// configurations to, or OutStdout. Empty prints them as before.
+	Out string
+}
+
+var generateFlags = []flagSpec{
+	{name: "name", value: "<name>", description: "Peer name; a batch is named <name>-1, <name>-2, ..."},
+	{name: "protocol", value: "<protocol>", description: "Protocol the client starts with: udp, tcp, ws or wss"},
+	{name: "host", value: "<host>", description: "Address clients dial, instead of the configured or detected one"},
+	{name: "dns", value: "<ip,...>", description: "DNS servers of the client"},
+	{name: "expires", value: "<when>", description: "Revoke the peers after a duration (72h, 30d) or at a date (2026-12-31)"},
+	{name: "count", value: "<n>", description: "Number of configurations to generate (default 1)"},
+	{name: "public-key", value: "<key>", description: "Enroll a client that ran \"c keygen\"; the configuration carries no private key"},
+	{name: "psk", description: "Give each peer a pre-shared key, which the handshake requires on top of its key pair"},
+	{name: "out", value: "<path>", description: "Write to a file, a directory or a .zip archive; - prints to stdout and keeps no copy"},
+}
+
+// parseGenerate reads the generateFlags in values, resolving --expires
+// against now.
+func parseGenerate(values map[string]string, now time.Time) (Generate, error) {
+	g := Generate{
+		Options: config.GenerateOptions{
+			Name: strings.TrimSpace(values["name"]),
+			Host: strings.TrimSpace(values["host"]),
+		},
+		Out: strings.TrimSpace(values["out"]),
+	}
+	g.Options.Discard = g.Out == OutStdout
+	if raw := values["psk"]; raw != "" {
+		psk, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Generate{}, fmt.Errorf("invalid --psk value %q", raw)
+		}
+		g.Options.PresharedKey = psk
+	}
+	if raw := values["protocol"]; raw != "" {
+		protocol, err := settings.ParseProtocol(raw)
+		if err != nil {
+			return Generate{}, fmt.Errorf("unknown protocol %q: use udp, tcp, ws or wss", raw)
+		}
+		g.Options.Protocol = protocol
+	}
+	if raw := values["dns"]; raw != "" {
+		for field := range strings.SplitSeq(raw, ",") {
+			addr, err := netip.ParseAddr(strings.TrimSpace(field))
+			if err != nil {
+				return Generate{}, fmt.Errorf("invalid DNS server %q", field)
+			}
+			g.Options.DNS = append(g.Options.DNS, addr)
+		}
+	}
+	if raw := values["expires"]; raw != "" {
+		expiresAt, err := parseExpiry(raw, now)
+		if err != nil {
+			return Generate{}, err
+		}
+		g.Options.ExpiresAt = expiresAt
+	}
+	if raw := values["count"]; raw != "" {
+		count, err := strconv.Atoi(raw)
+		if err != nil || count < 1 {
+			return Generate{}, fmt.Errorf("invalid count %q: use a positive number", raw)
+		}
+		g.Options.Count = count
+	}
+	if raw := values["public-key"]; raw != "" {
+		public, err := keys.ParseKey(raw)
+		if err != nil {
+			return Generate{}, fmt.Errorf("invalid public key: %w", err)
+		}
+		if g.Options.Count > 1 {
+			return Generate{}, errors.New("--public-key enrolls a single peer; drop --count")
+		}
+		g.Options.PublicKey = public
+	}
+	return g, nil
+}
+
+// parseExpiry accepts a duration such as 72h or 30d, counted from now, an
+// RFC 3339 time, or a date, which expires at the start of that day in the
+// local time zone.
+func parseExpiry(raw string, now time.Time) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, n), nil
+		}
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, raw, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry %q: use a duration such as 72h or 30d, or a date such as 2026-12-31", raw)
+}
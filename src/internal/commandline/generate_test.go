@@ -0,0 +1,100 @@
+package commandline
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"tungo/internal/config/settings"
+)
+
+func TestParseGenerateFlags(t *testing.T) {
+	got, err := Parse([]string{
+		"s", "gen", "--name", "team", "--protocol=wss", "--host", "vpn.example.com",
+		"--dns", "1.1.1.1, 2606:4700:4700::1111", "--count", "3", "--out", "-",
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	options := got.Generate.Options
+	wantDNS := []netip.Addr{netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("2606:4700:4700::1111")}
+	if options.Name != "team" || options.Protocol != settings.WSS || options.Host != "vpn.example.com" ||
+		options.Count != 3 || !options.Discard || !slices.Equal(options.DNS, wantDNS) || got.Generate.Out != OutStdout {
+		t.Fatalf("Generate = %+v", got.Generate)
+	}
+
+	got, err = Parse([]string{"s", "gen", "--public-key", strings.Repeat("A", 43) + "="})
+	if err != nil || len(got.Generate.Options.PublicKey) != 32 {
+		t.Fatalf("Parse() = %+v, %v; want an enrollment", got.Generate, err)
+	}
+
+	got, err = Parse([]string{"s", "gen", "--psk", "--count", "2"})
+	if err != nil || !got.Generate.Options.PresharedKey || got.Generate.Options.Count != 2 {
+		t.Fatalf("Parse() = %+v, %v; want pre-shared keys", got.Generate, err)
+	}
+
+	got, err = Parse([]string{"s", "gen"})
+	if err != nil || got.Generate.Options.PresharedKey || got.Generate.Options.Count != 0 || got.Generate.Out != "" || got.Generate.Options.Discard {
+		t.Fatalf("Parse() = %+v, %v; want default generation", got.Generate, err)
+	}
+}
+
+func TestParseGenerateFlagErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"s", "gen", "--protocol", "sctp"},
+		{"s", "gen", "--dns", "1.1.1.1,resolver"},
+		{"s", "gen", "--count", "0"},
+		{"s", "gen", "--expires", "soon"},
+		{"s", "gen", "--public-key", "AAAA"},
+		{"s", "gen", "--public-key", strings.Repeat("A", 43) + "=", "--count", "2"},
+		{"s", "gen", "--psk=maybe"},
+		{"s", "peers", "list", "--count", "2"},
+		{"c", "--out", "/tmp/x"},
+	} {
+		if _, err := Parse(args); err == nil {
+			t.Fatalf("expected error for args=%q", args)
+		}
+	}
+	_, err := Parse([]string{"c", "--name", "phone"})
+	if err == nil || !strings.Contains(err.Error(), `flag --name does not apply to "c"`) {
+		t.Fatalf("error = %v, want flag rejected for c", err)
+	}
+}
+
+func TestParseExpiry(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Time{
+		"72h":                  now.Add(72 * time.Hour),
+		"30d":                  now.AddDate(0, 0, 30),
+		"2026-12-31":           time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+		"2026-06-01T08:00:00Z": time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for raw, want := range tests {
+		got, err := parseExpiry(raw, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseExpiry(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "0d", "-1h", "tomorrow"} {
+		if _, err := parseExpiry(raw, now); err == nil {
+			t.Errorf("parseExpiry(%q) expected error", raw)
+		}
+	}
+}
+
+func TestCommandHelpListsCommandFlags(t *testing.T) {
+	got, err := Parse([]string{"s", "gen", "--help"})
+	if err != nil || !got.Help {
+		t.Fatalf("Parse() = %+v, %v", got, err)
+	}
+	help := CommandHelp("tungo", got)
+	if !strings.Contains(help, "--count <n>") || !strings.Contains(help, "--out <path>") ||
+		strings.Index(help, "--count") > strings.Index(help, "--config") {
+		t.Fatalf("unexpected command help: %q", help)
+	}
+	if strings.Contains(CommandHelp("tungo", Invocation{Help: true, topic: findSpec([]string{"c"})}), "--count") {
+		t.Fatal("client help lists generator flags")
+	}
+}
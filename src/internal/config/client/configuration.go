@@ -2,10 +2,15 @@ package client
 
 import (
 	"fmt"
+	"net/netip"
+	"strings"
+	"time"
 	"tungo/internal/config/settings"
 )
 
 type Configuration struct {
+	// Version is the schema of the file; see Migrations.
+	Version         int               `json:"Version"`
 	ClientID        int               `json:"ClientID"`
 	TCPSettings     settings.Settings `json:"TCPSettings"`
 	UDPSettings     settings.Settings `json:"UDPSettings"`
@@ -18,13 +23,181 @@ type Configuration struct {
 	ClientPublicKey []byte `json:"ClientPublicKey"`
 
 	// ClientPrivateKey is the client's X25519 static private key (32 bytes).
-	// MUST derive ClientPublicKey when processed with X25519.
-	ClientPrivateKey []byte `json:"ClientPrivateKey"`
+	// MUST derive ClientPublicKey when processed with X25519. Empty in an
+	// enrolled configuration, whose key is in the KeyStore.
+	ClientPrivateKey []byte `json:"ClientPrivateKey,omitempty"`
+
+	// PrivateKeyFile holds ClientPrivateKey instead of this file; a relative
+	// path is next to the configuration.
+	PrivateKeyFile string `json:"PrivateKeyFile,omitempty"`
+
+	// PresharedKey MUST match the PresharedKey of the server's AllowedPeers
+	// entry. Optional.
+	PresharedKey []byte `json:"PresharedKey,omitempty"`
+
+	// MetricsAddress is the IP:port of the Prometheus metrics listener.
+	// Empty disables metrics.
+	MetricsAddress string `json:"MetricsAddress,omitempty"`
+	// MetricsAllowRemote permits a MetricsAddress that is not a loopback
+	// address. The metrics are served without authentication.
+	MetricsAllowRemote bool `json:"MetricsAllowRemote,omitempty"`
+
+	// Endpoints lists the servers to try, in order, when a session starts.
+	// Empty means the server of the active Protocol settings only.
+	Endpoints []Endpoint `json:"Endpoints,omitempty"`
+
+	// UDPFallbackAfter moves a UDP endpoint to UDPFallback after this many
+	// consecutive failed handshakes. Zero disables the fallback.
+	UDPFallbackAfter int `json:"UDPFallbackAfter,omitempty"`
+
+	// UDPFallback is WS or WSS; defaults to WS.
+	UDPFallback settings.Protocol `json:"UDPFallback,omitempty"`
+
+	// Reconnect bounds the delay between reconnect attempts.
+	Reconnect Reconnect `json:"Reconnect,omitzero"`
+
+	// KillSwitch blocks all egress outside the tunnel while the client runs,
+	// including between sessions. Linux only.
+	KillSwitch bool `json:"KillSwitch,omitempty"`
+
+	// Proxy configures the local listeners of the rootless client
+	// ("tungo c proxy").
+	Proxy Proxy `json:"Proxy,omitzero"`
+}
+
+// Proxy holds the listen addresses and credentials of the rootless client
+// proxies. Empty addresses take the defaults.
+type Proxy struct {
+	// SOCKS5Address is the IP:port of the SOCKS5 proxy. Default
+	// 127.0.0.1:1080.
+	SOCKS5Address string `json:"SOCKS5Address,omitempty"`
+	// HTTPAddress is the IP:port of the HTTP proxy. Default 127.0.0.1:8080.
+	HTTPAddress string `json:"HTTPAddress,omitempty"`
+	// Username and Password are required from proxy clients, with SOCKS5
+	// username/password authentication or HTTP Proxy-Authorization, so that
+	// other users of the host cannot use the tunnel. The rootless client
+	// does not start without them.
+	Username string `json:"Username,omitempty"`
+	Password string `json:"Password,omitempty"`
+}
+
+const (
+	DefaultSOCKS5Address = "127.0.0.1:1080"
+	DefaultHTTPAddress   = "127.0.0.1:8080"
+)
+
+// Addresses returns the SOCKS5 and HTTP listen addresses.
+func (p Proxy) Addresses() (socks5, http string) {
+	socks5, http = DefaultSOCKS5Address, DefaultHTTPAddress
+	if p.SOCKS5Address != "" {
+		socks5 = p.SOCKS5Address
+	}
+	if p.HTTPAddress != "" {
+		http = p.HTTPAddress
+	}
+	return socks5, http
+}
+
+func (p Proxy) validate() error {
+	socks5, http := p.Addresses()
+	if _, err := netip.ParseAddrPort(socks5); err != nil {
+		return fmt.Errorf("invalid Proxy.SOCKS5Address %q: %w", socks5, err)
+	}
+	if _, err := netip.ParseAddrPort(http); err != nil {
+		return fmt.Errorf("invalid Proxy.HTTPAddress %q: %w", http, err)
+	}
+	if socks5 == http {
+		return fmt.Errorf("invalid Proxy: SOCKS5Address and HTTPAddress are both %s", socks5)
+	}
+	if (p.Username == "") != (p.Password == "") {
+		return fmt.Errorf("invalid Proxy: Username and Password must be set together")
+	}
+	// SOCKS5 limits both to 255 bytes; HTTP Basic splits at the first colon.
+	if len(p.Username) > 255 || len(p.Password) > 255 {
+		return fmt.Errorf("invalid Proxy: Username and Password must be at most 255 bytes")
+	}
+	if strings.Contains(p.Username, ":") {
+		return fmt.Errorf("invalid Proxy.Username: must not contain a colon")
+	}
+	return nil
+}
+
+// Reconnect configures the exponential reconnect backoff. Zero fields take
+// the defaults.
+type Reconnect struct {
+	// InitialDelayMs is the delay after the first failure. Default 500.
+	InitialDelayMs int `json:"InitialDelayMs,omitempty"`
+	// MaxDelayMs caps the delay. Default 30000.
+	MaxDelayMs int `json:"MaxDelayMs,omitempty"`
+	// RejectionWindowMs stops the client once the server has rejected its
+	// handshakes for this long without a session in between. Default
+	// 600000; -1 retries forever.
+	RejectionWindowMs int `json:"RejectionWindowMs,omitempty"`
+}
+
+const (
+	DefaultReconnectInitialDelay    = 500 * time.Millisecond
+	DefaultReconnectMaxDelay        = 30 * time.Second
+	DefaultReconnectRejectionWindow = 10 * time.Minute
+)
+
+// Delays returns the initial and maximum reconnect delays.
+func (r Reconnect) Delays() (initial, maximum time.Duration) {
+	initial, maximum = DefaultReconnectInitialDelay, DefaultReconnectMaxDelay
+	if r.InitialDelayMs > 0 {
+		initial = time.Duration(r.InitialDelayMs) * time.Millisecond
+	}
+	if r.MaxDelayMs > 0 {
+		maximum = time.Duration(r.MaxDelayMs) * time.Millisecond
+	}
+	return initial, max(initial, maximum)
+}
+
+// RejectionWindow returns how long rejected handshakes must persist to stop
+// the client; zero means never.
+func (r Reconnect) RejectionWindow() time.Duration {
+	switch {
+	case r.RejectionWindowMs < 0:
+		return 0
+	case r.RejectionWindowMs == 0:
+		return DefaultReconnectRejectionWindow
+	default:
+		return time.Duration(r.RejectionWindowMs) * time.Millisecond
+	}
+}
+
+func (r Reconnect) validate() error {
+	if r.InitialDelayMs < 0 {
+		return fmt.Errorf("invalid Reconnect.InitialDelayMs %d: must be >= 0", r.InitialDelayMs)
+	}
+	if r.MaxDelayMs < 0 {
+		return fmt.Errorf("invalid Reconnect.MaxDelayMs %d: must be >= 0", r.MaxDelayMs)
+	}
+	if r.InitialDelayMs > 0 && r.MaxDelayMs > 0 && r.InitialDelayMs > r.MaxDelayMs {
+		return fmt.Errorf("invalid Reconnect: InitialDelayMs %d exceeds MaxDelayMs %d", r.InitialDelayMs, r.MaxDelayMs)
+	}
+	if r.RejectionWindowMs < -1 {
+		return fmt.Errorf("invalid Reconnect.RejectionWindowMs %d: must be >= -1", r.RejectionWindowMs)
+	}
+	return nil
+}
+
+// Endpoint is one server the client may connect to. Server and Port
+// override the settings of the Protocol profile when set.
+type Endpoint struct {
+	Server   settings.Host     `json:"Server,omitzero"`
+	Protocol settings.Protocol `json:"Protocol"`
+	Port     int               `json:"Port,omitempty"`
 }
 
 func (c *Configuration) ActiveSettings() (settings.Settings, error) {
+	return c.EndpointSettings(Endpoint{Protocol: c.Protocol})
+}
+
+// EndpointSettings returns the connection settings for an endpoint.
+func (c *Configuration) EndpointSettings(endpoint Endpoint) (settings.Settings, error) {
 	var active settings.Settings
-	switch c.Protocol {
+	switch endpoint.Protocol {
 	case settings.UDP:
 		active = c.UDPSettings
 	case settings.TCP:
@@ -32,12 +205,53 @@ func (c *Configuration) ActiveSettings() (settings.Settings, error) {
 	case settings.WS, settings.WSS:
 		active = c.WSSettings
 	default:
-		return settings.Settings{}, fmt.Errorf("unsupported protocol: %v", c.Protocol)
+		return settings.Settings{}, fmt.Errorf("unsupported protocol: %v", endpoint.Protocol)
 	}
 
-	active.Protocol = c.Protocol
+	active.Protocol = endpoint.Protocol
+	if endpoint.Server != (settings.Host{}) {
+		active.Server = endpoint.Server
+	}
+	if endpoint.Port != 0 {
+		active.Port = endpoint.Port
+	}
 	if err := active.DeriveIP(c.ClientID); err != nil {
 		return settings.Settings{}, err
 	}
 	return active, nil
 }
+
+// CandidateSettings returns the settings of every endpoint in the order they
+// should be tried.
+func (c *Configuration) CandidateSettings() ([]settings.Settings, error) {
+	if len(c.Endpoints) == 0 {
+		active, err := c.ActiveSettings()
+		if err != nil {
+			return nil, err
+		}
+		return []settings.Settings{active}, nil
+	}
+	candidates := make([]settings.Settings, 0, len(c.Endpoints))
+	for i, endpoint := range c.Endpoints {
+		candidate, err := c.EndpointSettings(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("Endpoints[%d]: %w", i, err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// UDPFallbackSettings returns the settings that replace a UDP endpoint after
+// UDPFallbackAfter failed handshakes. The endpoint keeps its server; the
+// port comes from WSSettings.
+func (c *Configuration) UDPFallbackSettings(udp settings.Settings) (settings.Settings, error) {
+	protocol := c.UDPFallback
+	if protocol == settings.UNKNOWN {
+		protocol = settings.WS
+	}
+	if protocol != settings.WS && protocol != settings.WSS {
+		return settings.Settings{}, fmt.Errorf("invalid UDPFallback %v: must be WS or WSS", protocol)
+	}
+	return c.EndpointSettings(Endpoint{Server: udp.Server, Protocol: protocol})
+}
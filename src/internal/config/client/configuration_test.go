@@ -4,6 +4,7 @@ import (
 	"net/netip"
 	"reflect"
 	"testing"
+	"time"
 
 	"tungo/internal/config/settings"
 )
@@ -91,3 +92,85 @@ func TestConfiguration_ActiveSettingsDerivesLegacyClientAddress(t *testing.T) {
 		t.Fatalf("IPv4 = %v, want %v", active.IPv4, want)
 	}
 }
+
+func TestConfiguration_EndpointSettingsOverridesProfile(t *testing.T) {
+	cfg := Configuration{
+		ClientID: 1,
+		Protocol: settings.UDP,
+		UDPSettings: settings.Settings{
+			Addressing: settings.Addressing{TunName: "udp0", Server: settings.Host{IPv4: "198.51.100.1"}, Port: 9090},
+		},
+		WSSettings: settings.Settings{
+			Addressing: settings.Addressing{TunName: "ws0", Server: settings.Host{IPv4: "198.51.100.1"}, Port: 8080},
+		},
+		Endpoints: []Endpoint{
+			{Protocol: settings.UDP, Server: settings.Host{Domain: "backup.example.com"}},
+			{Protocol: settings.WSS, Port: 443},
+		},
+	}
+
+	got, err := cfg.CandidateSettings()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 candidates, got %d", len(got))
+	}
+	if got[0].Server.Domain != "backup.example.com" || got[0].Port != 9090 || got[0].TunName != "udp0" {
+		t.Fatalf("unexpected first candidate: %+v", got[0])
+	}
+	if got[1].Protocol != settings.WSS || got[1].Port != 443 || got[1].Server.IPv4 != "198.51.100.1" {
+		t.Fatalf("unexpected second candidate: %+v", got[1])
+	}
+}
+
+func TestConfiguration_UDPFallbackSettings(t *testing.T) {
+	cfg := Configuration{
+		ClientID:   1,
+		WSSettings: settings.Settings{Addressing: settings.Addressing{TunName: "ws0", Port: 8080}},
+	}
+	udp := settings.Settings{Addressing: settings.Addressing{Server: settings.Host{IPv4: "192.0.2.1"}}}
+
+	got, err := cfg.UDPFallbackSettings(udp)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Protocol != settings.WS || got.Server != udp.Server || got.Port != 8080 {
+		t.Fatalf("unexpected fallback: %+v", got)
+	}
+
+	cfg.UDPFallback = settings.TCP
+	if _, err := cfg.UDPFallbackSettings(udp); err == nil {
+		t.Fatal("expected error for a non-WebSocket fallback")
+	}
+}
+
+func TestReconnect_Defaults(t *testing.T) {
+	initial, maximum := Reconnect{}.Delays()
+	if initial != DefaultReconnectInitialDelay || maximum != DefaultReconnectMaxDelay {
+		t.Fatalf("Delays() = %v, %v, want defaults", initial, maximum)
+	}
+	// a maximum below the default initial delay lifts to the initial delay
+	if _, maximum := (Reconnect{MaxDelayMs: 100}).Delays(); maximum != DefaultReconnectInitialDelay {
+		t.Fatalf("max delay = %v, want %v", maximum, DefaultReconnectInitialDelay)
+	}
+	if got := (Reconnect{}).RejectionWindow(); got != DefaultReconnectRejectionWindow {
+		t.Fatalf("RejectionWindow() = %v, want %v", got, DefaultReconnectRejectionWindow)
+	}
+	if got := (Reconnect{RejectionWindowMs: 1500}).RejectionWindow(); got != 1500*time.Millisecond {
+		t.Fatalf("RejectionWindow() = %v, want 1.5s", got)
+	}
+	if got := (Reconnect{RejectionWindowMs: -1}).RejectionWindow(); got != 0 {
+		t.Fatalf("RejectionWindow() = %v, want 0 for unlimited", got)
+	}
+}
+
+func TestProxy_Addresses(t *testing.T) {
+	if socks5, http := (Proxy{}).Addresses(); socks5 != DefaultSOCKS5Address || http != DefaultHTTPAddress {
+		t.Fatalf("Addresses() = %q, %q, want defaults", socks5, http)
+	}
+	socks5, http := Proxy{SOCKS5Address: "127.0.0.1:1081"}.Addresses()
+	if socks5 != "127.0.0.1:1081" || http != DefaultHTTPAddress {
+		t.Fatalf("Addresses() = %q, %q", socks5, http)
+	}
+}
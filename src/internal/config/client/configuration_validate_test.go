@@ -41,6 +41,18 @@ func TestValidate_FailsWhenNoIPv4AndNoIPv6Subnet(t *testing.T) {
 	}
 }
 
+func TestValidate_PresharedKeyLength(t *testing.T) {
+	cfg := validClientConfiguration(t)
+	cfg.PresharedKey = make([]byte, 32)
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected 32-byte pre-shared key to be valid, got %v", err)
+	}
+	cfg.PresharedKey = make([]byte, 16)
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "invalid PresharedKey length") {
+		t.Fatalf("expected pre-shared key length error, got %v", err)
+	}
+}
+
 func validClientConfiguration(t *testing.T) Configuration {
 	t.Helper()
 	return Configuration{
@@ -165,6 +177,39 @@ func TestValidate_AllowsCustomDNS(t *testing.T) {
 	}
 }
 
+func TestValidate_MetricsAddress(t *testing.T) {
+	cfg := validClientConfiguration(t)
+	cfg.MetricsAddress = "[::1]:9464"
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected metrics address to be valid, got %v", err)
+	}
+	cfg.MetricsAddress = "127.0.0.1"
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected error for metrics address without port")
+	}
+	cfg.MetricsAddress = "0.0.0.0:9100"
+	if err := Validate(cfg); err == nil {
+		t.Fatal("expected error for a non-loopback metrics address")
+	}
+	cfg.MetricsAllowRemote = true
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected MetricsAllowRemote to permit the address, got %v", err)
+	}
+}
+
+func TestValidate_Routing(t *testing.T) {
+	cfg := validClientConfiguration(t)
+	cfg.UDPSettings.IncludeRoutes = []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")}
+	cfg.UDPSettings.ExcludeRoutes = []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected routing to be valid, got %v", err)
+	}
+	cfg.UDPSettings.IncludeRoutes = []netip.Prefix{netip.MustParsePrefix("10.20.0.1/16")}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "IncludeRoutes[0]") {
+		t.Fatalf("expected error for include route with host bits, got %v", err)
+	}
+}
+
 func TestValidate_RejectsInvalidServerHost(t *testing.T) {
 	t.Parallel()
 
@@ -341,3 +386,99 @@ func TestValidate_WSSZeroPortRejectsNonWSS(t *testing.T) {
 		t.Fatalf("expected port 0 to be rejected for WS protocol, got %v", err)
 	}
 }
+
+func TestValidate_Endpoints(t *testing.T) {
+	cfg := validClientConfiguration(t)
+	cfg.Endpoints = []Endpoint{
+		{Protocol: settings.UDP},
+		{Protocol: settings.UDP, Server: mustHostForValidate(t, "vpn.example.com"), Port: 70000},
+	}
+
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "Endpoints[1]: invalid Port 70000") {
+		t.Fatalf("expected endpoint port error, got %v", err)
+	}
+
+	cfg.Endpoints[1].Port = 0
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected endpoints to be valid, got %v", err)
+	}
+}
+
+func TestValidate_UDPFallback(t *testing.T) {
+	cfg := validClientConfiguration(t)
+	cfg.UDPFallbackAfter = 3
+
+	err := Validate(cfg)
+	if err == nil || !strings.Contains(err.Error(), "UDP fallback settings: TunName is not configured") {
+		t.Fatalf("expected fallback profile error, got %v", err)
+	}
+
+	cfg.WSSettings = settings.Settings{
+		Addressing: settings.Addressing{
+			TunName:    "ws0",
+			IPv4Subnet: netip.MustParsePrefix("10.2.0.0/24"),
+			Port:       8080,
+		},
+	}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected fallback to be valid, got %v", err)
+	}
+
+	cfg.UDPFallbackAfter = -1
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "UDPFallbackAfter") {
+		t.Fatalf("expected UDPFallbackAfter error, got %v", err)
+	}
+}
+
+func TestValidate_Reconnect(t *testing.T) {
+	cfg := validClientConfiguration(t)
+	cfg.Reconnect = Reconnect{InitialDelayMs: 2000, MaxDelayMs: 1000}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "exceeds MaxDelayMs") {
+		t.Fatalf("expected reconnect bounds error, got %v", err)
+	}
+
+	cfg.Reconnect = Reconnect{RejectionWindowMs: -2}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "RejectionWindowMs") {
+		t.Fatalf("expected RejectionWindowMs error, got %v", err)
+	}
+
+	cfg.Reconnect = Reconnect{RejectionWindowMs: -1}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected unlimited rejections to be valid, got %v", err)
+	}
+}
+
+func TestValidate_Proxy(t *testing.T) {
+	cfg := validClientConfiguration(t)
+	cfg.Proxy = Proxy{SOCKS5Address: "localhost:1080"}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "SOCKS5Address") {
+		t.Fatalf("expected SOCKS5Address error, got %v", err)
+	}
+
+	cfg.Proxy = Proxy{HTTPAddress: DefaultSOCKS5Address}
+	if err := Validate(cfg); err == nil || !strings.Contains(err.Error(), "both") {
+		t.Fatalf("expected address conflict error, got %v", err)
+	}
+
+	cfg.Proxy = Proxy{SOCKS5Address: "[::1]:1080", HTTPAddress: "0.0.0.0:3128"}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected proxy addresses to be valid, got %v", err)
+	}
+
+	for _, proxy := range []Proxy{
+		{Username: "user"},
+		{Password: "secret"},
+		{Username: "us:er", Password: "secret"},
+		{Username: "user", Password: strings.Repeat("x", 256)},
+	} {
+		cfg.Proxy = proxy
+		if err := Validate(cfg); err == nil {
+			t.Fatalf("expected credentials %+v to be invalid", proxy)
+		}
+	}
+	cfg.Proxy = Proxy{Username: "user", Password: "secret"}
+	if err := Validate(cfg); err != nil {
+		t.Fatalf("expected proxy credentials to be valid, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"regexp"
+	"slices"
+	"strings"
+
+	"tungo/internal/config/settings"
+)
+
+// A client instance runs one named configuration,
+// client_configuration.json.<name>, next to other instances. The unnamed
+// instance runs the active configuration.
+
+var instanceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]{0,31}$`)
+
+// ValidateInstanceName accepts names that are also valid TUN and systemd
+// instance names: up to 32 letters, digits, '-' and '_'.
+func ValidateInstanceName(name string) error {
+	if !instanceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q: use up to 32 letters, digits, '-' or '_'", name)
+	}
+	return nil
+}
+
+// InstancePath returns the configuration path of the named instance, or of
+// the active configuration when name is empty.
+func InstancePath(resolver Resolver, name string) (string, error) {
+	path, err := resolver.Resolve()
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return path, nil
+	}
+	if err := ValidateInstanceName(name); err != nil {
+		return "", err
+	}
+	return path + "." + name, nil
+}
+
+// InstanceName returns the instance of a named configuration path, or false
+// for paths that are not a valid instance.
+func InstanceName(resolver Resolver, path string) (string, bool) {
+	active, err := resolver.Resolve()
+	if err != nil {
+		return "", false
+	}
+	name, ok := strings.CutPrefix(path, active+".")
+	if !ok || ValidateInstanceName(name) != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// CheckInstanceConflicts rejects starting conf while the instances in
+// running are up. Instances may not share a TUN name or overlapping tunnel
+// subnets, only one may route the default route, and a kill switch needs the
+// host to itself.
+func CheckInstanceConflicts(conf *Configuration, running map[string]*Configuration) error {
+	profiles := conf.profilesInUse()
+	var errs []error
+	for _, name := range slices.Sorted(maps.Keys(running)) {
+		other := running[name]
+		label := name
+		if label == "" {
+			label = "(active)"
+		}
+		if conf.KillSwitch || other.KillSwitch {
+			errs = append(errs, fmt.Errorf("instance %s is running and a kill switch blocks every other tunnel", label))
+			continue
+		}
+		if err := profilesConflict(profiles, other.profilesInUse()); err != nil {
+			errs = append(errs, fmt.Errorf("conflicts with running instance %s: %w", label, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func profilesConflict(ours, theirs []settings.Settings) error {
+	for _, a := range ours {
+		for _, b := range theirs {
+			if err := profileConflict(a, b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func profileConflict(a, b settings.Settings) error {
+	switch {
+	case a.TunName != "" && a.TunName == b.TunName:
+		return fmt.Errorf("both use TUN %s", a.TunName)
+	case overlaps(a.IPv4Subnet, b.IPv4Subnet):
+		return fmt.Errorf("subnets %s and %s overlap", a.IPv4Subnet, b.IPv4Subnet)
+	case overlaps(a.IPv6Subnet, b.IPv6Subnet):
+		return fmt.Errorf("subnets %s and %s overlap", a.IPv6Subnet, b.IPv6Subnet)
+	case a.FullTunnel() && b.FullTunnel():
+		return errors.New("both route all traffic; set IncludeRoutes on one of them")
+	}
+	return nil
+}
+
+func overlaps(a, b netip.Prefix) bool {
+	return a.IsValid() && b.IsValid() && a.Overlaps(b)
+}
+
+// profilesInUse returns the settings of every endpoint the client may
+// connect to, including the UDP fallback.
+func (c *Configuration) profilesInUse() []settings.Settings {
+	profiles, err := c.CandidateSettings()
+	if err != nil {
+		return nil
+	}
+	if c.UDPFallbackAfter > 0 {
+		for _, profile := range profiles {
+			if profile.Protocol != settings.UDP {
+				continue
+			}
+			if fallback, err := c.UDPFallbackSettings(profile); err == nil {
+				profiles = append(profiles, fallback)
+			}
+			break
+		}
+	}
+	return profiles
+}
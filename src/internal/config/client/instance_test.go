@@ -0,0 +1,104 @@
+package client
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestValidateInstanceName(t *testing.T) {
+	for _, name := range []string{"prod", "staging-2", "eu_west", "A1"} {
+		if err := ValidateInstanceName(name); err != nil {
+			t.Errorf("ValidateInstanceName(%q) error = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-prod", "my prod", "a/b", "a.b", "(active)", strings.Repeat("x", 33)} {
+		if err := ValidateInstanceName(name); err == nil {
+			t.Errorf("ValidateInstanceName(%q) expected error", name)
+		}
+	}
+}
+
+func TestInstancePath(t *testing.T) {
+	resolver := managerTestMockResolver{path: "/etc/tungo/client_configuration.json"}
+	if got, err := InstancePath(resolver, ""); err != nil || got != "/etc/tungo/client_configuration.json" {
+		t.Fatalf("InstancePath(\"\") = %q, %v", got, err)
+	}
+	got, err := InstancePath(resolver, "prod")
+	if err != nil || got != "/etc/tungo/client_configuration.json.prod" {
+		t.Fatalf("InstancePath(prod) = %q, %v", got, err)
+	}
+	if _, err := InstancePath(resolver, "../prod"); err == nil {
+		t.Fatal("expected error for an invalid name")
+	}
+
+	if name, ok := InstanceName(resolver, got); !ok || name != "prod" {
+		t.Fatalf("InstanceName() = %q, %v", name, ok)
+	}
+	if _, ok := InstanceName(resolver, "/etc/tungo/client_configuration.json.my prod"); ok {
+		t.Fatal("expected a configuration with an invalid instance name to be rejected")
+	}
+}
+
+func instanceTestConfig(tun, subnet string, include ...netip.Prefix) *Configuration {
+	conf := validTestConfig()
+	conf.UDPSettings.TunName = tun
+	conf.UDPSettings.IPv4Subnet = netip.MustParsePrefix(subnet)
+	conf.UDPSettings.IncludeRoutes = include
+	return &conf
+}
+
+func TestCheckInstanceConflicts(t *testing.T) {
+	split := netip.MustParsePrefix("10.20.0.0/16")
+	tests := []struct {
+		name    string
+		conf    *Configuration
+		running map[string]*Configuration
+		want    string
+	}{
+		{
+			name:    "independent split tunnel",
+			conf:    instanceTestConfig("tun1", "10.1.0.0/24", split),
+			running: map[string]*Configuration{"prod": instanceTestConfig("tun0", "10.0.0.0/24")},
+		},
+		{
+			name:    "same TUN",
+			conf:    instanceTestConfig("tun0", "10.1.0.0/24", split),
+			running: map[string]*Configuration{"prod": instanceTestConfig("tun0", "10.0.0.0/24")},
+			want:    "instance prod: both use TUN tun0",
+		},
+		{
+			name:    "overlapping subnets",
+			conf:    instanceTestConfig("tun1", "10.0.0.0/16", split),
+			running: map[string]*Configuration{"": instanceTestConfig("tun0", "10.0.0.0/24")},
+			want:    "instance (active): subnets 10.0.0.0/16 and 10.0.0.0/24 overlap",
+		},
+		{
+			name:    "two full tunnels",
+			conf:    instanceTestConfig("tun1", "10.1.0.0/24"),
+			running: map[string]*Configuration{"prod": instanceTestConfig("tun0", "10.0.0.0/24")},
+			want:    "both route all traffic",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckInstanceConflicts(tt.conf, tt.running)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+
+	killSwitch := instanceTestConfig("tun0", "10.0.0.0/24")
+	killSwitch.KillSwitch = true
+	err := CheckInstanceConflicts(instanceTestConfig("tun1", "10.1.0.0/24", split), map[string]*Configuration{"prod": killSwitch})
+	if err == nil || !strings.Contains(err.Error(), "kill switch") {
+		t.Fatalf("expected kill switch conflict, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"tungo/internal/protocol/keys"
+)
+
+// KeyStore keeps the private keys made by "tungo c keygen" on this host. A
+// configuration enrolled with the public key of such a key carries no
+// ClientPrivateKey; the key is read from the store when the configuration
+// loads, so that it never leaves the host.
+type KeyStore struct {
+	resolver Resolver
+}
+
+// NewKeyStore keeps keys in a keys directory next to the configuration that
+// resolver points to.
+func NewKeyStore(resolver Resolver) *KeyStore {
+	return &KeyStore{resolver: resolver}
+}
+
+// Generate creates and stores a key pair and returns its public key.
+func (s *KeyStore) Generate() ([]byte, error) {
+	dir, err := s.dir()
+	if err != nil {
+		return nil, err
+	}
+	public, private, err := (&keys.DefaultKeyDeriver{}).GenerateX25519KeyPair()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate client keypair: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create key directory: %w", err)
+	}
+	encoded := keys.EncodeKey(private[:]) + "\n"
+	if err := os.WriteFile(keyPath(dir, public), []byte(encoded), 0600); err != nil {
+		return nil, fmt.Errorf("failed to save client private key: %w", err)
+	}
+	return public, nil
+}
+
+// PrivateKey returns the stored private key of public.
+func (s *KeyStore) PrivateKey(public []byte) ([]byte, error) {
+	if len(public) != 32 {
+		return nil, fmt.Errorf("invalid ClientPublicKey length %d, expected 32", len(public))
+	}
+	dir, err := s.dir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(keyPath(dir, public))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.New("configuration has no ClientPrivateKey and its ClientPublicKey was not made by \"tungo c keygen\" on this host")
+		}
+		return nil, fmt.Errorf("failed to read client private key: %w", err)
+	}
+	private, err := keys.ParseKey(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client private key file: %w", err)
+	}
+	derived, err := keys.PublicKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client private key file: %w", err)
+	}
+	if !bytes.Equal(derived, public) {
+		return nil, errors.New("client private key file does not match ClientPublicKey")
+	}
+	return private, nil
+}
+
+func (s *KeyStore) dir() (string, error) {
+	path, err := s.resolver.Resolve()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve client configuration path: %w", err)
+	}
+	return filepath.Join(filepath.Dir(path), "keys"), nil
+}
+
+// keyPath names a key file after its public key, in URL-safe base64 so that
+// it is a valid file name on every platform.
+func keyPath(dir string, public []byte) string {
+	return filepath.Join(dir, base64.RawURLEncoding.EncodeToString(public)+".key")
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tungo/internal/protocol/keys"
+)
+
+func TestKeyStoreGenerate(t *testing.T) {
+	store := NewKeyStore(NewPathResolver(filepath.Join(t.TempDir(), "client_configuration.json")))
+	public, err := store.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	private, err := store.PrivateKey(public)
+	if err != nil {
+		t.Fatalf("PrivateKey() error = %v", err)
+	}
+	if derived, _ := keys.PublicKey(private); string(derived) != string(public) {
+		t.Fatal("stored private key does not derive the public key")
+	}
+	dir, _ := store.dir()
+	info, err := os.Stat(keyPath(dir, public))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("key file mode = %v, %v; want 0600", info.Mode(), err)
+	}
+}
+
+func TestKeyStorePrivateKeyErrors(t *testing.T) {
+	store := NewKeyStore(NewPathResolver(filepath.Join(t.TempDir(), "client_configuration.json")))
+	public, err := store.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	unknown := make([]byte, 32)
+	if _, err := store.PrivateKey(unknown); err == nil || !strings.Contains(err.Error(), "c keygen") {
+		t.Fatalf("PrivateKey(unknown) error = %v, want a keygen hint", err)
+	}
+
+	dir, _ := store.dir()
+	other, _ := store.Generate()
+	data, _ := os.ReadFile(keyPath(dir, other))
+	if err := os.WriteFile(keyPath(dir, public), data, 0600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	if _, err := store.PrivateKey(public); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("PrivateKey(mismatched) error = %v", err)
+	}
+}
+
+func TestReaderReadEnrolledConfiguration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client_configuration.json")
+	store := NewKeyStore(NewPathResolver(path))
+	public, err := store.Generate()
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	enrolled := validTestConfig()
+	enrolled.ClientPublicKey = public
+	enrolled.ClientPrivateKey = nil
+	data, _ := json.Marshal(enrolled)
+	if strings.Contains(string(data), "ClientPrivateKey") {
+		t.Fatalf("enrolled configuration carries a private key: %s", data)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("write configuration: %v", err)
+	}
+
+	conf, err := read(path)
+	if err != nil {
+		t.Fatalf("read() error = %v", err)
+	}
+	if want, _ := store.PrivateKey(public); string(conf.ClientPrivateKey) != string(want) {
+		t.Fatal("read() did not load the enrolled private key")
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 type Manager struct {
 	resolver Resolver
+	instance string
 }
 
 func NewManager() *Manager {
@@ -10,8 +11,17 @@ func NewManager() *Manager {
 	}
 }
 
+// NewInstanceManager reads the configuration of a named client instance,
+// next to the configuration resolver points to.
+func NewInstanceManager(resolver Resolver, name string) *Manager {
+	return &Manager{
+		resolver: resolver,
+		instance: name,
+	}
+}
+
 func (m *Manager) Configuration() (*Configuration, error) {
-	path, err := m.resolver.Resolve()
+	path, err := InstancePath(m.resolver, m.instance)
 	if err != nil {
 		return nil, err
 	}
@@ -105,3 +105,23 @@ func TestManagerConfigurationSuccess(t *testing.T) {
 		t.Errorf("expected Protocol %d, got %d", settings.TCP, config.Protocol)
 	}
 }
+
+func TestManagerInstanceConfiguration(t *testing.T) {
+	path := createTempConfigFile(t, validTestConfig())
+	if err := os.Rename(path, path+".prod"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	manager := NewInstanceManager(NewPathResolver(path), "prod")
+	conf, err := manager.Configuration()
+	if err != nil {
+		t.Fatalf("Configuration() error = %v", err)
+	}
+	if conf.ClientID != 1 {
+		t.Fatalf("unexpected configuration %+v", conf)
+	}
+
+	manager.instance = "missing"
+	if _, err := manager.Configuration(); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing instance error, got %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"encoding/json"
+
+	"tungo/internal/config/migration"
+)
+
+// Migrations upgrades client configurations written by older releases.
+// Append a step whenever the meaning of the file changes.
+var Migrations = migration.NewRegistry("client",
+	migration.Step{Description: "add Version"},
+)
+
+// Decode parses configuration JSON, upgrading it in memory when an older
+// release wrote it. It does not validate the configuration.
+func Decode(data []byte) (Configuration, error) {
+	c, _, err := decode(data)
+	return c, err
+}
+
+// decode also returns the version that data had.
+func decode(data []byte) (Configuration, int, error) {
+	migrated, from, err := Migrations.Migrate(data)
+	if err != nil {
+		return Configuration{}, from, err
+	}
+	var c Configuration
+	if err := json.Unmarshal(migrated, &c); err != nil {
+		return Configuration{}, from, err
+	}
+	return c, from, nil
+}
@@ -5,9 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
+
+	"tungo/internal/config/keyfile"
+	"tungo/internal/config/migration"
 )
 
 func read(path string) (*Configuration, error) {
+	return load(path, true)
+}
+
+// Load reads and validates the configuration at path as the client would,
+// without changing the file.
+func Load(path string) (*Configuration, error) {
+	return load(path, false)
+}
+
+func load(path string, persist bool) (*Configuration, error) {
 	var configuration Configuration
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -17,7 +31,17 @@ func read(path string) (*Configuration, error) {
 		return nil, fmt.Errorf("failed to read client configuration %q: %w", path, err)
 	}
 
-	if err := json.Unmarshal(data, &configuration); err != nil {
+	// A file may also hold a shared tungo:// URI.
+	if trimmed := strings.TrimSpace(string(data)); IsURI(trimmed) {
+		configuration, err = ParseURI(trimmed)
+	} else {
+		configuration, err = readJSON(path, data, persist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid client configuration %q: %w", path, err)
+	}
+
+	if err := loadPrivateKey(path, &configuration); err != nil {
 		return nil, fmt.Errorf("invalid client configuration %q: %w", path, err)
 	}
 
@@ -27,3 +51,86 @@ func read(path string) (*Configuration, error) {
 
 	return &configuration, nil
 }
+
+// loadPrivateKey fills in a ClientPrivateKey kept out of the configuration,
+// in its PrivateKeyFile or in the KeyStore.
+func loadPrivateKey(path string, configuration *Configuration) error {
+	switch {
+	case configuration.PrivateKeyFile != "" && len(configuration.ClientPrivateKey) != 0:
+		return errors.New("configuration sets both ClientPrivateKey and PrivateKeyFile")
+	case configuration.PrivateKeyFile != "":
+		private, err := keyfile.Read(keyfile.Resolve(path, configuration.PrivateKeyFile))
+		if err != nil {
+			return err
+		}
+		configuration.ClientPrivateKey = private
+	case len(configuration.ClientPrivateKey) == 0:
+		private, err := NewKeyStore(NewPathResolver(path)).PrivateKey(configuration.ClientPublicKey)
+		if err != nil {
+			return err
+		}
+		configuration.ClientPrivateKey = private
+	}
+	return nil
+}
+
+// MoveKeyToFile moves the ClientPrivateKey of the configuration at path to
+// keyFile, encrypted when a passphrase is set, and keeps a reference to it in
+// its place. A relative keyFile is next to the configuration.
+func MoveKeyToFile(path, keyFile string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read client configuration %q: %w", path, err)
+	}
+	if IsURI(strings.TrimSpace(string(data))) {
+		return fmt.Errorf("client configuration %q is a URI; import it first", path)
+	}
+	configuration, _, err := decode(data)
+	if err != nil {
+		return fmt.Errorf("invalid client configuration %q: %w", path, err)
+	}
+	if configuration.PrivateKeyFile != "" {
+		return fmt.Errorf("private key is already in %s", configuration.PrivateKeyFile)
+	}
+	if len(configuration.ClientPrivateKey) == 0 {
+		return fmt.Errorf("client configuration %q has no ClientPrivateKey", path)
+	}
+	target := keyfile.Resolve(path, keyFile)
+	if _, err := os.Stat(target); err == nil {
+		return fmt.Errorf("%s already exists", target)
+	}
+	passphrase, err := keyfile.Passphrase()
+	if err != nil {
+		return err
+	}
+	if err := keyfile.Write(target, configuration.ClientPrivateKey, passphrase); err != nil {
+		return err
+	}
+	configuration.ClientPrivateKey = nil
+	configuration.PrivateKeyFile = keyFile
+	updated, err := json.MarshalIndent(configuration, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, updated, 0600); err != nil {
+		return fmt.Errorf("failed to write client configuration %q: %w", path, err)
+	}
+	return nil
+}
+
+// readJSON decodes a configuration file. With persist, a file written by an
+// older release is saved upgraded, keeping the original as a backup.
+func readJSON(path string, data []byte, persist bool) (Configuration, error) {
+	configuration, from, err := decode(data)
+	if err != nil || from == Migrations.Current() || !persist {
+		return configuration, err
+	}
+	upgraded, err := json.MarshalIndent(configuration, "", "\t")
+	if err != nil {
+		return Configuration{}, err
+	}
+	if _, err := migration.Save(path, data, upgraded, from); err != nil {
+		return Configuration{}, err
+	}
+	return configuration, nil
+}
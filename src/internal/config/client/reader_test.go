@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"tungo/internal/config/keyfile"
 	"tungo/internal/config/settings"
 )
 
@@ -28,6 +29,7 @@ func createTempClientConfigFile(t *testing.T, data interface{}) string {
 func validTestConfig() Configuration {
 	host := settings.Host{IPv4: "127.0.0.1"}
 	return Configuration{
+		Version:  Migrations.Current(),
 		ClientID: 1,
 		UDPSettings: settings.Settings{
 			Addressing: settings.Addressing{
@@ -109,3 +111,92 @@ func TestReaderReadInvalidConfiguration(t *testing.T) {
 		t.Fatalf("expected validation context, got %v", err)
 	}
 }
+
+func TestReaderReadURI(t *testing.T) {
+	expectedConfig := validTestConfig()
+	uri, err := MarshalURI(expectedConfig)
+	if err != nil {
+		t.Fatalf("MarshalURI() error: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "client_configuration.json")
+	if err := os.WriteFile(path, []byte(uri+"\n"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	config, err := read(path)
+	if err != nil {
+		t.Fatalf("read() returned error: %v", err)
+	}
+	if config.ClientID != expectedConfig.ClientID || config.UDPSettings.Port != expectedConfig.UDPSettings.Port {
+		t.Errorf("expected %+v, got %+v", expectedConfig, config)
+	}
+}
+
+func TestReaderReadMigratesOlderConfiguration(t *testing.T) {
+	legacy := validTestConfig()
+	legacy.Version = 0
+	path := createTempClientConfigFile(t, legacy)
+	original, _ := os.ReadFile(path)
+
+	config, err := read(path)
+	if err != nil {
+		t.Fatalf("read() returned error: %v", err)
+	}
+	if config.Version != Migrations.Current() {
+		t.Fatalf("Version = %d, want %d", config.Version, Migrations.Current())
+	}
+	backup, err := os.ReadFile(path + ".v0.bak")
+	if err != nil || string(backup) != string(original) {
+		t.Fatalf("backup = %q, %v", backup, err)
+	}
+	var saved Configuration
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Version != Migrations.Current() {
+		t.Fatalf("saved configuration = %s, %v", data, err)
+	}
+}
+
+func TestReaderReadNewerVersion(t *testing.T) {
+	newer := validTestConfig()
+	newer.Version = Migrations.Current() + 1
+	if _, err := read(createTempClientConfigFile(t, newer)); err == nil || !strings.Contains(err.Error(), "upgrade tungo") {
+		t.Fatalf("expected newer version error, got %v", err)
+	}
+}
+
+func TestMoveKeyToFile(t *testing.T) {
+	t.Setenv(keyfile.PassphraseEnv, "secret")
+	conf := validTestConfig()
+	conf.ClientPrivateKey[0] = 7
+	path := createTempClientConfigFile(t, conf)
+
+	if err := MoveKeyToFile(path, "client.key"); err != nil {
+		t.Fatalf("MoveKeyToFile: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "ClientPrivateKey") || !strings.Contains(string(data), `"PrivateKeyFile": "client.key"`) {
+		t.Fatalf("saved configuration = %s", data)
+	}
+	config, err := read(path)
+	if err != nil {
+		t.Fatalf("read() returned error: %v", err)
+	}
+	if string(config.ClientPrivateKey) != string(conf.ClientPrivateKey) {
+		t.Fatal("private key was not read from the key file")
+	}
+	if err := MoveKeyToFile(path, "other.key"); err == nil {
+		t.Fatal("expected error when the key is already in a key file")
+	}
+
+	t.Setenv(keyfile.PassphraseEnv, "")
+	if _, err := read(path); !errors.Is(err, keyfile.ErrNoPassphrase) {
+		t.Fatalf("expected ErrNoPassphrase, got %v", err)
+	}
+}
+
+func TestReaderReadKeyAndKeyFile(t *testing.T) {
+	conf := validTestConfig()
+	conf.PrivateKeyFile = "client.key"
+	if _, err := read(createTempClientConfigFile(t, conf)); err == nil || !strings.Contains(err.Error(), "sets both") {
+		t.Fatalf("expected error for an inline key and a key file, got %v", err)
+	}
+}
@@ -1,5 +1,38 @@
 package client
 
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
 type Resolver interface {
 	Resolve() (string, error)
 }
+
+type fixedResolver string
+
+// NewPathResolver resolves to path instead of the platform default.
+func NewPathResolver(path string) Resolver {
+	return fixedResolver(path)
+}
+
+func (r fixedResolver) Resolve() (string, error) {
+	return string(r), nil
+}
+
+type userResolver struct{}
+
+// NewUserResolver resolves to the configuration of the current user, for
+// commands that run without privileges and cannot read the system one.
+func NewUserResolver() Resolver {
+	return userResolver{}
+}
+
+func (userResolver) Resolve() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find the user configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "tungo", "client_configuration.json"), nil
+}
@@ -16,3 +16,14 @@ func TestClientResolverResolve(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, resolved)
 	}
 }
+
+func TestUserResolverResolve(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/home/user/.config")
+	resolved, err := NewUserResolver().Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+	if expected := "/home/user/.config/tungo/client_configuration.json"; resolved != expected {
+		t.Errorf("expected %q, got %q", expected, resolved)
+	}
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// URIPrefix starts a shareable configuration: tungo://<version>/<payload>.
+// Version 1 carries the configuration JSON, DEFLATE-compressed and encoded
+// with unpadded base64url, so that it fits a QR code.
+const URIPrefix = "tungo://"
+
+const (
+	uriVersion = "1"
+	// maxURIConfigSize bounds the decompressed payload.
+	maxURIConfigSize = 64 * 1024
+)
+
+// IsURI reports whether s looks like a shareable configuration rather than
+// JSON.
+func IsURI(s string) bool {
+	return len(s) >= len(URIPrefix) && strings.EqualFold(s[:len(URIPrefix)], URIPrefix)
+}
+
+// MarshalURI encodes c as a shareable tungo:// URI.
+func MarshalURI(c Configuration) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal client configuration: %w", err)
+	}
+	var compressed bytes.Buffer
+	w, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return URIPrefix + uriVersion + "/" + base64.RawURLEncoding.EncodeToString(compressed.Bytes()), nil
+}
+
+// ParseURI decodes a configuration made by MarshalURI. It does not validate
+// the configuration.
+func ParseURI(raw string) (Configuration, error) {
+	if !IsURI(raw) {
+		return Configuration{}, fmt.Errorf("configuration URI must start with %s", URIPrefix)
+	}
+	version, payload, ok := strings.Cut(raw[len(URIPrefix):], "/")
+	if !ok {
+		return Configuration{}, errors.New("configuration URI has no payload")
+	}
+	if version != uriVersion {
+		return Configuration{}, fmt.Errorf("unsupported configuration URI version %q", version)
+	}
+	compressed, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(payload, "="))
+	if err != nil {
+		return Configuration{}, fmt.Errorf("invalid configuration URI payload: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxURIConfigSize+1))
+	if err != nil {
+		return Configuration{}, fmt.Errorf("invalid configuration URI payload: %w", err)
+	}
+	if len(data) > maxURIConfigSize {
+		return Configuration{}, fmt.Errorf("configuration URI payload exceeds %d bytes", maxURIConfigSize)
+	}
+	c, err := Decode(data)
+	if err != nil {
+		return Configuration{}, fmt.Errorf("invalid configuration URI payload: %w", err)
+	}
+	return c, nil
+}
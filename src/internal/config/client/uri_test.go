@@ -0,0 +1,58 @@
+package client
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestURI_RoundTrip(t *testing.T) {
+	want := validTestConfig()
+	want.Reconnect = Reconnect{RejectionWindowMs: -1}
+	uri, err := MarshalURI(want)
+	if err != nil {
+		t.Fatalf("MarshalURI() error: %v", err)
+	}
+	if !strings.HasPrefix(uri, "tungo://1/") || strings.ContainsAny(uri[len(URIPrefix):], "+=") {
+		t.Fatalf("unexpected URI %q", uri)
+	}
+	data, _ := json.Marshal(want)
+	if len(uri) >= len(data) {
+		t.Fatalf("URI is %d bytes, JSON is %d bytes", len(uri), len(data))
+	}
+
+	got, err := ParseURI(strings.ToUpper(URIPrefix) + uri[len(URIPrefix):])
+	if err != nil {
+		t.Fatalf("ParseURI() error: %v", err)
+	}
+	gotData, _ := json.Marshal(got)
+	if string(gotData) != string(data) {
+		t.Fatalf("ParseURI() = %s, want %s", gotData, data)
+	}
+}
+
+func TestURI_ParseErrors(t *testing.T) {
+	oversized := func() string {
+		var compressed bytes.Buffer
+		w, _ := flate.NewWriter(&compressed, flate.BestCompression)
+		_, _ = w.Write(bytes.Repeat([]byte(" "), maxURIConfigSize+1))
+		_ = w.Close()
+		return URIPrefix + "1/" + base64.RawURLEncoding.EncodeToString(compressed.Bytes())
+	}()
+	cases := map[string]string{
+		"not a URI":       `{"ClientID":1}`,
+		"no payload":      "tungo://1",
+		"unknown version": "tungo://2/AAAA",
+		"bad base64":      "tungo://1/!!!",
+		"bad deflate":     "tungo://1/AAAA",
+		"oversized":       oversized,
+	}
+	for name, raw := range cases {
+		if _, err := ParseURI(raw); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"unicode"
 
 	"tungo/internal/config/settings"
+	"tungo/internal/metrics"
 )
 
 func Validate(configuration Configuration) error {
@@ -22,40 +23,82 @@ func Validate(configuration Configuration) error {
 	if len(configuration.X25519PublicKey) != 32 {
 		return fmt.Errorf("invalid X25519PublicKey (server) length %d, expected 32", len(configuration.X25519PublicKey))
 	}
+	if len(configuration.PresharedKey) != 0 && len(configuration.PresharedKey) != 32 {
+		return fmt.Errorf("invalid PresharedKey length %d, expected 32", len(configuration.PresharedKey))
+	}
 	active, err := configuration.ActiveSettings()
 	if err != nil {
 		return err
 	}
-	if strings.TrimSpace(active.TunName) == "" {
-		return fmt.Errorf("active settings: TunName is not configured")
+	if err := validateSettings(active); err != nil {
+		return fmt.Errorf("active settings: %w", err)
+	}
+	for i, endpoint := range configuration.Endpoints {
+		candidate, err := configuration.EndpointSettings(endpoint)
+		if err != nil {
+			return fmt.Errorf("Endpoints[%d]: %w", i, err)
+		}
+		if err := validateSettings(candidate); err != nil {
+			return fmt.Errorf("Endpoints[%d]: %w", i, err)
+		}
+	}
+	if configuration.UDPFallbackAfter < 0 {
+		return fmt.Errorf("invalid UDPFallbackAfter %d: must be >= 0", configuration.UDPFallbackAfter)
+	}
+	if configuration.UDPFallbackAfter > 0 {
+		fallback, err := configuration.UDPFallbackSettings(active)
+		if err != nil {
+			return err
+		}
+		if err := validateSettings(fallback); err != nil {
+			return fmt.Errorf("UDP fallback settings: %w", err)
+		}
+	}
+	if err := configuration.Reconnect.validate(); err != nil {
+		return err
+	}
+	if err := configuration.Proxy.validate(); err != nil {
+		return err
+	}
+	if configuration.MetricsAddress != "" {
+		if _, err := metrics.ParseAddress(configuration.MetricsAddress, configuration.MetricsAllowRemote); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateSettings(s settings.Settings) error {
+	if strings.TrimSpace(s.TunName) == "" {
+		return fmt.Errorf("TunName is not configured")
 	}
-	if strings.IndexFunc(active.TunName, func(r rune) bool {
+	if strings.IndexFunc(s.TunName, func(r rune) bool {
 		return unicode.IsSpace(r) || unicode.IsControl(r) || unicode.In(r, unicode.Cf)
 	}) >= 0 {
-		return fmt.Errorf("active settings: TunName contains unsupported characters")
+		return fmt.Errorf("TunName contains unsupported characters")
 	}
-	if active.Server == (settings.Host{}) {
-		return fmt.Errorf("active settings: Server is not configured")
+	if s.Server == (settings.Host{}) {
+		return fmt.Errorf("Server is not configured")
 	}
-	if err := validateServerHost(active.Server); err != nil {
-		return fmt.Errorf("active settings: %w", err)
+	if err := validateServerHost(s.Server); err != nil {
+		return err
 	}
-	if active.Port < 1 || active.Port > 65535 {
+	if s.Port < 1 || s.Port > 65535 {
 		// WSS: zero means "use default 443" in connection factory.
-		if configuration.Protocol != settings.WSS || active.Port != 0 {
-			return fmt.Errorf("active settings: invalid Port %d", active.Port)
+		if s.Protocol != settings.WSS || s.Port != 0 {
+			return fmt.Errorf("invalid Port %d", s.Port)
 		}
 	}
-	if !active.IPv4Subnet.IsValid() && !active.IPv6Subnet.IsValid() {
-		return fmt.Errorf("active settings: both IPv4Subnet and IPv6Subnet are invalid")
+	if !s.IPv4Subnet.IsValid() && !s.IPv6Subnet.IsValid() {
+		return fmt.Errorf("both IPv4Subnet and IPv6Subnet are invalid")
 	}
-	if err := validateDNSServers(active.DNSv4, false); err != nil {
-		return fmt.Errorf("active settings: %w", err)
+	if err := validateDNSServers(s.DNSv4, false); err != nil {
+		return err
 	}
-	if err := validateDNSServers(active.DNSv6, true); err != nil {
-		return fmt.Errorf("active settings: %w", err)
+	if err := validateDNSServers(s.DNSv6, true); err != nil {
+		return err
 	}
-	return nil
+	return s.Routing.Validate()
 }
 
 func validateServerHost(host settings.Host) error {
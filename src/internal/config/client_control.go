@@ -1,7 +1,6 @@
 package config
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -16,6 +15,7 @@ type clientControl struct {
 	selector clientSelector
 	creator  clientCreator
 	manager  clientconfigManager
+	keys     clientKeyStore
 }
 
 type clientObserver interface {
@@ -30,6 +30,10 @@ type clientCreator interface {
 	Create(configuration clientconfig.Configuration, name string) error
 }
 
+type clientKeyStore interface {
+	PrivateKey(public []byte) ([]byte, error)
+}
+
 type clientconfigManager interface {
 	Configuration() (*clientconfig.Configuration, error)
 }
@@ -73,7 +77,7 @@ func runtimeClientSettings(conf *clientconfig.Configuration) (settings.Settings,
 }
 
 func (c *clientControl) CreateFromJSON(name, rawJSON string) error {
-	configuration, err := parseClientConfigurationJSON(rawJSON)
+	configuration, err := parseClientConfigurationJSON(rawJSON, c.keys)
 	if err != nil {
 		return err
 	}
@@ -84,15 +88,29 @@ func (c *clientControl) Delete(path string) error {
 	return os.Remove(path)
 }
 
-func parseClientConfigurationJSON(input string) (clientconfig.Configuration, error) {
+// parseClientConfigurationJSON validates an enrolled configuration with its
+// key from keys, but returns it without the key.
+func parseClientConfigurationJSON(input string, keys clientKeyStore) (clientconfig.Configuration, error) {
 	clean := strings.TrimFunc(input, func(r rune) bool {
 		return unicode.IsSpace(r) || unicode.IsControl(r) || unicode.In(r, unicode.Cf)
 	})
 	var cfg clientconfig.Configuration
-	if err := json.Unmarshal([]byte(clean), &cfg); err != nil {
+	var err error
+	if clientconfig.IsURI(clean) {
+		cfg, err = clientconfig.ParseURI(clean)
+	} else {
+		cfg, err = clientconfig.Decode([]byte(clean))
+	}
+	if err != nil {
 		return clientconfig.Configuration{}, fmt.Errorf("invalid client configuration: %w", err)
 	}
-	if err := clientconfig.Validate(cfg); err != nil {
+	validated := cfg
+	if len(cfg.ClientPrivateKey) == 0 && keys != nil {
+		if validated.ClientPrivateKey, err = keys.PrivateKey(cfg.ClientPublicKey); err != nil {
+			return clientconfig.Configuration{}, fmt.Errorf("invalid client configuration: %w", err)
+		}
+	}
+	if err := clientconfig.Validate(validated); err != nil {
 		return clientconfig.Configuration{}, fmt.Errorf("invalid client configuration: %w", err)
 	}
 	return cfg, nil
@@ -2,9 +2,12 @@ package config
 
 import (
 	"context"
+	"net/netip"
+	"time"
 
 	clientconfig "tungo/internal/config/client"
 	serverconfig "tungo/internal/config/server"
+	"tungo/internal/config/settings"
 )
 
 type Controls struct {
@@ -21,6 +24,7 @@ type ClientConfigurationControl interface {
 	Select(path string) error
 	ValidateActive() error
 	RuntimeInfo() (RuntimeInfo, error)
+	// CreateFromJSON accepts configuration JSON or a tungo:// URI.
 	CreateFromJSON(name, rawJSON string) error
 	Delete(path string) error
 }
@@ -33,16 +37,61 @@ type ClientControl interface {
 type ServerConfigurationControl interface {
 	RuntimeInfo() (RuntimeInfo, error)
 	GenerateClientConfiguration() (GeneratedClientConfiguration, error)
+	// GenerateClientConfigurations generates options.Count configurations.
+	// It stops at the first error and returns the configurations generated
+	// before it, whose peers the server already allows.
+	GenerateClientConfigurations(options GenerateOptions) ([]GeneratedClientConfiguration, error)
+	// ClientConfigurationURI returns the tungo:// URI of a client
+	// configuration this server generated.
+	ClientConfigurationURI(clientID int) (string, error)
 	ListPeers() ([]ServerPeer, error)
 	SetPeerEnabled(clientID int, enabled bool) error
+	RenamePeer(clientID int, name string) error
 	RemovePeer(clientID int) error
 }
 
 type GeneratedClientConfiguration struct {
+	ClientID int
+	// Name is the peer name on the server.
+	Name string
 	JSON string
+	URI  string
+	// Path is the copy kept next to the server configuration; empty when
+	// GenerateOptions.Discard is set.
 	Path string
 }
 
+// GenerateOptions override the defaults of generated client configurations.
+// The zero value generates one configuration, as GenerateClientConfiguration
+// does.
+type GenerateOptions struct {
+	// Name names the peer instead of client-<id>. A batch names its peers
+	// Name-1, Name-2 and so on.
+	Name string
+	// Protocol is the protocol the client starts with, one the server has
+	// enabled. Zero picks the server's default.
+	Protocol settings.Protocol
+	// Host is the address clients dial, replacing the Host setting and
+	// address detection.
+	Host string
+	// DNS replaces the default resolvers of the client.
+	DNS []netip.Addr
+	// ExpiresAt revokes the peers once it passes. Zero never expires.
+	ExpiresAt time.Time
+	// Count is the size of the batch; zero means one.
+	Count int
+	// PublicKey enrolls a client that made its own key pair with
+	// "tungo c keygen": the peer gets this key, and the configuration
+	// carries no private key. It takes a Count of one.
+	PublicKey []byte
+	// PresharedKey gives each peer a new pre-shared key, kept with the peer
+	// and in its configuration.
+	PresharedKey bool
+	// Discard keeps no copy of the configurations next to the server
+	// configuration, so that the private keys only reach the caller.
+	Discard bool
+}
+
 type ServerSessionRevoker interface {
 	RevokeByPubKey(pubKey []byte) int
 }
@@ -58,6 +107,9 @@ type ServerRuntimeControl interface {
 		revoker ServerSessionRevoker,
 		updater ServerAllowedPeersUpdater,
 	)
+	// LoadPeerTraffic and SavePeerTraffic persist usage keyed by string(PublicKey).
+	LoadPeerTraffic() (map[string]serverconfig.PeerTraffic, error)
+	SavePeerTraffic(traffic map[string]serverconfig.PeerTraffic) error
 }
 
 type ServerControl interface {
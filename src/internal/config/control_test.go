@@ -57,11 +57,31 @@ func makeTestConfig() clientconfig.Configuration {
 	}
 }
 
+func TestParseClientConfigurationJSON_URI(t *testing.T) {
+	want := makeTestConfig()
+	uri, err := clientconfig.MarshalURI(want)
+	if err != nil {
+		t.Fatalf("MarshalURI() error: %v", err)
+	}
+
+	cfg, err := parseClientConfigurationJSON("  "+uri+"\n", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TCPSettings.Server != want.TCPSettings.Server || cfg.TCPSettings.Port != want.TCPSettings.Port {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+
+	if _, err := parseClientConfigurationJSON("tungo://1/AAAA", nil); err == nil || !strings.Contains(err.Error(), "invalid client configuration") {
+		t.Fatalf("expected invalid configuration error, got %v", err)
+	}
+}
+
 func TestParseClientConfigurationJSON_Simple(t *testing.T) {
 	want := makeTestConfig()
 	raw, _ := json.Marshal(want)
 
-	cfg, err := parseClientConfigurationJSON(string(raw))
+	cfg, err := parseClientConfigurationJSON(string(raw), nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -78,7 +98,7 @@ func TestParseClientConfigurationJSON_WithBOMAndZeroWidthAndControl(t *testing.T
 	raw, _ := json.Marshal(want)
 	dirty := "\uFEFF\u200B\x00\x07  " + string(raw) + "  \x0B\u200B\uFEFF"
 
-	cfg, err := parseClientConfigurationJSON(dirty)
+	cfg, err := parseClientConfigurationJSON(dirty, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -92,7 +112,7 @@ func TestParseClientConfigurationJSON_RejectsFormatCharacterInsideTunName(t *tes
 	cfg.TCPSettings.TunName = "tun\u200b0"
 	raw, _ := json.Marshal(cfg)
 
-	_, err := parseClientConfigurationJSON(string(raw))
+	_, err := parseClientConfigurationJSON(string(raw), nil)
 	if err == nil || !strings.Contains(err.Error(), "TunName contains unsupported characters") {
 		t.Fatalf("expected TunName validation error, got %v", err)
 	}
@@ -103,7 +123,7 @@ func TestParseClientConfigurationJSON_PrettyPrintCRLF(t *testing.T) {
 	raw, _ := json.MarshalIndent(want, "", "  ")
 	pretty := strings.ReplaceAll(string(raw), "\n", "\r\n")
 
-	cfg, err := parseClientConfigurationJSON(pretty)
+	cfg, err := parseClientConfigurationJSON(pretty, nil)
 	if err != nil {
 		t.Fatalf("failed to parse CRLF JSON: %v", err)
 	}
@@ -117,7 +137,7 @@ func TestParseClientConfigurationJSON_NonBreakingSpaceTrim(t *testing.T) {
 	raw, _ := json.Marshal(want)
 	dirty := "\u00A0\u00A0" + string(raw) + "\u00A0"
 
-	cfg, err := parseClientConfigurationJSON(dirty)
+	cfg, err := parseClientConfigurationJSON(dirty, nil)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -127,7 +147,7 @@ func TestParseClientConfigurationJSON_NonBreakingSpaceTrim(t *testing.T) {
 }
 
 func TestParseClientConfigurationJSON_Invalid(t *testing.T) {
-	_, err := parseClientConfigurationJSON("not a valid { json")
+	_, err := parseClientConfigurationJSON("not a valid { json", nil)
 	if err == nil {
 		t.Fatal("expected error for invalid JSON, got nil")
 	}
@@ -138,12 +158,38 @@ func TestParseClientConfigurationJSON_InvalidByValidation(t *testing.T) {
 	cfg.ClientID = 0
 	raw, _ := json.Marshal(cfg)
 
-	_, err := parseClientConfigurationJSON(string(raw))
+	_, err := parseClientConfigurationJSON(string(raw), nil)
 	if err == nil {
 		t.Fatal("expected validation error for invalid client configuration")
 	}
 }
 
+type clientKeyStoreFunc func(public []byte) ([]byte, error)
+
+func (f clientKeyStoreFunc) PrivateKey(public []byte) ([]byte, error) { return f(public) }
+
+func TestParseClientConfigurationJSON_Enrolled(t *testing.T) {
+	enrolled := makeTestConfig()
+	enrolled.ClientPrivateKey = nil
+	raw, _ := json.Marshal(enrolled)
+
+	if _, err := parseClientConfigurationJSON(string(raw), nil); err == nil {
+		t.Fatal("expected an enrolled configuration without a key store to be rejected")
+	}
+	keys := clientKeyStoreFunc(func([]byte) ([]byte, error) { return make([]byte, 32), nil })
+	cfg, err := parseClientConfigurationJSON(string(raw), keys)
+	if err != nil {
+		t.Fatalf("parseClientConfigurationJSON() error = %v", err)
+	}
+	if cfg.ClientPrivateKey != nil {
+		t.Fatal("the private key from the key store must not be written to the configuration")
+	}
+	missing := clientKeyStoreFunc(func([]byte) ([]byte, error) { return nil, errors.New("not made here") })
+	if _, err := parseClientConfigurationJSON(string(raw), missing); err == nil || !strings.Contains(err.Error(), "not made here") {
+		t.Fatalf("expected the key store error, got %v", err)
+	}
+}
+
 func TestClientControlDelegates(t *testing.T) {
 	wantErr := errors.New("delegate failed")
 	selectedPath := ""
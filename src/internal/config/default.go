@@ -8,7 +8,9 @@ import (
 	"tungo/internal/platform"
 )
 
-const defaultServerConfigurationPath = "/etc/tungo/server_configuration.json"
+// DefaultServerConfigurationPath is the server configuration used without
+// --config.
+const DefaultServerConfigurationPath = "/etc/tungo/server_configuration.json"
 
 func DefaultStorageDirectory() (string, error) {
 	path, err := clientconfig.NewResolver().Resolve()
@@ -25,17 +27,25 @@ func NewClientControl() ClientControl {
 		selector: clientconfig.NewSelector(clientResolver),
 		creator:  clientconfig.NewCreator(clientResolver),
 		manager:  clientconfig.NewManager(),
+		keys:     clientconfig.NewKeyStore(clientResolver),
 	}
 }
 
 func NewServerControl() ServerControl {
+	return NewServerControlAt(DefaultServerConfigurationPath)
+}
+
+// NewServerControlAt manages the server configuration at path. Generated
+// client configurations are stored next to it.
+func NewServerControlAt(path string) ServerControl {
 	if !platform.ServerModeSupported() {
 		return nil
 	}
 
 	return &serverControl{
-		configPath: defaultServerConfigurationPath,
-		manager:    serverconfig.NewManager(defaultServerConfigurationPath),
+		configPath: path,
+		manager:    serverconfig.NewManager(path),
+		traffic:    serverconfig.NewTrafficStore(path),
 	}
 }
 
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"bytes"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"net/netip"
+	"slices"
 	"strings"
+	"time"
 	"tungo/internal/config/client"
 	serverconfig "tungo/internal/config/server"
 	"tungo/internal/config/settings"
@@ -42,14 +47,32 @@ func newGenerator(
 }
 
 // Generate creates a new client configuration, registers the peer with the server,
-// and returns the resulting client.Configuration.
-func (g *generator) generate() (*client.Configuration, error) {
+// and returns the resulting client.Configuration. It uses options.Name as it
+// is and ignores options.Count.
+func (g *generator) generate(options GenerateOptions) (*client.Configuration, error) {
 	serverConf, err := g.serverconfigManager.Configuration()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read server configuration: %w", err)
 	}
 
-	serverHost, err := g.resolveServerHost(serverConf.Host)
+	protocol := options.Protocol
+	if protocol == settings.UNKNOWN {
+		protocol = getDefaultProtocol(serverConf)
+	} else if !protocolEnabled(serverConf, protocol) {
+		return nil, fmt.Errorf("protocol %v is not enabled on this server", protocol)
+	}
+	if !options.ExpiresAt.IsZero() && !options.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiry %s is in the past", options.ExpiresAt.Format(time.RFC3339))
+	}
+	if err := checkEnrollment(serverConf, options.PublicKey); err != nil {
+		return nil, err
+	}
+
+	configuredHost := serverConf.Host
+	if options.Host != "" {
+		configuredHost = options.Host
+	}
+	serverHost, err := g.resolveServerHost(configuredHost)
 	if err != nil {
 		return nil, err
 	}
@@ -71,43 +94,65 @@ func (g *generator) generate() (*client.Configuration, error) {
 		return nil, err
 	}
 
-	clientPubKey, clientPrivKey, err := g.keyDeriver.GenerateX25519KeyPair()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate client keypair: %w", err)
+	clientPubKey, clientPrivKey := options.PublicKey, []byte(nil)
+	if clientPubKey == nil {
+		public, private, err := g.keyDeriver.GenerateX25519KeyPair()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate client keypair: %w", err)
+		}
+		clientPubKey, clientPrivKey = public, private[:]
+	}
+
+	var presharedKey []byte
+	if options.PresharedKey {
+		presharedKey = make([]byte, 32)
+		if _, err := rand.Read(presharedKey); err != nil {
+			return nil, fmt.Errorf("failed to generate pre-shared key: %w", err)
+		}
 	}
 
 	newPeer := serverconfig.AllowedPeer{
-		Name:      fmt.Sprintf("client-%d", clientID),
-		PublicKey: clientPubKey,
-		Enabled:   true,
-		ClientID:  clientID,
+		Name:         peerName(options.Name, clientID),
+		PublicKey:    clientPubKey,
+		Enabled:      true,
+		ClientID:     clientID,
+		ExpiresAt:    options.ExpiresAt,
+		PresharedKey: presharedKey,
 	}
 	if err := g.serverconfigManager.AddAllowedPeer(newPeer); err != nil {
 		return nil, fmt.Errorf("failed to add client to AllowedPeers: %w", err)
 	}
 
-	defaultProtocol := getDefaultProtocol(serverConf)
-	tcpSettings, tcpErr := deriveClientSettings(serverConf.TCPSettings, serverHost, settings.TCP)
+	tcpSettings, tcpErr := deriveClientSettings(serverConf.TCPSettings, serverHost, serverConf.ClientRouting, settings.TCP)
 	if tcpErr != nil {
 		return nil, fmt.Errorf("failed to derive tcp settings: %w", tcpErr)
 	}
-	udpSettings, udpErr := deriveClientSettings(serverConf.UDPSettings, serverHost, settings.UDP)
+	udpSettings, udpErr := deriveClientSettings(serverConf.UDPSettings, serverHost, serverConf.ClientRouting, settings.UDP)
 	if udpErr != nil {
 		return nil, fmt.Errorf("failed to derive udp settings: %w", udpErr)
 	}
-	wsSettings, wsErr := deriveClientSettings(serverConf.WSSettings, serverHost, settings.WS)
+	wsProtocol := protocol
+	if protocol != settings.WSS {
+		wsProtocol = settings.WS
+	}
+	wsSettings, wsErr := deriveClientSettings(serverConf.WSSettings, serverHost, serverConf.ClientRouting, wsProtocol)
 	if wsErr != nil {
 		return nil, fmt.Errorf("failed to derive ws settings: %w", wsErr)
 	}
+	for _, s := range []*settings.Settings{&tcpSettings, &udpSettings, &wsSettings} {
+		setDNS(s, options.DNS)
+	}
 	conf := client.Configuration{
+		Version:          client.Migrations.Current(),
 		ClientID:         clientID,
 		TCPSettings:      tcpSettings,
 		UDPSettings:      udpSettings,
 		WSSettings:       wsSettings,
 		X25519PublicKey:  serverConf.X25519PublicKey,
-		Protocol:         defaultProtocol,
+		Protocol:         protocol,
 		ClientPublicKey:  clientPubKey,
-		ClientPrivateKey: clientPrivKey[:],
+		ClientPrivateKey: clientPrivKey,
+		PresharedKey:     presharedKey,
 	}
 
 	return &conf, nil
@@ -163,6 +208,7 @@ func (g *generator) resolveServerHost(configured string) (settings.Host, error)
 func deriveClientSettings(
 	serverSettings settings.Settings,
 	serverHost settings.Host,
+	routing settings.Routing,
 	protocol settings.Protocol,
 ) (settings.Settings, error) {
 	mtu := serverSettings.MTU
@@ -180,6 +226,11 @@ func deriveClientSettings(
 			Server:     serverHost,
 			Port:       serverSettings.Port,
 		},
+		Routing: settings.Routing{
+			IncludeRoutes: slices.Clone(routing.IncludeRoutes),
+			ExcludeRoutes: slices.Clone(routing.ExcludeRoutes),
+			AllowLAN:      routing.AllowLAN,
+		},
 		MTU:           mtu,
 		Protocol:      protocol,
 		Encryption:    serverSettings.Encryption,
@@ -204,6 +255,77 @@ func deriveClientTunName(protocol settings.Protocol) (string, error) {
 	}
 }
 
+// checkEnrollment accepts the public key of a client that keeps its private
+// key, unless another peer already uses it.
+func checkEnrollment(conf *serverconfig.Configuration, public []byte) error {
+	if public == nil {
+		return nil
+	}
+	if len(public) != 32 {
+		return fmt.Errorf("invalid client public key length %d, expected 32", len(public))
+	}
+	for _, peer := range conf.AllowedPeers {
+		if bytes.Equal(peer.PublicKey, public) {
+			return fmt.Errorf("public key is already enrolled as peer #%d", peer.ClientID)
+		}
+	}
+	return nil
+}
+
+func protocolEnabled(conf *serverconfig.Configuration, protocol settings.Protocol) bool {
+	switch protocol {
+	case settings.UDP:
+		return conf.EnableUDP
+	case settings.TCP:
+		return conf.EnableTCP
+	case settings.WS, settings.WSS:
+		return conf.EnableWS
+	default:
+		return false
+	}
+}
+
+// setDNS replaces the default resolvers of s with servers, when given.
+func setDNS(s *settings.Settings, servers []netip.Addr) {
+	s.DNSv4, s.DNSv6 = nil, nil
+	for _, server := range servers {
+		if server.Unmap().Is4() {
+			s.DNSv4 = append(s.DNSv4, server.Unmap().String())
+		} else {
+			s.DNSv6 = append(s.DNSv6, server.String())
+		}
+	}
+}
+
+// peerName returns name, or client-<id> when name is empty.
+func peerName(name string, clientID int) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return name
+	}
+	return fmt.Sprintf("client-%d", clientID)
+}
+
+// batchNames returns the peer names of a batch of count peers: name-1,
+// name-2 and so on, or the default names when name is empty. A batch of one
+// keeps name as it is.
+func batchNames(name string, count int) ([]string, error) {
+	if count < 1 {
+		return nil, errors.New("count must be at least 1")
+	}
+	name = strings.TrimSpace(name)
+	names := make([]string, count)
+	for i := range names {
+		switch {
+		case name == "":
+		case count == 1:
+			names[i] = name
+		default:
+			names[i] = fmt.Sprintf("%s-%d", name, i+1)
+		}
+	}
+	return names, nil
+}
+
 func getDefaultProtocol(conf *serverconfig.Configuration) settings.Protocol {
 	if conf.EnableUDP {
 		return settings.UDP
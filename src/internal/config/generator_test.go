@@ -1,10 +1,13 @@
 package config
 
 import (
+	"bytes"
 	"errors"
 	"net/netip"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	nip "tungo/internal/config/addressing"
 	serverconfig "tungo/internal/config/server"
@@ -82,8 +85,10 @@ func (m *mockMgr) AddAllowedPeer(peer serverconfig.AllowedPeer) error {
 }
 func (m *mockMgr) ListAllowedPeers() ([]serverconfig.AllowedPeer, error) { return nil, nil }
 func (m *mockMgr) SetAllowedPeerEnabled(_ int, _ bool) error             { return nil }
+func (m *mockMgr) RenameAllowedPeer(_ int, _ string) error               { return nil }
 func (m *mockMgr) RemoveAllowedPeer(_ int) error                         { return nil }
 func (m *mockMgr) InvalidateCache()                                      {}
+func (m *mockMgr) SetPrivateKeyFile(_ string) error                      { return nil }
 
 // mockResolver implements hostResolver for tests.
 type mockResolver struct {
@@ -166,7 +171,7 @@ func TestGenerate_success(t *testing.T) {
 		ipv6: "2001:db8::1",
 	})
 
-	conf, err := g.generate()
+	conf, err := g.generate(GenerateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected: %v", err)
 	}
@@ -200,11 +205,148 @@ func TestGenerate_success(t *testing.T) {
 	}
 }
 
+func TestGenerate_emits_client_routing(t *testing.T) {
+	mgr := &mockMgr{cfg: validCfg()}
+	mgr.cfg.ClientRouting = settings.Routing{
+		IncludeRoutes: []netip.Prefix{mustPrefix("10.20.0.0/16")},
+		ExcludeRoutes: []netip.Prefix{mustPrefix("10.20.99.0/24")},
+		AllowLAN:      true,
+	}
+	g := generatorWithMocks(mgr, mockResolver{ipv4: "192.0.2.10"})
+
+	conf, err := g.generate(GenerateOptions{})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	for _, got := range []settings.Settings{conf.TCPSettings, conf.UDPSettings, conf.WSSettings} {
+		if !reflect.DeepEqual(got.Routing, mgr.cfg.ClientRouting) {
+			t.Fatalf("%s routing: want %+v, got %+v", got.Protocol, mgr.cfg.ClientRouting, got.Routing)
+		}
+	}
+	conf.UDPSettings.IncludeRoutes[0] = mustPrefix("172.16.0.0/12")
+	if mgr.cfg.ClientRouting.IncludeRoutes[0] != mustPrefix("10.20.0.0/16") {
+		t.Fatal("generated routing must not alias the server configuration")
+	}
+}
+
+func TestGenerate_options(t *testing.T) {
+	mgr := &mockMgr{cfg: validCfg()}
+	g := generatorWithMocks(mgr, mockResolver{})
+	expiresAt := time.Now().Add(time.Hour)
+
+	conf, err := g.generate(GenerateOptions{
+		Name:      "alice",
+		Protocol:  settings.WSS,
+		Host:      "vpn.example.com",
+		DNS:       []netip.Addr{netip.MustParseAddr("10.2.0.1"), netip.MustParseAddr("fd00:2::1")},
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if conf.Protocol != settings.WSS || conf.WSSettings.Protocol != settings.WSS {
+		t.Fatalf("protocol = %v/%v, want WSS", conf.Protocol, conf.WSSettings.Protocol)
+	}
+	if conf.WSSettings.Server.Domain != "vpn.example.com" {
+		t.Fatalf("server = %+v, want vpn.example.com", conf.WSSettings.Server)
+	}
+	for _, s := range []settings.Settings{conf.TCPSettings, conf.UDPSettings, conf.WSSettings} {
+		if !reflect.DeepEqual(s.DNSv4, []string{"10.2.0.1"}) || !reflect.DeepEqual(s.DNSv6, []string{"fd00:2::1"}) {
+			t.Fatalf("%v DNS = %v %v", s.Protocol, s.DNSv4, s.DNSv6)
+		}
+	}
+	if len(mgr.addedPeers) != 1 {
+		t.Fatalf("added %d peers, want 1", len(mgr.addedPeers))
+	}
+	if peer := mgr.addedPeers[0]; peer.Name != "alice" || !peer.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("peer = %q expiring %v, want alice expiring %v", peer.Name, peer.ExpiresAt, expiresAt)
+	}
+}
+
+func TestGenerate_rejects_bad_options(t *testing.T) {
+	tests := map[string]GenerateOptions{
+		"disabled protocol": {Protocol: settings.UDP},
+		"past expiry":       {ExpiresAt: time.Now().Add(-time.Minute)},
+	}
+	for name, options := range tests {
+		mgr := &mockMgr{cfg: validCfg()}
+		if _, err := generatorWithMocks(mgr, mockResolver{}).generate(options); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if mgr.incCalls != 0 || len(mgr.addedPeers) != 0 {
+			t.Errorf("%s: server configuration changed", name)
+		}
+	}
+}
+
+func TestGenerate_enrollment(t *testing.T) {
+	mgr := &mockMgr{cfg: validCfg()}
+	public := bytes.Repeat([]byte{9}, 32)
+
+	conf, err := generatorWithMocks(mgr, mockResolver{}).generate(GenerateOptions{PublicKey: public})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if conf.ClientPrivateKey != nil || !bytes.Equal(conf.ClientPublicKey, public) {
+		t.Fatalf("client keys = %x/%x, want the enrolled public key only", conf.ClientPublicKey, conf.ClientPrivateKey)
+	}
+	if len(mgr.addedPeers) != 1 || !bytes.Equal(mgr.addedPeers[0].PublicKey, public) {
+		t.Fatalf("added peers = %+v", mgr.addedPeers)
+	}
+
+	mgr.cfg.AllowedPeers = mgr.addedPeers
+	incCalls := mgr.incCalls
+	_, err = generatorWithMocks(mgr, mockResolver{}).generate(GenerateOptions{PublicKey: public})
+	if err == nil || !strings.Contains(err.Error(), "already enrolled as peer #8") {
+		t.Fatalf("expected duplicate enrollment error, got %v", err)
+	}
+	if mgr.incCalls != incCalls {
+		t.Fatal("rejected enrollment changed the client counter")
+	}
+}
+
+func TestGenerate_preshared_key(t *testing.T) {
+	mgr := &mockMgr{cfg: validCfg()}
+	conf, err := generatorWithMocks(mgr, mockResolver{}).generate(GenerateOptions{PresharedKey: true})
+	if err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if len(conf.PresharedKey) != 32 || len(mgr.addedPeers) != 1 || !bytes.Equal(mgr.addedPeers[0].PresharedKey, conf.PresharedKey) {
+		t.Fatalf("pre-shared key = %x, peers = %+v", conf.PresharedKey, mgr.addedPeers)
+	}
+
+	conf, err = generatorWithMocks(mgr, mockResolver{}).generate(GenerateOptions{})
+	if err != nil || conf.PresharedKey != nil || mgr.addedPeers[1].PresharedKey != nil {
+		t.Fatalf("pre-shared key without the option: %v", err)
+	}
+}
+
+func TestBatchNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		want  []string
+	}{
+		{"alice", 1, []string{"alice"}},
+		{"team", 3, []string{"team-1", "team-2", "team-3"}},
+		{"", 2, []string{"", ""}},
+	}
+	for _, tt := range tests {
+		got, err := batchNames(tt.name, tt.count)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("batchNames(%q, %d) = %q, %v; want %q", tt.name, tt.count, got, err, tt.want)
+		}
+	}
+	if _, err := batchNames("team", -1); err == nil {
+		t.Error("expected error for a negative count")
+	}
+}
+
 func TestGenerate_config_error(t *testing.T) {
 	mgr := &mockMgr{cfgErr: errors.New("cfg-fail")}
 	g := generatorWithMocks(mgr, mockResolver{})
 
-	_, err := g.generate()
+	_, err := g.generate(GenerateOptions{})
 	if err == nil || !strings.Contains(err.Error(), "failed to read server configuration") {
 		t.Fatalf("want config read error, got %v", err)
 	}
@@ -219,7 +361,7 @@ func TestGenerate_detect_server_host_error(t *testing.T) {
 		ipv6Err: errors.New("resolve-fail"),
 	})
 
-	_, err := g.generate()
+	_, err := g.generate(GenerateOptions{})
 	if err == nil || !strings.Contains(err.Error(), "failed to detect server host") {
 		t.Fatalf("want server host detection error, got %v", err)
 	}
@@ -234,7 +376,7 @@ func TestGenerate_detects_ipv6_only_server_host(t *testing.T) {
 		ipv6:    "2001:db8::1",
 	})
 
-	conf, err := g.generate()
+	conf, err := g.generate(GenerateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -254,7 +396,7 @@ func TestGenerate_configured_server_host_has_priority(t *testing.T) {
 		ipv6Err: errors.New("no-ipv6"),
 	})
 
-	conf, err := g.generate()
+	conf, err := g.generate(GenerateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected: %v", err)
 	}
@@ -276,7 +418,7 @@ func TestGenerate_clientID_matches_allocated_IPs(t *testing.T) {
 		ipv6: "2001:db8::1",
 	})
 
-	conf, err := g.generate()
+	conf, err := g.generate(GenerateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected: %v", err)
 	}
@@ -347,7 +489,7 @@ func TestDeriveClientSettings_copies_fields_correctly(t *testing.T) {
 	}
 	host := settings.Host{IPv4: "192.0.2.1", IPv6: "2001:db8::1"}
 
-	got, err := deriveClientSettings(serverS, host, settings.TCP)
+	got, err := deriveClientSettings(serverS, host, settings.Routing{}, settings.TCP)
 	if err != nil {
 		t.Fatalf("deriveClientSettings returned error: %v", err)
 	}
@@ -390,7 +532,7 @@ func TestDeriveClientSettings_copies_fields_correctly(t *testing.T) {
 
 func TestDeriveClientSettings_udp_uses_safe_mtu(t *testing.T) {
 	serverS := settings.Settings{MTU: 1400}
-	got, err := deriveClientSettings(serverS, settings.Host{}, settings.UDP)
+	got, err := deriveClientSettings(serverS, settings.Host{}, settings.Routing{}, settings.UDP)
 	if err != nil {
 		t.Fatalf("deriveClientSettings returned error: %v", err)
 	}
@@ -403,14 +545,14 @@ func TestDeriveClientSettings_udp_uses_safe_mtu(t *testing.T) {
 }
 
 func TestDeriveClientSettings_unsupported_protocol(t *testing.T) {
-	_, err := deriveClientSettings(settings.Settings{}, settings.Host{}, settings.UNKNOWN)
+	_, err := deriveClientSettings(settings.Settings{}, settings.Host{}, settings.Routing{}, settings.UNKNOWN)
 	if !errors.Is(err, ErrUnsupportedProtocol) {
 		t.Fatalf("protocol %v: want ErrUnsupportedProtocol, got %v", settings.UNKNOWN, err)
 	}
 }
 
 func TestDeriveClientSettings_wss_uses_ws_tun_name(t *testing.T) {
-	got, err := deriveClientSettings(settings.Settings{}, settings.Host{}, settings.WSS)
+	got, err := deriveClientSettings(settings.Settings{}, settings.Host{}, settings.Routing{}, settings.WSS)
 	if err != nil {
 		t.Fatalf("deriveClientSettings returned error: %v", err)
 	}
@@ -432,7 +574,7 @@ func TestGenerate_no_ipv6_on_server(t *testing.T) {
 		ipv6Err: errors.New("no-ipv6"),
 	})
 
-	conf, err := g.generate()
+	conf, err := g.generate(GenerateOptions{})
 	if err != nil {
 		t.Fatalf("unexpected: %v", err)
 	}
@@ -470,7 +612,7 @@ func TestGenerate_ensure_ipv6_subnets_error(t *testing.T) {
 		ipv6: "2001:db8::1",
 	})
 
-	_, err := g.generate()
+	_, err := g.generate(GenerateOptions{})
 	if err == nil || !strings.Contains(err.Error(), "failed to auto-enable IPv6 subnets") {
 		t.Fatalf("want auto-enable error, got %v", err)
 	}
@@ -488,7 +630,7 @@ func TestGenerate_reread_config_error(t *testing.T) {
 		ipv6: "2001:db8::1",
 	})
 
-	_, err := g.generate()
+	_, err := g.generate(GenerateOptions{})
 	if err == nil || !strings.Contains(err.Error(), "failed to re-read server configuration") {
 		t.Fatalf("want re-read error, got %v", err)
 	}
@@ -502,7 +644,7 @@ func TestGenerate_keypair_error(t *testing.T) {
 	})
 	g.keyDeriver = &mockKeyDeriver{genErr: errors.New("keygen-fail")}
 
-	_, err := g.generate()
+	_, err := g.generate(GenerateOptions{})
 	if err == nil || !strings.Contains(err.Error(), "failed to generate client keypair") {
 		t.Fatalf("want keypair error, got %v", err)
 	}
@@ -518,7 +660,7 @@ func TestGenerate_add_peer_error(t *testing.T) {
 		ipv6Err: errors.New("no-ipv6"),
 	})
 
-	_, err := g.generate()
+	_, err := g.generate(GenerateOptions{})
 	if err == nil || !strings.Contains(err.Error(), "failed to add client to AllowedPeers") {
 		t.Fatalf("want add-peer error, got %v", err)
 	}
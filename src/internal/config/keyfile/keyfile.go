@@ -0,0 +1,222 @@
+// Package keyfile keeps private keys in files of their own, out of the
+// configuration. A key file holds the base64 key, or the key encrypted with
+// a passphrase.
+package keyfile
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+
+	"tungo/internal/protocol/keys"
+)
+
+const (
+	// PassphraseEnv holds the passphrase of encrypted key files.
+	PassphraseEnv = "TUNGO_KEY_PASSPHRASE"
+	// PassphraseCredential is the systemd credential (LoadCredential=) that
+	// holds the passphrase when PassphraseEnv is not set.
+	PassphraseCredential = "tungo-key-passphrase"
+)
+
+// ErrNoPassphrase reports an encrypted key file without a passphrase to
+// unlock it.
+var ErrNoPassphrase = fmt.Errorf("key file is encrypted: set %s or the systemd credential %s", PassphraseEnv, PassphraseCredential)
+
+// envelope is an encrypted key file. The key is sealed with
+// ChaCha20-Poly1305 under a key derived from the passphrase with Argon2id.
+type envelope struct {
+	KDF        string `json:"KDF"`
+	Time       uint32 `json:"Time"`
+	Memory     uint32 `json:"Memory"`
+	Threads    uint8  `json:"Threads"`
+	Salt       []byte `json:"Salt"`
+	Nonce      []byte `json:"Nonce"`
+	Ciphertext []byte `json:"Ciphertext"`
+}
+
+const kdfArgon2id = "argon2id"
+
+// Argon2id parameters of new key files, as recommended by RFC 9106 for
+// memory-constrained hosts.
+const (
+	argonTime    = 3
+	argonMemory  = 64 * 1024
+	argonThreads = 4
+)
+
+// Limits on the Argon2id parameters read from a key file, so that a crafted
+// file cannot make the server allocate or spin without bound before the
+// passphrase is checked. They are far above the parameters Write uses.
+const (
+	maxArgonTime    = 16
+	maxArgonMemory  = 1024 * 1024 // KiB, 1 GiB
+	maxArgonThreads = 64
+)
+
+// Resolve returns the path of keyFile, which is relative to the directory
+// of the configuration at configPath.
+func Resolve(configPath, keyFile string) string {
+	if filepath.IsAbs(keyFile) {
+		return keyFile
+	}
+	return filepath.Join(filepath.Dir(configPath), keyFile)
+}
+
+// DefaultName names the key file of the configuration at configPath.
+func DefaultName(configPath string) string {
+	return strings.TrimSuffix(filepath.Base(configPath), ".json") + ".key"
+}
+
+// Read returns the private key in the file at path. The file must belong to
+// this user and must not be accessible by anyone else.
+func Read(path string) ([]byte, error) {
+	if err := checkPermissions(path); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		private, err := keys.ParseKey(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+		}
+		return private, nil
+	}
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	passphrase, err := Passphrase()
+	if err != nil {
+		return nil, err
+	}
+	if passphrase == nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoPassphrase)
+	}
+	private, err := e.open(passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt key file %s: %w", path, err)
+	}
+	return private, nil
+}
+
+// Write stores private at path, replacing the file, encrypted when
+// passphrase is not empty.
+func Write(path string, private, passphrase []byte) error {
+	if len(private) != 32 {
+		return fmt.Errorf("invalid private key length %d, expected 32", len(private))
+	}
+	data := []byte(keys.EncodeKey(private) + "\n")
+	if len(passphrase) > 0 {
+		e, err := seal(private, passphrase)
+		if err != nil {
+			return err
+		}
+		if data, err = json.MarshalIndent(e, "", "\t"); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key file directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// Passphrase returns the passphrase from PassphraseEnv or from the systemd
+// credential PassphraseCredential, or nil when neither is set.
+func Passphrase() ([]byte, error) {
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(dir, PassphraseCredential))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credential %s: %w", PassphraseCredential, err)
+	}
+	return bytes.TrimRight(data, "\r\n"), nil
+}
+
+func seal(private, passphrase []byte) (envelope, error) {
+	e := envelope{
+		KDF:     kdfArgon2id,
+		Time:    argonTime,
+		Memory:  argonMemory,
+		Threads: argonThreads,
+		Salt:    make([]byte, 16),
+		Nonce:   make([]byte, chacha20poly1305.NonceSize),
+	}
+	if _, err := rand.Read(e.Salt); err != nil {
+		return envelope{}, err
+	}
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return envelope{}, err
+	}
+	aead, err := chacha20poly1305.New(e.key(passphrase))
+	if err != nil {
+		return envelope{}, err
+	}
+	e.Ciphertext = aead.Seal(nil, e.Nonce, private, []byte(e.KDF))
+	return e, nil
+}
+
+func (e envelope) open(passphrase []byte) ([]byte, error) {
+	if e.KDF != kdfArgon2id {
+		return nil, fmt.Errorf("unsupported KDF %q", e.KDF)
+	}
+	if e.Time == 0 || e.Memory == 0 || e.Threads == 0 || len(e.Nonce) != chacha20poly1305.NonceSize {
+		return nil, errors.New("invalid parameters")
+	}
+	if e.Time > maxArgonTime || e.Memory > maxArgonMemory || e.Threads > maxArgonThreads {
+		return nil, fmt.Errorf("argon2id parameters exceed the limits (time %d, memory %d KiB, threads %d)",
+			maxArgonTime, maxArgonMemory, maxArgonThreads)
+	}
+	aead, err := chacha20poly1305.New(e.key(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	private, err := aead.Open(nil, e.Nonce, e.Ciphertext, []byte(e.KDF))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted file")
+	}
+	if len(private) != 32 {
+		return nil, fmt.Errorf("invalid private key length %d, expected 32", len(private))
+	}
+	return private, nil
+}
+
+func (e envelope) key(passphrase []byte) []byte {
+	return argon2.IDKey(passphrase, e.Salt, e.Time, e.Memory, e.Threads, chacha20poly1305.KeySize)
+}
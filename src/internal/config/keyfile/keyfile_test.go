@@ -0,0 +1,148 @@
+package keyfile
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func testKey() []byte {
+	return bytes.Repeat([]byte{7}, 32)
+}
+
+func TestWriteRead_Plain(t *testing.T) {
+	t.Setenv(PassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "server.key")
+	if err := Write(path, testKey(), nil); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	got, err := Read(path)
+	if err != nil || !bytes.Equal(got, testKey()) {
+		t.Fatalf("Read = %x, %v", got, err)
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+			t.Fatalf("mode = %v, want 0600", info.Mode().Perm())
+		}
+	}
+}
+
+func TestWriteRead_Encrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.key")
+	if err := Write(path, testKey(), []byte("secret")); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "argon2id") || strings.Contains(string(data), "BwcHBwcH") {
+		t.Fatalf("key file is not encrypted: %s", data)
+	}
+
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	if _, err := Read(path); !errors.Is(err, ErrNoPassphrase) {
+		t.Fatalf("expected ErrNoPassphrase, got %v", err)
+	}
+	t.Setenv(PassphraseEnv, "wrong")
+	if _, err := Read(path); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("expected wrong passphrase error, got %v", err)
+	}
+	t.Setenv(PassphraseEnv, "secret")
+	if got, err := Read(path); err != nil || !bytes.Equal(got, testKey()) {
+		t.Fatalf("Read = %x, %v", got, err)
+	}
+}
+
+func TestPassphrase_SystemdCredential(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, PassphraseCredential), []byte("from-credential\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+	if got, err := Passphrase(); err != nil || string(got) != "from-credential" {
+		t.Fatalf("Passphrase = %q, %v", got, err)
+	}
+	t.Setenv(PassphraseEnv, "from-env")
+	if got, _ := Passphrase(); string(got) != "from-env" {
+		t.Fatalf("environment must take precedence, got %q", got)
+	}
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv("CREDENTIALS_DIRECTORY", t.TempDir())
+	if got, err := Passphrase(); err != nil || got != nil {
+		t.Fatalf("Passphrase without credential = %q, %v", got, err)
+	}
+}
+
+func TestRead_RejectsLoosePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permissions are ACLs on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "server.key")
+	if err := Write(path, testKey(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil || !strings.Contains(err.Error(), "chmod 600") {
+		t.Fatalf("expected permission error, got %v", err)
+	}
+	if _, err := Read(filepath.Dir(path)); err == nil {
+		t.Fatal("expected error for a directory")
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.key")
+	if err := os.WriteFile(path, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("expected error for invalid key")
+	}
+	if err := Write(path, []byte{1}, nil); err == nil {
+		t.Fatal("expected error for short key")
+	}
+}
+
+func TestOpen_RejectsExcessiveParameters(t *testing.T) {
+	sealed, err := seal(testKey(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, tamper := range map[string]func(*envelope){
+		"time":    func(e *envelope) { e.Time = maxArgonTime + 1 },
+		"memory":  func(e *envelope) { e.Memory = maxArgonMemory + 1 },
+		"threads": func(e *envelope) { e.Threads = maxArgonThreads + 1 },
+	} {
+		e := sealed
+		tamper(&e)
+		if _, err := e.open([]byte("secret")); err == nil || !strings.Contains(err.Error(), "exceed") {
+			t.Fatalf("%s: expected a limit error, got %v", name, err)
+		}
+	}
+	if got, err := sealed.open([]byte("secret")); err != nil || !bytes.Equal(got, testKey()) {
+		t.Fatalf("open = %x, %v", got, err)
+	}
+}
+
+func TestResolveAndDefaultName(t *testing.T) {
+	config := filepath.Join("/etc", "tungo", "server_configuration.json")
+	if got := Resolve(config, "server.key"); got != filepath.Join("/etc", "tungo", "server.key") {
+		t.Fatalf("Resolve relative = %q", got)
+	}
+	abs := filepath.Join(t.TempDir(), "k")
+	if got := Resolve(config, abs); got != abs {
+		t.Fatalf("Resolve absolute = %q", got)
+	}
+	if got := DefaultName(config); got != "server_configuration.key" {
+		t.Fatalf("DefaultName = %q", got)
+	}
+	if got := DefaultName("/etc/tungo/client_configuration.json.prod"); got != "client_configuration.json.prod.key" {
+		t.Fatalf("DefaultName instance = %q", got)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package keyfile
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// checkPermissions rejects key files that others can read or replace.
+func checkPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("key file %s is not a regular file", path)
+	}
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("key file %s is accessible by group or others (mode %#o): run chmod 600 %s", path, perm, path)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		if uid := os.Geteuid(); int(stat.Uid) != uid && stat.Uid != 0 {
+			return fmt.Errorf("key file %s belongs to uid %d, not to this user or root", path, stat.Uid)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package keyfile
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkPermissions only requires a regular file: access on Windows is
+// governed by ACLs, which the configuration directory already restricts.
+func checkPermissions(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("key file %s is not a regular file", path)
+	}
+	return nil
+}
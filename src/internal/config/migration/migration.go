@@ -0,0 +1,117 @@
+// Package migration upgrades configuration files written by older releases
+// to the schema this binary understands.
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNewerVersion reports a configuration written by a newer release.
+var ErrNewerVersion = errors.New("configuration is newer than this tungo")
+
+// Document is a configuration file as its top-level JSON members. A Step
+// edits it in place.
+type Document map[string]json.RawMessage
+
+// Step upgrades a Document by one version. A nil Apply only raises the
+// version.
+type Step struct {
+	Description string
+	Apply       func(Document) error
+}
+
+// Registry holds the steps of one kind of configuration. Step i upgrades
+// version i to i+1, so the current version is the number of steps. A file
+// without a Version field is version 0.
+type Registry struct {
+	name  string
+	steps []Step
+}
+
+func NewRegistry(name string, steps ...Step) *Registry {
+	return &Registry{name: name, steps: steps}
+}
+
+// Current is the version that this binary writes.
+func (r *Registry) Current() int {
+	return len(r.steps)
+}
+
+// Migrate upgrades data to the current version, one step at a time, and
+// returns the version data had. Data that is already current is returned
+// unchanged.
+func (r *Registry) Migrate(data []byte) ([]byte, int, error) {
+	var document Document
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, 0, err
+	}
+	version := 0
+	if raw, ok := document["Version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil || version < 0 {
+			return nil, 0, fmt.Errorf("invalid Version %s", raw)
+		}
+	}
+	current := r.Current()
+	if version > current {
+		return nil, version, fmt.Errorf("%w: %s configuration version %d, this tungo supports up to version %d; upgrade tungo",
+			ErrNewerVersion, r.name, version, current)
+	}
+	if version == current {
+		return data, version, nil
+	}
+	for v := version; v < current; v++ {
+		if apply := r.steps[v].Apply; apply != nil {
+			if err := apply(document); err != nil {
+				return nil, version, fmt.Errorf("failed to migrate %s configuration to version %d (%s): %w",
+					r.name, v+1, r.steps[v].Description, err)
+			}
+		}
+	}
+	document["Version"] = json.RawMessage(fmt.Sprint(current))
+	migrated, err := json.Marshal(document)
+	if err != nil {
+		return nil, version, err
+	}
+	return migrated, version, nil
+}
+
+// Save replaces the file at path with data. The previous contents, original,
+// are kept next to it as <path>.v<from>.bak, or <path>.v<from>.<n>.bak when
+// that backup already exists. Save returns the backup path.
+func Save(path string, original, data []byte, from int) (string, error) {
+	backup, err := writeBackup(path, original, from)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write migrated configuration: %w", err)
+	}
+	return backup, nil
+}
+
+func writeBackup(path string, original []byte, from int) (string, error) {
+	for n := 0; ; n++ {
+		backup := fmt.Sprintf("%s.v%d.bak", path, from)
+		if n > 0 {
+			backup = fmt.Sprintf("%s.v%d.%d.bak", path, from, n)
+		}
+		f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to back up configuration: %w", err)
+		}
+		if _, err := f.Write(original); err != nil {
+			_ = f.Close()
+			return "", fmt.Errorf("failed to back up configuration: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return "", fmt.Errorf("failed to back up configuration: %w", err)
+		}
+		return backup, nil
+	}
+}
@@ -0,0 +1,116 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testRegistry() *Registry {
+	return NewRegistry("test",
+		Step{Description: "start versioning"},
+		Step{Description: "rename Old to New", Apply: func(d Document) error {
+			if old, ok := d["Old"]; ok {
+				d["New"] = old
+				delete(d, "Old")
+			}
+			return nil
+		}},
+	)
+}
+
+func TestMigrate_StepByStep(t *testing.T) {
+	r := testRegistry()
+	migrated, from, err := r.Migrate([]byte(`{"Old":"x","Keep":1}`))
+	if err != nil {
+		t.Fatalf("Migrate error: %v", err)
+	}
+	if from != 0 {
+		t.Fatalf("from = %d, want 0", from)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(migrated, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["Version"] != float64(2) || got["New"] != "x" || got["Keep"] != float64(1) {
+		t.Fatalf("migrated = %s", migrated)
+	}
+	if _, ok := got["Old"]; ok {
+		t.Fatalf("Old was not removed: %s", migrated)
+	}
+}
+
+func TestMigrate_FromIntermediateVersion(t *testing.T) {
+	migrated, from, err := testRegistry().Migrate([]byte(`{"Version":1,"Old":"x"}`))
+	if err != nil || from != 1 {
+		t.Fatalf("Migrate = %d, %v", from, err)
+	}
+	if !strings.Contains(string(migrated), `"New":"x"`) {
+		t.Fatalf("migrated = %s", migrated)
+	}
+}
+
+func TestMigrate_CurrentUnchanged(t *testing.T) {
+	data := []byte(`{"Version": 2, "Old": "x"}`)
+	migrated, from, err := testRegistry().Migrate(data)
+	if err != nil || from != 2 || string(migrated) != string(data) {
+		t.Fatalf("Migrate = %s, %d, %v", migrated, from, err)
+	}
+}
+
+func TestMigrate_NewerVersion(t *testing.T) {
+	_, from, err := testRegistry().Migrate([]byte(`{"Version":3}`))
+	if !errors.Is(err, ErrNewerVersion) {
+		t.Fatalf("expected ErrNewerVersion, got %v", err)
+	}
+	if from != 3 || !strings.Contains(err.Error(), "up to version 2") {
+		t.Fatalf("unexpected error %v (from %d)", err, from)
+	}
+}
+
+func TestMigrate_InvalidInput(t *testing.T) {
+	for _, data := range []string{`not json`, `{"Version":"2"}`, `{"Version":-1}`} {
+		if _, _, err := testRegistry().Migrate([]byte(data)); err == nil {
+			t.Fatalf("Migrate(%s) expected error", data)
+		}
+	}
+}
+
+func TestMigrate_StepError(t *testing.T) {
+	r := NewRegistry("test", Step{Description: "fail", Apply: func(Document) error {
+		return errors.New("boom")
+	}})
+	if _, _, err := r.Migrate([]byte(`{}`)); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected step error, got %v", err)
+	}
+}
+
+func TestSave_KeepsBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "conf.json")
+	if err := os.WriteFile(path, []byte("v0"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := Save(path, []byte("v0"), []byte("v1"), 0)
+	if err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if backup != path+".v0.bak" {
+		t.Fatalf("backup = %q", backup)
+	}
+	second, err := Save(path, []byte("again"), []byte("v1"), 0)
+	if err != nil {
+		t.Fatalf("second Save error: %v", err)
+	}
+	if second != path+".v0.1.bak" {
+		t.Fatalf("second backup = %q", second)
+	}
+	for file, want := range map[string]string{path: "v1", backup: "v0", second: "again"} {
+		data, err := os.ReadFile(file)
+		if err != nil || string(data) != want {
+			t.Fatalf("%s = %q, %v; want %q", file, data, err, want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"errors"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"testing"
 
 	clientconfig "tungo/internal/config/client"
@@ -31,6 +33,8 @@ type runtimeInfoServerManager struct {
 	setErr        error
 	removeID      int
 	removeErr     error
+	renameID      int
+	renameName    string
 }
 
 func (m runtimeInfoServerManager) Configuration() (*serverconfig.Configuration, error) {
@@ -54,12 +58,17 @@ func (m *runtimeInfoServerManager) SetAllowedPeerEnabled(id int, enabled bool) e
 	m.setEnabled = enabled
 	return m.setErr
 }
+func (m *runtimeInfoServerManager) RenameAllowedPeer(id int, name string) error {
+	m.renameID, m.renameName = id, name
+	return nil
+}
 func (m *runtimeInfoServerManager) RemoveAllowedPeer(id int) error {
 	m.removeID = id
 	return m.removeErr
 }
-func (m *runtimeInfoServerManager) EnsureIPv6Subnets() error { return nil }
-func (m *runtimeInfoServerManager) InvalidateCache()         {}
+func (m *runtimeInfoServerManager) EnsureIPv6Subnets() error         { return nil }
+func (m *runtimeInfoServerManager) SetPrivateKeyFile(_ string) error { return nil }
+func (m *runtimeInfoServerManager) InvalidateCache()                 {}
 
 func TestServerControlConfiguration(t *testing.T) {
 	peerKey := make([]byte, 32)
@@ -313,6 +322,54 @@ func TestServerControlListPeersError(t *testing.T) {
 	}
 }
 
+func TestServerControlListPeersIncludesTraffic(t *testing.T) {
+	manager := &runtimeInfoServerManager{
+		peers: []serverconfig.AllowedPeer{
+			{Name: "client-1", PublicKey: []byte("one"), ClientID: 1},
+			{Name: "client-2", PublicKey: []byte("two"), ClientID: 2},
+		},
+	}
+	store := serverconfig.NewTrafficStore(filepath.Join(t.TempDir(), "server_configuration.json"))
+	if err := store.Save(map[string]serverconfig.PeerTraffic{"one": {RXBytes: 10, TXBytes: 20}}); err != nil {
+		t.Fatal(err)
+	}
+	control := serverControl{manager: manager, traffic: store}
+
+	got, err := control.ListPeers()
+	if err != nil {
+		t.Fatalf("ListPeers() error = %v", err)
+	}
+	if got[0].Traffic != (serverconfig.PeerTraffic{RXBytes: 10, TXBytes: 20}) {
+		t.Fatalf("client-1 traffic = %+v", got[0].Traffic)
+	}
+	if got[1].Traffic != (serverconfig.PeerTraffic{}) {
+		t.Fatalf("client-2 traffic = %+v, want zero", got[1].Traffic)
+	}
+}
+
+func TestServerControlListPeersWithUnreadableTraffic(t *testing.T) {
+	manager := &runtimeInfoServerManager{
+		peers: []serverconfig.AllowedPeer{{Name: "client-1", PublicKey: []byte("one"), ClientID: 1}},
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "server_traffic.json"), []byte("{corrupt"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := serverconfig.NewTrafficStore(filepath.Join(dir, "server_configuration.json"))
+	if _, err := store.Load(); err == nil {
+		t.Fatal("expected the corrupt traffic file to fail to load")
+	}
+	control := serverControl{manager: manager, traffic: store}
+
+	got, err := control.ListPeers()
+	if err != nil {
+		t.Fatalf("ListPeers() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Traffic != (serverconfig.PeerTraffic{}) {
+		t.Fatalf("expected the peer with zero traffic, got %+v", got)
+	}
+}
+
 func TestServerControlSetAndRemovePeer(t *testing.T) {
 	manager := &runtimeInfoServerManager{}
 	control := serverControl{manager: manager}
@@ -324,6 +381,12 @@ func TestServerControlSetAndRemovePeer(t *testing.T) {
 		t.Fatalf("unexpected set call: id=%d enabled=%v", manager.setID, manager.setEnabled)
 	}
 
+	if err := control.RenamePeer(9, "laptop"); err != nil {
+		t.Fatalf("RenamePeer() error = %v", err)
+	}
+	if manager.renameID != 9 || manager.renameName != "laptop" {
+		t.Fatalf("unexpected rename: %d %q", manager.renameID, manager.renameName)
+	}
 	if err := control.RemovePeer(8); err != nil {
 		t.Fatalf("RemovePeer() error = %v", err)
 	}
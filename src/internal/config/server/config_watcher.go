@@ -25,7 +25,8 @@ type AllowedPeersUpdater interface {
 const DefaultWatchInterval = 30 * time.Second
 
 // ConfigWatcher monitors AllowedPeers configuration changes and:
-// 1. Revokes sessions for peers that are removed or disabled
+// 1. Revokes sessions for peers that are removed or disabled, and for peers
+// that expire, which is noticed on the next poll
 // 2. Updates the runtime AllowedPeers map for new peer lookups
 //
 // Uses fsnotify for instant updates, with polling as fallback.
@@ -139,11 +140,12 @@ func (w *ConfigWatcher) loadCurrentState() map[string]peerAccessState {
 		return nil
 	}
 
+	now := time.Now()
 	peers := make(map[string]peerAccessState, len(conf.AllowedPeers))
 	for _, peer := range conf.AllowedPeers {
 		key := string(peer.PublicKey)
 		peers[key] = peerAccessState{
-			enabled:  peer.Enabled,
+			enabled:  peer.Active(now),
 			clientID: peer.ClientID,
 		}
 	}
@@ -151,7 +153,7 @@ func (w *ConfigWatcher) loadCurrentState() map[string]peerAccessState {
 }
 
 // checkAndRevoke compares current config with previous state and:
-// 1. Revokes sessions for peers that were removed or disabled
+// 1. Revokes sessions for peers that were removed, disabled or expired
 // 2. Updates the runtime AllowedPeers map for new handshake lookups
 func (w *ConfigWatcher) checkAndRevoke(prevPeers map[string]peerAccessState) map[string]peerAccessState {
 	conf, err := w.configManager.Configuration()
@@ -161,18 +163,19 @@ func (w *ConfigWatcher) checkAndRevoke(prevPeers map[string]peerAccessState) map
 	}
 
 	// Build current state map
+	now := time.Now()
 	currentPeers := make(map[string]peerAccessState, len(conf.AllowedPeers))
 	for _, peer := range conf.AllowedPeers {
 		key := string(peer.PublicKey)
 		currentPeers[key] = peerAccessState{
-			enabled:  peer.Enabled,
+			enabled:  peer.Active(now),
 			clientID: peer.ClientID,
 		}
 	}
 
 	// Find peers to revoke:
 	// 1. Previously existed and enabled, now removed
-	// 2. Previously existed and enabled, now disabled
+	// 2. Previously existed and enabled, now disabled or expired
 	for pubKeyStr, prevState := range prevPeers {
 		if !prevState.enabled {
 			continue // Was already disabled, nothing to revoke
@@ -76,7 +76,8 @@ func (m *mockConfigManager) ListAllowedPeers() ([]AllowedPeer, error)  { return
 func (m *mockConfigManager) SetAllowedPeerEnabled(_ int, _ bool) error { return nil }
 func (m *mockConfigManager) RemoveAllowedPeer(_ int) error             { return nil }
 
-func (m *mockConfigManager) EnsureIPv6Subnets() error { return nil }
+func (m *mockConfigManager) EnsureIPv6Subnets() error         { return nil }
+func (m *mockConfigManager) SetPrivateKeyFile(_ string) error { return nil }
 
 func (m *mockConfigManager) InvalidateCache() {
 	m.mu.Lock()
@@ -156,6 +157,27 @@ func TestConfigWatcher_RevokesDisabledPeer(t *testing.T) {
 	}
 }
 
+func TestConfigWatcher_RevokesExpiredPeer(t *testing.T) {
+	pubKey := make([]byte, 32)
+	pubKey[0] = 1
+	expired := &Configuration{
+		AllowedPeers: []AllowedPeer{
+			{PublicKey: pubKey, Enabled: true, ClientID: 1, ExpiresAt: time.Now().Add(-time.Second)},
+		},
+	}
+	configManager := &mockConfigManager{config: expired}
+	revoker := &mockRevoker{}
+	watcher := NewConfigWatcher(configManager, revoker, nil, "", 10*time.Millisecond)
+
+	// The peer was active on the previous poll and expired since.
+	prevPeers := map[string]peerAccessState{string(pubKey): {enabled: true, clientID: 1}}
+	watcher.checkAndRevoke(prevPeers)
+
+	if revoked := revoker.revokedKeys(); len(revoked) != 1 || revoked[0][0] != 1 {
+		t.Fatalf("revoked = %v, want the expired peer", revoked)
+	}
+}
+
 func TestConfigWatcher_RevokesRemovedPeer(t *testing.T) {
 	pubKey1 := make([]byte, 32)
 	pubKey1[0] = 1
@@ -2,10 +2,13 @@ package server
 
 import (
 	"net/netip"
+	"time"
 	"tungo/internal/config/settings"
 )
 
 type Configuration struct {
+	// Version is the schema of the file; see Migrations.
+	Version     int               `json:"Version"`
 	TCPSettings settings.Settings `json:"TCPSettings"`
 	UDPSettings settings.Settings `json:"UDPSettings"`
 	WSSettings  settings.Settings `json:"WSSettings"`
@@ -13,11 +16,24 @@ type Configuration struct {
 	// A zero value enables automatic address detection.
 	Host             string `json:"Host"`
 	X25519PublicKey  []byte `json:"X25519PublicKey"`
-	X25519PrivateKey []byte `json:"X25519PrivateKey"`
-	ClientCounter    int    `json:"ClientCounter"`
-	EnableTCP        bool   `json:"EnableTCP"`
-	EnableUDP        bool   `json:"EnableUDP"`
-	EnableWS         bool   `json:"EnableWS"`
+	X25519PrivateKey []byte `json:"X25519PrivateKey,omitempty"`
+	// PrivateKeyFile keeps X25519PrivateKey out of this file; a relative
+	// path is next to the configuration. The key is read from it when the
+	// configuration loads and is never written back here.
+	PrivateKeyFile string `json:"PrivateKeyFile,omitempty"`
+	ClientCounter  int    `json:"ClientCounter"`
+	EnableTCP      bool   `json:"EnableTCP"`
+	EnableUDP      bool   `json:"EnableUDP"`
+	EnableWS       bool   `json:"EnableWS"`
+	// MetricsAddress is the IP:port of the Prometheus metrics listener.
+	// Empty disables metrics.
+	MetricsAddress string `json:"MetricsAddress,omitempty"`
+	// MetricsAllowRemote permits a MetricsAddress that is not a loopback
+	// address. The metrics are served without authentication.
+	MetricsAllowRemote bool `json:"MetricsAllowRemote,omitempty"`
+	// ClientRouting is written to generated client configurations. The zero
+	// value generates full-tunnel clients.
+	ClientRouting settings.Routing `json:"ClientRouting,omitzero"`
 
 	// AllowedPeers is the list of authorized clients.
 	// Each peer is identified by their X25519 static public key.
@@ -42,10 +58,38 @@ type AllowedPeer struct {
 	// ClientID is the 1-based ordinal passed to AllocateClientIP at registration time.
 	// Each peer must have a unique, positive ClientID.
 	ClientID int `json:"ClientID"`
+
+	// AllowedIPs are networks behind the client (site-to-site). The server
+	// routes them into the tunnel while the client is connected and accepts
+	// them as packet sources from that client. Optional.
+	AllowedIPs []netip.Prefix `json:"AllowedIPs,omitempty"`
+
+	// ExpiresAt revokes the peer once it passes. The zero value never
+	// expires.
+	ExpiresAt time.Time `json:"ExpiresAt,omitzero"`
+
+	// PresharedKey, when set, is the 32-byte key the client must also have.
+	// The handshake proves it and mixes it into the traffic keys. Optional.
+	PresharedKey []byte `json:"PresharedKey,omitempty"`
+
+	// Traffic is cumulative usage filled in from the TrafficStore when peers
+	// are listed. It is not part of the configuration file.
+	Traffic PeerTraffic `json:"-"`
+}
+
+// Active reports whether the peer may connect at now: it is enabled and has
+// not expired.
+func (p AllowedPeer) Active(now time.Time) bool {
+	return p.Enabled && !p.Expired(now)
+}
+
+func (p AllowedPeer) Expired(now time.Time) bool {
+	return !p.ExpiresAt.IsZero() && !now.Before(p.ExpiresAt)
 }
 
 func New() *Configuration {
 	configuration := &Configuration{
+		Version:          Migrations.Current(),
 		X25519PublicKey:  nil,
 		X25519PrivateKey: nil,
 		ClientCounter:    0,
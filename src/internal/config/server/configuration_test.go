@@ -4,6 +4,7 @@ import (
 	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
 	"tungo/internal/config/settings"
 )
@@ -23,6 +24,26 @@ func mkValid() *Configuration {
 	return cfg
 }
 
+func TestAllowedPeer_Active(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		peer AllowedPeer
+		want bool
+	}{
+		{"enabled without expiry", AllowedPeer{Enabled: true}, true},
+		{"disabled", AllowedPeer{Enabled: false}, false},
+		{"before expiry", AllowedPeer{Enabled: true, ExpiresAt: now.Add(time.Second)}, true},
+		{"at expiry", AllowedPeer{Enabled: true, ExpiresAt: now}, false},
+		{"disabled before expiry", AllowedPeer{Enabled: false, ExpiresAt: now.Add(time.Hour)}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.peer.Active(now); got != tt.want {
+			t.Errorf("%s: Active() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
 // --- Tests for defaultSettings and ApplyServerDefaults/applyDefaults ---
 
 func TestConfiguration_DefaultSettingsValues(t *testing.T) {
@@ -141,6 +162,41 @@ func TestValidate_SkipsDisabledProtocol(t *testing.T) {
 	}
 }
 
+func TestValidate_MetricsAddress(t *testing.T) {
+	cfg := mkValid()
+	cfg.MetricsAddress = "127.0.0.1:9464"
+	if err := Validate(*cfg); err != nil {
+		t.Fatalf("expected valid metrics address, got: %v", err)
+	}
+	cfg.MetricsAddress = "localhost:9464"
+	if err := Validate(*cfg); err == nil {
+		t.Fatalf("expected error for non-IP metrics address")
+	}
+	cfg.MetricsAddress = "0.0.0.0:9100"
+	if err := Validate(*cfg); err == nil {
+		t.Fatalf("expected error for a non-loopback metrics address")
+	}
+	cfg.MetricsAllowRemote = true
+	if err := Validate(*cfg); err != nil {
+		t.Fatalf("expected MetricsAllowRemote to permit the address, got: %v", err)
+	}
+}
+
+func TestValidate_ClientRouting(t *testing.T) {
+	cfg := mkValid()
+	cfg.ClientRouting = settings.Routing{
+		IncludeRoutes: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")},
+		AllowLAN:      true,
+	}
+	if err := Validate(*cfg); err != nil {
+		t.Fatalf("expected valid client routing, got: %v", err)
+	}
+	cfg.ClientRouting.ExcludeRoutes = []netip.Prefix{netip.MustParsePrefix("0.0.0.0/1")}
+	if err := Validate(*cfg); err == nil {
+		t.Fatalf("expected error for too broad exclude route")
+	}
+}
+
 func TestValidate_InterfaceNameEmpty(t *testing.T) {
 	cfg := mkValid()
 	cfg.TCPSettings.TunName = ""
@@ -312,6 +368,22 @@ func TestValidateAllowedPeers_InvalidKeyLength(t *testing.T) {
 	}
 }
 
+func TestValidateAllowedPeers_InvalidPresharedKeyLength(t *testing.T) {
+	cfg := mkValid()
+	cfg.AllowedPeers = []AllowedPeer{
+		{
+			PublicKey:    make([]byte, 32),
+			Enabled:      true,
+			ClientID:     5,
+			PresharedKey: make([]byte, 16),
+		},
+	}
+	err := validateAllowedPeers(cfg.AllowedPeers)
+	if err == nil || !strings.Contains(err.Error(), "invalid PresharedKey length") {
+		t.Fatalf("expected pre-shared key length error, got: %v", err)
+	}
+}
+
 func TestValidateAllowedPeers_InvalidClientID(t *testing.T) {
 	cfg := mkValid()
 	cfg.AllowedPeers = []AllowedPeer{
@@ -413,3 +485,40 @@ func TestValidate_PropagatesValidateAllowedPeersError(t *testing.T) {
 		t.Fatal("expected Validate to propagate ValidateAllowedPeers error")
 	}
 }
+
+func TestValidate_PeerAllowedIPs(t *testing.T) {
+	key := func(b byte) []byte {
+		k := make([]byte, 32)
+		k[0] = b
+		return k
+	}
+	for _, tc := range []struct {
+		name    string
+		first   []netip.Prefix
+		second  []netip.Prefix
+		wantErr string
+	}{
+		{name: "valid", first: []netip.Prefix{netip.MustParsePrefix("192.168.10.0/24")}, second: []netip.Prefix{netip.MustParsePrefix("fd00:10::/64")}},
+		{name: "host bits", first: []netip.Prefix{netip.MustParsePrefix("192.168.10.1/24")}, wantErr: "host bits"},
+		{name: "tunnel subnet", first: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/16")}, wantErr: "overlaps tunnel subnet"},
+		{name: "peer conflict", first: []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")}, second: []netip.Prefix{netip.MustParsePrefix("192.168.10.0/24")}, wantErr: "AllowedIPs conflict"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := mkValid()
+			cfg.AllowedPeers = []AllowedPeer{
+				{PublicKey: key(1), Enabled: true, ClientID: 1, AllowedIPs: tc.first},
+				{PublicKey: key(2), Enabled: true, ClientID: 2, AllowedIPs: tc.second},
+			}
+			err := Validate(*cfg)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid config, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected %q error, got: %v", tc.wantErr, err)
+			}
+		})
+	}
+}
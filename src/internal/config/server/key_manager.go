@@ -13,6 +13,9 @@ import (
 type KeyManager interface {
 	// PrepareKeys guarantees that X25519 keys are presented in configuration
 	PrepareKeys() error
+	// MoveToFile moves the private key out of the configuration into
+	// keyFile.
+	MoveToFile(keyFile string) error
 }
 
 const (
@@ -42,6 +45,15 @@ func (m *X25519KeyManager) PrepareKeys() error {
 	return m.generateAndStoreKeysInConfiguration()
 }
 
+// MoveToFile keeps the private key in keyFile instead of the configuration,
+// generating the key pair first when there is none.
+func (m *X25519KeyManager) MoveToFile(keyFile string) error {
+	if err := m.PrepareKeys(); err != nil {
+		return err
+	}
+	return m.configurationManager.SetPrivateKeyFile(keyFile)
+}
+
 func (m *X25519KeyManager) keysAreInConfiguration() (bool, error) {
 	configuration, err := m.configurationManager.Configuration()
 	if err != nil {
@@ -23,6 +23,7 @@ type mockConfigurationManager struct {
 	injectCalls int
 	lastPub     []byte
 	lastPriv    []byte
+	keyFile     string
 }
 
 func (m *mockConfigurationManager) Configuration() (*Configuration, error) {
@@ -55,6 +56,11 @@ func (m *mockConfigurationManager) RemoveAllowedPeer(_ int) error             {
 func (m *mockConfigurationManager) EnsureIPv6Subnets() error { return nil }
 func (m *mockConfigurationManager) InvalidateCache()         {}
 
+func (m *mockConfigurationManager) SetPrivateKeyFile(keyFile string) error {
+	m.keyFile = keyFile
+	return nil
+}
+
 // ---------- Helpers ----------
 
 func newKM(manager *mockConfigurationManager) *X25519KeyManager {
@@ -308,3 +314,13 @@ func Test_generateAndStoreKeysInConfiguration_RandError(t *testing.T) {
 		t.Fatalf("expected rand error, got %v", err)
 	}
 }
+
+func TestMoveToFile_PreparesKeysFirst(t *testing.T) {
+	manager := &mockConfigurationManager{cfg: &Configuration{}}
+	if err := newKM(manager).MoveToFile("server.key"); err != nil {
+		t.Fatalf("unexpected: %v", err)
+	}
+	if manager.injectCalls != 1 || manager.keyFile != "server.key" {
+		t.Fatalf("injectCalls=%d keyFile=%q", manager.injectCalls, manager.keyFile)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"net/netip"
 	"os"
 	"time"
+
+	"tungo/internal/config/keyfile"
 )
 
 type ConfigurationManager interface {
@@ -17,16 +19,19 @@ type ConfigurationManager interface {
 	SetAllowedPeerEnabled(clientID int, enabled bool) error
 	RemoveAllowedPeer(clientID int) error
 	EnsureIPv6Subnets() error
+	SetPrivateKeyFile(keyFile string) error
 	InvalidateCache()
 }
 
 type Manager struct {
+	path   string
 	reader Reader
 	writer Writer
 }
 
 func NewManager(path string) *Manager {
 	return &Manager{
+		path:   path,
 		writer: newDefaultWriter(path),
 		reader: NewTTLReader(newReader(path), time.Minute*15),
 	}
@@ -57,7 +62,16 @@ func (c *Manager) update(fn func(*Configuration) error) error {
 	if err := fn(conf); err != nil {
 		return err
 	}
-	return c.writer.Write(*conf)
+	return c.write(*conf)
+}
+
+// write saves conf. A key kept in PrivateKeyFile stays out of the
+// configuration.
+func (c *Manager) write(conf Configuration) error {
+	if conf.PrivateKeyFile != "" {
+		conf.X25519PrivateKey = nil
+	}
+	return c.writer.Write(conf)
 }
 
 func (c *Manager) IncrementClientCounter() error {
@@ -75,6 +89,11 @@ func (c *Manager) InjectX25519Keys(public, private []byte) error {
 		return fmt.Errorf("invalid private key length: got %d, want 32", len(private))
 	}
 	return c.update(func(conf *Configuration) error {
+		if conf.PrivateKeyFile != "" {
+			if err := c.writeKeyFile(conf.PrivateKeyFile, private); err != nil {
+				return err
+			}
+		}
 		conf.X25519PublicKey = append([]byte(nil), public...)
 		conf.X25519PrivateKey = append([]byte(nil), private...)
 		return nil
@@ -100,10 +119,12 @@ func (c *Manager) ListAllowedPeers() ([]AllowedPeer, error) {
 	peers := make([]AllowedPeer, len(conf.AllowedPeers))
 	for i := range conf.AllowedPeers {
 		peers[i] = AllowedPeer{
-			Name:      conf.AllowedPeers[i].Name,
-			PublicKey: append([]byte(nil), conf.AllowedPeers[i].PublicKey...),
-			Enabled:   conf.AllowedPeers[i].Enabled,
-			ClientID:  conf.AllowedPeers[i].ClientID,
+			Name:       conf.AllowedPeers[i].Name,
+			PublicKey:  append([]byte(nil), conf.AllowedPeers[i].PublicKey...),
+			Enabled:    conf.AllowedPeers[i].Enabled,
+			ClientID:   conf.AllowedPeers[i].ClientID,
+			AllowedIPs: append([]netip.Prefix(nil), conf.AllowedPeers[i].AllowedIPs...),
+			ExpiresAt:  conf.AllowedPeers[i].ExpiresAt,
 		}
 	}
 	return peers, nil
@@ -126,6 +147,23 @@ func (c *Manager) SetAllowedPeerEnabled(clientID int, enabled bool) error {
 	})
 }
 
+func (c *Manager) RenameAllowedPeer(clientID int, name string) error {
+	if clientID <= 0 {
+		return fmt.Errorf("invalid client id %d", clientID)
+	}
+
+	return c.update(func(conf *Configuration) error {
+		for i := range conf.AllowedPeers {
+			if conf.AllowedPeers[i].ClientID != clientID {
+				continue
+			}
+			conf.AllowedPeers[i].Name = name
+			return nil
+		}
+		return fmt.Errorf("allowed peer with ClientID %d not found", clientID)
+	})
+}
+
 func (c *Manager) RemoveAllowedPeer(clientID int) error {
 	if clientID <= 0 {
 		return fmt.Errorf("invalid client id %d", clientID)
@@ -167,7 +205,37 @@ func (c *Manager) EnsureIPv6Subnets() error {
 	}
 
 	conf.ApplyServerDefaults()
-	return c.writer.Write(*conf)
+	return c.write(*conf)
+}
+
+// SetPrivateKeyFile moves X25519PrivateKey to keyFile, encrypted when a
+// passphrase is set, and keeps a reference to it in its place. An existing
+// file is never replaced: it may hold another key.
+func (c *Manager) SetPrivateKeyFile(keyFile string) error {
+	return c.update(func(conf *Configuration) error {
+		if conf.PrivateKeyFile != "" {
+			return fmt.Errorf("private key is already in %s", conf.PrivateKeyFile)
+		}
+		if len(conf.X25519PrivateKey) != 32 {
+			return fmt.Errorf("invalid private key length: got %d, want 32", len(conf.X25519PrivateKey))
+		}
+		if _, err := os.Stat(keyfile.Resolve(c.path, keyFile)); err == nil {
+			return fmt.Errorf("%s already exists", keyfile.Resolve(c.path, keyFile))
+		}
+		if err := c.writeKeyFile(keyFile, conf.X25519PrivateKey); err != nil {
+			return err
+		}
+		conf.PrivateKeyFile = keyFile
+		return nil
+	})
+}
+
+func (c *Manager) writeKeyFile(keyFile string, private []byte) error {
+	passphrase, err := keyfile.Passphrase()
+	if err != nil {
+		return err
+	}
+	return keyfile.Write(keyfile.Resolve(c.path, keyFile), private, passphrase)
 }
 
 // InvalidateCache clears the cached configuration if the reader supports it.
@@ -6,9 +6,15 @@ import (
 	"errors"
 	"io/fs"
 	"net/netip"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"golang.org/x/crypto/curve25519"
+
+	"tungo/internal/config/keyfile"
 )
 
 type ManagerMockWriter struct {
@@ -711,3 +717,112 @@ func TestManager_EnsureIPv6Subnets_WriteError(t *testing.T) {
 		t.Fatalf("expected write error, got %v", err)
 	}
 }
+
+func TestManager_RenameAllowedPeer(t *testing.T) {
+	initialConf := New()
+	initialConf.AllowedPeers = []AllowedPeer{
+		{Name: "old", PublicKey: bytes.Repeat([]byte{5}, 32), Enabled: true, ClientID: 1},
+	}
+	writer := &ManagerMockWriter{}
+	manager := &Manager{
+		writer: writer,
+		reader: &ManagerMockReader{Config: initialConf},
+	}
+
+	if err := manager.RenameAllowedPeer(1, "laptop"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	written, ok := writer.WrittenData.(Configuration)
+	if !ok || written.AllowedPeers[0].Name != "laptop" {
+		t.Fatalf("expected renamed peer, got %+v", writer.WrittenData)
+	}
+	if err := manager.RenameAllowedPeer(0, "x"); err == nil || !strings.Contains(err.Error(), "invalid client id") {
+		t.Fatalf("expected invalid client id error, got %v", err)
+	}
+	if err := manager.RenameAllowedPeer(9, "x"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestManager_SetPrivateKeyFile_MovesKeyOutOfConfiguration(t *testing.T) {
+	t.Setenv("Host", "")
+	t.Setenv("ServerIP", "")
+	t.Setenv(keyfile.PassphraseEnv, "")
+	conf := New()
+	conf.X25519PublicKey, conf.X25519PrivateKey = bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{1}, 32)
+	path := createTempConfigFile(t, conf)
+	manager := NewManager(path)
+
+	if err := manager.SetPrivateKeyFile("server.key"); err != nil {
+		t.Fatalf("SetPrivateKeyFile: %v", err)
+	}
+	if err := manager.IncrementClientCounter(); err != nil {
+		t.Fatalf("IncrementClientCounter: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	var saved Configuration
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved configuration: %v", err)
+	}
+	if saved.PrivateKeyFile != "server.key" || len(saved.X25519PrivateKey) != 0 {
+		t.Fatalf("saved PrivateKeyFile=%q X25519PrivateKey=%v", saved.PrivateKeyFile, saved.X25519PrivateKey)
+	}
+
+	manager.InvalidateCache()
+	got, err := manager.Configuration()
+	if err != nil {
+		t.Fatalf("Configuration: %v", err)
+	}
+	if !bytes.Equal(got.X25519PrivateKey, conf.X25519PrivateKey) {
+		t.Fatal("private key was not read from the key file")
+	}
+	if err := manager.SetPrivateKeyFile("other.key"); err == nil {
+		t.Fatal("expected error when the key is already in a key file")
+	}
+}
+
+func TestManager_SetPrivateKeyFile_KeepsExistingFile(t *testing.T) {
+	conf := New()
+	conf.X25519PublicKey, conf.X25519PrivateKey = bytes.Repeat([]byte{2}, 32), bytes.Repeat([]byte{1}, 32)
+	path := createTempConfigFile(t, conf)
+	existing := filepath.Join(filepath.Dir(path), "server.key")
+	if err := os.WriteFile(existing, []byte("other"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := NewManager(path).SetPrivateKeyFile("server.key"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected already exists error, got %v", err)
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "other" {
+		t.Fatal("existing key file was replaced")
+	}
+}
+
+func TestManager_GeneratedKeyGoesToEncryptedKeyFile(t *testing.T) {
+	t.Setenv("Host", "")
+	t.Setenv("ServerIP", "")
+	t.Setenv(keyfile.PassphraseEnv, "secret")
+	conf := New()
+	conf.PrivateKeyFile = "server.key"
+	path := createTempConfigFile(t, conf)
+	manager := NewManager(path)
+
+	if err := NewX25519KeyManager(manager).PrepareKeys(); err != nil {
+		t.Fatalf("PrepareKeys: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(filepath.Dir(path), "server.key"))
+	if !bytes.HasPrefix(data, []byte("{")) {
+		t.Fatalf("key file is not encrypted: %s", data)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "X25519PrivateKey") {
+		t.Fatalf("private key written to the configuration: %s", data)
+	}
+	manager.InvalidateCache()
+	got, err := manager.Configuration()
+	if err != nil {
+		t.Fatalf("Configuration: %v", err)
+	}
+	public, err := curve25519.X25519(got.X25519PrivateKey, curve25519.Basepoint)
+	if err != nil || !bytes.Equal(public, got.X25519PublicKey) {
+		t.Fatal("key pair read back does not match")
+	}
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+
+	"tungo/internal/config/migration"
+)
+
+// Migrations upgrades server configurations written by older releases.
+// Append a step whenever the meaning of the file changes.
+var Migrations = migration.NewRegistry("server",
+	migration.Step{Description: "replace FallbackServerAddress with Host", Apply: migrateFallbackServerAddress},
+)
+
+func migrateFallbackServerAddress(d migration.Document) error {
+	raw, ok := d["FallbackServerAddress"]
+	if !ok {
+		return nil
+	}
+	delete(d, "FallbackServerAddress")
+	var fallback, host string
+	if err := json.Unmarshal(raw, &fallback); err != nil {
+		return err
+	}
+	if h, ok := d["Host"]; ok {
+		if err := json.Unmarshal(h, &host); err != nil {
+			return err
+		}
+	}
+	if strings.TrimSpace(host) != "" || strings.TrimSpace(fallback) == "" {
+		return nil
+	}
+	encoded, err := json.Marshal(strings.TrimSpace(fallback))
+	if err != nil {
+		return err
+	}
+	d["Host"] = encoded
+	return nil
+}
@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"strconv"
 	"strings"
+
+	"tungo/internal/config/keyfile"
+	"tungo/internal/config/migration"
 )
 
 type Reader interface {
@@ -15,12 +19,20 @@ type Reader interface {
 
 type reader struct {
 	path string
+	// readOnly upgrades older files in memory without saving them.
+	readOnly bool
 }
 
 func newReader(path string) *reader {
 	return &reader{path: path}
 }
 
+// Load reads and validates the configuration at path as the server would,
+// without changing the file.
+func Load(path string) (*Configuration, error) {
+	return (&reader{path: path, readOnly: true}).read()
+}
+
 func (c *reader) read() (*Configuration, error) {
 	configuration, err := c.readFromDisk()
 	if err != nil {
@@ -41,19 +53,16 @@ func (c *reader) readFromDisk() (Configuration, error) {
 		}
 		return Configuration{}, fmt.Errorf("configuration file %q is unreadable: %w", c.path, err)
 	}
-	type configurationWithDeprecatedFields struct {
-		Configuration
-		// FallbackServerAddress is deprecated. Host is used instead.
-		FallbackServerAddress string `json:"FallbackServerAddress"`
+	fileBytes, err = c.migrate(fileBytes)
+	if err != nil {
+		return Configuration{}, err
 	}
-	var withDeprecated configurationWithDeprecatedFields
-	if err := json.Unmarshal(fileBytes, &withDeprecated); err != nil {
+	var actual Configuration
+	if err := json.Unmarshal(fileBytes, &actual); err != nil {
 		return Configuration{}, fmt.Errorf("configuration file %q is invalid: %w", c.path, err)
 	}
-	actual := withDeprecated.Configuration
-	if strings.TrimSpace(actual.Host) == "" &&
-		strings.TrimSpace(withDeprecated.FallbackServerAddress) != "" {
-		actual.Host = strings.TrimSpace(withDeprecated.FallbackServerAddress)
+	if err := c.loadPrivateKey(&actual); err != nil {
+		return Configuration{}, err
 	}
 	c.setEnvServerHost(&actual)
 	c.setEnvEnabledProtocols(&actual)
@@ -61,6 +70,53 @@ func (c *reader) readFromDisk() (Configuration, error) {
 	return actual, nil
 }
 
+// migrate upgrades a file written by an older release and saves it, keeping
+// the original as a backup.
+func (c *reader) migrate(data []byte) ([]byte, error) {
+	migrated, from, err := Migrations.Migrate(data)
+	if err != nil {
+		return nil, fmt.Errorf("configuration file %q is invalid: %w", c.path, err)
+	}
+	if from == Migrations.Current() {
+		return data, nil
+	}
+	var configuration Configuration
+	if err := json.Unmarshal(migrated, &configuration); err != nil {
+		return nil, fmt.Errorf("configuration file %q is invalid: %w", c.path, err)
+	}
+	canonical, err := json.MarshalIndent(configuration, "", "\t")
+	if err != nil || c.readOnly {
+		return canonical, err
+	}
+	backup, err := migration.Save(c.path, data, canonical, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate configuration file %q: %w", c.path, err)
+	}
+	slog.Info("Migrated server configuration", "path", c.path, "from", from, "to", Migrations.Current(), "backup", backup)
+	return canonical, nil
+}
+
+// loadPrivateKey reads X25519PrivateKey from PrivateKeyFile. Before the
+// first key pair exists, a missing file leaves the key empty, so that the
+// X25519KeyManager generates one into it.
+func (c *reader) loadPrivateKey(conf *Configuration) error {
+	if conf.PrivateKeyFile == "" {
+		return nil
+	}
+	if len(conf.X25519PrivateKey) != 0 {
+		return fmt.Errorf("configuration file %q sets both X25519PrivateKey and PrivateKeyFile", c.path)
+	}
+	private, err := keyfile.Read(keyfile.Resolve(c.path, conf.PrivateKeyFile))
+	if errors.Is(err, os.ErrNotExist) && len(conf.X25519PublicKey) == 0 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("configuration file %q: %w", c.path, err)
+	}
+	conf.X25519PrivateKey = private
+	return nil
+}
+
 func (c *reader) setEnvServerHost(conf *Configuration) {
 	host := strings.TrimSpace(os.Getenv("Host"))
 	if len(host) > 0 {
@@ -1,12 +1,14 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"tungo/internal/config/keyfile"
 	"tungo/internal/config/settings"
 )
 
@@ -259,3 +261,80 @@ func TestRead_EnableFlags_Valid_Invalid_Unset_AllBranches(t *testing.T) {
 		t.Fatalf("unset env should keep file values, got UDP=%v TCP=%v WS=%v", conf.EnableUDP, conf.EnableTCP, conf.EnableWS)
 	}
 }
+
+func TestRead_MigratesOlderConfigurationWithBackup(t *testing.T) {
+	path := createTempConfigFile(t, struct {
+		FallbackServerAddress string `json:"FallbackServerAddress"`
+	}{FallbackServerAddress: "vpn.example.com"})
+	original, _ := os.ReadFile(path)
+	t.Setenv("Host", "")
+	t.Setenv("ServerIP", "10.0.0.1")
+
+	conf, err := newReader(path).read()
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if conf.Version != Migrations.Current() {
+		t.Fatalf("Version = %d, want %d", conf.Version, Migrations.Current())
+	}
+	backup, err := os.ReadFile(path + ".v0.bak")
+	if err != nil || string(backup) != string(original) {
+		t.Fatalf("backup = %q, %v; want %q", backup, err, original)
+	}
+	var saved Configuration
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("saved configuration: %v", err)
+	}
+	if saved.Version != Migrations.Current() || saved.Host != "vpn.example.com" {
+		t.Fatalf("saved Version=%d Host=%q; environment must not be persisted", saved.Version, saved.Host)
+	}
+	if strings.Contains(string(data), "FallbackServerAddress") {
+		t.Fatalf("deprecated field kept: %s", data)
+	}
+
+	if _, err := newReader(path).read(); err != nil {
+		t.Fatalf("second read error: %v", err)
+	}
+	if _, err := os.Stat(path + ".v1.bak"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("current configuration must not be backed up again: %v", err)
+	}
+}
+
+func TestRead_NewerVersionFails(t *testing.T) {
+	path := createTempConfigFile(t, Configuration{Version: Migrations.Current() + 1})
+	_, err := newReader(path).read()
+	if err == nil || !strings.Contains(err.Error(), "upgrade tungo") {
+		t.Fatalf("expected newer version error, got %v", err)
+	}
+}
+
+func TestRead_PrivateKeyFile(t *testing.T) {
+	t.Setenv("Host", "")
+	t.Setenv("ServerIP", "")
+	t.Setenv(keyfile.PassphraseEnv, "")
+	conf := New()
+	private := bytes.Repeat([]byte{1}, 32)
+	conf.X25519PublicKey = bytes.Repeat([]byte{2}, 32)
+	conf.PrivateKeyFile = "server.key"
+	path := createTempConfigFile(t, conf)
+
+	if _, err := newReader(path).read(); err == nil || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected missing key file error once a public key exists, got %v", err)
+	}
+	if err := keyfile.Write(filepath.Join(filepath.Dir(path), "server.key"), private, nil); err != nil {
+		t.Fatal(err)
+	}
+	got, err := newReader(path).read()
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if string(got.X25519PrivateKey) != string(private) {
+		t.Fatal("private key was not read from the key file")
+	}
+
+	conf.X25519PrivateKey = private
+	if _, err := newReader(createTempConfigFile(t, conf)).read(); err == nil || !strings.Contains(err.Error(), "sets both") {
+		t.Fatalf("expected error for an inline key and a key file, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const trafficFileName = "server_traffic.json"
+
+// PeerTraffic is the cumulative tunnel usage of one peer, counted as plaintext
+// IP traffic at the server TUN. RX is client → server, TX is server → client.
+type PeerTraffic struct {
+	RXBytes   uint64 `json:"RXBytes"`
+	RXPackets uint64 `json:"RXPackets"`
+	TXBytes   uint64 `json:"TXBytes"`
+	TXPackets uint64 `json:"TXPackets"`
+}
+
+// TrafficStore persists per-peer usage next to the server configuration.
+// Usage is kept out of the configuration file so that periodic flushes do not
+// trigger configuration reloads.
+type TrafficStore struct {
+	path string
+}
+
+type trafficRecord struct {
+	PublicKey []byte `json:"PublicKey"`
+	PeerTraffic
+}
+
+func NewTrafficStore(configPath string) *TrafficStore {
+	return &TrafficStore{path: filepath.Join(filepath.Dir(configPath), trafficFileName)}
+}
+
+// Load returns persisted usage keyed by string(PublicKey). A missing file is
+// not an error.
+func (s *TrafficStore) Load() (map[string]PeerTraffic, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]PeerTraffic{}, nil
+		}
+		return nil, fmt.Errorf("traffic file %q is unreadable: %w", s.path, err)
+	}
+	var records []trafficRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("traffic file %q is invalid: %w", s.path, err)
+	}
+	traffic := make(map[string]PeerTraffic, len(records))
+	for _, record := range records {
+		traffic[string(record.PublicKey)] = record.PeerTraffic
+	}
+	return traffic, nil
+}
+
+// Save atomically replaces the persisted usage.
+func (s *TrafficStore) Save(traffic map[string]PeerTraffic) error {
+	records := make([]trafficRecord, 0, len(traffic))
+	for key, usage := range traffic {
+		records = append(records, trafficRecord{PublicKey: []byte(key), PeerTraffic: usage})
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return string(records[i].PublicKey) < string(records[j].PublicKey)
+	})
+	data, err := json.MarshalIndent(records, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, trafficFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
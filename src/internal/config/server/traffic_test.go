@@ -0,0 +1,54 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTrafficStore_SaveLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := NewTrafficStore(filepath.Join(dir, "server_configuration.json"))
+	want := map[string]PeerTraffic{
+		"key-a": {RXBytes: 1, RXPackets: 2, TXBytes: 3, TXPackets: 4},
+		"key-b": {RXBytes: 5},
+	}
+
+	if err := store.Save(want); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dir, trafficFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Fatalf("traffic file permissions = %o, want 600", perm)
+	}
+	got, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != len(want) || got["key-a"] != want["key-a"] || got["key-b"] != want["key-b"] {
+		t.Fatalf("Load() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTrafficStore_LoadMissingFile(t *testing.T) {
+	store := NewTrafficStore(filepath.Join(t.TempDir(), "server_configuration.json"))
+
+	got, err := store.Load()
+	if err != nil || len(got) != 0 {
+		t.Fatalf("Load() = (%v, %v), want empty", got, err)
+	}
+}
+
+func TestTrafficStore_LoadInvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, trafficFileName), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewTrafficStore(filepath.Join(dir, "server_configuration.json")).Load(); err == nil {
+		t.Fatal("expected error for invalid traffic file")
+	}
+}
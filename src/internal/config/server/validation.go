@@ -6,12 +6,21 @@ import (
 	"strings"
 
 	"tungo/internal/config/settings"
+	"tungo/internal/metrics"
 )
 
 func Validate(configuration Configuration) error {
 	if configuration.Host != "" && strings.TrimSpace(configuration.Host) == "" {
 		return fmt.Errorf("host is empty")
 	}
+	if configuration.MetricsAddress != "" {
+		if _, err := metrics.ParseAddress(configuration.MetricsAddress, configuration.MetricsAllowRemote); err != nil {
+			return err
+		}
+	}
+	if err := configuration.ClientRouting.Validate(); err != nil {
+		return fmt.Errorf("ClientRouting: %w", err)
+	}
 
 	profiles := configuration.Profiles()
 	ifNames := make(map[string]struct{}, len(profiles))
@@ -74,7 +83,10 @@ func Validate(configuration Configuration) error {
 		return fmt.Errorf("two or more subnets are overlapping")
 	}
 
-	return validateAllowedPeers(configuration.AllowedPeers)
+	if err := validateAllowedPeers(configuration.AllowedPeers); err != nil {
+		return err
+	}
+	return validatePeerAllowedIPs(configuration.AllowedPeers, subnets)
 }
 
 func validateSubnetContainsAddr(
@@ -127,6 +139,9 @@ func validateAllowedPeers(peers []AllowedPeer) error {
 		if peer.ClientID <= 0 {
 			return fmt.Errorf("peer %d: invalid ClientID %d: must be > 0", i, peer.ClientID)
 		}
+		if len(peer.PresharedKey) != 0 && len(peer.PresharedKey) != 32 {
+			return fmt.Errorf("peer %d: invalid PresharedKey length %d, expected 32", i, len(peer.PresharedKey))
+		}
 		if previous, exists := seenClientIDs[peer.ClientID]; exists {
 			return fmt.Errorf(
 				"ClientID conflict: peer %d and peer %d both have ClientID %d",
@@ -147,3 +162,39 @@ func validateAllowedPeers(peers []AllowedPeer) error {
 
 	return nil
 }
+
+// validatePeerAllowedIPs requires canonical prefixes that overlap neither
+// tunnel subnets nor the AllowedIPs of another peer, so that every routed
+// network has exactly one owner.
+func validatePeerAllowedIPs(peers []AllowedPeer, subnets []netip.Prefix) error {
+	type owned struct {
+		prefix netip.Prefix
+		peer   int
+	}
+	var seen []owned
+	for i, peer := range peers {
+		for _, prefix := range peer.AllowedIPs {
+			if !prefix.IsValid() {
+				return fmt.Errorf("peer %d: invalid AllowedIPs entry %q", i, prefix)
+			}
+			if prefix != prefix.Masked() {
+				return fmt.Errorf("peer %d: AllowedIPs entry %s has host bits set, expected %s", i, prefix, prefix.Masked())
+			}
+			for _, subnet := range subnets {
+				if prefix.Overlaps(subnet) {
+					return fmt.Errorf("peer %d: AllowedIPs entry %s overlaps tunnel subnet %s", i, prefix, subnet)
+				}
+			}
+			for _, other := range seen {
+				if prefix.Overlaps(other.prefix) {
+					return fmt.Errorf(
+						"AllowedIPs conflict: peer %d %s overlaps peer %d %s",
+						other.peer, other.prefix, i, prefix,
+					)
+				}
+			}
+			seen = append(seen, owned{prefix: prefix, peer: i})
+		}
+	}
+	return nil
+}
@@ -3,10 +3,13 @@ package config
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 
+	clientconfig "tungo/internal/config/client"
 	serverconfig "tungo/internal/config/server"
 	"tungo/internal/config/settings"
 	"tungo/internal/protocol/keys"
@@ -16,6 +19,12 @@ import (
 type serverControl struct {
 	configPath string
 	manager    serverconfigManager
+	traffic    trafficStore
+}
+
+type trafficStore interface {
+	Load() (map[string]serverconfig.PeerTraffic, error)
+	Save(map[string]serverconfig.PeerTraffic) error
 }
 
 type serverconfigManager interface {
@@ -25,8 +34,10 @@ type serverconfigManager interface {
 	AddAllowedPeer(peer serverconfig.AllowedPeer) error
 	ListAllowedPeers() ([]serverconfig.AllowedPeer, error)
 	SetAllowedPeerEnabled(clientID int, enabled bool) error
+	RenameAllowedPeer(clientID int, name string) error
 	RemoveAllowedPeer(clientID int) error
 	EnsureIPv6Subnets() error
+	SetPrivateKeyFile(keyFile string) error
 	InvalidateCache()
 }
 
@@ -82,23 +93,74 @@ func (c *serverControl) RuntimeInfo() (RuntimeInfo, error) {
 }
 
 func (c *serverControl) GenerateClientConfiguration() (GeneratedClientConfiguration, error) {
+	generated, err := c.GenerateClientConfigurations(GenerateOptions{})
+	if err != nil {
+		return GeneratedClientConfiguration{}, err
+	}
+	return generated[0], nil
+}
+
+func (c *serverControl) GenerateClientConfigurations(options GenerateOptions) ([]GeneratedClientConfiguration, error) {
+	count := options.Count
+	if count == 0 {
+		count = 1
+	}
+	names, err := batchNames(options.Name, count)
+	if err != nil {
+		return nil, err
+	}
+	if options.PublicKey != nil && count > 1 {
+		return nil, errors.New("a public key enrolls a single peer")
+	}
 	if err := serverconfig.NewX25519KeyManager(c.manager).PrepareKeys(); err != nil {
-		return GeneratedClientConfiguration{}, fmt.Errorf("could not prepare server keys: %w", err)
+		return nil, fmt.Errorf("could not prepare server keys: %w", err)
 	}
 	gen := newGenerator(c.manager, &keys.DefaultKeyDeriver{}, host.NewDialResolver())
-	conf, err := gen.generate()
-	if err != nil {
-		return GeneratedClientConfiguration{}, err
+	generated := make([]GeneratedClientConfiguration, 0, count)
+	for _, name := range names {
+		options.Name = name
+		conf, err := gen.generate(options)
+		if err != nil {
+			return generated, err
+		}
+		data, err := json.MarshalIndent(conf, "", "  ")
+		if err != nil {
+			return generated, fmt.Errorf("failed to marshal client configuration: %w", err)
+		}
+		uri, err := clientconfig.MarshalURI(*conf)
+		if err != nil {
+			return generated, err
+		}
+		var path string
+		if !options.Discard {
+			if path, err = writeServerClientConfigFile(c.configPath, conf.ClientID, data); err != nil {
+				return generated, fmt.Errorf("failed to save client configuration: %w", err)
+			}
+		}
+		generated = append(generated, GeneratedClientConfiguration{
+			ClientID: conf.ClientID,
+			Name:     peerName(name, conf.ClientID),
+			JSON:     string(data),
+			URI:      uri,
+			Path:     path,
+		})
 	}
-	data, err := json.MarshalIndent(conf, "", "  ")
+	return generated, nil
+}
+
+func (c *serverControl) ClientConfigurationURI(clientID int) (string, error) {
+	data, err := os.ReadFile(serverClientConfigPath(c.configPath, clientID))
 	if err != nil {
-		return GeneratedClientConfiguration{}, fmt.Errorf("failed to marshal client configuration: %w", err)
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("configuration of client #%d was not generated on this server", clientID)
+		}
+		return "", fmt.Errorf("failed to read configuration of client #%d: %w", clientID, err)
 	}
-	path, err := writeServerClientConfigFile(c.configPath, conf.ClientID, data)
+	conf, err := clientconfig.Decode(data)
 	if err != nil {
-		return GeneratedClientConfiguration{}, fmt.Errorf("failed to save client configuration: %w", err)
+		return "", fmt.Errorf("invalid configuration of client #%d: %w", clientID, err)
 	}
-	return GeneratedClientConfiguration{JSON: string(data), Path: path}, nil
+	return clientconfig.MarshalURI(conf)
 }
 
 func (c *serverControl) ListPeers() ([]ServerPeer, error) {
@@ -106,16 +168,42 @@ func (c *serverControl) ListPeers() ([]ServerPeer, error) {
 	if err != nil {
 		return nil, err
 	}
+	// The peers are listed without usage when the statistics are unreadable.
+	var traffic map[string]serverconfig.PeerTraffic
+	if c.traffic != nil {
+		if traffic, err = c.traffic.Load(); err != nil {
+			slog.Warn("failed to load peer traffic, listing peers without it", "err", err)
+		}
+	}
 	for i := range peers {
 		peers[i].PublicKey = append([]byte(nil), peers[i].PublicKey...)
+		peers[i].Traffic = traffic[string(peers[i].PublicKey)]
 	}
 	return peers, nil
 }
 
+func (c *serverControl) LoadPeerTraffic() (map[string]serverconfig.PeerTraffic, error) {
+	if c.traffic == nil {
+		return map[string]serverconfig.PeerTraffic{}, nil
+	}
+	return c.traffic.Load()
+}
+
+func (c *serverControl) SavePeerTraffic(traffic map[string]serverconfig.PeerTraffic) error {
+	if c.traffic == nil {
+		return nil
+	}
+	return c.traffic.Save(traffic)
+}
+
 func (c *serverControl) SetPeerEnabled(clientID int, enabled bool) error {
 	return c.manager.SetAllowedPeerEnabled(clientID, enabled)
 }
 
+func (c *serverControl) RenamePeer(clientID int, name string) error {
+	return c.manager.RenameAllowedPeer(clientID, name)
+}
+
 func (c *serverControl) RemovePeer(clientID int) error {
 	return c.manager.RemoveAllowedPeer(clientID)
 }
@@ -125,6 +213,10 @@ func writeServerClientConfigFile(configPath string, clientID int, data []byte) (
 	if err := os.MkdirAll(dir, 0700); err != nil {
 		return "", fmt.Errorf("failed to create server config directory: %w", err)
 	}
-	path := filepath.Join(dir, fmt.Sprintf("client_configuration.json.%d", clientID))
+	path := serverClientConfigPath(configPath, clientID)
 	return path, os.WriteFile(path, data, 0600)
 }
+
+func serverClientConfigPath(configPath string, clientID int) string {
+	return filepath.Join(filepath.Dir(configPath), fmt.Sprintf("client_configuration.json.%d", clientID))
+}
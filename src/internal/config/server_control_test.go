@@ -2,12 +2,15 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	clientconfig "tungo/internal/config/client"
 	serverconfig "tungo/internal/config/server"
 )
 
@@ -157,3 +160,66 @@ func TestServerControlGenerateClientConfiguration(t *testing.T) {
 		t.Fatalf("stat generated configuration: %v", err)
 	}
 }
+
+func TestServerControlGenerateClientConfigurations_Batch(t *testing.T) {
+	t.Setenv("X25519_PUBLIC_KEY", "")
+	t.Setenv("X25519_PRIVATE_KEY", "")
+	conf := validCfg()
+	conf.X25519PublicKey = make([]byte, 32)
+	conf.X25519PrivateKey = make([]byte, 32)
+	dir := t.TempDir()
+	control := serverControl{
+		configPath: filepath.Join(dir, "server_configuration.json"),
+		manager:    &mockMgr{cfg: conf},
+	}
+
+	generated, err := control.GenerateClientConfigurations(GenerateOptions{Name: "team", Count: 2})
+	if err != nil {
+		t.Fatalf("GenerateClientConfigurations() error = %v", err)
+	}
+	if len(generated) != 2 {
+		t.Fatalf("generated %d configurations, want 2", len(generated))
+	}
+	for i, g := range generated {
+		if wantID, wantName := 8+i, fmt.Sprintf("team-%d", i+1); g.ClientID != wantID || g.Name != wantName {
+			t.Fatalf("generated[%d] = #%d %q, want #%d %q", i, g.ClientID, g.Name, wantID, wantName)
+		}
+		if _, err := os.Stat(g.Path); err != nil {
+			t.Fatalf("stat generated configuration: %v", err)
+		}
+	}
+
+	discarded, err := control.GenerateClientConfigurations(GenerateOptions{Discard: true})
+	if err != nil {
+		t.Fatalf("GenerateClientConfigurations() error = %v", err)
+	}
+	if len(discarded) != 1 || discarded[0].Path != "" || discarded[0].Name != "client-10" {
+		t.Fatalf("discarded = %+v, want one unsaved client-10", discarded)
+	}
+	if _, err := os.Stat(serverClientConfigPath(control.configPath, 10)); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("discarded configuration was saved: %v", err)
+	}
+}
+
+func TestServerControlClientConfigurationURI(t *testing.T) {
+	dir := t.TempDir()
+	control := &serverControl{configPath: filepath.Join(dir, "server_configuration.json")}
+
+	if _, err := control.ClientConfigurationURI(4); err == nil || !strings.Contains(err.Error(), "was not generated") {
+		t.Fatalf("expected missing configuration error, got %v", err)
+	}
+
+	want := makeTestConfig()
+	data, _ := json.Marshal(want)
+	if _, err := writeServerClientConfigFile(control.configPath, 4, data); err != nil {
+		t.Fatalf("writeServerClientConfigFile() error = %v", err)
+	}
+	uri, err := control.ClientConfigurationURI(4)
+	if err != nil {
+		t.Fatalf("ClientConfigurationURI() error = %v", err)
+	}
+	got, err := clientconfig.ParseURI(uri)
+	if err != nil || got.ClientID != want.ClientID || got.TCPSettings.Port != want.TCPSettings.Port {
+		t.Fatalf("ParseURI() = %+v, %v; want %+v", got, err, want)
+	}
+}
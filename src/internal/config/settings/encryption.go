@@ -10,12 +10,17 @@ type Encryption int
 
 const (
 	ChaCha20Poly1305 Encryption = iota
+	// AES256GCM is offered in the handshake; the session falls back to
+	// ChaCha20Poly1305 unless the peer selects it too.
+	AES256GCM
 )
 
 func (e Encryption) MarshalJSON() ([]byte, error) {
 	switch e {
 	case ChaCha20Poly1305:
 		return json.Marshal("ChaCha20Poly1305")
+	case AES256GCM:
+		return json.Marshal("AES256GCM")
 	default:
 		return nil, errors.New("invalid encryption")
 	}
@@ -26,9 +31,13 @@ func (e *Encryption) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
 	}
-	if s == "ChaCha20Poly1305" {
+	switch s {
+	case "ChaCha20Poly1305":
 		*e = ChaCha20Poly1305
-		return nil
+	case "AES256GCM":
+		*e = AES256GCM
+	default:
+		return errors.New("invalid encryption")
 	}
-	return errors.New("invalid encryption")
+	return nil
 }
@@ -13,6 +13,7 @@ func TestEncryption_MarshalJSON(t *testing.T) {
 		wantErr bool
 	}{
 		{"valid ChaCha20Poly1305", ChaCha20Poly1305, `"ChaCha20Poly1305"`, false},
+		{"valid AES256GCM", AES256GCM, `"AES256GCM"`, false},
 		{"invalid value", Encryption(99), ``, true},
 	}
 
@@ -61,16 +62,17 @@ func TestEncryption_UnmarshalJSON(t *testing.T) {
 }
 
 func TestEncryptionJSON_RoundTrip(t *testing.T) {
-	orig := ChaCha20Poly1305
-	data, err := json.Marshal(orig)
-	if err != nil {
-		t.Fatalf("json.Marshal error: %v", err)
-	}
-	var got Encryption
-	if err := json.Unmarshal(data, &got); err != nil {
-		t.Fatalf("json.Unmarshal error: %v", err)
-	}
-	if got != orig {
-		t.Errorf("round-trip: got %v, want %v", got, orig)
+	for _, orig := range []Encryption{ChaCha20Poly1305, AES256GCM} {
+		data, err := json.Marshal(orig)
+		if err != nil {
+			t.Fatalf("json.Marshal error: %v", err)
+		}
+		var got Encryption
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("json.Unmarshal error: %v", err)
+		}
+		if got != orig {
+			t.Errorf("round-trip: got %v, want %v", got, orig)
+		}
 	}
 }
@@ -45,21 +45,32 @@ func (p *Protocol) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return err
 	}
-	switch strings.ToUpper(s) {
-	case "UNKNOWN":
+	if strings.EqualFold(s, "UNKNOWN") {
 		*p = UNKNOWN
+		return nil
+	}
+	protocol, err := ParseProtocol(s)
+	if err != nil {
+		return err
+	}
+	*p = protocol
+	return nil
+}
+
+// ParseProtocol parses a protocol name such as "udp", ignoring case.
+func ParseProtocol(s string) (Protocol, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
 	case "TCP":
-		*p = TCP
+		return TCP, nil
 	case "UDP":
-		*p = UDP
+		return UDP, nil
 	case "WS":
-		*p = WS
+		return WS, nil
 	case "WSS":
-		*p = WSS
+		return WSS, nil
 	default:
-		return ErrInvalidProtocol
+		return UNKNOWN, ErrInvalidProtocol
 	}
-	return nil
 }
 
 func (p Protocol) String() string {
@@ -2,6 +2,7 @@ package settings
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 )
 
@@ -104,3 +105,17 @@ func TestProtocol_String(t *testing.T) {
 		}
 	}
 }
+
+func TestParseProtocol(t *testing.T) {
+	for input, want := range map[string]Protocol{"udp": UDP, " TCP ": TCP, "Ws": WS, "wss": WSS} {
+		got, err := ParseProtocol(input)
+		if err != nil || got != want {
+			t.Errorf("ParseProtocol(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "unknown", "sctp"} {
+		if _, err := ParseProtocol(input); !errors.Is(err, ErrInvalidProtocol) {
+			t.Errorf("ParseProtocol(%q) error = %v, want ErrInvalidProtocol", input, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// LANPrefixes are the private and link-local networks that bypass the tunnel
+// when AllowLAN is set.
+var LANPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+// Routing selects which destinations a client sends through the tunnel.
+// The zero value is a full tunnel.
+type Routing struct {
+	// IncludeRoutes, when set, replaces the full tunnel: only these prefixes
+	// are routed into the tunnel.
+	IncludeRoutes []netip.Prefix `json:"IncludeRoutes,omitempty"`
+	// ExcludeRoutes are routed around the tunnel through the physical uplink.
+	ExcludeRoutes []netip.Prefix `json:"ExcludeRoutes,omitempty"`
+	// AllowLAN excludes LANPrefixes from the tunnel.
+	AllowLAN bool `json:"AllowLAN,omitempty"`
+}
+
+// FullTunnel reports whether all traffic is routed into the tunnel.
+func (r Routing) FullTunnel() bool {
+	return len(r.IncludeRoutes) == 0
+}
+
+// BypassRoutes returns the prefixes routed around the tunnel.
+func (r Routing) BypassRoutes() []netip.Prefix {
+	bypass := append([]netip.Prefix(nil), r.ExcludeRoutes...)
+	if r.AllowLAN {
+		bypass = append(bypass, LANPrefixes...)
+	}
+	return bypass
+}
+
+// Validate rejects prefixes that cannot be installed as routes. Excluded
+// prefixes must be narrower than the /1 halves of the full tunnel so that
+// they take priority over it.
+func (r Routing) Validate() error {
+	for i, prefix := range r.IncludeRoutes {
+		if err := validateRoutePrefix(prefix, 1); err != nil {
+			return fmt.Errorf("IncludeRoutes[%d]: %w", i, err)
+		}
+	}
+	for i, prefix := range r.ExcludeRoutes {
+		if err := validateRoutePrefix(prefix, 2); err != nil {
+			return fmt.Errorf("ExcludeRoutes[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func validateRoutePrefix(prefix netip.Prefix, minBits int) error {
+	if !prefix.IsValid() {
+		return fmt.Errorf("invalid prefix")
+	}
+	if prefix.Addr().Is4In6() {
+		return fmt.Errorf("prefix %s must be plain IPv4", prefix)
+	}
+	if prefix != prefix.Masked() {
+		return fmt.Errorf("prefix %s has host bits set, use %s", prefix, prefix.Masked())
+	}
+	if prefix.Bits() < minBits {
+		return fmt.Errorf("prefix %s is too broad, minimum length is /%d", prefix, minBits)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package settings
+
+import (
+	"encoding/json"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouting_FullTunnelAndBypass(t *testing.T) {
+	var r Routing
+	if !r.FullTunnel() {
+		t.Fatal("zero routing must be a full tunnel")
+	}
+	if len(r.BypassRoutes()) != 0 {
+		t.Fatalf("zero routing must not bypass anything, got %v", r.BypassRoutes())
+	}
+
+	exclude := netip.MustParsePrefix("203.0.113.0/24")
+	r = Routing{
+		IncludeRoutes: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16")},
+		ExcludeRoutes: []netip.Prefix{exclude},
+		AllowLAN:      true,
+	}
+	if r.FullTunnel() {
+		t.Fatal("include routes must disable the full tunnel")
+	}
+	want := append([]netip.Prefix{exclude}, LANPrefixes...)
+	if got := r.BypassRoutes(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("BypassRoutes: want %v, got %v", want, got)
+	}
+}
+
+func TestRouting_Validate(t *testing.T) {
+	valid := Routing{
+		IncludeRoutes: []netip.Prefix{netip.MustParsePrefix("10.20.0.0/16"), netip.MustParsePrefix("2001:db8::/32")},
+		ExcludeRoutes: []netip.Prefix{netip.MustParsePrefix("203.0.113.0/24")},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid routing, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		routing Routing
+		want    string
+	}{
+		{"invalid", Routing{IncludeRoutes: []netip.Prefix{{}}}, "IncludeRoutes[0]: invalid prefix"},
+		{"host bits", Routing{ExcludeRoutes: []netip.Prefix{netip.MustParsePrefix("203.0.113.7/24")}}, "host bits"},
+		{"default include", Routing{IncludeRoutes: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}}, "too broad"},
+		{"half exclude", Routing{ExcludeRoutes: []netip.Prefix{netip.MustParsePrefix("8000::/1")}}, "too broad"},
+		{"mapped", Routing{IncludeRoutes: []netip.Prefix{netip.MustParsePrefix("::ffff:10.0.0.0/104")}}, "plain IPv4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.routing.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSettings_JSON_Routing(t *testing.T) {
+	data := []byte(`{"IncludeRoutes":["10.20.0.0/16"],"AllowLAN":true}`)
+	var s Settings
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(s.IncludeRoutes) != 1 || s.IncludeRoutes[0] != netip.MustParsePrefix("10.20.0.0/16") || !s.AllowLAN {
+		t.Fatalf("unexpected routing: %+v", s.Routing)
+	}
+
+	out, err := json.Marshal(Settings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(out), "Routes") || strings.Contains(string(out), "AllowLAN") {
+		t.Fatalf("zero routing must be omitted, got %s", out)
+	}
+}
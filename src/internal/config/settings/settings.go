@@ -2,6 +2,7 @@ package settings
 
 type Settings struct {
 	Addressing
+	Routing
 	MTU           int           `json:"MTU"`
 	Protocol      Protocol      `json:"Protocol"`
 	Encryption    Encryption    `json:"Encryption"`
@@ -5,6 +5,9 @@ type Config struct {
 	UnitPath   string
 	UnitName   string
 	BinaryPath string
+	// InstanceUnitPath is the template unit that runs named client
+	// instances as tungo-client@<name>.service.
+	InstanceUnitPath string
 }
 
 func DefaultConfig() Config {
@@ -13,5 +16,7 @@ func DefaultConfig() Config {
 		UnitPath:   "/etc/systemd/system/tungo.service",
 		UnitName:   "tungo.service",
 		BinaryPath: "/usr/local/bin/tungo",
+
+		InstanceUnitPath: "/etc/systemd/system/tungo-client@.service",
 	}
 }
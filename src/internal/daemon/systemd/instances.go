@@ -0,0 +1,77 @@
+package systemd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	clientconfig "tungo/internal/config/client"
+)
+
+// InstanceUnitName returns the unit that runs the named client instance.
+func (i *UnitInstaller) InstanceUnitName(name string) string {
+	template := filepath.Base(i.config.InstanceUnitPath)
+	prefix, suffix, _ := strings.Cut(template, "@")
+	return prefix + "@" + name + suffix
+}
+
+// SetupInstance installs the instance template unit, then enables and starts
+// the named client instance.
+func (i *UnitInstaller) SetupInstance(name string) error {
+	if err := clientconfig.ValidateInstanceName(name); err != nil {
+		return err
+	}
+	if !i.Available() {
+		return fmt.Errorf("systemd is not available")
+	}
+	if err := ValidateTungoBinaryForSystemd(i.hooks, i.config.BinaryPath); err != nil {
+		return err
+	}
+	content := []byte(InstanceUnitFileContent(i.config.BinaryPath))
+	if err := i.hooks.WriteFile(i.config.InstanceUnitPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", i.config.InstanceUnitPath, err)
+	}
+	if err := i.commander.Run("systemctl", "daemon-reload"); err != nil {
+		return fmt.Errorf("failed to run systemctl daemon-reload: %w", err)
+	}
+	unit := i.InstanceUnitName(name)
+	if err := i.commander.Run("systemctl", "enable", "--now", unit); err != nil {
+		return fmt.Errorf("failed to run systemctl enable --now %s: %w", unit, err)
+	}
+	return nil
+}
+
+// RemoveInstance stops and disables the named client instance. The template
+// stays for the other instances.
+func (i *UnitInstaller) RemoveInstance(name string) error {
+	if err := clientconfig.ValidateInstanceName(name); err != nil {
+		return err
+	}
+	if !i.Available() {
+		return fmt.Errorf("systemd is not available")
+	}
+	unit := i.InstanceUnitName(name)
+	if err := i.commander.Run("systemctl", "disable", "--now", unit); err != nil && !IsSystemdDisabledError(err) {
+		return fmt.Errorf("failed to run systemctl disable --now %s: %w", unit, err)
+	}
+	return nil
+}
+
+// IsInstanceActive reports whether the named client instance runs as a unit.
+func (i *UnitInstaller) IsInstanceActive(name string) (bool, error) {
+	if err := clientconfig.ValidateInstanceName(name); err != nil {
+		return false, err
+	}
+	if !i.Available() {
+		return false, fmt.Errorf("systemd is not available")
+	}
+	unit := i.InstanceUnitName(name)
+	output, err := i.commander.CombinedOutput("systemctl", "is-active", unit)
+	if err != nil {
+		if IsSystemdNotActiveError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to run systemctl is-active %s: %w", unit, err)
+	}
+	return ActiveStateBlocksRuntimeStart(ParseUnitActiveState(output, nil)), nil
+}
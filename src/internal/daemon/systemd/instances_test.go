@@ -0,0 +1,123 @@
+package systemd
+
+import (
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// argsCommander records whole command lines, since instance commands differ
+// only past the first argument.
+type argsCommander struct {
+	calls  []string
+	output []byte
+}
+
+func (c *argsCommander) CombinedOutput(name string, args ...string) ([]byte, error) {
+	c.calls = append(c.calls, strings.Join(append([]string{name}, args...), " "))
+	return c.output, nil
+}
+
+func (c *argsCommander) Output(name string, args ...string) ([]byte, error) {
+	return c.CombinedOutput(name, args...)
+}
+
+func (c *argsCommander) Run(name string, args ...string) error {
+	_, err := c.CombinedOutput(name, args...)
+	return err
+}
+
+func withInstanceHooks(t *testing.T, write func(string, []byte, os.FileMode) error) {
+	t.Helper()
+	withSystemdHooks(
+		t,
+		func(string) (os.FileInfo, error) { return nil, nil },
+		func(name string) (string, error) {
+			if name == "systemctl" {
+				return "/bin/systemctl", nil
+			}
+			return "", exec.ErrNotFound
+		},
+		write,
+	)
+}
+
+func TestSetupInstance_WritesTemplateAndStartsInstance(t *testing.T) {
+	var gotPath, gotContent string
+	withInstanceHooks(t, func(path string, data []byte, _ os.FileMode) error {
+		gotPath, gotContent = path, string(data)
+		return nil
+	})
+	cmd := &argsCommander{}
+	installer := NewUnitInstaller(cmd)
+
+	if err := installer.SetupInstance("prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotPath != defaultSystemdConfig.InstanceUnitPath {
+		t.Fatalf("write path: got %q want %q", gotPath, defaultSystemdConfig.InstanceUnitPath)
+	}
+	if !strings.Contains(gotContent, `ExecStart="/usr/local/bin/tungo" c up %i`) ||
+		!strings.Contains(gotContent, "Description=TunGo VPN client %i") {
+		t.Fatalf("unexpected template content: %q", gotContent)
+	}
+	want := []string{"systemctl daemon-reload", "systemctl enable --now tungo-client@prod.service"}
+	if !reflect.DeepEqual(cmd.calls, want) {
+		t.Fatalf("calls: got %v want %v", cmd.calls, want)
+	}
+}
+
+func TestRemoveInstance_DisablesInstanceOnly(t *testing.T) {
+	withInstanceHooks(t, func(string, []byte, os.FileMode) error { return nil })
+	removed := false
+	removePath = func(string) error {
+		removed = true
+		return nil
+	}
+	cmd := &argsCommander{}
+	installer := NewUnitInstaller(cmd)
+
+	if err := installer.RemoveInstance("prod"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if removed {
+		t.Fatal("expected the template unit to stay")
+	}
+	want := []string{"systemctl disable --now tungo-client@prod.service"}
+	if !reflect.DeepEqual(cmd.calls, want) {
+		t.Fatalf("calls: got %v want %v", cmd.calls, want)
+	}
+}
+
+func TestIsInstanceActive(t *testing.T) {
+	withInstanceHooks(t, func(string, []byte, os.FileMode) error { return nil })
+	cmd := &argsCommander{output: []byte("active\n")}
+	installer := NewUnitInstaller(cmd)
+
+	active, err := installer.IsInstanceActive("prod")
+	if err != nil || !active {
+		t.Fatalf("IsInstanceActive() = %v, %v", active, err)
+	}
+	if want := []string{"systemctl is-active tungo-client@prod.service"}; !reflect.DeepEqual(cmd.calls, want) {
+		t.Fatalf("calls: got %v want %v", cmd.calls, want)
+	}
+}
+
+func TestInstanceOperations_RejectInvalidName(t *testing.T) {
+	cmd := &argsCommander{}
+	installer := NewUnitInstaller(cmd)
+	if err := installer.SetupInstance("../prod"); err == nil {
+		t.Fatal("expected SetupInstance error")
+	}
+	if err := installer.RemoveInstance("a b"); err == nil {
+		t.Fatal("expected RemoveInstance error")
+	}
+	if _, err := installer.IsInstanceActive(""); err == nil {
+		t.Fatal("expected IsInstanceActive error")
+	}
+	if len(cmd.calls) != 0 {
+		t.Fatalf("unexpected calls: %v", cmd.calls)
+	}
+}
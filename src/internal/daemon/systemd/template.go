@@ -4,15 +4,27 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+
+	"tungo/internal/commandline"
 )
 
 func UnitFileContent(binaryPath string, args []string) string {
+	return unitFileContent("TunGo VPN Service", binaryPath, args)
+}
+
+// InstanceUnitFileContent renders the template unit of named client
+// instances; systemd substitutes the instance name for %i.
+func InstanceUnitFileContent(binaryPath string) string {
+	return unitFileContent("TunGo VPN client %i", binaryPath, commandline.InstanceArgs("%i"))
+}
+
+func unitFileContent(description, binaryPath string, args []string) string {
 	execStart := strconv.Quote(binaryPath)
 	if len(args) > 0 {
 		execStart += " " + strings.Join(args, " ")
 	}
 	return fmt.Sprintf(`[Unit]
-Description=TunGo VPN Service
+Description=%s
 After=network-online.target
 Wants=network-online.target
 
@@ -25,5 +37,5 @@ RestartSec=5
 
 [Install]
 WantedBy=multi-user.target
-`, execStart)
+`, description, execStart)
 }
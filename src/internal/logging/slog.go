@@ -1,9 +1,11 @@
 package logging
 
 import (
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"strings"
 	"sync"
 )
 
@@ -46,8 +48,44 @@ func CurrentOutput() io.Writer {
 	return output
 }
 
+// Format selects how log records are rendered.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// ParseFormat accepts "text" and "json".
+func ParseFormat(s string) (Format, error) {
+	switch format := Format(strings.ToLower(strings.TrimSpace(s))); format {
+	case FormatText, FormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown log format %q: use text or json", s)
+	}
+}
+
+// ParseLevel accepts debug, info, warn and error, optionally with an offset
+// such as "debug-4".
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return 0, fmt.Errorf("unknown log level %q: use debug, info, warn or error", s)
+	}
+	return level, nil
+}
+
 func NewLogger(level slog.Leveler) *slog.Logger {
-	return slog.New(slog.NewTextHandler(Writer(), &slog.HandlerOptions{
-		Level: level,
-	}))
+	return New(level, FormatText)
+}
+
+// New returns a logger that writes records in format to the current logging
+// sink.
+func New(level slog.Leveler, format Format) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	if format == FormatJSON {
+		return slog.New(slog.NewJSONHandler(Writer(), options))
+	}
+	return slog.New(slog.NewTextHandler(Writer(), options))
 }
@@ -60,3 +60,34 @@ func TestWriter_ForwardsToCurrentOutput(t *testing.T) {
 		t.Fatalf("expected forwarded content, got %q", buf.String())
 	}
 }
+
+func TestNew_JSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	prev := SetOutput(&buf)
+	t.Cleanup(func() { SetOutput(prev) })
+
+	logger := New(slog.LevelWarn, FormatJSON)
+	logger.Info("hidden")
+	logger.Warn("shown", "key", "value")
+	if got := strings.TrimSpace(buf.String()); !strings.HasPrefix(got, "{") || strings.Contains(got, "hidden") || !strings.Contains(got, `"key":"value"`) {
+		t.Fatalf("unexpected JSON log output %q", got)
+	}
+}
+
+func TestParseFormatAndLevel(t *testing.T) {
+	if format, err := ParseFormat(" JSON "); err != nil || format != FormatJSON {
+		t.Fatalf("ParseFormat() = %q, %v", format, err)
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	if level, err := ParseLevel("debug"); err != nil || level != slog.LevelDebug {
+		t.Fatalf("ParseLevel() = %v, %v", level, err)
+	}
+	if level, err := ParseLevel("WARN"); err != nil || level != slog.LevelWarn {
+		t.Fatalf("ParseLevel() = %v, %v", level, err)
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+}
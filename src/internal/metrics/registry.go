@@ -0,0 +1,140 @@
+// Package metrics renders runtime counters in the Prometheus text exposition
+// format. Metrics are read from their existing sources at scrape time, so the
+// hot paths only maintain the atomics they already have.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Prometheus text exposition format version 0.0.4.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type kind string
+
+const (
+	kindCounter kind = "counter"
+	kindGauge   kind = "gauge"
+)
+
+type family struct {
+	name    string
+	help    string
+	kind    kind
+	label   string
+	collect func() map[string]float64
+}
+
+// Registry holds metric families in registration order.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter registers a monotonically increasing value.
+func (r *Registry) Counter(name, help string, value func() uint64) {
+	r.add(family{name: name, help: help, kind: kindCounter, collect: func() map[string]float64 {
+		return map[string]float64{"": float64(value())}
+	}})
+}
+
+// Gauge registers a value that can go up and down.
+func (r *Registry) Gauge(name, help string, value func() float64) {
+	r.add(family{name: name, help: help, kind: kindGauge, collect: func() map[string]float64 {
+		return map[string]float64{"": value()}
+	}})
+}
+
+// CounterVec registers counters partitioned by one label.
+func (r *Registry) CounterVec(name, help, label string, values func() map[string]float64) {
+	r.add(family{name: name, help: help, kind: kindCounter, label: label, collect: values})
+}
+
+// GaugeVec registers gauges partitioned by one label.
+func (r *Registry) GaugeVec(name, help, label string, values func() map[string]float64) {
+	r.add(family{name: name, help: help, kind: kindGauge, label: label, collect: values})
+}
+
+func (r *Registry) add(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// WriteTo renders every family in the text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := append([]family(nil), r.families...)
+	r.mu.Unlock()
+
+	counter := &countingWriter{w: w}
+	buffered := bufio.NewWriter(counter)
+	for _, f := range families {
+		_, _ = fmt.Fprintf(buffered, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		_, _ = fmt.Fprintf(buffered, "# TYPE %s %s\n", f.name, f.kind)
+		samples := f.collect()
+		labels := make([]string, 0, len(samples))
+		for labelValue := range samples {
+			labels = append(labels, labelValue)
+		}
+		sort.Strings(labels)
+		for _, labelValue := range labels {
+			value := formatValue(samples[labelValue])
+			if f.label == "" {
+				_, _ = fmt.Fprintf(buffered, "%s %s\n", f.name, value)
+				continue
+			}
+			_, _ = fmt.Fprintf(buffered, "%s{%s=\"%s\"} %s\n", f.name, f.label, escapeLabel(labelValue), value)
+		}
+	}
+	err := buffered.Flush()
+	return counter.n, err
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_, _ = r.WriteTo(w)
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WritesTextExposition(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("test_total", "Things counted.", func() uint64 { return 3 })
+	registry.Gauge("test_gauge", "Line one\nline two.", func() float64 { return 0.5 })
+	registry.GaugeVec("test_by_label", "Per label.", "protocol", func() map[string]float64 {
+		return map[string]float64{"UDP": 2, `a"b`: 1}
+	})
+	registry.Gauge("test_inf", "Infinite.", func() float64 { return math.Inf(1) })
+
+	var out strings.Builder
+	n, err := registry.WriteTo(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_total Things counted.
+# TYPE test_total counter
+test_total 3
+# HELP test_gauge Line one\nline two.
+# TYPE test_gauge gauge
+test_gauge 0.5
+# HELP test_by_label Per label.
+# TYPE test_by_label gauge
+test_by_label{protocol="UDP"} 2
+test_by_label{protocol="a\"b"} 1
+# HELP test_inf Infinite.
+# TYPE test_inf gauge
+test_inf +Inf
+`
+	if out.String() != want {
+		t.Fatalf("exposition mismatch:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if n != int64(len(want)) {
+		t.Fatalf("WriteTo returned %d, want %d", n, len(want))
+	}
+}
+
+func TestServe_ExposesMetricsUntilCancelled(t *testing.T) {
+	registry := NewRegistry()
+	registry.Counter("test_total", "Things counted.", func() uint64 { return 7 })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serve(ctx, listener, registry) }()
+
+	response, err := http.Get("http://" + listener.Addr().String() + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+	if got := response.Header.Get("Content-Type"); got != ContentType {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if !strings.Contains(string(body), "test_total 7\n") {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("serve returned %v", err)
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:9464", "127.0.0.2:9464", "[::1]:9464"} {
+		if _, err := ParseAddress(address, false); err != nil {
+			t.Fatalf("ParseAddress(%q) = %v", address, err)
+		}
+	}
+	for _, address := range []string{"", "localhost:9464", "127.0.0.1", "127.0.0.1:0", "0.0.0.0:9100", "[::]:9100", "10.0.0.1:9100"} {
+		if _, err := ParseAddress(address, false); err == nil {
+			t.Fatalf("ParseAddress(%q) succeeded", address)
+		}
+	}
+	for _, address := range []string{"0.0.0.0:9100", "10.0.0.1:9100"} {
+		if _, err := ParseAddress(address, true); err != nil {
+			t.Fatalf("ParseAddress(%q) with allowRemote = %v", address, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+const shutdownTimeout = 2 * time.Second
+
+// ParseAddress validates a metrics listen address. Only IP literals are
+// accepted so that the listener binds exactly where it was configured. The
+// metrics are served without authentication, so addresses other than
+// loopback ones need allowRemote.
+func ParseAddress(address string, allowRemote bool) (netip.AddrPort, error) {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid metrics address %q: %w", address, err)
+	}
+	if addrPort.Port() == 0 {
+		return netip.AddrPort{}, fmt.Errorf("invalid metrics address %q: port must be set", address)
+	}
+	if !allowRemote && !addrPort.Addr().IsLoopback() {
+		return netip.AddrPort{}, fmt.Errorf("invalid metrics address %q: not a loopback address and MetricsAllowRemote is not set", address)
+	}
+	return addrPort, nil
+}
+
+// Serve exposes registry on /metrics at address until ctx is cancelled.
+func Serve(ctx context.Context, address string, allowRemote bool, registry *Registry) error {
+	addrPort, err := ParseAddress(address, allowRemote)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", addrPort.String())
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+	return serve(ctx, listener, registry)
+}
+
+func serve(ctx context.Context, listener net.Listener, registry *Registry) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("metrics listening", "address", listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
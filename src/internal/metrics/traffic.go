@@ -0,0 +1,13 @@
+package metrics
+
+import "tungo/internal/trafficstats"
+
+// RegisterTraffic exports the totals of the global traffic collector.
+func RegisterTraffic(registry *Registry) {
+	registry.Counter("tungo_rx_bytes_total",
+		"Plaintext bytes received from the tunnel.",
+		func() uint64 { return trafficstats.SnapshotGlobal().RXBytesTotal })
+	registry.Counter("tungo_tx_bytes_total",
+		"Plaintext bytes sent into the tunnel.",
+		func() uint64 { return trafficstats.SnapshotGlobal().TXBytesTotal })
+}
@@ -1,18 +1,61 @@
 package chacha20
 
 import (
+	"crypto/aes"
 	"crypto/cipher"
 	"fmt"
 
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
+// Cipher is the AEAD of a session. Both ciphers take 32-byte keys and 12-byte
+// nonces and add a 16-byte tag, so the UDP and TCP framing, nonces and epochs
+// do not depend on the cipher.
+type Cipher byte
+
+const (
+	ChaCha20Poly1305 Cipher = iota
+	AES256GCM
+)
+
+// New creates the AEAD for key.
+func (c Cipher) New(key []byte) (cipher.AEAD, error) {
+	switch c {
+	case ChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	case AES256GCM:
+		if len(key) != chacha20poly1305.KeySize {
+			return nil, fmt.Errorf("invalid AES-256-GCM key size %d", len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	default:
+		return nil, fmt.Errorf("unknown cipher %d", c)
+	}
+}
+
+func (c Cipher) String() string {
+	switch c {
+	case ChaCha20Poly1305:
+		return "ChaCha20-Poly1305"
+	case AES256GCM:
+		return "AES-256-GCM"
+	default:
+		return fmt.Sprintf("Cipher(%d)", byte(c))
+	}
+}
+
 // KeyMaterial is the part of a completed handshake needed to start an
 // encrypted session. The interface lives here because ChaCha20 consumes it.
 type KeyMaterial interface {
 	ID() [32]byte
 	KeyClientToServer() []byte
 	KeyServerToClient() []byte
+	// Cipher is the AEAD negotiated in the handshake.
+	Cipher() Cipher
 }
 
 // NewAEADsFromHandshake creates send and receive ciphers for the local peer.
@@ -34,11 +77,11 @@ func NewAEADsFromHandshake(
 		sendKey, recvKey = s2c, c2s
 	}
 
-	send, err = chacha20poly1305.New(sendKey)
+	send, err = h.Cipher().New(sendKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("create send AEAD: %w", err)
 	}
-	recv, err = chacha20poly1305.New(recvKey)
+	recv, err = h.Cipher().New(recvKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("create receive AEAD: %w", err)
 	}
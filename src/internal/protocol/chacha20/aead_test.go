@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/hex"
+	"fmt"
 	"io"
 	"testing"
 
@@ -11,8 +12,9 @@ import (
 )
 
 type fakeHandshake struct {
-	s2c []byte
-	c2s []byte
+	s2c    []byte
+	c2s    []byte
+	cipher Cipher
 }
 
 func (f fakeHandshake) ID() [32]byte {
@@ -28,6 +30,7 @@ func (f fakeHandshake) ClientSideHandshake(_ io.ReadWriter) error {
 }
 
 func (f fakeHandshake) KeyServerToClient() []byte { return f.s2c }
+func (f fakeHandshake) Cipher() Cipher            { return f.cipher }
 func (f fakeHandshake) KeyClientToServer() []byte { return f.c2s }
 
 func sealOpen(t *testing.T, send, recv interface {
@@ -100,3 +103,82 @@ func TestNewAEADsFromHandshake_InvalidKeySizes(t *testing.T) {
 		})
 	}
 }
+
+func TestCipher_New(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, chacha20poly1305.KeySize)
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	var sealed [][]byte
+	for _, c := range []Cipher{ChaCha20Poly1305, AES256GCM} {
+		aead, err := c.New(key)
+		if err != nil {
+			t.Fatalf("%s: %v", c, err)
+		}
+		// The framing reserves the same nonce and tag space for both.
+		if aead.NonceSize() != chacha20poly1305.NonceSize || aead.Overhead() != chacha20poly1305.Overhead {
+			t.Fatalf("%s: nonce %d, overhead %d", c, aead.NonceSize(), aead.Overhead())
+		}
+		sealed = append(sealed, aead.Seal(nil, nonce, []byte("payload"), nil))
+	}
+	if bytes.Equal(sealed[0], sealed[1]) {
+		t.Fatal("both ciphers produced the same ciphertext")
+	}
+	if _, err := AES256GCM.New(key[:16]); err == nil {
+		t.Fatal("expected an error for an AES-128 key")
+	}
+	if _, err := Cipher(9).New(key); err == nil {
+		t.Fatal("expected an error for an unknown cipher")
+	}
+}
+
+func TestNewAEADsFromHandshake_AES256GCM(t *testing.T) {
+	h := fakeHandshake{
+		s2c:    bytes.Repeat([]byte{1}, chacha20poly1305.KeySize),
+		c2s:    bytes.Repeat([]byte{2}, chacha20poly1305.KeySize),
+		cipher: AES256GCM,
+	}
+	serverSend, serverRecv, err := NewAEADsFromHandshake(h, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientSend, clientRecv, err := NewAEADsFromHandshake(h, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealOpen(t, serverSend, clientRecv)
+	sealOpen(t, clientSend, serverRecv)
+
+	h.cipher = ChaCha20Poly1305
+	_, chachaRecv, err := NewAEADsFromHandshake(h, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	if _, err := chachaRecv.Open(nil, nonce, clientSend.Seal(nil, nonce, []byte("x"), nil), nil); err == nil {
+		t.Fatal("ChaCha20-Poly1305 opened an AES-256-GCM packet")
+	}
+}
+
+func BenchmarkCipher_SealOpen(b *testing.B) {
+	key := bytes.Repeat([]byte{7}, chacha20poly1305.KeySize)
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	aad := make([]byte, 60)
+	for _, c := range []Cipher{ChaCha20Poly1305, AES256GCM} {
+		for _, size := range []int{64, 1400, 9000} {
+			b.Run(fmt.Sprintf("%s/%d", c, size), func(b *testing.B) {
+				aead, err := c.New(key)
+				if err != nil {
+					b.Fatal(err)
+				}
+				buf := make([]byte, size, size+aead.Overhead())
+				b.SetBytes(int64(size))
+				b.ReportAllocs()
+				for b.Loop() {
+					sealed := aead.Seal(buf[:0], nonce, buf[:size], aad)
+					if _, err := aead.Open(sealed[:0], nonce, sealed, aad); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
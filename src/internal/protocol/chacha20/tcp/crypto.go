@@ -25,7 +25,7 @@ type epochSession struct {
 //
 // Every encrypted frame is prefixed with a 2-byte epoch tag:
 //
-//	[2B epoch BE] [ciphertext + Poly1305 tag]
+//	[2B epoch BE] [ciphertext + 16B tag]
 type Crypto struct {
 	mu           sync.RWMutex
 	recvNewest   epochSession
@@ -34,6 +34,8 @@ type Crypto struct {
 	sessionId    [32]byte
 	isServer     bool
 	epochCounter uint16
+	// aead builds the ciphers of new epochs.
+	aead chacha20.Cipher
 }
 
 func NewCrypto(id [32]byte, sendCipher, recvCipher cipher.AEAD, isServer bool) *Crypto {
@@ -127,11 +129,11 @@ func (c *Crypto) StageEpoch(c2s, s2c []byte) (uint16, error) {
 	if c.isServer {
 		sendKey, recvKey = recvKey, sendKey
 	}
-	sendCipher, err := chacha20poly1305.New(sendKey)
+	sendCipher, err := c.aead.New(sendKey)
 	if err != nil {
 		return 0, err
 	}
-	recvCipher, err := chacha20poly1305.New(recvKey)
+	recvCipher, err := c.aead.New(recvKey)
 	if err != nil {
 		return 0, err
 	}
@@ -163,6 +165,13 @@ func (c *Crypto) PromoteSendEpoch(epoch uint16) {
 	}
 }
 
+// SendEpoch returns the epoch currently used for outbound encryption.
+func (c *Crypto) SendEpoch() uint16 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.send.epoch
+}
+
 // RetirePreviousEpoch removes a previous epoch after local send and authenticated peer
 // traffic have both advanced to the newest epoch.
 func (c *Crypto) RetirePreviousEpoch() bool {
@@ -207,6 +207,22 @@ func TestCrypto_StageEpoch_SendStillUsesOldEpoch(t *testing.T) {
 	}
 }
 
+func TestCrypto_SendEpochFollowsPromotion(t *testing.T) {
+	client, _ := newCryptoPair(t)
+	epoch, err := client.StageEpoch(randKey(), randKey())
+	if err != nil {
+		t.Fatalf("Rekey: %v", err)
+	}
+	if got := client.SendEpoch(); got != 0 {
+		t.Fatalf("SendEpoch() before promotion = %d, want 0", got)
+	}
+
+	client.PromoteSendEpoch(epoch)
+	if got := client.SendEpoch(); got != epoch {
+		t.Fatalf("SendEpoch() after promotion = %d, want %d", got, epoch)
+	}
+}
+
 func TestCrypto_PromoteSendEpoch_UnknownEpochKeepsConsistentSlot(t *testing.T) {
 	client, server := newCryptoPair(t)
 	if _, err := client.StageEpoch(randKey(), randKey()); err != nil {
@@ -469,3 +485,34 @@ func TestCrypto_Zeroize(t *testing.T) {
 		t.Fatal("expected previous session send nonce to be zeroized")
 	}
 }
+
+func BenchmarkCrypto_EncryptDecrypt(b *testing.B) {
+	const size = 1400
+	for _, aead := range []chacha20.Cipher{chacha20.ChaCha20Poly1305, chacha20.AES256GCM} {
+		b.Run(aead.String(), func(b *testing.B) {
+			// NewFromHandshake zeroes the keys of the handshake.
+			hs := &mockHandshake{id: randID(), server: randKey(), client: randKey(), cipher: aead}
+			peer := &mockHandshake{id: hs.id, server: bytes.Clone(hs.server), client: bytes.Clone(hs.client), cipher: aead}
+			client, _, err := NewFromHandshake(hs, false)
+			if err != nil {
+				b.Fatal(err)
+			}
+			server, _, err := NewFromHandshake(peer, true)
+			if err != nil {
+				b.Fatal(err)
+			}
+			buf := make([]byte, EpochPrefixSize+size, EpochPrefixSize+size+chacha20poly1305.Overhead)
+			b.SetBytes(size)
+			b.ReportAllocs()
+			for b.Loop() {
+				encrypted, err := client.Encrypt(buf[:EpochPrefixSize+size])
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := server.Decrypt(encrypted); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@ func NewFromHandshake(handshake chacha20.KeyMaterial,
 	}
 
 	core := NewCrypto(handshake.ID(), sendCipher, recvCipher, isServer)
+	core.aead = handshake.Cipher()
 	// Directional raw keys live in controller for rekey derivation.
 	c2s := handshake.KeyClientToServer()
 	s2c := handshake.KeyServerToClient()
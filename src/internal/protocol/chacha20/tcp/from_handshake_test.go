@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"testing"
+	"tungo/internal/protocol/chacha20"
 
 	"golang.org/x/crypto/chacha20poly1305"
 )
@@ -13,11 +14,13 @@ type mockHandshake struct {
 	id     [32]byte
 	server []byte
 	client []byte
+	cipher chacha20.Cipher
 }
 
 func (m *mockHandshake) ID() [32]byte              { return m.id }
 func (m *mockHandshake) KeyServerToClient() []byte { return m.server }
 func (m *mockHandshake) KeyClientToServer() []byte { return m.client }
+func (m *mockHandshake) Cipher() chacha20.Cipher   { return m.cipher }
 func (m *mockHandshake) ServerSideHandshake(_ io.ReadWriter) (int, error) {
 	return 0, nil
 }
@@ -152,3 +155,16 @@ func TestFactory_FromHandshake_Client_InvalidServerKey(t *testing.T) {
 		t.Fatalf("expected nil controller")
 	}
 }
+
+func TestFactory_FromHandshake_AES256GCM(t *testing.T) {
+	keyGen := testKeyGenerator{}
+	hs := &mockHandshake{server: keyGen.validKey(), client: keyGen.validKey(), cipher: chacha20.AES256GCM}
+	c, _, err := NewFromHandshake(hs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Epochs staged by rekeys must use the negotiated cipher too.
+	if c.aead != chacha20.AES256GCM {
+		t.Fatalf("epoch cipher = %s, want %s", c.aead, chacha20.AES256GCM)
+	}
+}
@@ -25,6 +25,8 @@ type Crypto struct {
 	rekeyMu      sync.Mutex
 	sendEpoch    uint16
 	epochCounter uint16
+	// aead builds the ciphers of new epochs.
+	aead chacha20.Cipher
 }
 
 func NewCrypto(
@@ -121,11 +123,11 @@ func (c *Crypto) StageEpoch(c2s, s2c []byte) (uint16, error) {
 	if c.isServer {
 		sendKey, recvKey = recvKey, sendKey
 	}
-	sendCipher, err := chacha20poly1305.New(sendKey)
+	sendCipher, err := c.aead.New(sendKey)
 	if err != nil {
 		return 0, fmt.Errorf("rekey: build send cipher: %w", err)
 	}
-	recvCipher, err := chacha20poly1305.New(recvKey)
+	recvCipher, err := c.aead.New(recvKey)
 	if err != nil {
 		return 0, fmt.Errorf("rekey: build recv cipher: %w", err)
 	}
@@ -143,6 +145,11 @@ func (c *Crypto) PromoteSendEpoch(epoch uint16) {
 	c.mu.Unlock()
 }
 
+// SendEpoch returns the epoch currently used for outbound encryption.
+func (c *Crypto) SendEpoch() uint16 {
+	return c.currentSendEpoch()
+}
+
 func (c *Crypto) currentSendEpoch() uint16 {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -1,6 +1,7 @@
 package udp
 
 import (
+	"bytes"
 	"crypto/cipher"
 	"encoding/binary"
 	"errors"
@@ -288,6 +289,9 @@ func TestCrypto_PromoteSendEpoch(t *testing.T) {
 	if c.currentSendEpoch() != 42 {
 		t.Fatalf("expected sendEpoch=42, got %d", c.currentSendEpoch())
 	}
+	if c.SendEpoch() != 42 {
+		t.Fatalf("expected SendEpoch()=42, got %d", c.SendEpoch())
+	}
 }
 
 func TestCrypto_StageEpoch_BadKey(t *testing.T) {
@@ -380,3 +384,34 @@ func TestCrypto_RouteID(t *testing.T) {
 		t.Fatalf("unexpected route id: got %x", got)
 	}
 }
+
+func BenchmarkCrypto_EncryptDecrypt(b *testing.B) {
+	const size = 1400
+	for _, aead := range []chacha20.Cipher{chacha20.ChaCha20Poly1305, chacha20.AES256GCM} {
+		b.Run(aead.String(), func(b *testing.B) {
+			// NewFromHandshake zeroes the keys of the handshake.
+			hs := &mockHandshake{id: randID(), server: randKey(), client: randKey(), cipher: aead}
+			peer := &mockHandshake{id: hs.id, server: bytes.Clone(hs.server), client: bytes.Clone(hs.client), cipher: aead}
+			client, _, err := NewFromHandshake(hs, false)
+			if err != nil {
+				b.Fatal(err)
+			}
+			server, _, err := NewFromHandshake(peer, true)
+			if err != nil {
+				b.Fatal(err)
+			}
+			buf := make([]byte, PayloadOffset+size, PayloadOffset+size+chacha20poly1305.Overhead)
+			b.SetBytes(size)
+			b.ReportAllocs()
+			for b.Loop() {
+				encrypted, err := client.Encrypt(buf[:PayloadOffset+size])
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := server.Decrypt(encrypted); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
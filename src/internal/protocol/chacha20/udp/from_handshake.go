@@ -20,6 +20,7 @@ func NewFromHandshake(
 	s2c := handshake.KeyServerToClient()
 
 	core := NewCrypto(handshake.ID(), sendCipher, recvCipher, isServer)
+	core.aead = handshake.Cipher()
 	sm := rekey.NewStateMachine(core, c2s, s2c)
 	securemem.ZeroBytes(c2s)
 	securemem.ZeroBytes(s2c)
@@ -3,7 +3,10 @@ package keys
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
 	"io"
+	"strings"
 
 	"golang.org/x/crypto/chacha20poly1305"
 	"golang.org/x/crypto/curve25519"
@@ -29,6 +32,31 @@ func (d *DefaultKeyDeriver) GenerateX25519KeyPair() ([]byte, [32]byte, error) {
 	return public, private, err
 }
 
+// EncodeKey encodes a key as base64, as configuration files store keys.
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParseKey decodes a 32-byte key made by EncodeKey.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("key is not base64: %w", err)
+	}
+	if len(key) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("invalid key length %d, expected %d", len(key), curve25519.ScalarSize)
+	}
+	return key, nil
+}
+
+// PublicKey derives the X25519 public key of a 32-byte private key.
+func PublicKey(private []byte) ([]byte, error) {
+	if len(private) != curve25519.ScalarSize {
+		return nil, fmt.Errorf("invalid private key length %d, expected %d", len(private), curve25519.ScalarSize)
+	}
+	return curve25519.X25519(private, curve25519.Basepoint)
+}
+
 func (d *DefaultKeyDeriver) DeriveKey(sharedSecret, salt, info []byte) ([]byte, error) {
 	r := hkdf.New(sha256.New, sharedSecret, salt, info)
 	key := make([]byte, chacha20poly1305.KeySize)
@@ -73,3 +73,32 @@ func TestDefaultKeyDeriver_DeriveKey(t *testing.T) {
 		t.Fatal("expected different output for different info context")
 	}
 }
+
+func TestPublicKey(t *testing.T) {
+	d := &DefaultKeyDeriver{}
+	pub, priv, err := d.GenerateX25519KeyPair()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := PublicKey(priv[:])
+	if err != nil || string(got) != string(pub) {
+		t.Fatalf("PublicKey() = %x, %v; want %x", got, err, pub)
+	}
+	if _, err := PublicKey(priv[:31]); err == nil {
+		t.Fatal("expected error for a short private key")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := make([]byte, 32)
+	key[0] = 7
+	got, err := ParseKey(" " + EncodeKey(key) + "\n")
+	if err != nil || string(got) != string(key) {
+		t.Fatalf("ParseKey() = %x, %v; want %x", got, err, key)
+	}
+	for _, raw := range []string{"", "not base64!", EncodeKey(key[:16])} {
+		if _, err := ParseKey(raw); err == nil {
+			t.Errorf("ParseKey(%q) expected error", raw)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package noise
 
+import "net/netip"
+
 type testPeer struct {
-	PublicKey []byte
-	Enabled   bool
-	ClientID  int
+	PublicKey  []byte
+	Enabled    bool
+	ClientID   int
+	AllowedIPs []netip.Prefix
+	// PresharedKey is the peer's pre-shared key; nil for none.
+	PresharedKey []byte
 }
 
 type testPeers map[string]testPeer
@@ -16,7 +21,12 @@ func newTestAllowedPeers(peers []testPeer) AllowedPeersLookup {
 	return lookup
 }
 
-func (a testPeers) Lookup(publicKey []byte) (int, bool, bool) {
+func (a testPeers) Lookup(publicKey []byte) (PeerAccess, bool) {
 	peer, ok := a[string(publicKey)]
-	return peer.ClientID, peer.Enabled, ok
+	return PeerAccess{
+		ClientID:     peer.ClientID,
+		Enabled:      peer.Enabled,
+		AllowedIPs:   peer.AllowedIPs,
+		PresharedKey: peer.PresharedKey,
+	}, ok
 }
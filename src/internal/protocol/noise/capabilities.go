@@ -1,10 +1,149 @@
 package noise
 
+import (
+	"crypto/mlkem"
+	"encoding/binary"
+	"fmt"
+)
+
 // Capability identifies an optional feature advertised in the authenticated
-// Noise handshake payload. A capability set is encoded as one byte per entry.
+// Noise handshake payload.
+//
+// A payload starts with CapabilityRekeyV2 as a single byte, which is all that
+// peers predating capability records send and look for. Every other capability
+// follows as a record: the capability byte, the length of its data as two
+// big-endian bytes, and the data. Records of unknown capabilities are skipped.
 type Capability byte
 
 const (
 	CapabilityUnknown Capability = iota
 	CapabilityRekeyV2
+	// CapabilityTimestamp carries in msg1 a TAI64N Timestamp used for replay
+	// protection.
+	CapabilityTimestamp
+	// CapabilityPresharedKey marks a Noise IKpsk2 handshake, whose rekeys
+	// mix in the pre-shared key too. It has no data.
+	CapabilityPresharedKey
+	// CapabilityMLKEM768 carries in msg1 the initiator's ML-KEM-768
+	// encapsulation key and in msg2 the ciphertext. The shared secret is
+	// mixed into the traffic keys of the session and of every rekey.
+	CapabilityMLKEM768
+	// CapabilityAES256GCM offers AES-256-GCM instead of ChaCha20-Poly1305 as
+	// the transport AEAD.
+	CapabilityAES256GCM
 )
+
+// recordHeaderSize is the size of the capability and length of a record.
+const recordHeaderSize = 3
+
+// appendRecord appends the record of capability with data to payload.
+func appendRecord(payload []byte, capability Capability, data []byte) []byte {
+	payload = append(payload, byte(capability))
+	payload = binary.BigEndian.AppendUint16(payload, uint16(len(data)))
+	return append(payload, data...)
+}
+
+// capabilitySet maps the capabilities of a payload to their data.
+type capabilitySet map[Capability][]byte
+
+// decodeCapabilities decodes a payload. The data in the returned set aliases
+// payload.
+func decodeCapabilities(payload []byte) (capabilitySet, error) {
+	set := capabilitySet{}
+	if len(payload) > 0 && Capability(payload[0]) == CapabilityRekeyV2 {
+		set[CapabilityRekeyV2] = nil
+		payload = payload[1:]
+	}
+	for len(payload) > 0 {
+		capability := Capability(payload[0])
+		if len(payload) < recordHeaderSize {
+			return nil, invalidRecord(capability)
+		}
+		n := int(binary.BigEndian.Uint16(payload[1:recordHeaderSize]))
+		if len(payload)-recordHeaderSize < n {
+			return nil, invalidRecord(capability)
+		}
+		if _, ok := set[capability]; ok || capability == CapabilityRekeyV2 {
+			return nil, invalidRecord(capability)
+		}
+		set[capability] = payload[recordHeaderSize : recordHeaderSize+n]
+		payload = payload[recordHeaderSize+n:]
+	}
+	return set, nil
+}
+
+// invalidRecord returns the error for a malformed record of capability.
+func invalidRecord(capability Capability) error {
+	switch capability {
+	case CapabilityTimestamp:
+		return ErrInvalidTimestamp
+	case CapabilityMLKEM768:
+		return ErrInvalidEncapsulation
+	default:
+		return fmt.Errorf("%w: capability %d", ErrInvalidCapabilities, capability)
+	}
+}
+
+func (s capabilitySet) has(capability Capability) bool {
+	_, ok := s[capability]
+	return ok
+}
+
+// data returns the data of capability, which must be size bytes long.
+func (s capabilitySet) data(capability Capability, size int) ([]byte, bool, error) {
+	data, ok := s[capability]
+	if !ok {
+		return nil, false, nil
+	}
+	if len(data) != size {
+		return nil, false, invalidRecord(capability)
+	}
+	return data, true, nil
+}
+
+// initiatorPayload is the decoded msg1 payload.
+type initiatorPayload struct {
+	capabilities     capabilitySet
+	timestamp        *Timestamp
+	encapsulationKey []byte
+}
+
+// encodeInitiatorPayload advertises the capabilities of this client. A nil
+// encapsulationKey leaves out CapabilityMLKEM768. Capabilities without data
+// are listed in extra.
+func encodeInitiatorPayload(ts Timestamp, encapsulationKey []byte, extra ...Capability) []byte {
+	payload := make([]byte, 0, 1+recordHeaderSize*(2+len(extra))+TimestampSize+len(encapsulationKey))
+	payload = append(payload, byte(CapabilityRekeyV2))
+	for _, capability := range extra {
+		payload = appendRecord(payload, capability, nil)
+	}
+	payload = appendRecord(payload, CapabilityTimestamp, ts[:])
+	if encapsulationKey != nil {
+		payload = appendRecord(payload, CapabilityMLKEM768, encapsulationKey)
+	}
+	return payload
+}
+
+func decodeInitiatorPayload(payload []byte) (initiatorPayload, error) {
+	set, err := decodeCapabilities(payload)
+	if err != nil {
+		return initiatorPayload{}, err
+	}
+	decoded := initiatorPayload{capabilities: set}
+	ts, ok, err := set.data(CapabilityTimestamp, TimestampSize)
+	if err != nil {
+		return initiatorPayload{}, err
+	}
+	if ok {
+		decoded.timestamp = new(Timestamp)
+		copy(decoded.timestamp[:], ts)
+	}
+	if decoded.encapsulationKey, _, err = set.data(CapabilityMLKEM768, mlkem.EncapsulationKeySize768); err != nil {
+		return initiatorPayload{}, err
+	}
+	return decoded, nil
+}
+
+func (p initiatorPayload) advertises(capability Capability) bool {
+	return p.capabilities.has(capability)
+}
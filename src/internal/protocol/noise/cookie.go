@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 	"tungo/internal/protocol/securemem"
 
@@ -34,6 +35,8 @@ type CookieManager struct {
 	mu     sync.RWMutex
 	secret [32]byte
 	now    func() time.Time
+	// replies counts cookie replies issued under load.
+	replies atomic.Uint64
 }
 
 // NewCookieManager creates a new CookieManager with a random secret.
@@ -113,9 +116,15 @@ func (cm *CookieManager) CreateCookieReply(clientIP netip.Addr, clientEphemeral,
 	copy(reply[:CookieNonceSize], nonce[:])
 	aead.Seal(reply[CookieNonceSize:CookieNonceSize], nonce[:], cookieValue, clientEphemeral)
 
+	cm.replies.Add(1)
 	return reply, nil
 }
 
+// RepliesIssued returns the number of cookie replies created so far.
+func (cm *CookieManager) RepliesIssued() uint64 {
+	return cm.replies.Load()
+}
+
 // DecryptCookieReply decrypts a cookie reply received from the server.
 // Used by the client after receiving a cookie challenge.
 func DecryptCookieReply(reply, clientEphemeral, serverPubKey []byte) ([]byte, error) {
@@ -55,6 +55,9 @@ func TestCookie_EncryptionDecryption(t *testing.T) {
 	if len(reply) != CookieReplySize {
 		t.Fatalf("cookie reply should be %d bytes, got %d", CookieReplySize, len(reply))
 	}
+	if cm.RepliesIssued() != 1 {
+		t.Fatalf("RepliesIssued = %d, want 1", cm.RepliesIssued())
+	}
 
 	// Decrypt
 	decrypted, err := DecryptCookieReply(reply, clientEphemeral, serverPubKey)
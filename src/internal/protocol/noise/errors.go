@@ -23,6 +23,28 @@ var (
 	// ErrPeerDisabled indicates the client is disabled in AllowedPeers.
 	ErrPeerDisabled = errors.New("peer disabled")
 
+	// ErrStaleInitiation indicates a msg1 whose timestamp is not newer than
+	// the last one accepted from the same client, i.e. a replay.
+	ErrStaleInitiation = errors.New("stale handshake initiation")
+
+	// ErrInvalidTimestamp indicates a malformed msg1 timestamp.
+	ErrInvalidTimestamp = errors.New("invalid handshake timestamp")
+
+	// ErrPresharedKeyMismatch indicates that only one side of a handshake
+	// has a pre-shared key for the client.
+	ErrPresharedKeyMismatch = errors.New("pre-shared key mismatch")
+
+	// ErrInvalidEncapsulation indicates a malformed ML-KEM encapsulation key
+	// or ciphertext.
+	ErrInvalidEncapsulation = errors.New("invalid ML-KEM encapsulation")
+
+	// ErrRejected indicates a rejection reply from the server: it read msg1
+	// and refused the client key.
+	ErrRejected = errors.New("handshake rejected by server")
+
+	// ErrInvalidCapabilities indicates a malformed capability record.
+	ErrInvalidCapabilities = errors.New("invalid handshake capabilities")
+
 	// ErrUnknownProtocol indicates an unknown protocol version.
 	ErrUnknownProtocol = errors.New("unknown protocol version")
 
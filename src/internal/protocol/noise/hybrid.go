@@ -0,0 +1,67 @@
+package noise
+
+import (
+	"crypto/mlkem"
+	"crypto/sha256"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	"tungo/internal/protocol/securemem"
+)
+
+const hybridMixLabel = "tungo ml-kem-768 traffic keys v1"
+
+// encapsulate makes the ML-KEM shared secret and ciphertext for the initiator's
+// encapsulation key.
+func encapsulate(encapsulationKey []byte) (sharedKey, ciphertext []byte, err error) {
+	ek, err := mlkem.NewEncapsulationKey768(encapsulationKey)
+	if err != nil {
+		return nil, nil, ErrInvalidEncapsulation
+	}
+	sharedKey, ciphertext = ek.Encapsulate()
+	return sharedKey, ciphertext, nil
+}
+
+func decapsulate(dk *mlkem.DecapsulationKey768, ciphertext []byte) ([]byte, error) {
+	if dk == nil {
+		return nil, ErrInvalidEncapsulation
+	}
+	sharedKey, err := dk.Decapsulate(ciphertext)
+	if err != nil {
+		return nil, ErrInvalidEncapsulation
+	}
+	return sharedKey, nil
+}
+
+// mixSecret derives the traffic keys from the Noise keys and secret, bound to
+// the session ID, and zeroes the Noise keys.
+func mixSecret(material sessionMaterial, secret []byte, salt []byte, label string) sessionMaterial {
+	ikm := append(append(append([]byte(nil), material.clientKey...), material.serverKey...), secret...)
+	defer securemem.ZeroBytes(ikm)
+	securemem.ZeroBytes(material.clientKey)
+	securemem.ZeroBytes(material.serverKey)
+
+	info := append([]byte(label), material.id[:]...)
+	r := hkdf.New(sha256.New, ikm, salt, info)
+	mixed := sessionMaterial{
+		id:        material.id,
+		clientKey: make([]byte, 32),
+		serverKey: make([]byte, 32),
+	}
+	// HKDF-SHA256 yields up to 8160 bytes; 64 cannot fail.
+	_, _ = io.ReadFull(r, mixed.clientKey)
+	_, _ = io.ReadFull(r, mixed.serverKey)
+	return mixed
+}
+
+// mixHybridSecret mixes the ML-KEM shared secret into material, so that the
+// session stays confidential if X25519 is broken, e.g. by a quantum computer,
+// as long as ML-KEM is not. A nil sharedKey leaves material as it is.
+func mixHybridSecret(material sessionMaterial, sharedKey []byte) sessionMaterial {
+	if sharedKey == nil {
+		return material
+	}
+	defer securemem.ZeroBytes(sharedKey)
+	return mixSecret(material, sharedKey, nil, hybridMixLabel)
+}
@@ -0,0 +1,253 @@
+package noise
+
+import (
+	"bytes"
+	"crypto/mlkem"
+	"crypto/rand"
+	"errors"
+	"maps"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	transport "tungo/internal/transport/tcp"
+)
+
+func TestCapabilityRecords(t *testing.T) {
+	data := make([]byte, mlkem.EncapsulationKeySize768)
+	_, _ = rand.Read(data)
+	payload := append([]byte{byte(CapabilityRekeyV2)}, 0x7f, 0, 2, 0xaa, 0xbb)
+	payload = appendRecord(payload, CapabilityAES256GCM, nil)
+	payload = appendRecord(payload, CapabilityMLKEM768, data)
+
+	set, err := decodeCapabilities(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.has(CapabilityRekeyV2) || !set.has(CapabilityAES256GCM) || !bytes.Equal(set[CapabilityMLKEM768], data) {
+		t.Fatalf("unexpected capabilities %v", slices.Collect(maps.Keys(set)))
+	}
+	if len(set) != 4 {
+		t.Fatalf("decoded %d capabilities, want 4 with the unknown one", len(set))
+	}
+	if _, _, err := set.data(CapabilityMLKEM768, mlkem.CiphertextSize768); !errors.Is(err, ErrInvalidEncapsulation) {
+		t.Fatalf("expected ErrInvalidEncapsulation for a wrong size, got %v", err)
+	}
+
+	for name, src := range map[string][]byte{
+		"truncated header": {byte(CapabilityRekeyV2), 0x7f, 0},
+		"truncated data":   payload[:len(payload)-1],
+		"duplicate":        appendRecord(slices.Clone(payload), CapabilityAES256GCM, nil),
+		"rekey record":     appendRecord([]byte{byte(CapabilityRekeyV2)}, CapabilityRekeyV2, nil),
+	} {
+		if _, err := decodeCapabilities(src); err == nil {
+			t.Fatalf("%s: decodeCapabilities succeeded", name)
+		}
+	}
+	if set, err := decodeCapabilities(nil); err != nil || len(set) != 0 {
+		t.Fatalf("empty payload: %v, %v", set, err)
+	}
+}
+
+// decodeLegacyPayload decodes a msg1 payload as servers predating capability
+// records do.
+func decodeLegacyPayload(payload []byte) bool {
+	return slices.Contains(payload, byte(CapabilityRekeyV2))
+}
+
+func TestInitiatorPayload_MLKEM768(t *testing.T) {
+	dk, err := mlkem.GenerateKey768()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek := dk.EncapsulationKey().Bytes()
+	ts := NewTimestamp(time.Unix(1_700_000_000, 0))
+	payload := encodeInitiatorPayload(ts, ek)
+
+	decoded, err := decodeInitiatorPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.advertises(CapabilityMLKEM768) || !bytes.Equal(decoded.encapsulationKey, ek) || *decoded.timestamp != ts {
+		t.Fatalf("unexpected payload %+v", decoded)
+	}
+	if !decodeLegacyPayload(payload) {
+		t.Fatal("legacy server cannot decode the payload")
+	}
+	if _, err := decodeInitiatorPayload(payload[:len(payload)-1]); !errors.Is(err, ErrInvalidEncapsulation) {
+		t.Fatalf("expected ErrInvalidEncapsulation for a truncated key, got %v", err)
+	}
+}
+
+// hybridTestPeers makes a server and a client that the server allows.
+func hybridTestPeers(t *testing.T) (*IKHandshake, *IKHandshake) {
+	t.Helper()
+	serverKP, err := cipherSuite.GenerateKeypair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKP, err := cipherSuite.GenerateKeypair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedPeers := newTestAllowedPeers([]testPeer{{PublicKey: clientKP.Public, Enabled: true, ClientID: 1}})
+	server := NewIKHandshakeServer(serverKP.Public, serverKP.Private, allowedPeers, nil, nil, NewReplayGuard())
+	client := NewIKHandshakeClient(clientKP.Public, clientKP.Private, serverKP.Public)
+	return server, client
+}
+
+func hybridTestConns(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		closeTestConn(clientConn)
+		closeTestConn(serverConn)
+	})
+	return clientConn, serverConn
+}
+
+func TestIKHandshake_MLKEM768(t *testing.T) {
+	server, client := hybridTestPeers(t)
+	clientConn, serverConn := hybridTestConns(t)
+	clientTransport, _ := transport.NewFramedConn(clientConn, 2048)
+	serverTransport, _ := transport.NewFramedConn(serverConn, 2048)
+	completeTestHandshake(t, server, client, serverTransport, clientTransport)
+
+	if !server.Supports(CapabilityMLKEM768) || !client.Supports(CapabilityMLKEM768) {
+		t.Fatal("ML-KEM-768 was not negotiated")
+	}
+	if !bytes.Equal(server.KeyClientToServer(), client.KeyClientToServer()) ||
+		!bytes.Equal(server.KeyServerToClient(), client.KeyServerToClient()) {
+		t.Fatal("traffic keys do not match")
+	}
+
+	prologue := []byte("current session")
+	msg1, err := client.StartRekeyV2(prologue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg2, serverC2S, serverS2C, err := server.RespondRekeyV2(prologue, msg1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientC2S, clientS2C, err := client.FinishRekeyV2(msg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(serverC2S, clientC2S) || !bytes.Equal(serverS2C, clientS2C) {
+		t.Fatal("rekeyed traffic keys do not match")
+	}
+	if bytes.Equal(clientC2S, client.KeyClientToServer()) {
+		t.Fatal("rekey kept the traffic key")
+	}
+
+	// A rekey without the encapsulation key must not fall back.
+	hs, err := client.newInitiatorState(prologue, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	classic, _, _, err := hs.WriteMessage(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := server.RespondRekeyV2(prologue, classic); !errors.Is(err, ErrInvalidEncapsulation) {
+		t.Fatalf("expected ErrInvalidEncapsulation, got %v", err)
+	}
+}
+
+func TestIKHandshake_MLKEM768LegacyClient(t *testing.T) {
+	server, client := hybridTestPeers(t)
+	clientConn, serverConn := hybridTestConns(t)
+	clientTransport, _ := transport.NewFramedConn(clientConn, 2048)
+	serverTransport, _ := transport.NewFramedConn(serverConn, 2048)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		_, err := server.ServerSideHandshake(serverTransport)
+		serverErr <- err
+	}()
+	hs, err := client.newInitiatorState(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg1, _, _, err := hs.WriteMessage(nil, encodeInitiatorPayload(nextTimestamp(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg1WithMAC, err := AppendMACs(msg1, client.peerPubKey, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := clientTransport.Write(PrependVersion(msg1WithMAC)); err != nil {
+		t.Fatal(err)
+	}
+	response, err := readHandshakeMessage(clientTransport, "read msg2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	material, err := client.completeInitiatorFromMsg2(hs, response, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+	if server.Supports(CapabilityMLKEM768) || client.Supports(CapabilityMLKEM768) {
+		t.Fatal("ML-KEM-768 negotiated with a client that did not advertise it")
+	}
+	if !bytes.Equal(server.KeyClientToServer(), material.clientKey) {
+		t.Fatal("traffic keys do not match")
+	}
+}
+
+func TestIKHandshake_MLKEM768LegacyServer(t *testing.T) {
+	server, client := hybridTestPeers(t)
+	clientConn, serverConn := hybridTestConns(t)
+	clientTransport, _ := transport.NewFramedConn(clientConn, 2048)
+	serverTransport, _ := transport.NewFramedConn(serverConn, 2048)
+
+	// The server answers as servers without CapabilityMLKEM768 do.
+	serverKey := make(chan []byte, 1)
+	go func() {
+		defer close(serverKey)
+		// Unblocks the client on failure; the test fails on a nil key.
+		defer closeTestConn(serverConn)
+		msg, err := readHandshakeMessage(serverTransport, "read msg1")
+		if err != nil {
+			return
+		}
+		msg1WithMAC, err := CheckVersion(msg)
+		if err != nil {
+			return
+		}
+		hs, err := server.newResponderState(nil, false)
+		if err != nil {
+			return
+		}
+		payload, _, _, err := hs.ReadMessage(nil, ExtractNoiseMsg(msg1WithMAC))
+		if err != nil {
+			return
+		}
+		if !decodeLegacyPayload(payload) {
+			return
+		}
+		msg2, cs1, cs2, err := hs.WriteMessage(nil, []byte{byte(CapabilityRekeyV2)})
+		if err != nil {
+			return
+		}
+		if _, err := serverTransport.Write(msg2); err != nil {
+			return
+		}
+		serverKey <- extractSessionMaterial(cs1, cs2, hs.ChannelBinding()).clientKey
+	}()
+	if err := client.ClientSideHandshake(clientTransport); err != nil {
+		t.Fatal(err)
+	}
+	if client.Supports(CapabilityMLKEM768) {
+		t.Fatal("ML-KEM-768 negotiated with a server that did not select it")
+	}
+	if !bytes.Equal(<-serverKey, client.KeyClientToServer()) {
+		t.Fatal("client did not fall back to the X25519 traffic keys")
+	}
+}
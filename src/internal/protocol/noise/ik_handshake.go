@@ -2,10 +2,12 @@ package noise
 
 import (
 	"bytes"
+	"crypto/mlkem"
 	"fmt"
 	"io"
 	"net/netip"
 	"slices"
+	"tungo/internal/protocol/chacha20"
 	"tungo/internal/protocol/securemem"
 
 	noiselib "github.com/flynn/noise"
@@ -17,7 +19,18 @@ var cipherSuite = noiselib.NewCipherSuite(noiselib.DH25519, noiselib.CipherChaCh
 type AllowedPeersLookup interface {
 	// Lookup returns peer authz data for the given public key.
 	// found=false means unknown peer.
-	Lookup(pubKey []byte) (clientID int, enabled bool, found bool)
+	Lookup(pubKey []byte) (access PeerAccess, found bool)
+}
+
+// PeerAccess is the authorization data of one configured client.
+type PeerAccess struct {
+	ClientID int
+	Enabled  bool
+	// AllowedIPs are networks behind the client routed into its session.
+	AllowedIPs []netip.Prefix
+	// PresharedKey, when set, is required from the client and mixed into
+	// the handshake with Noise IKpsk2.
+	PresharedKey []byte
 }
 
 // IKHandshake implements Noise IK handshake with DoS protection.
@@ -26,6 +39,11 @@ type IKHandshake struct {
 	clientKey              []byte
 	serverKey              []byte
 	negotiatedCapabilities []Capability
+	// presharedKey is the client's pre-shared key, configured on the client
+	// and taken from AllowedPeers on the server.
+	presharedKey []byte
+	// offerAES256GCM negotiates AES-256-GCM when the peer offers it too.
+	offerAES256GCM bool
 
 	// Server-side fields
 	serverPubKey  []byte
@@ -33,6 +51,7 @@ type IKHandshake struct {
 	allowedPeers  AllowedPeersLookup
 	cookieManager *CookieManager
 	loadMonitor   *LoadMonitor
+	replayGuard   *ReplayGuard
 
 	// Client-side fields
 	clientPubKey  []byte
@@ -45,9 +64,12 @@ type IKHandshake struct {
 
 	// Cookie for retry (client-side)
 	cookie []byte
+	// hybridKey decapsulates the ML-KEM ciphertext of msg2 (client-side).
+	hybridKey *mlkem.DecapsulationKey768
 
 	// Rekey V2 keeps the initiator state between Init and Ack.
-	pendingRekey *noiselib.HandshakeState
+	pendingRekey          *noiselib.HandshakeState
+	pendingRekeyHybridKey *mlkem.DecapsulationKey768
 }
 
 type sessionMaterial struct {
@@ -59,16 +81,20 @@ type sessionMaterial struct {
 type serverHandshakeOutcome struct {
 	clientID               int
 	clientPubKey           []byte
+	allowedIPs             []netip.Prefix
 	negotiatedCapabilities []Capability
+	presharedKey           []byte
 	material               sessionMaterial
 }
 
 // NewIKHandshakeServer creates a new IK handshake for server-side use.
+// A nil replayGuard disables initiation replay protection.
 func NewIKHandshakeServer(
 	serverPubKey, serverPrivKey []byte,
 	allowedPeers AllowedPeersLookup,
 	cookieManager *CookieManager,
 	loadMonitor *LoadMonitor,
+	replayGuard *ReplayGuard,
 ) *IKHandshake {
 	return &IKHandshake{
 		serverPubKey:  serverPubKey,
@@ -76,6 +102,7 @@ func NewIKHandshakeServer(
 		allowedPeers:  allowedPeers,
 		cookieManager: cookieManager,
 		loadMonitor:   loadMonitor,
+		replayGuard:   replayGuard,
 	}
 }
 
@@ -83,18 +110,46 @@ func NewIKHandshakeServer(
 func NewIKHandshakeClient(
 	clientPubKey, clientPrivKey []byte,
 	serverPubKey []byte,
+) *IKHandshake {
+	return NewIKHandshakeClientWithPresharedKey(clientPubKey, clientPrivKey, serverPubKey, nil)
+}
+
+// NewIKHandshakeClientWithPresharedKey creates a client-side handshake that
+// mixes presharedKey into the handshake with Noise IKpsk2. The handshake fails
+// unless the server has the same key; an empty presharedKey is a plain IK
+// handshake.
+func NewIKHandshakeClientWithPresharedKey(
+	clientPubKey, clientPrivKey []byte,
+	serverPubKey []byte,
+	presharedKey []byte,
 ) *IKHandshake {
 	return &IKHandshake{
 		clientPubKey:  clientPubKey,
 		clientPrivKey: clientPrivKey,
 		peerPubKey:    serverPubKey,
+		presharedKey:  presharedKey,
 	}
 }
 
+// OfferAES256GCM makes the handshake negotiate AES-256-GCM as the transport
+// AEAD when the peer offers it too. Otherwise the session uses
+// ChaCha20-Poly1305. It must be called before the handshake.
+func (h *IKHandshake) OfferAES256GCM() {
+	h.offerAES256GCM = true
+}
+
 func (h *IKHandshake) ID() [32]byte              { return h.id }
 func (h *IKHandshake) KeyClientToServer() []byte { return h.clientKey }
 func (h *IKHandshake) KeyServerToClient() []byte { return h.serverKey }
 
+// Cipher returns the transport AEAD negotiated during the handshake.
+func (h *IKHandshake) Cipher() chacha20.Cipher {
+	if h.Supports(CapabilityAES256GCM) {
+		return chacha20.AES256GCM
+	}
+	return chacha20.ChaCha20Poly1305
+}
+
 // Supports reports whether capability was negotiated during the handshake.
 func (h *IKHandshake) Supports(capability Capability) bool {
 	return slices.Contains(h.negotiatedCapabilities, capability)
@@ -121,7 +176,7 @@ func (h *IKHandshake) ServerSideHandshake(transport io.ReadWriter) (int, error)
 	if err != nil {
 		return 0, err
 	}
-	msg1WithMAC, err := CheckVersion(msgWithVersion)
+	version, msg1WithMAC, err := checkVersion(msgWithVersion)
 	if err != nil {
 		return 0, err
 	}
@@ -136,16 +191,18 @@ func (h *IKHandshake) ServerSideHandshake(transport io.ReadWriter) (int, error)
 		return 0, err
 	}
 
-	outcome, err := h.runResponderNoise(transport, msg1WithMAC)
+	outcome, err := h.runResponderNoise(transport, msg1WithMAC, version == ProtocolVersionPSK)
 	if err != nil {
 		return 0, err
 	}
 	h.applySessionMaterial(outcome.material)
 	h.authenticatedClientPubKey = append(h.authenticatedClientPubKey[:0], outcome.clientPubKey...)
+	h.allowedIPs = append(h.allowedIPs[:0], outcome.allowedIPs...)
 	h.negotiatedCapabilities = append(
 		h.negotiatedCapabilities[:0],
 		outcome.negotiatedCapabilities...,
 	)
+	h.presharedKey = outcome.presharedKey
 	return outcome.clientID, nil
 }
 
@@ -159,6 +216,10 @@ func (h *IKHandshake) ClientSideHandshake(transport io.ReadWriter) error {
 	if err != nil {
 		return err
 	}
+	if isRejectionReply(response, hs.LocalEphemeral(), h.peerPubKey) {
+		zeroizeLocalEphemeral(hs)
+		return ErrRejected
+	}
 	if IsCookieReply(response) {
 		cookie, err := DecryptCookieReply(response, hs.LocalEphemeral().Public, h.peerPubKey)
 		zeroizeLocalEphemeral(hs)
@@ -171,9 +232,13 @@ func (h *IKHandshake) ClientSideHandshake(transport io.ReadWriter) error {
 		if err != nil {
 			return err
 		}
+		if isRejectionReply(response, hs.LocalEphemeral(), h.peerPubKey) {
+			zeroizeLocalEphemeral(hs)
+			return ErrRejected
+		}
 		if IsCookieReply(response) {
 			zeroizeLocalEphemeral(hs)
-			return fmt.Errorf("noise: unexpected cookie reply on retry")
+			return fmt.Errorf("noise: unexpected cookie reply on retry: %w", ErrCookieRequired)
 		}
 		material, err := h.completeInitiatorFromMsg2(hs, response, false)
 		zeroizeLocalEphemeral(hs)
@@ -210,6 +275,9 @@ func (h *IKHandshake) validateClientConfig() error {
 	if h.peerPubKey == nil {
 		return ErrMissingServerKey
 	}
+	if len(h.presharedKey) > 0 && len(h.presharedKey) != PresharedKeySize {
+		return fmt.Errorf("noise: invalid pre-shared key length %d, expected %d", len(h.presharedKey), PresharedKeySize)
+	}
 	return nil
 }
 
@@ -252,18 +320,24 @@ func (h *IKHandshake) transportRemoteIP(transport io.ReadWriter) (netip.Addr, bo
 	return tr.RemoteAddrPort().Addr(), true
 }
 
-func (h *IKHandshake) newResponderState(prologue []byte) (*noiselib.HandshakeState, error) {
+// newResponderState starts a responder handshake. With psk it is IKpsk2, and
+// the pre-shared key must be set with SetPresharedKey before msg2 is written.
+func (h *IKHandshake) newResponderState(prologue []byte, psk bool) (*noiselib.HandshakeState, error) {
 	staticKey := noiselib.DHKey{
 		Private: h.serverPrivKey,
 		Public:  h.serverPubKey,
 	}
-	hs, err := noiselib.NewHandshakeState(noiselib.Config{
+	config := noiselib.Config{
 		CipherSuite:   cipherSuite,
 		Pattern:       noiselib.HandshakeIK,
 		Initiator:     false,
 		StaticKeypair: staticKey,
 		Prologue:      prologue,
-	})
+	}
+	if psk {
+		config.PresharedKeyPlacement = 2
+	}
+	hs, err := noiselib.NewHandshakeState(config)
 	if err != nil {
 		return nil, fmt.Errorf("noise: server handshake state: %w", err)
 	}
@@ -273,33 +347,71 @@ func (h *IKHandshake) newResponderState(prologue []byte) (*noiselib.HandshakeSta
 func (h *IKHandshake) runResponderNoise(
 	transport io.ReadWriter,
 	msg1WithMAC []byte,
+	psk bool,
 ) (serverHandshakeOutcome, error) {
-	hs, err := h.newResponderState(nil)
+	hs, err := h.newResponderState(nil, psk)
 	if err != nil {
 		return serverHandshakeOutcome{}, err
 	}
 	defer zeroizeLocalEphemeral(hs)
 
 	noiseMsg := ExtractNoiseMsg(msg1WithMAC)
-	advertised, _, _, err := hs.ReadMessage(nil, noiseMsg)
+	rawPayload, _, _, err := hs.ReadMessage(nil, noiseMsg)
 	if err != nil {
 		return serverHandshakeOutcome{}, fmt.Errorf("noise: read msg1: %w", err)
 	}
+	payload, err := decodeInitiatorPayload(rawPayload)
+	if err != nil {
+		return serverHandshakeOutcome{}, err
+	}
 
 	clientPubKey := hs.PeerStatic()
-	clientID, enabled, found := h.allowedPeers.Lookup(clientPubKey)
+	access, found := h.allowedPeers.Lookup(clientPubKey)
 	if !found {
-		return serverHandshakeOutcome{}, ErrUnknownPeer
+		return serverHandshakeOutcome{}, h.reject(transport, hs, ErrUnknownPeer)
+	}
+	if !access.Enabled {
+		return serverHandshakeOutcome{}, h.reject(transport, hs, ErrPeerDisabled)
 	}
-	if !enabled {
-		return serverHandshakeOutcome{}, ErrPeerDisabled
+	// A wrong key only shows on the client, which cannot read msg2; a
+	// missing or unexpected one is rejected here.
+	if psk != (len(access.PresharedKey) > 0) {
+		return serverHandshakeOutcome{}, h.reject(transport, hs, ErrPresharedKeyMismatch)
+	}
+	if psk {
+		if err := hs.SetPresharedKey(access.PresharedKey); err != nil {
+			return serverHandshakeOutcome{}, fmt.Errorf("noise: set pre-shared key: %w", err)
+		}
+	}
+	if err := h.checkReplay(clientPubKey, payload.timestamp); err != nil {
+		return serverHandshakeOutcome{}, err
 	}
 
 	var selected []byte
 	var negotiated []Capability
-	if slices.Contains(advertised, byte(CapabilityRekeyV2)) {
-		selected = []byte{byte(CapabilityRekeyV2)}
-		negotiated = []Capability{CapabilityRekeyV2}
+	if payload.advertises(CapabilityRekeyV2) {
+		selected = append(selected, byte(CapabilityRekeyV2))
+		negotiated = append(negotiated, CapabilityRekeyV2)
+	}
+	for _, capability := range []Capability{CapabilityTimestamp, CapabilityPresharedKey, CapabilityAES256GCM} {
+		if capability == CapabilityPresharedKey && !psk ||
+			capability == CapabilityAES256GCM && !h.offerAES256GCM {
+			continue
+		}
+		if payload.advertises(capability) {
+			selected = appendRecord(selected, capability, nil)
+			negotiated = append(negotiated, capability)
+		}
+	}
+	var hybridSecret []byte
+	if payload.encapsulationKey != nil {
+		var ciphertext []byte
+		if hybridSecret, ciphertext, err = encapsulate(payload.encapsulationKey); err != nil {
+			return serverHandshakeOutcome{}, err
+		}
+		defer securemem.ZeroBytes(hybridSecret)
+		selected = appendRecord(selected, CapabilityMLKEM768, ciphertext)
+		negotiated = append(negotiated, CapabilityMLKEM768)
 	}
 	msg2, cs1, cs2, err := hs.WriteMessage(nil, selected)
 	if err != nil {
@@ -312,28 +424,57 @@ func (h *IKHandshake) runResponderNoise(
 		return serverHandshakeOutcome{}, fmt.Errorf("noise: handshake not complete after msg2")
 	}
 
+	material := mixHybridSecret(extractSessionMaterial(cs1, cs2, hs.ChannelBinding()), hybridSecret)
 	return serverHandshakeOutcome{
-		clientID:               clientID,
+		clientID:               access.ClientID,
 		clientPubKey:           clientPubKey,
+		allowedIPs:             access.AllowedIPs,
 		negotiatedCapabilities: negotiated,
-		material:               extractSessionMaterial(cs1, cs2, hs.ChannelBinding()),
+		presharedKey:           access.PresharedKey,
+		material:               material,
 	}, nil
 }
 
-func (h *IKHandshake) newInitiatorState(prologue []byte) (*noiselib.HandshakeState, error) {
+// checkReplay rejects an initiation that is not newer than the last one
+// accepted from the same client. Clients that never sent a timestamp are
+// accepted for backward compatibility.
+func (h *IKHandshake) checkReplay(clientPubKey []byte, ts *Timestamp) error {
+	if h.replayGuard == nil {
+		return nil
+	}
+	if ts == nil {
+		if !h.replayGuard.AcceptLegacy(clientPubKey) {
+			return ErrStaleInitiation
+		}
+		return nil
+	}
+	if !h.replayGuard.Accept(clientPubKey, *ts) {
+		return ErrStaleInitiation
+	}
+	return nil
+}
+
+// newInitiatorState starts an initiator handshake, IKpsk2 with a non-empty
+// psk.
+func (h *IKHandshake) newInitiatorState(prologue []byte, psk []byte) (*noiselib.HandshakeState, error) {
 	clientStatic := noiselib.DHKey{
 		Private: h.clientPrivKey,
 		Public:  h.clientPubKey,
 	}
 
-	hs, err := noiselib.NewHandshakeState(noiselib.Config{
+	config := noiselib.Config{
 		CipherSuite:   cipherSuite,
 		Pattern:       noiselib.HandshakeIK,
 		Initiator:     true,
 		StaticKeypair: clientStatic,
 		PeerStatic:    h.peerPubKey,
 		Prologue:      prologue,
-	})
+	}
+	if len(psk) > 0 {
+		config.PresharedKey = psk
+		config.PresharedKeyPlacement = 2
+	}
+	hs, err := noiselib.NewHandshakeState(config)
 	if err != nil {
 		return nil, fmt.Errorf("noise: client handshake state: %w", err)
 	}
@@ -345,12 +486,28 @@ func (h *IKHandshake) initiatorAttempt(
 	sendErrPrefix string,
 	readErrPrefix string,
 ) (*noiselib.HandshakeState, []byte, error) {
-	hs, err := h.newInitiatorState(nil)
+	ts := nextTimestamp()
+	version := byte(ProtocolVersion)
+	var extra []Capability
+	if len(h.presharedKey) > 0 {
+		version = ProtocolVersionPSK
+		extra = append(extra, CapabilityPresharedKey)
+	}
+	if h.offerAES256GCM {
+		extra = append(extra, CapabilityAES256GCM)
+	}
+	hybridKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		return nil, nil, fmt.Errorf("noise: generate ML-KEM key: %w", err)
+	}
+	h.hybridKey = hybridKey
+	encapsulationKey := hybridKey.EncapsulationKey().Bytes()
+	hs, err := h.newInitiatorState(nil, h.presharedKey)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	msg1, _, _, err := hs.WriteMessage(nil, []byte{byte(CapabilityRekeyV2)})
+	msg1, _, _, err := hs.WriteMessage(nil, encodeInitiatorPayload(ts, encapsulationKey, extra...))
 	if err != nil {
 		zeroizeLocalEphemeral(hs)
 		return nil, nil, fmt.Errorf("noise: write msg1: %w", err)
@@ -360,7 +517,7 @@ func (h *IKHandshake) initiatorAttempt(
 		zeroizeLocalEphemeral(hs)
 		return nil, nil, fmt.Errorf("noise: append MACs: %w", err)
 	}
-	msgWithVersion := PrependVersion(msg1WithMAC)
+	msgWithVersion := prependVersion(version, msg1WithMAC)
 	if _, err := transport.Write(msgWithVersion); err != nil {
 		zeroizeLocalEphemeral(hs)
 		return nil, nil, fmt.Errorf("%s: %w", sendErrPrefix, err)
@@ -388,15 +545,38 @@ func (h *IKHandshake) completeInitiatorFromMsg2(
 	if verifyServerStatic && !bytes.Equal(hs.PeerStatic(), h.peerPubKey) {
 		return sessionMaterial{}, fmt.Errorf("noise: server static key mismatch")
 	}
+	hybridKey := h.hybridKey
+	h.hybridKey = nil
+	set, err := decodeCapabilities(selected)
+	if err != nil {
+		return sessionMaterial{}, fmt.Errorf("noise: read msg2: %w", err)
+	}
+	var hybridSecret []byte
 	h.negotiatedCapabilities = h.negotiatedCapabilities[:0]
-	for _, capability := range selected {
-		capability := Capability(capability)
-		if capability != CapabilityRekeyV2 {
+	for capability, data := range set {
+		switch {
+		case capability == CapabilityMLKEM768 && hybridKey != nil:
+			if len(data) != mlkem.CiphertextSize768 {
+				return sessionMaterial{}, ErrInvalidEncapsulation
+			}
+			if hybridSecret, err = decapsulate(hybridKey, data); err != nil {
+				return sessionMaterial{}, err
+			}
+		case len(data) > 0:
+			return sessionMaterial{}, fmt.Errorf("noise: server selected unsupported capability")
+		case capability == CapabilityRekeyV2, capability == CapabilityTimestamp:
+		case capability == CapabilityPresharedKey && len(h.presharedKey) > 0:
+		case capability == CapabilityAES256GCM && h.offerAES256GCM:
+		default:
 			return sessionMaterial{}, fmt.Errorf("noise: server selected unsupported capability")
 		}
 		h.negotiatedCapabilities = append(h.negotiatedCapabilities, capability)
 	}
-	return extractSessionMaterial(cs1, cs2, hs.ChannelBinding()), nil
+	slices.Sort(h.negotiatedCapabilities)
+	if len(h.presharedKey) > 0 && !h.Supports(CapabilityPresharedKey) {
+		return sessionMaterial{}, fmt.Errorf("noise: server did not accept the pre-shared key: %w", ErrPresharedKeyMismatch)
+	}
+	return mixHybridSecret(extractSessionMaterial(cs1, cs2, hs.ChannelBinding()), hybridSecret), nil
 }
 
 func extractSessionMaterial(
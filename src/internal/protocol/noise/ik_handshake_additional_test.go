@@ -190,7 +190,7 @@ func TestIKHandshake_Extra_GettersAndNilResult(t *testing.T) {
 
 func TestIKHandshake_Server_ReadErrorAndMissingServerKey(t *testing.T) {
 	t.Run("missing server key", func(t *testing.T) {
-		h := NewIKHandshakeServer(nil, nil, newTestAllowedPeers(nil), nil, nil)
+		h := NewIKHandshakeServer(nil, nil, newTestAllowedPeers(nil), nil, nil, nil)
 		_, err := h.ServerSideHandshake(&queueTransport{})
 		if !errors.Is(err, ErrMissingServerKey) {
 			t.Fatalf("expected ErrMissingServerKey, got %v", err)
@@ -205,6 +205,7 @@ func TestIKHandshake_Server_ReadErrorAndMissingServerKey(t *testing.T) {
 			newTestAllowedPeers(nil),
 			nil,
 			nil,
+			nil,
 		)
 		_, err := h.ServerSideHandshake(&queueTransport{readErr: io.ErrUnexpectedEOF})
 		if err == nil || !strings.Contains(err.Error(), "noise: read msg1") {
@@ -234,6 +235,7 @@ func TestIKHandshake_Server_UnderLoadCookieRequiredBranches(t *testing.T) {
 			newTestAllowedPeers(allowedPeers),
 			cm,
 			lm,
+			nil,
 		)
 
 		_, err := h.ServerSideHandshake(&queueTransport{reads: [][]byte{msg}})
@@ -252,6 +254,7 @@ func TestIKHandshake_Server_UnderLoadCookieRequiredBranches(t *testing.T) {
 			newTestAllowedPeers(allowedPeers),
 			cm,
 			lm,
+			nil,
 		)
 
 		tr := &queueRemoteTransport{
@@ -378,6 +381,7 @@ func TestIKHandshake_Server_ErrorBranches(t *testing.T) {
 			}),
 			cm,
 			lm,
+			nil,
 		)
 
 		msg := newClientMsg1WithVersion(t, clientKP.Private, clientKP.Public, serverKP.Public)
@@ -411,6 +415,7 @@ func TestIKHandshake_Server_ErrorBranches(t *testing.T) {
 			newTestAllowedPeers(nil),
 			nil,
 			nil,
+			nil,
 		)
 
 		_, err = h.ServerSideHandshake(&queueTransport{reads: [][]byte{msg}})
@@ -434,6 +439,7 @@ func TestIKHandshake_Server_ErrorBranches(t *testing.T) {
 			newTestAllowedPeers(nil),
 			nil,
 			nil,
+			nil,
 		)
 
 		_, err = h.ServerSideHandshake(&queueTransport{reads: [][]byte{msg}})
@@ -455,6 +461,7 @@ func TestIKHandshake_Server_ErrorBranches(t *testing.T) {
 			}),
 			nil,
 			nil,
+			nil,
 		)
 
 		tr := &queueTransport{
@@ -578,7 +585,7 @@ func TestIKHandshake_NewResponderState_SucceedsWithDeferredKeyValidation(t *test
 		serverPrivKey: []byte{2},
 		allowedPeers:  newTestAllowedPeers(nil),
 	}
-	hs, err := hServer.newResponderState(nil)
+	hs, err := hServer.newResponderState(nil, false)
 	if err != nil {
 		t.Fatalf("expected handshake state creation to succeed, got %v", err)
 	}
@@ -587,7 +594,7 @@ func TestIKHandshake_NewResponderState_SucceedsWithDeferredKeyValidation(t *test
 	}
 	zeroizeLocalEphemeral(hs)
 
-	if _, err := hServer.runResponderNoise(&queueTransport{}, []byte("msg")); err == nil ||
+	if _, err := hServer.runResponderNoise(&queueTransport{}, []byte("msg"), false); err == nil ||
 		!strings.Contains(err.Error(), "read msg1") {
 		t.Fatalf("expected runResponderNoise read msg1 error, got %v", err)
 	}
@@ -672,7 +679,7 @@ func TestIKHandshake_ClientRejectsUnsupportedServerSelection(t *testing.T) {
 		t:          t,
 		serverPub:  serverKP.Public,
 		serverPriv: serverKP.Private,
-		selection:  []byte{0xFF},
+		selection:  appendRecord(nil, 0xFF, nil),
 	}
 
 	err := h.ClientSideHandshake(tr)
@@ -694,6 +701,7 @@ func TestIKHandshake_ServerFallsBackForLegacyClient(t *testing.T) {
 		}}),
 		nil,
 		nil,
+		nil,
 	)
 	tr := &queueTransport{reads: [][]byte{
 		newClientMsg1WithVersion(t, clientKP.Private, clientKP.Public, serverKP.Public),
@@ -732,7 +740,7 @@ func TestIKHandshake_NewInitiatorState_SucceedsWithDeferredKeyValidation(t *test
 		clientPrivKey: []byte{2},
 		peerPubKey:    make([]byte, 32),
 	}
-	hs, err := h.newInitiatorState(nil)
+	hs, err := h.newInitiatorState(nil, nil)
 	if err != nil {
 		t.Fatalf("expected handshake state creation to succeed, got %v", err)
 	}
@@ -760,7 +768,7 @@ func TestIKHandshake_RunResponderNoise_ReadMsg1Error(t *testing.T) {
 		serverPrivKey: []byte{2},
 		allowedPeers:  newTestAllowedPeers(nil),
 	}
-	if _, err := h.runResponderNoise(&queueTransport{}, []byte("msg")); err == nil ||
+	if _, err := h.runResponderNoise(&queueTransport{}, []byte("msg"), false); err == nil ||
 		!strings.Contains(err.Error(), "read msg1") {
 		t.Fatalf("expected read msg1 error, got %v", err)
 	}
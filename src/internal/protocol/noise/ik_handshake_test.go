@@ -2,11 +2,13 @@ package noise
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"net"
 	"net/netip"
 	"testing"
 
+	"tungo/internal/protocol/chacha20"
 	transport "tungo/internal/transport/tcp"
 )
 
@@ -48,9 +50,10 @@ func TestIKHandshake_Success(t *testing.T) {
 	// Configure allowed peers
 	allowedPeers := []testPeer{
 		{
-			PublicKey: clientKP.Public,
-			Enabled:   true,
-			ClientID:  5,
+			PublicKey:  clientKP.Public,
+			Enabled:    true,
+			ClientID:   5,
+			AllowedIPs: []netip.Prefix{netip.MustParsePrefix("192.168.50.0/24")},
 		},
 	}
 
@@ -64,6 +67,7 @@ func TestIKHandshake_Success(t *testing.T) {
 		newTestAllowedPeers(allowedPeers),
 		cookieManager,
 		loadMonitor,
+		nil,
 	)
 
 	clientHS := NewIKHandshakeClient(
@@ -123,6 +127,9 @@ func TestIKHandshake_Success(t *testing.T) {
 	if !bytes.Equal(serverHS.ClientPubKey(), clientKP.Public) {
 		t.Fatal("result client pub key mismatch")
 	}
+	if got := serverHS.AllowedIPs(); len(got) != 1 || got[0] != netip.MustParsePrefix("192.168.50.0/24") {
+		t.Fatalf("AllowedIPs = %v, want the configured prefix", got)
+	}
 	if !clientHS.Supports(CapabilityRekeyV2) || !serverHS.Supports(CapabilityRekeyV2) {
 		t.Fatal("Rekey V2 capability was not negotiated")
 	}
@@ -142,7 +149,7 @@ func TestIKHandshake_RekeyV2(t *testing.T) {
 		Enabled:   true,
 		ClientID:  1,
 	}})
-	serverHS := NewIKHandshakeServer(serverKP.Public, serverKP.Private, allowedPeers, nil, nil)
+	serverHS := NewIKHandshakeServer(serverKP.Public, serverKP.Private, allowedPeers, nil, nil, nil)
 	clientHS := NewIKHandshakeClient(clientKP.Public, clientKP.Private, serverKP.Public)
 
 	clientConn, serverConn := net.Pipe()
@@ -186,6 +193,7 @@ func TestIKHandshake_RekeyV2RejectsWrongPrologue(t *testing.T) {
 		}}),
 		nil,
 		nil,
+		nil,
 	)
 	clientHS := NewIKHandshakeClient(clientKP.Public, clientKP.Private, serverKP.Public)
 
@@ -225,7 +233,7 @@ func TestIKHandshake_UnknownClient(t *testing.T) {
 	serverHS := NewIKHandshakeServer(
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
-		cookieManager, loadMonitor,
+		cookieManager, loadMonitor, nil,
 	)
 
 	// Client uses unknown key
@@ -256,6 +264,9 @@ func TestIKHandshake_UnknownClient(t *testing.T) {
 	if srvErr == nil || srvErr != ErrUnknownPeer {
 		t.Fatalf("expected ErrUnknownPeer, got: %v", srvErr)
 	}
+	if cliErr := <-cliCh; !errors.Is(cliErr, ErrRejected) {
+		t.Fatalf("expected ErrRejected on the client, got: %v", cliErr)
+	}
 }
 
 func TestIKHandshake_DisabledClient(t *testing.T) {
@@ -277,7 +288,7 @@ func TestIKHandshake_DisabledClient(t *testing.T) {
 	serverHS := NewIKHandshakeServer(
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
-		cookieManager, loadMonitor,
+		cookieManager, loadMonitor, nil,
 	)
 
 	clientHS := NewIKHandshakeClient(
@@ -293,18 +304,22 @@ func TestIKHandshake_DisabledClient(t *testing.T) {
 	serverAdapter, _ := transport.NewFramedConn(serverConn, 2048)
 
 	srvCh := make(chan error, 1)
+	cliCh := make(chan error, 1)
 	go func() {
 		_, err := serverHS.ServerSideHandshake(serverAdapter)
 		srvCh <- err
 	}()
 	go func() {
-		_ = clientHS.ClientSideHandshake(clientAdapter)
+		cliCh <- clientHS.ClientSideHandshake(clientAdapter)
 	}()
 
 	srvErr := <-srvCh
 	if srvErr == nil || srvErr != ErrPeerDisabled {
 		t.Fatalf("expected ErrPeerDisabled, got: %v", srvErr)
 	}
+	if cliErr := <-cliCh; !errors.Is(cliErr, ErrRejected) {
+		t.Fatalf("expected ErrRejected on the client, got: %v", cliErr)
+	}
 }
 
 func TestIKHandshake_KeyMismatch(t *testing.T) {
@@ -327,7 +342,7 @@ func TestIKHandshake_KeyMismatch(t *testing.T) {
 	serverHS := NewIKHandshakeServer(
 		impostorKP.Public, impostorKP.Private,
 		newTestAllowedPeers(allowedPeers),
-		cookieManager, loadMonitor,
+		cookieManager, loadMonitor, nil,
 	)
 
 	// Client expects real server's key
@@ -396,7 +411,7 @@ func TestIKHandshake_FreshEphemeralPerHandshake(t *testing.T) {
 		serverHS := NewIKHandshakeServer(
 			serverKP.Public, serverKP.Private,
 			newTestAllowedPeers(allowedPeers),
-			cookieManager, loadMonitor,
+			cookieManager, loadMonitor, nil,
 		)
 		clientHS := NewIKHandshakeClient(
 			clientKP.Public, clientKP.Private,
@@ -466,6 +481,7 @@ func TestIKHandshake_ServerMissingAllowedPeers(t *testing.T) {
 		serverKP.Public, serverKP.Private,
 		nil, // No allowed peers
 		nil, nil,
+		nil,
 	)
 
 	serverConn, _ := net.Pipe()
@@ -491,6 +507,7 @@ func TestIKHandshake_InvalidMAC1(t *testing.T) {
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
 		cookieManager, nil,
+		nil,
 	)
 
 	// Create a transport that sends garbage
@@ -532,6 +549,7 @@ func TestIKHandshake_AllowedIPsInResult(t *testing.T) {
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
 		cookieManager, nil,
+		nil,
 	)
 
 	clientHS := NewIKHandshakeClient(clientKP.Public, clientKP.Private, serverKP.Public)
@@ -568,7 +586,7 @@ func TestSecurity_HandshakeReplayMsg1(t *testing.T) {
 	serverHS1 := NewIKHandshakeServer(
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
-		cookieManager, loadMonitor,
+		cookieManager, loadMonitor, nil,
 	)
 	clientHS1 := NewIKHandshakeClient(clientKP.Public, clientKP.Private, serverKP.Public)
 
@@ -588,7 +606,7 @@ func TestSecurity_HandshakeReplayMsg1(t *testing.T) {
 	serverHS2 := NewIKHandshakeServer(
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
-		cookieManager, loadMonitor,
+		cookieManager, loadMonitor, nil,
 	)
 	clientHS2 := NewIKHandshakeClient(clientKP.Public, clientKP.Private, serverKP.Public)
 
@@ -632,6 +650,7 @@ func TestSecurity_RejectUnknownProtocolVersions(t *testing.T) {
 			serverKP.Public, serverKP.Private,
 			newTestAllowedPeers(allowedPeers),
 			cookieManager, nil,
+			nil,
 		)
 
 		clientConn, serverConn := net.Pipe()
@@ -664,6 +683,7 @@ func TestSecurity_RejectUnknownProtocolVersions(t *testing.T) {
 			serverKP.Public, serverKP.Private,
 			newTestAllowedPeers(allowedPeers),
 			cookieManager, nil,
+			nil,
 		)
 
 		clientConn, serverConn := net.Pipe()
@@ -681,7 +701,7 @@ func TestSecurity_RejectUnknownProtocolVersions(t *testing.T) {
 		go func() {
 			// Send message with future version
 			msg := make([]byte, MinTotalSizeWithVersion)
-			msg[0] = 2 // Version 2 = future/reserved
+			msg[0] = 3 // Version 3 = future/reserved
 			_, _ = clientAdapter.Write(msg)
 		}()
 
@@ -696,6 +716,7 @@ func TestSecurity_RejectUnknownProtocolVersions(t *testing.T) {
 			serverKP.Public, serverKP.Private,
 			newTestAllowedPeers(allowedPeers),
 			cookieManager, nil,
+			nil,
 		)
 
 		clientConn, serverConn := net.Pipe()
@@ -745,6 +766,7 @@ func TestSecurity_SpoofedSourceIP(t *testing.T) {
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
 		cookieManager, nil,
+		nil,
 	)
 
 	clientHS := NewIKHandshakeClient(clientKP.Public, clientKP.Private, serverKP.Public)
@@ -847,7 +869,7 @@ func TestSecurity_CookieBoundToEphemeral(t *testing.T) {
 
 func TestIKHandshake_ServerMissingKeys(t *testing.T) {
 	// Server with nil keys
-	serverHS := NewIKHandshakeServer(nil, nil, newTestAllowedPeers(nil), nil, nil)
+	serverHS := NewIKHandshakeServer(nil, nil, newTestAllowedPeers(nil), nil, nil, nil)
 	serverConn, _ := net.Pipe()
 	defer closeTestConn(serverConn)
 	serverAdapter, _ := transport.NewFramedConn(serverConn, 2048)
@@ -863,6 +885,7 @@ func TestIKHandshake_AuthResult_NilBeforeHandshake(t *testing.T) {
 	serverHS := NewIKHandshakeServer(
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(nil), nil, nil,
+		nil,
 	)
 	if serverHS.ClientPubKey() != nil || serverHS.AllowedIPs() != nil {
 		t.Fatal("expected nil authentication result before handshake")
@@ -893,6 +916,7 @@ func TestSecurity_MAC1VerifiedBeforeAllocation(t *testing.T) {
 		serverKP.Public, serverKP.Private,
 		newTestAllowedPeers(allowedPeers),
 		cookieManager, nil,
+		nil,
 	)
 
 	// Send a message with invalid MAC1 - should be rejected immediately
@@ -928,3 +952,34 @@ func TestSecurity_MAC1VerifiedBeforeAllocation(t *testing.T) {
 	// The key point: server rejected BEFORE doing any DH or allocating session state
 	// (This is verified by the quick return with ErrInvalidMAC1)
 }
+
+func TestIKHandshake_AES256GCM(t *testing.T) {
+	tests := map[string]struct {
+		server, client bool
+		want           chacha20.Cipher
+	}{
+		"both offer":     {server: true, client: true, want: chacha20.AES256GCM},
+		"server only":    {server: true, want: chacha20.ChaCha20Poly1305},
+		"client only":    {client: true, want: chacha20.ChaCha20Poly1305},
+		"neither offers": {want: chacha20.ChaCha20Poly1305},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server, client := hybridTestPeers(t)
+			if test.server {
+				server.OfferAES256GCM()
+			}
+			if test.client {
+				client.OfferAES256GCM()
+			}
+			clientConn, serverConn := hybridTestConns(t)
+			clientTransport, _ := transport.NewFramedConn(clientConn, 2048)
+			serverTransport, _ := transport.NewFramedConn(serverConn, 2048)
+			completeTestHandshake(t, server, client, serverTransport, clientTransport)
+
+			if server.Cipher() != test.want || client.Cipher() != test.want {
+				t.Fatalf("server %s, client %s, want %s", server.Cipher(), client.Cipher(), test.want)
+			}
+		})
+	}
+}
@@ -16,9 +16,13 @@ const (
 	// ProtocolVersion is the current protocol version (IK pattern).
 	// Version 0: Reserved (reject)
 	// Version 1: Noise IK (current)
-	// Version 2+: Reserved for future
+	// Version 2: Noise IKpsk2, for clients with a pre-shared key
+	// Version 3+: Reserved for future
 	ProtocolVersion = 1
 
+	// ProtocolVersionPSK is the version of msg1 in Noise IKpsk2 handshakes.
+	ProtocolVersionPSK = 2
+
 	// VersionSize is the size of the protocol version prefix.
 	VersionSize = 1
 
@@ -182,8 +186,12 @@ func AppendMACs(msg1, serverPubKey, cookie []byte) ([]byte, error) {
 // PrependVersion adds the protocol version byte to a message.
 // Wire format: [version (1)] [msg...]
 func PrependVersion(msg []byte) []byte {
+	return prependVersion(ProtocolVersion, msg)
+}
+
+func prependVersion(version byte, msg []byte) []byte {
 	result := make([]byte, VersionSize+len(msg))
-	result[0] = ProtocolVersion
+	result[0] = version
 	copy(result[VersionSize:], msg)
 	return result
 }
@@ -192,16 +200,22 @@ func PrependVersion(msg []byte) []byte {
 // Returns error if version is unsupported or message is too short.
 // This MUST be called BEFORE MAC1 verification.
 func CheckVersion(msgWithVersion []byte) ([]byte, error) {
+	_, msg, err := checkVersion(msgWithVersion)
+	return msg, err
+}
+
+// checkVersion is CheckVersion that also returns the version.
+func checkVersion(msgWithVersion []byte) (byte, []byte, error) {
 	if len(msgWithVersion) < MinTotalSizeWithVersion {
-		return nil, ErrMsgTooShort
+		return 0, nil, ErrMsgTooShort
 	}
 
 	version := msgWithVersion[0]
 	switch version {
-	case ProtocolVersion: // Version 1 = IK
-		return msgWithVersion[VersionSize:], nil
+	case ProtocolVersion, ProtocolVersionPSK: // Version 1 = IK, 2 = IKpsk2
+		return version, msgWithVersion[VersionSize:], nil
 	default:
-		// Version 0 and 2+ are unknown/reserved
-		return nil, ErrUnknownProtocol
+		// Version 0 and 3+ are unknown/reserved
+		return 0, nil, ErrUnknownProtocol
 	}
 }
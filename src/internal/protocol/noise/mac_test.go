@@ -297,7 +297,7 @@ func TestCheckVersion_Unknown(t *testing.T) {
 		name    string
 		version byte
 	}{
-		{"version 2 (future)", 2},
+		{"version 3 (future)", 3},
 		{"version 99 (unknown)", 99},
 		{"version 255 (max)", 255},
 	}
@@ -0,0 +1,13 @@
+package noise
+
+// PresharedKeySize is the length of a pre-shared key.
+const PresharedKeySize = 32
+
+// sessionPresharedKey returns the pre-shared key of the session, or nil when
+// none was negotiated. Rekeys use IKpsk2 with it as the handshake did.
+func (h *IKHandshake) sessionPresharedKey() []byte {
+	if !h.Supports(CapabilityPresharedKey) || len(h.presharedKey) == 0 {
+		return nil
+	}
+	return h.presharedKey
+}
@@ -0,0 +1,158 @@
+package noise
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+
+	transport "tungo/internal/transport/tcp"
+)
+
+// runPresharedKeyHandshake runs a handshake with serverPSK configured for the
+// peer and clientPSK on the client, and returns both results.
+func runPresharedKeyHandshake(t *testing.T, serverPSK, clientPSK []byte) (*IKHandshake, *IKHandshake, error, error) {
+	t.Helper()
+	serverKP, err := cipherSuite.GenerateKeypair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKP, err := cipherSuite.GenerateKeypair(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowedPeers := newTestAllowedPeers([]testPeer{{
+		PublicKey:    clientKP.Public,
+		Enabled:      true,
+		ClientID:     1,
+		PresharedKey: serverPSK,
+	}})
+	serverHS := NewIKHandshakeServer(serverKP.Public, serverKP.Private, allowedPeers, nil, nil, NewReplayGuard())
+	clientHS := NewIKHandshakeClientWithPresharedKey(clientKP.Public, clientKP.Private, serverKP.Public, clientPSK)
+
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		closeTestConn(clientConn)
+		closeTestConn(serverConn)
+	})
+	clientTransport, _ := transport.NewFramedConn(clientConn, 2048)
+	serverTransport, _ := transport.NewFramedConn(serverConn, 2048)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		_, err := serverHS.ServerSideHandshake(serverTransport)
+		if err != nil {
+			// Unblock the client, which waits for msg2.
+			closeTestConn(serverConn)
+		}
+		serverErr <- err
+	}()
+	clientErr := clientHS.ClientSideHandshake(clientTransport)
+	if clientErr != nil {
+		closeTestConn(clientConn)
+	}
+	return serverHS, clientHS, <-serverErr, clientErr
+}
+
+func TestIKHandshake_PresharedKey(t *testing.T) {
+	psk := bytes.Repeat([]byte{7}, PresharedKeySize)
+	serverHS, clientHS, serverErr, clientErr := runPresharedKeyHandshake(t, psk, psk)
+	if serverErr != nil || clientErr != nil {
+		t.Fatalf("handshake: server %v, client %v", serverErr, clientErr)
+	}
+	if !serverHS.Supports(CapabilityPresharedKey) || !clientHS.Supports(CapabilityPresharedKey) {
+		t.Fatal("pre-shared key capability was not negotiated")
+	}
+	if !bytes.Equal(serverHS.KeyClientToServer(), clientHS.KeyClientToServer()) ||
+		!bytes.Equal(serverHS.KeyServerToClient(), clientHS.KeyServerToClient()) {
+		t.Fatal("traffic keys do not match")
+	}
+
+	prologue := []byte("current session")
+	msg1, err := clientHS.StartRekeyV2(prologue)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg2, serverC2S, serverS2C, err := serverHS.RespondRekeyV2(prologue, msg1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientC2S, clientS2C, err := clientHS.FinishRekeyV2(msg2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clientC2S, serverC2S) || !bytes.Equal(clientS2C, serverS2C) {
+		t.Fatal("rekey traffic keys do not match")
+	}
+}
+
+func TestIKHandshake_PresharedKeyMismatch(t *testing.T) {
+	psk := bytes.Repeat([]byte{7}, PresharedKeySize)
+	other := bytes.Repeat([]byte{8}, PresharedKeySize)
+	for name, tc := range map[string]struct {
+		serverPSK, clientPSK []byte
+		serverErr, clientErr error
+	}{
+		// With IKpsk2 the key is mixed in after the server has read msg1,
+		// so only the client, which cannot read msg2, notices a wrong one.
+		"wrong key":          {serverPSK: psk, clientPSK: other},
+		"client without":     {serverPSK: psk, serverErr: ErrPresharedKeyMismatch, clientErr: ErrRejected},
+		"server without":     {clientPSK: psk, serverErr: ErrPresharedKeyMismatch, clientErr: ErrRejected},
+		"invalid key length": {clientPSK: psk[:16]},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, serverErr, clientErr := runPresharedKeyHandshake(t, tc.serverPSK, tc.clientPSK)
+			if clientErr == nil {
+				t.Fatal("client handshake succeeded")
+			}
+			if tc.serverErr != nil && !errors.Is(serverErr, tc.serverErr) {
+				t.Fatalf("server error = %v, want %v", serverErr, tc.serverErr)
+			}
+			if tc.clientErr != nil && !errors.Is(clientErr, tc.clientErr) {
+				t.Fatalf("client error = %v, want %v", clientErr, tc.clientErr)
+			}
+		})
+	}
+}
+
+func TestPresharedKeyInitiationUsesIKpsk2(t *testing.T) {
+	serverKP, _ := cipherSuite.GenerateKeypair(nil)
+	clientKP, _ := cipherSuite.GenerateKeypair(nil)
+	psk := bytes.Repeat([]byte{7}, PresharedKeySize)
+	client := NewIKHandshakeClientWithPresharedKey(clientKP.Public, clientKP.Private, serverKP.Public, psk)
+	tr := &queueTransport{}
+	if _, _, err := client.initiatorAttempt(tr, "send", "read"); err == nil {
+		t.Fatal("expected read error without a server")
+	}
+	version, msg1WithMAC, err := checkVersion(tr.writes[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != ProtocolVersionPSK {
+		t.Fatalf("msg1 version = %d, want %d", version, ProtocolVersionPSK)
+	}
+
+	server := NewIKHandshakeServer(serverKP.Public, serverKP.Private, newTestAllowedPeers(nil), nil, nil, nil)
+	plain, err := server.newResponderState(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err := plain.ReadMessage(nil, ExtractNoiseMsg(msg1WithMAC)); err == nil {
+		t.Fatal("IK responder read an IKpsk2 msg1")
+	}
+	hs, err := server.newResponderState(nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, _, _, err := hs.ReadMessage(nil, ExtractNoiseMsg(msg1WithMAC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeInitiatorPayload(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.advertises(CapabilityPresharedKey) || len(decoded.capabilities[CapabilityPresharedKey]) != 0 {
+		t.Fatalf("unexpected payload %+v", decoded)
+	}
+}
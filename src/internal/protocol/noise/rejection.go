@@ -0,0 +1,63 @@
+package noise
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"io"
+	"tungo/internal/protocol/securemem"
+
+	noiselib "github.com/flynn/noise"
+	"golang.org/x/crypto/blake2s"
+)
+
+const (
+	// RejectionReplySize is the size of a rejection reply, a BLAKE2s-256 tag.
+	// It is shorter than any msg2 and than a cookie reply.
+	RejectionReplySize = blake2s.Size
+
+	// RejectionLabel is the label for rejection reply authentication.
+	RejectionLabel = "rejection"
+)
+
+// rejectionTag authenticates the rejection of the initiation that used
+// clientEphemeral. The key is the DH of the server static key and the client
+// ephemeral key, which only the server and the initiator can compute, so an
+// on-path attacker cannot forge or replay rejections.
+func rejectionTag(dh, clientEphemeral []byte) []byte {
+	h, _ := blake2s.New256(dh)
+	h.Write([]byte(RejectionLabel))
+	h.Write([]byte(ProtocolID))
+	h.Write([]byte{byte(ProtocolVersion)})
+	h.Write(clientEphemeral)
+	return h.Sum(nil)
+}
+
+// reject sends the initiator of hs a rejection reply and returns reason. It
+// must only be called after msg1 was read: the initiator then proved its
+// static key, so the reply only tells a key holder that its key is refused.
+func (h *IKHandshake) reject(transport io.Writer, hs *noiselib.HandshakeState, reason error) error {
+	clientEphemeral := hs.PeerEphemeral()
+	dh, err := cipherSuite.DH(h.serverPrivKey, clientEphemeral)
+	if err != nil {
+		return reason
+	}
+	defer securemem.ZeroBytes(dh)
+	if _, err := transport.Write(rejectionTag(dh, clientEphemeral)); err != nil {
+		return fmt.Errorf("%w; send rejection reply: %w", reason, err)
+	}
+	return reason
+}
+
+// isRejectionReply reports whether response is the server's rejection of the
+// initiation that used ephemeral.
+func isRejectionReply(response []byte, ephemeral noiselib.DHKey, serverPubKey []byte) bool {
+	if len(response) != RejectionReplySize || ephemeral.Private == nil {
+		return false
+	}
+	dh, err := cipherSuite.DH(ephemeral.Private, serverPubKey)
+	if err != nil {
+		return false
+	}
+	defer securemem.ZeroBytes(dh)
+	return hmac.Equal(response, rejectionTag(dh, ephemeral.Public))
+}
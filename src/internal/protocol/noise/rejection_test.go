@@ -0,0 +1,61 @@
+package noise
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestRejectionReply_BoundToEphemeralAndServer(t *testing.T) {
+	serverKP, _ := cipherSuite.GenerateKeypair(nil)
+	otherKP, _ := cipherSuite.GenerateKeypair(nil)
+	ephemeral, _ := cipherSuite.GenerateKeypair(nil)
+	otherEphemeral, _ := cipherSuite.GenerateKeypair(nil)
+
+	dh, err := cipherSuite.DH(serverKP.Private, ephemeral.Public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply := rejectionTag(dh, ephemeral.Public)
+	if len(reply) != RejectionReplySize || IsCookieReply(reply) {
+		t.Fatalf("unexpected reply size %d", len(reply))
+	}
+	if !isRejectionReply(reply, ephemeral, serverKP.Public) {
+		t.Fatal("rejection reply not recognized")
+	}
+	if isRejectionReply(reply, otherEphemeral, serverKP.Public) {
+		t.Fatal("rejection reply accepted for another initiation")
+	}
+	if isRejectionReply(reply, ephemeral, otherKP.Public) {
+		t.Fatal("rejection reply accepted from another server")
+	}
+	forged := make([]byte, RejectionReplySize)
+	_, _ = rand.Read(forged)
+	if isRejectionReply(forged, ephemeral, serverKP.Public) {
+		t.Fatal("forged rejection reply accepted")
+	}
+}
+
+func TestIKHandshake_RejectionReplyOnlyAfterMsg1(t *testing.T) {
+	serverKP, _ := cipherSuite.GenerateKeypair(nil)
+	clientKP, _ := cipherSuite.GenerateKeypair(nil)
+	h := NewIKHandshakeServer(serverKP.Public, serverKP.Private, newTestAllowedPeers(nil), nil, nil, nil)
+
+	tr := &queueTransport{reads: [][]byte{newClientMsg1WithVersion(t, clientKP.Private, clientKP.Public, serverKP.Public)}}
+	if _, err := h.ServerSideHandshake(tr); !errors.Is(err, ErrUnknownPeer) {
+		t.Fatalf("expected ErrUnknownPeer, got %v", err)
+	}
+	if len(tr.writes) != 1 || len(tr.writes[0]) != RejectionReplySize {
+		t.Fatalf("expected one rejection reply, got %d writes", len(tr.writes))
+	}
+
+	// msg1 encrypted to another server key is not answered.
+	otherKP, _ := cipherSuite.GenerateKeypair(nil)
+	tr = &queueTransport{reads: [][]byte{newClientMsg1WithVersion(t, clientKP.Private, clientKP.Public, otherKP.Public)}}
+	if _, err := h.ServerSideHandshake(tr); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(tr.writes) != 0 {
+		t.Fatalf("unexpected reply to an unreadable msg1: %d writes", len(tr.writes))
+	}
+}
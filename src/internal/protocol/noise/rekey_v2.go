@@ -2,10 +2,14 @@ package noise
 
 import (
 	"bytes"
+	"crypto/mlkem"
 	"fmt"
+
+	"tungo/internal/protocol/securemem"
 )
 
-// StartRekeyV2 creates the first Noise IK message of a rehandshake.
+// StartRekeyV2 creates the first Noise IK message of a rehandshake. With
+// CapabilityMLKEM768 its payload is a new ML-KEM encapsulation key.
 func (h *IKHandshake) StartRekeyV2(prologue []byte) ([]byte, error) {
 	if !h.Supports(CapabilityRekeyV2) {
 		return nil, fmt.Errorf("noise: rekey v2 was not negotiated")
@@ -16,16 +20,26 @@ func (h *IKHandshake) StartRekeyV2(prologue []byte) ([]byte, error) {
 	if h.pendingRekey != nil {
 		return nil, fmt.Errorf("noise: rekey already in progress")
 	}
-	hs, err := h.newInitiatorState(prologue)
+	hs, err := h.newInitiatorState(prologue, h.sessionPresharedKey())
 	if err != nil {
 		return nil, err
 	}
-	msg1, _, _, err := hs.WriteMessage(nil, nil)
+	var hybridKey *mlkem.DecapsulationKey768
+	var payload []byte
+	if h.Supports(CapabilityMLKEM768) {
+		if hybridKey, err = mlkem.GenerateKey768(); err != nil {
+			zeroizeLocalEphemeral(hs)
+			return nil, fmt.Errorf("noise: generate ML-KEM key: %w", err)
+		}
+		payload = hybridKey.EncapsulationKey().Bytes()
+	}
+	msg1, _, _, err := hs.WriteMessage(nil, payload)
 	if err != nil {
 		zeroizeLocalEphemeral(hs)
 		return nil, fmt.Errorf("noise: write rekey msg1: %w", err)
 	}
 	h.pendingRekey = hs
+	h.pendingRekeyHybridKey = hybridKey
 	return msg1, nil
 }
 
@@ -36,10 +50,11 @@ func (h *IKHandshake) FinishRekeyV2(msg2 []byte) ([]byte, []byte, error) {
 	if hs == nil {
 		return nil, nil, fmt.Errorf("noise: no rekey in progress")
 	}
-	h.pendingRekey = nil
+	hybridKey := h.pendingRekeyHybridKey
+	h.pendingRekey, h.pendingRekeyHybridKey = nil, nil
 	defer zeroizeLocalEphemeral(hs)
 
-	_, cs1, cs2, err := hs.ReadMessage(nil, msg2)
+	ciphertext, cs1, cs2, err := hs.ReadMessage(nil, msg2)
 	if err != nil {
 		return nil, nil, fmt.Errorf("noise: read rekey msg2: %w", err)
 	}
@@ -49,7 +64,13 @@ func (h *IKHandshake) FinishRekeyV2(msg2 []byte) ([]byte, []byte, error) {
 	if !bytes.Equal(hs.PeerStatic(), h.peerPubKey) {
 		return nil, nil, fmt.Errorf("noise: server static key mismatch")
 	}
-	material := extractSessionMaterial(cs1, cs2, hs.ChannelBinding())
+	var hybridSecret []byte
+	if hybridKey != nil {
+		if hybridSecret, err = decapsulate(hybridKey, ciphertext); err != nil {
+			return nil, nil, fmt.Errorf("noise: read rekey msg2: %w", err)
+		}
+	}
+	material := mixHybridSecret(extractSessionMaterial(cs1, cs2, hs.ChannelBinding()), hybridSecret)
 	return material.clientKey, material.serverKey, nil
 }
 
@@ -62,13 +83,19 @@ func (h *IKHandshake) RespondRekeyV2(prologue, msg1 []byte) ([]byte, []byte, []b
 	if err := h.validateServerConfig(); err != nil {
 		return nil, nil, nil, err
 	}
-	hs, err := h.newResponderState(prologue)
+	psk := h.sessionPresharedKey()
+	hs, err := h.newResponderState(prologue, psk != nil)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 	defer zeroizeLocalEphemeral(hs)
+	if psk != nil {
+		if err := hs.SetPresharedKey(psk); err != nil {
+			return nil, nil, nil, fmt.Errorf("noise: set pre-shared key: %w", err)
+		}
+	}
 
-	_, _, _, err = hs.ReadMessage(nil, msg1)
+	encapsulationKey, _, _, err := hs.ReadMessage(nil, msg1)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("noise: read rekey msg1: %w", err)
 	}
@@ -76,21 +103,28 @@ func (h *IKHandshake) RespondRekeyV2(prologue, msg1 []byte) ([]byte, []byte, []b
 	if !bytes.Equal(clientPubKey, h.authenticatedClientPubKey) {
 		return nil, nil, nil, ErrUnknownPeer
 	}
-	_, enabled, found := h.allowedPeers.Lookup(clientPubKey)
+	access, found := h.allowedPeers.Lookup(clientPubKey)
 	if !found {
 		return nil, nil, nil, ErrUnknownPeer
 	}
-	if !enabled {
+	if !access.Enabled {
 		return nil, nil, nil, ErrPeerDisabled
 	}
 
-	msg2, cs1, cs2, err := hs.WriteMessage(nil, nil)
+	var hybridSecret, ciphertext []byte
+	if h.Supports(CapabilityMLKEM768) {
+		if hybridSecret, ciphertext, err = encapsulate(encapsulationKey); err != nil {
+			return nil, nil, nil, fmt.Errorf("noise: read rekey msg1: %w", err)
+		}
+		defer securemem.ZeroBytes(hybridSecret)
+	}
+	msg2, cs1, cs2, err := hs.WriteMessage(nil, ciphertext)
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("noise: write rekey msg2: %w", err)
 	}
 	if cs1 == nil || cs2 == nil {
 		return nil, nil, nil, fmt.Errorf("noise: rekey handshake not complete after msg2")
 	}
-	material := extractSessionMaterial(cs1, cs2, hs.ChannelBinding())
+	material := mixHybridSecret(extractSessionMaterial(cs1, cs2, hs.ChannelBinding()), hybridSecret)
 	return msg2, material.clientKey, material.serverKey, nil
 }
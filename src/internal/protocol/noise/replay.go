@@ -0,0 +1,114 @@
+package noise
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// TimestampSize is the size of a TAI64N initiation timestamp.
+const TimestampSize = 12
+
+// tai64Epoch is the TAI64 label of 1970-01-01 00:00:10 TAI.
+const tai64Epoch = 0x400000000000000a
+
+// Timestamp is a TAI64N timestamp carried in the encrypted msg1 payload.
+// Big-endian encoding makes bytewise order match time order.
+type Timestamp [TimestampSize]byte
+
+// NewTimestamp encodes t as TAI64N.
+func NewTimestamp(t time.Time) Timestamp {
+	var ts Timestamp
+	binary.BigEndian.PutUint64(ts[:8], uint64(tai64Epoch+t.Unix()))
+	binary.BigEndian.PutUint32(ts[8:], uint32(t.Nanosecond()))
+	return ts
+}
+
+// After reports whether ts is strictly later than other.
+func (ts Timestamp) After(other Timestamp) bool {
+	return bytes.Compare(ts[:], other[:]) > 0
+}
+
+var (
+	lastTimestampMu sync.Mutex
+	lastTimestamp   Timestamp
+)
+
+// nextTimestamp returns the current time as TAI64N, strictly increasing
+// within the process even when the wall clock is coarse or steps back.
+func nextTimestamp() Timestamp {
+	lastTimestampMu.Lock()
+	defer lastTimestampMu.Unlock()
+	ts := NewTimestamp(time.Now())
+	if !ts.After(lastTimestamp) {
+		ts = lastTimestamp.increment()
+	}
+	lastTimestamp = ts
+	return ts
+}
+
+func (ts Timestamp) increment() Timestamp {
+	seconds := binary.BigEndian.Uint64(ts[:8])
+	nanos := binary.BigEndian.Uint32(ts[8:]) + 1
+	if nanos >= uint32(time.Second) {
+		seconds, nanos = seconds+1, 0
+	}
+	var next Timestamp
+	binary.BigEndian.PutUint64(next[:8], seconds)
+	binary.BigEndian.PutUint32(next[8:], nanos)
+	return next
+}
+
+// ReplayGuard remembers the latest initiation timestamp accepted from each
+// client static key, so a captured msg1 cannot be replayed to evict the
+// live session. State is kept in memory only; a server restart accepts the
+// next fresh initiation from every peer.
+type ReplayGuard struct {
+	mu     sync.Mutex
+	latest map[[32]byte]Timestamp
+}
+
+// NewReplayGuard creates an empty ReplayGuard.
+func NewReplayGuard() *ReplayGuard {
+	return &ReplayGuard{latest: make(map[[32]byte]Timestamp)}
+}
+
+// Accept records ts for pubKey and reports whether it is newer than every
+// timestamp previously accepted for that key.
+func (g *ReplayGuard) Accept(pubKey []byte, ts Timestamp) bool {
+	key, ok := replayKey(pubKey)
+	if !ok {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if latest, seen := g.latest[key]; seen && !ts.After(latest) {
+		return false
+	}
+	g.latest[key] = ts
+	return true
+}
+
+// AcceptLegacy reports whether an initiation without a timestamp may be
+// accepted for pubKey. Once a key has sent a timestamp, initiations without
+// one are captures from before the client upgraded and are rejected.
+func (g *ReplayGuard) AcceptLegacy(pubKey []byte) bool {
+	key, ok := replayKey(pubKey)
+	if !ok {
+		return false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, seen := g.latest[key]
+	return !seen
+}
+
+func replayKey(pubKey []byte) ([32]byte, bool) {
+	var key [32]byte
+	if len(pubKey) != len(key) {
+		return key, false
+	}
+	copy(key[:], pubKey)
+	return key, true
+}
@@ -0,0 +1,178 @@
+package noise
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewTimestamp_OrdersByTime(t *testing.T) {
+	base := time.Unix(1_700_000_000, 500)
+	earlier := NewTimestamp(base)
+	later := NewTimestamp(base.Add(time.Nanosecond))
+	nextSecond := NewTimestamp(base.Add(time.Second - 501))
+
+	if !later.After(earlier) || earlier.After(later) {
+		t.Fatal("expected later timestamp to sort after earlier one")
+	}
+	if !nextSecond.After(later) {
+		t.Fatal("expected ordering across a second boundary")
+	}
+	if earlier.After(earlier) {
+		t.Fatal("timestamp must not be after itself")
+	}
+}
+
+func TestNextTimestamp_StrictlyIncreasing(t *testing.T) {
+	previous := nextTimestamp()
+	for range 1000 {
+		current := nextTimestamp()
+		if !current.After(previous) {
+			t.Fatalf("timestamp did not increase: %x then %x", previous, current)
+		}
+		previous = current
+	}
+}
+
+func TestTimestampIncrement_CarriesIntoSeconds(t *testing.T) {
+	ts := NewTimestamp(time.Unix(10, int64(time.Second-1)))
+	next := ts.increment()
+	if next != NewTimestamp(time.Unix(11, 0)) {
+		t.Fatalf("unexpected increment result %x", next)
+	}
+}
+
+func TestReplayGuard(t *testing.T) {
+	guard := NewReplayGuard()
+	key := bytes.Repeat([]byte{1}, 32)
+	other := bytes.Repeat([]byte{2}, 32)
+	first := NewTimestamp(time.Unix(100, 0))
+	second := NewTimestamp(time.Unix(101, 0))
+
+	if !guard.AcceptLegacy(key) {
+		t.Fatal("legacy initiation must be accepted before any timestamp")
+	}
+	if !guard.Accept(key, first) {
+		t.Fatal("first timestamp must be accepted")
+	}
+	if guard.Accept(key, first) {
+		t.Fatal("replayed timestamp must be rejected")
+	}
+	if !guard.Accept(key, second) {
+		t.Fatal("newer timestamp must be accepted")
+	}
+	if guard.Accept(key, first) {
+		t.Fatal("older timestamp must be rejected")
+	}
+	if guard.AcceptLegacy(key) {
+		t.Fatal("legacy initiation must be rejected once the key sent a timestamp")
+	}
+	if !guard.Accept(other, first) {
+		t.Fatal("timestamps must be tracked per key")
+	}
+	if guard.Accept([]byte{1}, second) || guard.AcceptLegacy([]byte{1}) {
+		t.Fatal("malformed key must be rejected")
+	}
+}
+
+func TestDecodeInitiatorPayload(t *testing.T) {
+	ts := NewTimestamp(time.Unix(1_700_000_000, 0))
+
+	legacy, err := decodeInitiatorPayload([]byte{byte(CapabilityRekeyV2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.timestamp != nil || !legacy.advertises(CapabilityRekeyV2) {
+		t.Fatalf("unexpected legacy payload %+v", legacy)
+	}
+
+	decoded, err := decodeInitiatorPayload(encodeInitiatorPayload(ts, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.timestamp == nil || *decoded.timestamp != ts {
+		t.Fatalf("timestamp not decoded: %+v", decoded)
+	}
+	if !decoded.advertises(CapabilityRekeyV2) || !decoded.advertises(CapabilityTimestamp) {
+		t.Fatalf("capabilities not decoded: %+v", decoded.capabilities)
+	}
+
+	truncated := encodeInitiatorPayload(ts, nil)
+	if _, err := decodeInitiatorPayload(truncated[:len(truncated)-1]); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Fatalf("expected ErrInvalidTimestamp for truncated payload, got %v", err)
+	}
+	doubled := append(encodeInitiatorPayload(ts, nil), byte(CapabilityTimestamp))
+	doubled = append(doubled, ts[:]...)
+	if _, err := decodeInitiatorPayload(doubled); !errors.Is(err, ErrInvalidTimestamp) {
+		t.Fatalf("expected ErrInvalidTimestamp for repeated timestamp, got %v", err)
+	}
+}
+
+func captureClientMsg1(t *testing.T, clientPub, clientPriv, serverPub []byte) []byte {
+	t.Helper()
+	h := NewIKHandshakeClient(clientPub, clientPriv, serverPub)
+	tr := &queueTransport{}
+	if _, _, err := h.initiatorAttempt(tr, "send", "read"); err == nil {
+		t.Fatal("expected read error without a server")
+	}
+	if len(tr.writes) != 1 {
+		t.Fatalf("expected one msg1, got %d writes", len(tr.writes))
+	}
+	return tr.writes[0]
+}
+
+func TestIKHandshake_ServerRejectsReplayedInitiation(t *testing.T) {
+	serverKP, _ := cipherSuite.GenerateKeypair(nil)
+	clientKP, _ := cipherSuite.GenerateKeypair(nil)
+	peers := newTestAllowedPeers([]testPeer{{PublicKey: clientKP.Public, Enabled: true, ClientID: 1}})
+	guard := NewReplayGuard()
+	newServer := func() *IKHandshake {
+		return NewIKHandshakeServer(serverKP.Public, serverKP.Private, peers, nil, nil, guard)
+	}
+
+	captured := captureClientMsg1(t, clientKP.Public, clientKP.Private, serverKP.Public)
+	fresh := newServer()
+	if _, err := fresh.ServerSideHandshake(&queueTransport{reads: [][]byte{captured}}); err != nil {
+		t.Fatalf("first initiation must be accepted: %v", err)
+	}
+	if !fresh.Supports(CapabilityTimestamp) {
+		t.Fatal("server must select the timestamp capability")
+	}
+
+	replay := &queueTransport{reads: [][]byte{captured}}
+	if _, err := newServer().ServerSideHandshake(replay); !errors.Is(err, ErrStaleInitiation) {
+		t.Fatalf("expected ErrStaleInitiation for replayed msg1, got %v", err)
+	}
+	if len(replay.writes) != 0 {
+		t.Fatal("server must not answer a replayed msg1")
+	}
+
+	legacy := newClientMsg1WithVersion(t, clientKP.Private, clientKP.Public, serverKP.Public)
+	if _, err := newServer().ServerSideHandshake(&queueTransport{reads: [][]byte{legacy}}); !errors.Is(err, ErrStaleInitiation) {
+		t.Fatalf("expected legacy msg1 to be rejected after a timestamped one, got %v", err)
+	}
+
+	next := captureClientMsg1(t, clientKP.Public, clientKP.Private, serverKP.Public)
+	if _, err := newServer().ServerSideHandshake(&queueTransport{reads: [][]byte{next}}); err != nil {
+		t.Fatalf("newer initiation must be accepted: %v", err)
+	}
+}
+
+func TestIKHandshake_ServerAcceptsLegacyClientWithReplayGuard(t *testing.T) {
+	serverKP, _ := cipherSuite.GenerateKeypair(nil)
+	clientKP, _ := cipherSuite.GenerateKeypair(nil)
+	peers := newTestAllowedPeers([]testPeer{{PublicKey: clientKP.Public, Enabled: true, ClientID: 1}})
+	guard := NewReplayGuard()
+
+	for range 2 {
+		h := NewIKHandshakeServer(serverKP.Public, serverKP.Private, peers, nil, nil, guard)
+		msg1 := newClientMsg1WithVersion(t, clientKP.Private, clientKP.Public, serverKP.Public)
+		if _, err := h.ServerSideHandshake(&queueTransport{reads: [][]byte{msg1}}); err != nil {
+			t.Fatalf("legacy client must be accepted: %v", err)
+		}
+		if h.Supports(CapabilityTimestamp) {
+			t.Fatal("timestamp capability must not be selected for a legacy client")
+		}
+	}
+}
@@ -133,7 +133,7 @@ func (c *ClientRekeyCoordinator) buildRekeyInitV2Locked(dst []byte) ([]byte, boo
 
 	packetLen := serviceHeaderLen + len(c.pendingV2Init)
 	if len(dst) < packetLen {
-		return nil, false, nil
+		return nil, false, fmt.Errorf("rekey init of %d bytes exceeds the %d-byte buffer", packetLen, len(dst))
 	}
 	payload := dst[:packetLen]
 	copy(payload[serviceHeaderLen:], c.pendingV2Init)
@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"tungo/internal/config/settings"
 	"tungo/internal/protocol/chacha20/rekey"
 	"tungo/internal/protocol/keys"
 	"tungo/internal/protocol/noise"
@@ -35,9 +36,9 @@ func newTestAllowedPeers(peers []testPeer) testPeers {
 	return lookup
 }
 
-func (p testPeers) Lookup(publicKey []byte) (int, bool, bool) {
+func (p testPeers) Lookup(publicKey []byte) (noise.PeerAccess, bool) {
 	peer, found := p[string(publicKey)]
-	return peer.ClientID, peer.Enabled, found
+	return noise.PeerAccess{ClientID: peer.ClientID, Enabled: peer.Enabled}, found
 }
 
 func (r *rekeyTestEpochManager) StageEpoch(_, _ []byte) (uint16, error) {
@@ -141,7 +142,10 @@ func TestServerRekeyCoordinator_Handle_Success(t *testing.T) {
 	}
 }
 
-func TestRekeyV2(t *testing.T) {
+// rekeyV2Handshakes completes an IK handshake between a server and a client
+// it allows.
+func rekeyV2Handshakes(t *testing.T) (serverHandshake, clientHandshake *noise.IKHandshake) {
+	t.Helper()
 	crypto := &keys.DefaultKeyDeriver{}
 	serverPub, serverPriv, err := crypto.GenerateX25519KeyPair()
 	if err != nil {
@@ -151,7 +155,7 @@ func TestRekeyV2(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	serverHandshake := noise.NewIKHandshakeServer(
+	serverHandshake = noise.NewIKHandshakeServer(
 		serverPub[:],
 		serverPriv[:],
 		newTestAllowedPeers([]testPeer{{
@@ -161,12 +165,15 @@ func TestRekeyV2(t *testing.T) {
 		}}),
 		nil,
 		nil,
+		nil,
 	)
-	clientHandshake := noise.NewIKHandshakeClient(clientPub[:], clientPriv[:], serverPub[:])
+	clientHandshake = noise.NewIKHandshakeClient(clientPub[:], clientPriv[:], serverPub[:])
 
 	clientConn, serverConn := net.Pipe()
-	defer func() { _ = clientConn.Close() }()
-	defer func() { _ = serverConn.Close() }()
+	t.Cleanup(func() {
+		_ = clientConn.Close()
+		_ = serverConn.Close()
+	})
 	clientTransport, err := transport.NewFramedConn(clientConn, 2048)
 	if err != nil {
 		t.Fatal(err)
@@ -186,6 +193,12 @@ func TestRekeyV2(t *testing.T) {
 	if err := <-serverResult; err != nil {
 		t.Fatal(err)
 	}
+	return serverHandshake, clientHandshake
+}
+
+func TestRekeyV2(t *testing.T) {
+	crypto := &keys.DefaultKeyDeriver{}
+	serverHandshake, clientHandshake := rekeyV2Handshakes(t)
 
 	initialC2S := append([]byte(nil), clientHandshake.KeyClientToServer()...)
 	initialS2C := append([]byte(nil), clientHandshake.KeyServerToClient()...)
@@ -202,7 +215,7 @@ func TestRekeyV2(t *testing.T) {
 	)
 	serverCoordinator := NewServerRekeyCoordinator(serverController, serverHandshake)
 
-	init, ok, err := clientCoordinator.MaybeBuildRekeyInit(time.Now(), make([]byte, 1500))
+	init, ok, err := clientCoordinator.MaybeBuildRekeyInit(time.Now(), make([]byte, settings.DefaultEthernetMTU))
 	if err != nil || !ok {
 		t.Fatalf("build RekeyInitV2: ok=%v err=%v", ok, err)
 	}
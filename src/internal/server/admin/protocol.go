@@ -0,0 +1,59 @@
+// Package admin serves the local control socket of a running server.
+//
+// The wire format is one JSON Request per connection answered by one JSON
+// Response, which keeps the socket usable from shell tools such as socat:
+//
+//	echo '{"command":"list"}' | socat - UNIX-CONNECT:/run/tungo/admin.sock
+package admin
+
+import (
+	"net/netip"
+	"time"
+)
+
+// DefaultSocketPath is where `tungo s` listens for admin requests.
+const DefaultSocketPath = "/run/tungo/admin.sock"
+
+type Command string
+
+const (
+	CommandList      Command = "list"
+	CommandTerminate Command = "terminate"
+)
+
+// Request selects a command. Terminate requires exactly one of PublicKey or
+// InternalIP.
+type Request struct {
+	Command Command `json:"command"`
+	// PublicKey is the client's static X25519 key (base64 in JSON).
+	PublicKey []byte `json:"public_key,omitempty"`
+	// InternalIP is the tunnel address allocated to the client.
+	InternalIP string `json:"internal_ip,omitempty"`
+}
+
+type Response struct {
+	Error      string    `json:"error,omitempty"`
+	Sessions   []Session `json:"sessions,omitempty"`
+	Terminated int       `json:"terminated,omitempty"`
+}
+
+// Session describes one live session of a single protocol tunnel.
+type Session struct {
+	Name         string         `json:"name,omitempty"`
+	PublicKey    []byte         `json:"public_key"`
+	InternalIP   netip.Addr     `json:"internal_ip"`
+	ExternalAddr netip.AddrPort `json:"external_addr"`
+	LastActivity time.Time      `json:"last_activity"`
+	Protocol     string         `json:"protocol"`
+	SendEpoch    uint16         `json:"send_epoch"`
+	// Traffic is the usage of this session only.
+	Traffic Traffic `json:"traffic"`
+}
+
+// Traffic counts plaintext IP packets; RX is client → server.
+type Traffic struct {
+	RXBytes   uint64 `json:"rx_bytes"`
+	RXPackets uint64 `json:"rx_packets"`
+	TXBytes   uint64 `json:"tx_bytes"`
+	TXPackets uint64 `json:"tx_packets"`
+}
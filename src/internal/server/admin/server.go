@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	maxRequestSize = 64 << 10
+	requestTimeout = 5 * time.Second
+)
+
+// SessionRegistry is the view of the running server exposed over the socket.
+type SessionRegistry interface {
+	Sessions() []Session
+	RevokeByPubKey(publicKey []byte) int
+	TerminateByInternalIP(addr netip.Addr) int
+}
+
+// Server answers admin requests on a Unix-domain socket.
+type Server struct {
+	path      string
+	registry  SessionRegistry
+	authorize func(net.Conn) error
+}
+
+func NewServer(path string, registry SessionRegistry) *Server {
+	return &Server{
+		path:      path,
+		registry:  registry,
+		authorize: authorizePeer,
+	}
+}
+
+// Serve listens until ctx is cancelled. The socket file is created with 0600
+// permissions inside a 0700 directory and removed on return.
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(s.path) }()
+
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	slog.Info("admin socket listening", "path", s.path)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("admin socket accept failed: %w", err)
+		}
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) listen() (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create admin socket directory: %w", err)
+	}
+	if info, err := os.Lstat(s.path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("admin socket path %s exists and is not a socket", s.path)
+		}
+		// A stale socket from a previous run prevents bind.
+		if err := os.Remove(s.path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale admin socket: %w", err)
+		}
+	}
+	listener, err := net.Listen("unix", s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on admin socket: %w", err)
+	}
+	if err := os.Chmod(s.path, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to restrict admin socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	if err := s.authorize(conn); err != nil {
+		slog.Warn("admin socket connection rejected", "err", err)
+		_ = json.NewEncoder(conn).Encode(Response{Error: "permission denied"})
+		return
+	}
+
+	var request Request
+	if err := json.NewDecoder(io.LimitReader(conn, maxRequestSize)).Decode(&request); err != nil {
+		_ = json.NewEncoder(conn).Encode(Response{Error: fmt.Sprintf("invalid request: %v", err)})
+		return
+	}
+	response := s.handle(request)
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		slog.Warn("admin socket write failed", "err", err)
+	}
+}
+
+func (s *Server) handle(request Request) Response {
+	switch request.Command {
+	case CommandList:
+		return Response{Sessions: s.registry.Sessions()}
+	case CommandTerminate:
+		terminated, err := s.terminate(request)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		slog.Info("admin socket terminated sessions", "count", terminated)
+		return Response{Terminated: terminated}
+	default:
+		return Response{Error: fmt.Sprintf("unknown command %q", request.Command)}
+	}
+}
+
+func (s *Server) terminate(request Request) (int, error) {
+	hasKey := len(request.PublicKey) > 0
+	hasIP := request.InternalIP != ""
+	if hasKey == hasIP {
+		return 0, errors.New("terminate requires exactly one of public_key or internal_ip")
+	}
+	if hasKey {
+		return s.registry.RevokeByPubKey(request.PublicKey), nil
+	}
+	addr, err := netip.ParseAddr(request.InternalIP)
+	if err != nil {
+		return 0, fmt.Errorf("invalid internal_ip: %w", err)
+	}
+	return s.registry.TerminateByInternalIP(addr), nil
+}
@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeRegistry struct {
+	sessions     []Session
+	revokedKey   []byte
+	terminatedIP netip.Addr
+}
+
+func (r *fakeRegistry) Sessions() []Session { return r.sessions }
+
+func (r *fakeRegistry) RevokeByPubKey(publicKey []byte) int {
+	r.revokedKey = publicKey
+	return 2
+}
+
+func (r *fakeRegistry) TerminateByInternalIP(addr netip.Addr) int {
+	r.terminatedIP = addr
+	return 1
+}
+
+func startTestServer(t *testing.T, registry SessionRegistry, authorize func(net.Conn) error) string {
+	t.Helper()
+	// Unix socket paths are length-limited; t.TempDir() can exceed it.
+	dir, err := os.MkdirTemp("", "tungo-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "run", "admin.sock")
+
+	server := NewServer(path, registry)
+	server.authorize = authorize
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("socket was not removed: %v", err)
+		}
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("admin socket did not appear")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func allowAll(net.Conn) error { return nil }
+
+func TestServer_ListSessions(t *testing.T) {
+	registry := &fakeRegistry{sessions: []Session{{
+		Name:       "client-1",
+		PublicKey:  []byte("key"),
+		InternalIP: netip.MustParseAddr("10.0.0.2"),
+		Protocol:   "UDP",
+		SendEpoch:  4,
+	}}}
+	path := startTestServer(t, registry, allowAll)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Fatalf("socket permissions = %o, want 600", perm)
+	}
+
+	response, err := Call(context.Background(), path, Request{Command: CommandList})
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if len(response.Sessions) != 1 {
+		t.Fatalf("sessions = %d, want 1", len(response.Sessions))
+	}
+	got := response.Sessions[0]
+	if got.Name != "client-1" || string(got.PublicKey) != "key" ||
+		got.InternalIP != netip.MustParseAddr("10.0.0.2") || got.Protocol != "UDP" || got.SendEpoch != 4 {
+		t.Fatalf("unexpected session %+v", got)
+	}
+}
+
+func TestServer_Terminate(t *testing.T) {
+	registry := &fakeRegistry{}
+	path := startTestServer(t, registry, allowAll)
+
+	response, err := Call(context.Background(), path, Request{Command: CommandTerminate, PublicKey: []byte("key")})
+	if err != nil || response.Terminated != 2 || string(registry.revokedKey) != "key" {
+		t.Fatalf("terminate by key: response=%+v err=%v revoked=%q", response, err, registry.revokedKey)
+	}
+
+	response, err = Call(context.Background(), path, Request{Command: CommandTerminate, InternalIP: "10.0.0.5"})
+	if err != nil || response.Terminated != 1 || registry.terminatedIP != netip.MustParseAddr("10.0.0.5") {
+		t.Fatalf("terminate by ip: response=%+v err=%v ip=%v", response, err, registry.terminatedIP)
+	}
+}
+
+func TestServer_RejectsInvalidRequests(t *testing.T) {
+	path := startTestServer(t, &fakeRegistry{}, allowAll)
+
+	tests := []struct {
+		name    string
+		request Request
+		want    string
+	}{
+		{"unknown command", Request{Command: "reboot"}, "unknown command"},
+		{"no selector", Request{Command: CommandTerminate}, "exactly one"},
+		{"both selectors", Request{Command: CommandTerminate, PublicKey: []byte("k"), InternalIP: "10.0.0.2"}, "exactly one"},
+		{"bad ip", Request{Command: CommandTerminate, InternalIP: "nope"}, "invalid internal_ip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Call(context.Background(), path, tt.request)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Call() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_RejectsUnauthorizedPeer(t *testing.T) {
+	registry := &fakeRegistry{}
+	path := startTestServer(t, registry, func(net.Conn) error { return errors.New("uid 1000 is not allowed") })
+
+	_, err := Call(context.Background(), path, Request{Command: CommandTerminate, PublicKey: []byte("key")})
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Fatalf("Call() error = %v, want permission denied", err)
+	}
+	if registry.revokedKey != nil {
+		t.Fatal("unauthorized request reached the registry")
+	}
+}
+
+func TestServer_RefusesNonSocketPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "admin.sock")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	err := NewServer(path, &fakeRegistry{}).Serve(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Fatalf("Serve() error = %v, want not a socket", err)
+	}
+}
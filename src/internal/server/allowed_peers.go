@@ -1,9 +1,13 @@
 package server
 
 import (
+	"net/netip"
+	"slices"
 	"sync/atomic"
+	"time"
 
 	serverconfig "tungo/internal/config/server"
+	"tungo/internal/protocol/noise"
 )
 
 type allowedPeers struct {
@@ -11,8 +15,12 @@ type allowedPeers struct {
 }
 
 type allowedPeer struct {
-	enabled  bool
-	clientID int
+	name         string
+	enabled      bool
+	clientID     int
+	allowedIPs   []netip.Prefix
+	expiresAt    time.Time
+	presharedKey []byte
 }
 
 func newAllowedPeers(peers []serverconfig.AllowedPeer) *allowedPeers {
@@ -21,25 +29,63 @@ func newAllowedPeers(peers []serverconfig.AllowedPeer) *allowedPeers {
 	return lookup
 }
 
-func (a *allowedPeers) Lookup(publicKey []byte) (int, bool, bool) {
+func (a *allowedPeers) Lookup(publicKey []byte) (noise.PeerAccess, bool) {
 	peers := a.peers.Load()
 	if peers == nil {
-		return 0, false, false
+		return noise.PeerAccess{}, false
 	}
 	peer, ok := (*peers)[string(publicKey)]
 	if !ok {
-		return 0, false, false
+		return noise.PeerAccess{}, false
 	}
-	return peer.clientID, peer.enabled, true
+	return noise.PeerAccess{
+		ClientID:     peer.clientID,
+		Enabled:      peer.enabled && (peer.expiresAt.IsZero() || time.Now().Before(peer.expiresAt)),
+		AllowedIPs:   peer.allowedIPs,
+		PresharedKey: peer.presharedKey,
+	}, true
 }
 
-func (a *allowedPeers) Update(peers []serverconfig.AllowedPeer) {
+// Name returns the configured display name of the peer.
+func (a *allowedPeers) Name(publicKey []byte) (string, bool) {
+	peers := a.peers.Load()
+	if peers == nil {
+		return "", false
+	}
+	peer, ok := (*peers)[string(publicKey)]
+	return peer.name, ok
+}
+
+// Update replaces the lookup and returns the public keys whose AllowedIPs
+// changed, including peers that were added or removed with AllowedIPs.
+func (a *allowedPeers) Update(peers []serverconfig.AllowedPeer) [][]byte {
 	byPublicKey := make(map[string]allowedPeer, len(peers))
 	for _, peer := range peers {
 		byPublicKey[string(peer.PublicKey)] = allowedPeer{
-			enabled:  peer.Enabled,
-			clientID: peer.ClientID,
+			name:         peer.Name,
+			enabled:      peer.Enabled,
+			clientID:     peer.ClientID,
+			allowedIPs:   slices.Clone(peer.AllowedIPs),
+			expiresAt:    peer.ExpiresAt,
+			presharedKey: slices.Clone(peer.PresharedKey),
+		}
+	}
+	previous := a.peers.Swap(&byPublicKey)
+
+	var rerouted [][]byte
+	var before map[string]allowedPeer
+	if previous != nil {
+		before = *previous
+	}
+	for key, peer := range byPublicKey {
+		if !slices.Equal(before[key].allowedIPs, peer.allowedIPs) {
+			rerouted = append(rerouted, []byte(key))
+		}
+	}
+	for key, peer := range before {
+		if _, kept := byPublicKey[key]; !kept && len(peer.allowedIPs) > 0 {
+			rerouted = append(rerouted, []byte(key))
 		}
 	}
-	a.peers.Store(&byPublicKey)
+	return rerouted
 }
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"bytes"
+	"net/netip"
 	"testing"
+	"time"
 
 	serverconfig "tungo/internal/config/server"
 )
@@ -21,11 +24,83 @@ func TestAllowedPeersUpdateReplacesLookup(t *testing.T) {
 		Enabled:   false,
 	}})
 
-	if _, _, found := peers.Lookup(oldKey); found {
+	if _, found := peers.Lookup(oldKey); found {
 		t.Fatal("old peer remains after replacement")
 	}
-	clientID, enabled, found := peers.Lookup(newKey)
-	if !found || enabled || clientID != 2 {
-		t.Fatalf("Lookup() = (%d, %v, %v), want (2, false, true)", clientID, enabled, found)
+	access, found := peers.Lookup(newKey)
+	if !found || access.Enabled || access.ClientID != 2 {
+		t.Fatalf("Lookup() = (%+v, %v), want ClientID 2 disabled", access, found)
+	}
+}
+
+func TestAllowedPeersUpdateReportsRerouted(t *testing.T) {
+	site := []byte("site")
+	phone := []byte("phone")
+	lan := []netip.Prefix{netip.MustParsePrefix("192.168.10.0/24")}
+	peers := newAllowedPeers([]serverconfig.AllowedPeer{
+		{PublicKey: site, ClientID: 1, Enabled: true, AllowedIPs: lan},
+		{PublicKey: phone, ClientID: 2, Enabled: true},
+	})
+
+	if rerouted := peers.Update([]serverconfig.AllowedPeer{
+		{PublicKey: site, ClientID: 1, Enabled: true, AllowedIPs: lan},
+		{PublicKey: phone, ClientID: 2, Enabled: false},
+	}); len(rerouted) != 0 {
+		t.Fatalf("rerouted = %q, want none", rerouted)
+	}
+
+	moved := []netip.Prefix{netip.MustParsePrefix("192.168.20.0/24")}
+	rerouted := peers.Update([]serverconfig.AllowedPeer{
+		{PublicKey: site, ClientID: 1, Enabled: true, AllowedIPs: moved},
+		{PublicKey: phone, ClientID: 2, Enabled: true},
+	})
+	if len(rerouted) != 1 || string(rerouted[0]) != "site" {
+		t.Fatalf("rerouted = %q, want [site]", rerouted)
+	}
+	if access, _ := peers.Lookup(site); len(access.AllowedIPs) != 1 || access.AllowedIPs[0] != moved[0] {
+		t.Fatalf("AllowedIPs = %v, want %v", access.AllowedIPs, moved)
+	}
+}
+
+func TestAllowedPeersLookupDisablesExpiredPeer(t *testing.T) {
+	expired := []byte("expired")
+	current := []byte("current")
+	peers := newAllowedPeers([]serverconfig.AllowedPeer{
+		{PublicKey: expired, ClientID: 1, Enabled: true, ExpiresAt: time.Now().Add(-time.Minute)},
+		{PublicKey: current, ClientID: 2, Enabled: true, ExpiresAt: time.Now().Add(time.Hour)},
+	})
+
+	if access, found := peers.Lookup(expired); !found || access.Enabled {
+		t.Fatalf("Lookup(expired) = (%+v, %v), want found and disabled", access, found)
+	}
+	if access, found := peers.Lookup(current); !found || !access.Enabled {
+		t.Fatalf("Lookup(current) = (%+v, %v), want found and enabled", access, found)
+	}
+}
+
+func TestAllowedPeersLookupPresharedKey(t *testing.T) {
+	psk := bytes.Repeat([]byte{7}, 32)
+	peers := newAllowedPeers([]serverconfig.AllowedPeer{
+		{PublicKey: []byte("psk"), ClientID: 1, Enabled: true, PresharedKey: psk},
+		{PublicKey: []byte("plain"), ClientID: 2, Enabled: true},
+	})
+
+	if access, _ := peers.Lookup([]byte("psk")); !bytes.Equal(access.PresharedKey, psk) {
+		t.Fatalf("PresharedKey = %x, want %x", access.PresharedKey, psk)
+	}
+	if access, _ := peers.Lookup([]byte("plain")); access.PresharedKey != nil {
+		t.Fatalf("PresharedKey = %x, want none", access.PresharedKey)
+	}
+}
+
+func TestAllowedPeersName(t *testing.T) {
+	key := []byte("key")
+	peers := newAllowedPeers([]serverconfig.AllowedPeer{{Name: "phone", PublicKey: key, ClientID: 1}})
+
+	if name, found := peers.Name(key); !found || name != "phone" {
+		t.Fatalf("Name() = (%q, %v), want (phone, true)", name, found)
+	}
+	if _, found := peers.Name([]byte("other")); found {
+		t.Fatal("unexpected name for unknown key")
 	}
 }
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"tungo/internal/metrics"
+	"tungo/internal/trafficstats"
+)
+
+// rekeyCounter is implemented by tunnels that answer client rekeys.
+type rekeyCounter interface {
+	RekeysCompleted() uint64
+	EpochsExhausted() uint64
+}
+
+// registrationCounter is implemented by tunnels with concurrent handshakes.
+type registrationCounter interface {
+	RegistrationsInFlight() int
+}
+
+// serveMetrics runs the metrics listener. Failing to open it is logged but
+// does not stop the tunnels.
+func (s *Server) serveMetrics(ctx context.Context, registry *metrics.Registry) {
+	if err := metrics.Serve(ctx, s.configuration.MetricsAddress, s.configuration.MetricsAllowRemote, registry); err != nil {
+		slog.Warn("metrics listener unavailable", "err", err)
+	}
+}
+
+// metricsRegistry describes the server counters. It installs a global traffic
+// collector when none is set, so it must be called before tunnels are created.
+func (s *Server) metricsRegistry() *metrics.Registry {
+	if trafficstats.Global() == nil {
+		trafficstats.SetGlobal(trafficstats.NewCollector(time.Second, 0.35))
+	}
+
+	registry := metrics.NewRegistry()
+	if s.loadMonitor != nil {
+		registry.Gauge("tungo_server_handshakes_per_second",
+			"Handshake rate observed by the load monitor.",
+			func() float64 { return float64(s.loadMonitor.HandshakesPerSecond()) })
+	}
+	if s.cookieManager != nil {
+		registry.Counter("tungo_server_cookie_replies_total",
+			"Cookie replies issued to handshakes under load.",
+			s.cookieManager.RepliesIssued)
+	}
+	registry.GaugeVec("tungo_server_registrations_in_flight",
+		"Handshakes currently being processed.", "protocol",
+		s.collectProtocols(func(registered protocolRepository) (float64, bool) {
+			counter, ok := registered.tunnel.(registrationCounter)
+			if !ok {
+				return 0, false
+			}
+			return float64(counter.RegistrationsInFlight()), true
+		}))
+	registry.GaugeVec("tungo_server_sessions",
+		"Live sessions.", "protocol",
+		s.collectProtocols(func(registered protocolRepository) (float64, bool) {
+			return float64(registered.repository.Len()), true
+		}))
+	registry.CounterVec("tungo_server_idle_reaps_total",
+		"Sessions removed after being idle.", "protocol",
+		s.collectProtocols(func(registered protocolRepository) (float64, bool) {
+			return float64(registered.repository.Reaped()), true
+		}))
+	registry.CounterVec("tungo_server_rekeys_total",
+		"Rekeys completed.", "protocol",
+		s.collectProtocols(func(registered protocolRepository) (float64, bool) {
+			counter, ok := registered.tunnel.(rekeyCounter)
+			if !ok {
+				return 0, false
+			}
+			return float64(counter.RekeysCompleted()), true
+		}))
+	registry.CounterVec("tungo_server_epoch_exhausted_total",
+		"Rekeys refused because the peer ran out of key epochs.", "protocol",
+		s.collectProtocols(func(registered protocolRepository) (float64, bool) {
+			counter, ok := registered.tunnel.(rekeyCounter)
+			if !ok {
+				return 0, false
+			}
+			return float64(counter.EpochsExhausted()), true
+		}))
+	metrics.RegisterTraffic(registry)
+	return registry
+}
+
+// collectProtocols sums value over registered tunnels by protocol name.
+func (s *Server) collectProtocols(
+	value func(protocolRepository) (float64, bool),
+) func() map[string]float64 {
+	return func() map[string]float64 {
+		samples := make(map[string]float64)
+		for _, registered := range s.registered() {
+			if v, ok := value(registered); ok {
+				samples[registered.protocol.String()] += v
+			}
+		}
+		return samples
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"net/netip"
+	"strings"
+	"testing"
+
+	"tungo/internal/config/settings"
+	"tungo/internal/protocol/noise"
+	"tungo/internal/server/session"
+	"tungo/internal/trafficstats"
+)
+
+type countingTunnel struct{}
+
+func (countingTunnel) Run() error                 { return nil }
+func (countingTunnel) RekeysCompleted() uint64    { return 4 }
+func (countingTunnel) EpochsExhausted() uint64    { return 1 }
+func (countingTunnel) RegistrationsInFlight() int { return 2 }
+
+func TestServerMetricsExportTunnelCounters(t *testing.T) {
+	t.Cleanup(func() { trafficstats.SetGlobal(nil) })
+	trafficstats.SetGlobal(nil)
+
+	s := &Server{loadMonitor: noise.NewLoadMonitor(noise.DefaultLoadThreshold)}
+	udpRepo := session.NewRepository()
+	udpRepo.Add(session.NewPeer(nil, nil, netip.MustParseAddr("10.0.0.2"), netip.AddrPort{}, nil))
+	s.register(settings.UDP, udpRepo, countingTunnel{})
+	s.register(settings.TCP, session.NewRepository(), nil)
+
+	registry := s.metricsRegistry()
+	if trafficstats.Global() == nil {
+		t.Fatal("metrics did not install a traffic collector")
+	}
+	trafficstats.Global().AddRX(10)
+
+	var out strings.Builder
+	if _, err := registry.WriteTo(&out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`tungo_server_sessions{protocol="UDP"} 1`,
+		`tungo_server_sessions{protocol="TCP"} 0`,
+		`tungo_server_rekeys_total{protocol="UDP"} 4`,
+		`tungo_server_epoch_exhausted_total{protocol="UDP"} 1`,
+		`tungo_server_registrations_in_flight{protocol="UDP"} 2`,
+		`tungo_server_handshakes_per_second 0`,
+		`tungo_rx_bytes_total 10`,
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Fatalf("missing %q in:\n%s", line, out.String())
+		}
+	}
+	if strings.Contains(out.String(), `tungo_server_rekeys_total{protocol="TCP"}`) {
+		t.Fatalf("tunnel without counters exported rekeys:\n%s", out.String())
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"sync/atomic"
+
+	"tungo/internal/config/settings"
+)
+
+type routeTable interface {
+	AddRoute(settings.Settings, netip.Prefix) error
+	DeleteRoute(settings.Settings, netip.Prefix) error
+}
+
+// peerRoutes points the AllowedIPs of live sessions into one protocol TUN.
+// Sync only records the wanted set; route commands run on the run goroutine
+// so that repository locks are never held across them.
+type peerRoutes struct {
+	settings settings.Settings
+	table    routeTable
+	wanted   atomic.Pointer[[]netip.Prefix]
+	wake     chan struct{}
+	// installed is owned by the run goroutine.
+	installed map[netip.Prefix]struct{}
+}
+
+func newPeerRoutes(workerSettings settings.Settings, table routeTable) *peerRoutes {
+	return &peerRoutes{
+		settings:  workerSettings,
+		table:     table,
+		wake:      make(chan struct{}, 1),
+		installed: make(map[netip.Prefix]struct{}),
+	}
+}
+
+// Sync implements session.Router.
+func (r *peerRoutes) Sync(prefixes []netip.Prefix) {
+	wanted := append([]netip.Prefix(nil), prefixes...)
+	r.wanted.Store(&wanted)
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run applies route changes until ctx is cancelled. Installed routes are left
+// in place on exit: they are removed with the TUN device.
+func (r *peerRoutes) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+			r.reconcile()
+		}
+	}
+}
+
+func (r *peerRoutes) reconcile() {
+	wanted := make(map[netip.Prefix]struct{})
+	if prefixes := r.wanted.Load(); prefixes != nil {
+		for _, prefix := range *prefixes {
+			wanted[prefix] = struct{}{}
+		}
+	}
+	for prefix := range r.installed {
+		if _, keep := wanted[prefix]; keep {
+			continue
+		}
+		if err := r.table.DeleteRoute(r.settings, prefix); err != nil {
+			slog.Warn("failed to remove peer route", "prefix", prefix, "tun", r.settings.TunName, "err", err)
+		}
+		delete(r.installed, prefix)
+	}
+	for prefix := range wanted {
+		if _, done := r.installed[prefix]; done {
+			continue
+		}
+		if err := r.table.AddRoute(r.settings, prefix); err != nil {
+			slog.Warn("failed to add peer route", "prefix", prefix, "tun", r.settings.TunName, "err", err)
+			continue
+		}
+		r.installed[prefix] = struct{}{}
+	}
+}
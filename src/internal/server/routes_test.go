@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"tungo/internal/config/settings"
+)
+
+type recordingRouteTable struct {
+	added   []netip.Prefix
+	deleted []netip.Prefix
+	failAdd map[netip.Prefix]bool
+}
+
+func (t *recordingRouteTable) AddRoute(_ settings.Settings, prefix netip.Prefix) error {
+	if t.failAdd[prefix] {
+		return errors.New("add failed")
+	}
+	t.added = append(t.added, prefix)
+	return nil
+}
+
+func (t *recordingRouteTable) DeleteRoute(_ settings.Settings, prefix netip.Prefix) error {
+	t.deleted = append(t.deleted, prefix)
+	return nil
+}
+
+func TestPeerRoutesReconcile(t *testing.T) {
+	lan := netip.MustParsePrefix("192.168.50.0/24")
+	office := netip.MustParsePrefix("10.20.0.0/16")
+	table := &recordingRouteTable{}
+	routes := newPeerRoutes(settings.Settings{Addressing: settings.Addressing{TunName: "tungo0"}}, table)
+
+	routes.Sync([]netip.Prefix{lan, office})
+	routes.reconcile()
+	if len(table.added) != 2 || len(table.deleted) != 0 {
+		t.Fatalf("expected 2 routes added, got added=%v deleted=%v", table.added, table.deleted)
+	}
+
+	routes.Sync([]netip.Prefix{office})
+	routes.reconcile()
+	if len(table.added) != 2 || len(table.deleted) != 1 || table.deleted[0] != lan {
+		t.Fatalf("expected %s removed only, got added=%v deleted=%v", lan, table.added, table.deleted)
+	}
+}
+
+func TestPeerRoutesReconcileRetriesFailedAdd(t *testing.T) {
+	lan := netip.MustParsePrefix("192.168.50.0/24")
+	table := &recordingRouteTable{failAdd: map[netip.Prefix]bool{lan: true}}
+	routes := newPeerRoutes(settings.Settings{Addressing: settings.Addressing{TunName: "tungo0"}}, table)
+
+	routes.Sync([]netip.Prefix{lan})
+	routes.reconcile()
+	if len(table.added) != 0 {
+		t.Fatalf("expected no installed route, got %v", table.added)
+	}
+
+	table.failAdd = nil
+	routes.Sync([]netip.Prefix{lan})
+	routes.reconcile()
+	if len(table.added) != 1 || table.added[0] != lan {
+		t.Fatalf("expected failed route to be retried, got %v", table.added)
+	}
+}
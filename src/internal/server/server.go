@@ -13,6 +13,7 @@ import (
 	"tungo/internal/config"
 	"tungo/internal/config/settings"
 	"tungo/internal/protocol/noise"
+	"tungo/internal/server/admin"
 	"tungo/internal/server/session"
 	tcpserver "tungo/internal/server/tcp"
 	udpserver "tungo/internal/server/udp"
@@ -29,7 +30,15 @@ type protocolTunnel interface {
 
 // New builds a server that owns all configured protocol tunnels.
 func New() (*Server, error) {
-	control := config.NewServerControl()
+	return newServer(config.NewServerControl())
+}
+
+// NewAt builds a server from the configuration at path.
+func NewAt(path string) (*Server, error) {
+	return newServer(config.NewServerControlAt(path))
+}
+
+func newServer(control config.ServerControl) (*Server, error) {
 	if control == nil {
 		return nil, fmt.Errorf("server runtime is not supported on this platform")
 	}
@@ -50,6 +59,9 @@ func New() (*Server, error) {
 		allowedPeers:  newAllowedPeers(conf.AllowedPeers),
 		cookieManager: cookieManager,
 		loadMonitor:   noise.NewLoadMonitor(noise.DefaultLoadThreshold),
+		replayGuard:   noise.NewReplayGuard(),
+		adminSocket:   admin.DefaultSocketPath,
+		ledger:        session.NewLedger(),
 	}, nil
 }
 
@@ -66,16 +78,50 @@ func (s *Server) Run(ctx context.Context) error {
 	} else {
 		close(watcherDone)
 	}
+	adminDone := make(chan struct{})
+	go func() {
+		defer close(adminDone)
+		s.serveAdmin(runCtx)
+	}()
+	trafficDone := make(chan struct{})
+	go func() {
+		defer close(trafficDone)
+		s.runTrafficAccounting(runCtx)
+	}()
+	metricsDone := make(chan struct{})
+	if s.configuration.MetricsAddress != "" {
+		registry := s.metricsRegistry()
+		go func() {
+			defer close(metricsDone)
+			s.serveMetrics(runCtx, registry)
+		}()
+	} else {
+		close(metricsDone)
+	}
 
 	err := s.run(runCtx)
 	cancel()
 	<-watcherDone
+	<-adminDone
+	<-trafficDone
+	<-metricsDone
 	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
 		return nil
 	}
 	return err
 }
 
+// serveAdmin runs the local admin socket. Failing to open it is logged but does
+// not stop the tunnels.
+func (s *Server) serveAdmin(ctx context.Context) {
+	if s.adminSocket == "" {
+		return
+	}
+	if err := admin.NewServer(s.adminSocket, s).Serve(ctx); err != nil {
+		slog.Warn("admin socket unavailable", "err", err)
+	}
+}
+
 func (s *Server) Ready() bool {
 	return s.ready.Load()
 }
@@ -158,16 +204,25 @@ func (s *Server) newTunnel(
 	}
 }
 
+// newRepository creates the session repository of one protocol tunnel, with
+// traffic accounting and routes for the AllowedIPs of its sessions.
+func (s *Server) newRepository(ctx context.Context, workerSettings settings.Settings) *session.Repository {
+	repository := session.NewRepositoryWithLedger(s.ledger)
+	routes := newPeerRoutes(workerSettings, s.tunManager)
+	repository.SetRouter(routes)
+	go routes.run(ctx)
+	return repository
+}
+
 var _ config.ServerSessionRevoker = (*Server)(nil)
 var _ config.ServerAllowedPeersUpdater = (*Server)(nil)
+var _ admin.SessionRegistry = (*Server)(nil)
 
 func (s *Server) newTCPTunnel(
 	ctx context.Context,
 	tun io.ReadWriteCloser,
 	workerSettings settings.Settings,
 ) (protocolTunnel, error) {
-	sessionManager := session.NewRepository()
-
 	addrPort, addrPortErr := s.addrPortToListen(workerSettings.Server, workerSettings.Port)
 	if addrPortErr != nil {
 		return nil, addrPortErr
@@ -179,21 +234,15 @@ func (s *Server) newTCPTunnel(
 	}
 	slog.Info("server listening", "protocol", workerSettings.Protocol, "address", listener.Addr())
 
-	s.register(sessionManager)
-
+	sessionManager := s.newRepository(ctx, workerSettings)
 	server := tcpserver.New(
 		ctx, tun, listener, sessionManager,
 		func() *noise.IKHandshake {
-			return noise.NewIKHandshakeServer(
-				s.configuration.X25519PublicKey,
-				s.configuration.X25519PrivateKey,
-				s.allowedPeers,
-				s.cookieManager,
-				s.loadMonitor,
-			)
+			return s.newHandshake(workerSettings.Encryption)
 		},
 		workerSettings.IPv4Subnet, workerSettings.IPv6Subnet,
 	)
+	s.register(workerSettings.Protocol, sessionManager, server)
 	return server, nil
 }
 
@@ -202,8 +251,6 @@ func (s *Server) newWSTunnel(
 	tun io.ReadWriteCloser,
 	workerSettings settings.Settings,
 ) (protocolTunnel, error) {
-	sessionManager := session.NewRepository()
-
 	addrPort, addrPortErr := s.addrPortToListen(workerSettings.Server, workerSettings.Port)
 	if addrPortErr != nil {
 		return nil, addrPortErr
@@ -221,21 +268,15 @@ func (s *Server) newWSTunnel(
 	}
 	slog.Info("server listening", "protocol", workerSettings.Protocol, "address", tcpListener.Addr())
 
-	s.register(sessionManager)
-
+	sessionManager := s.newRepository(ctx, workerSettings)
 	server := tcpserver.New(
 		ctx, tun, wsListener, sessionManager,
 		func() *noise.IKHandshake {
-			return noise.NewIKHandshakeServer(
-				s.configuration.X25519PublicKey,
-				s.configuration.X25519PrivateKey,
-				s.allowedPeers,
-				s.cookieManager,
-				s.loadMonitor,
-			)
+			return s.newHandshake(workerSettings.Encryption)
 		},
 		workerSettings.IPv4Subnet, workerSettings.IPv6Subnet,
 	)
+	s.register(workerSettings.Protocol, sessionManager, server)
 	return server, nil
 }
 
@@ -244,8 +285,6 @@ func (s *Server) newUDPTunnel(
 	tun io.ReadWriteCloser,
 	workerSettings settings.Settings,
 ) (protocolTunnel, error) {
-	sessionManager := session.NewRepository()
-
 	addrPort, addrPortErr := s.addrPortToListen(workerSettings.Server, workerSettings.Port)
 	if addrPortErr != nil {
 		return nil, addrPortErr
@@ -257,24 +296,35 @@ func (s *Server) newUDPTunnel(
 	}
 	slog.Info("server listening", "protocol", workerSettings.Protocol, "address", conn.LocalAddr())
 
-	s.register(sessionManager)
-
+	sessionManager := s.newRepository(ctx, workerSettings)
 	server := udpserver.New(
 		ctx, tun, conn, sessionManager,
 		func() *noise.IKHandshake {
-			return noise.NewIKHandshakeServer(
-				s.configuration.X25519PublicKey,
-				s.configuration.X25519PrivateKey,
-				s.allowedPeers,
-				s.cookieManager,
-				s.loadMonitor,
-			)
+			return s.newHandshake(workerSettings.Encryption)
 		},
 		workerSettings.IPv4Subnet, workerSettings.IPv6Subnet,
 	)
+	s.register(workerSettings.Protocol, sessionManager, server)
 	return server, nil
 }
 
+// newHandshake creates the server side of a handshake that offers the AEAD
+// selected by encryption.
+func (s *Server) newHandshake(encryption settings.Encryption) *noise.IKHandshake {
+	handshake := noise.NewIKHandshakeServer(
+		s.configuration.X25519PublicKey,
+		s.configuration.X25519PrivateKey,
+		s.allowedPeers,
+		s.cookieManager,
+		s.loadMonitor,
+		s.replayGuard,
+	)
+	if encryption == settings.AES256GCM {
+		handshake.OfferAES256GCM()
+	}
+	return handshake
+}
+
 func (s *Server) addrPortToListen(
 	host settings.Host,
 	port int,
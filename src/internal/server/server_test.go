@@ -339,6 +339,9 @@ func (m *serverLifecycleTunManager) DisposeDevices(settings.Settings) error {
 	return m.disposeErr
 }
 
+func (*serverLifecycleTunManager) AddRoute(settings.Settings, netip.Prefix) error    { return nil }
+func (*serverLifecycleTunManager) DeleteRoute(settings.Settings, netip.Prefix) error { return nil }
+
 func TestServerRunOwnsCleanupAndReadiness(t *testing.T) {
 	manager := &serverLifecycleTunManager{}
 	server := &Server{configuration: &serverconfig.Configuration{}, tunManager: manager}
@@ -43,10 +43,13 @@ type Peer struct {
 	clientPubKey []byte
 	allowedAddrs map[netip.Addr]struct{}
 	allowedNets  []netip.Prefix
+	routes       atomic.Pointer[[]netip.Prefix] // configured AllowedIPs, replaced on reload
 	closed       atomic.Bool
 	lastActivity atomic.Int64 // unix seconds
 	roamedAddr   atomic.Pointer[netip.AddrPort]
 	cryptoMu     sync.RWMutex // protects crypto from concurrent zeroize
+	traffic      Traffic
+	aggregate    atomic.Pointer[Traffic] // per-public-key totals, set by Repository.Add
 }
 
 func NewPeer(
@@ -122,6 +125,27 @@ func (p *Peer) InternalAddr() netip.Addr {
 	return p.internalIP
 }
 
+// ClientPubKey returns a copy of the static key the client authenticated with.
+func (p *Peer) ClientPubKey() []byte {
+	return append([]byte(nil), p.clientPubKey...)
+}
+
+// SendEpoch returns the epoch used for outbound packets, or 0 when the
+// session crypto does not track epochs.
+func (p *Peer) SendEpoch() uint16 {
+	type sendEpochProvider interface {
+		SendEpoch() uint16
+	}
+
+	p.cryptoMu.RLock()
+	defer p.cryptoMu.RUnlock()
+	provider, ok := p.crypto.(sendEpochProvider)
+	if !ok || p.closed.Load() {
+		return 0
+	}
+	return provider.SendEpoch()
+}
+
 func (p *Peer) HandleRekey(
 	carrierEpoch uint16,
 	deriver keys.KeyDeriver,
@@ -158,9 +182,33 @@ func (p *Peer) IsSourceAllowed(srcIP netip.Addr) bool {
 			return true
 		}
 	}
+	for _, prefix := range p.Routes() {
+		if prefix.Contains(srcIP) {
+			return true
+		}
+	}
 	return false
 }
 
+// Routes returns the networks behind the client that are routed into this
+// session.
+func (p *Peer) Routes() []netip.Prefix {
+	if routes := p.routes.Load(); routes != nil {
+		return *routes
+	}
+	return nil
+}
+
+// SetRoutes replaces the routed networks, e.g. after a configuration reload.
+// Use Repository.UpdateRoutes for registered peers so kernel routes follow.
+func (p *Peer) SetRoutes(prefixes []netip.Prefix) {
+	routes := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		routes = append(routes, netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()).Masked())
+	}
+	p.routes.Store(&routes)
+}
+
 // ExternalAddrPort returns the roamed address if set, otherwise the registration address.
 func (p *Peer) ExternalAddrPort() netip.AddrPort {
 	if addr := p.roamedAddr.Load(); addr != nil {
@@ -190,6 +238,27 @@ func (p *Peer) LastActivity() time.Time {
 	return time.Unix(p.lastActivity.Load(), 0)
 }
 
+// CountRX records one data packet of the given size delivered to the TUN.
+func (p *Peer) CountRX(bytes int) {
+	p.traffic.addRX(bytes)
+	if aggregate := p.aggregate.Load(); aggregate != nil {
+		aggregate.addRX(bytes)
+	}
+}
+
+// CountTX records one data packet of the given size sent to the client.
+func (p *Peer) CountTX(bytes int) {
+	p.traffic.addTX(bytes)
+	if aggregate := p.aggregate.Load(); aggregate != nil {
+		aggregate.addTX(bytes)
+	}
+}
+
+// Traffic returns the usage of this session only.
+func (p *Peer) Traffic() TrafficSnapshot {
+	return p.traffic.Snapshot()
+}
+
 // Decrypt decrypts data while protecting session crypto from concurrent
 // lifecycle teardown and zeroization.
 func (p *Peer) Decrypt(data []byte) ([]byte, error) {
@@ -3,6 +3,7 @@ package session
 import (
 	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,14 +23,30 @@ type Repository struct {
 	// pubKeyToPeers tracks sessions by client public key for revocation support.
 	// Multiple sessions may exist for the same pubkey (e.g., TCP + UDP).
 	pubKeyToPeers map[string][]*Peer
+	// ledger, when set, aggregates traffic of authenticated peers by public key.
+	ledger *Ledger
+	// router, when set, is told the routed networks of live sessions.
+	router Router
+	reaped atomic.Uint64
+}
+
+// Router installs kernel routes for the networks behind connected clients.
+// Sync is called with the repository lock held and must not block.
+type Router interface {
+	Sync(prefixes []netip.Prefix)
 }
 
 func NewRepository() *Repository {
+	return NewRepositoryWithLedger(nil)
+}
+
+func NewRepositoryWithLedger(ledger *Ledger) *Repository {
 	return &Repository{
 		internalIpToPeer:  make(map[netip.Addr]*Peer),
 		routeIDToPeer:     make(map[uint64]*Peer),
 		allowedAddrToPeer: make(map[netip.Addr]*Peer),
 		pubKeyToPeers:     make(map[string][]*Peer),
+		ledger:            ledger,
 	}
 }
 
@@ -51,7 +68,55 @@ func (s *Repository) Add(peer *Peer) {
 	if len(peer.clientPubKey) > 0 {
 		key := string(peer.clientPubKey)
 		s.pubKeyToPeers[key] = append(s.pubKeyToPeers[key], peer)
+		if s.ledger != nil {
+			peer.aggregate.Store(s.ledger.traffic(key))
+		}
+	}
+	if len(peer.Routes()) > 0 {
+		s.syncRoutesLocked()
+	}
+}
+
+// SetRouter attaches router and syncs it with the current sessions.
+func (s *Repository) SetRouter(router Router) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.router = router
+	s.syncRoutesLocked()
+}
+
+// UpdateRoutes replaces the routed networks of every session authenticated
+// with pubKey without terminating them. Returns the number of sessions
+// updated.
+func (s *Repository) UpdateRoutes(pubKey []byte, prefixes []netip.Prefix) int {
+	if len(pubKey) == 0 {
+		return 0
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	peers := s.pubKeyToPeers[string(pubKey)]
+	for _, peer := range peers {
+		peer.SetRoutes(prefixes)
+	}
+	if len(peers) > 0 {
+		s.syncRoutesLocked()
+	}
+	return len(peers)
+}
+
+// syncRoutesLocked reports the routed networks of all live sessions to the
+// router. Caller MUST hold s.mu.Lock().
+func (s *Repository) syncRoutesLocked() {
+	if s.router == nil {
+		return
+	}
+	var prefixes []netip.Prefix
+	for _, peer := range s.internalIpToPeer {
+		prefixes = append(prefixes, peer.Routes()...)
 	}
+	s.router.Sync(prefixes)
 }
 
 // Delete removes peer from repository and zeroes key material.
@@ -131,8 +196,8 @@ func (s *Repository) FindByDestinationIP(addr netip.Addr) (*Peer, error) {
 // TerminateByPubKey finds and terminates all sessions for the given public key.
 // Returns the number of sessions terminated.
 //
-// SECURITY: Must be called after AllowedPeers config changes to prevent
-// stale AllowedIPs snapshots from persisting.
+// SECURITY: Must be called when a peer is removed or disabled. AllowedIPs
+// changes are applied in place with UpdateRoutes.
 //
 // LIFECYCLE: First closes all egress paths (signals workers to exit),
 // then removes from maps, then zeroes keys. This ordering prevents use-after-free.
@@ -159,6 +224,37 @@ func (s *Repository) TerminateByPubKey(pubKey []byte) int {
 	return len(toDelete)
 }
 
+// TerminateByInternalAddr terminates the session that owns addr, either as its
+// allocated internal IP or as a host route from its AllowedIPs.
+// Returns the number of sessions terminated.
+func (s *Repository) TerminateByInternalAddr(addr netip.Addr) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	normalized := addr.Unmap()
+	peer, found := s.internalIpToPeer[normalized]
+	if !found {
+		peer, found = s.allowedAddrToPeer[normalized]
+	}
+	if !found {
+		return 0
+	}
+	s.deleteLocked(peer)
+	return 1
+}
+
+// Peers returns a snapshot of all live sessions.
+func (s *Repository) Peers() []*Peer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	peers := make([]*Peer, 0, len(s.internalIpToPeer))
+	for _, peer := range s.internalIpToPeer {
+		peers = append(peers, peer)
+	}
+	return peers
+}
+
 // deleteLocked removes peer from repository. Caller MUST hold s.mu.Lock().
 // This is the internal implementation used by both Delete and TerminateByPubKey.
 //
@@ -210,6 +306,9 @@ func (s *Repository) deleteLocked(peer *Peer) {
 			delete(s.pubKeyToPeers, key)
 		}
 	}
+	if len(peer.Routes()) > 0 {
+		s.syncRoutesLocked()
+	}
 
 	// Step 4: Zero key material after all in-flight Send/Decrypt calls finish.
 	peer.cryptoMu.Lock()
@@ -252,5 +351,18 @@ func (s *Repository) ReapIdle(timeout time.Duration) int {
 			count++
 		}
 	}
+	s.reaped.Add(uint64(count))
 	return count
 }
+
+// Reaped returns the number of sessions removed by ReapIdle so far.
+func (s *Repository) Reaped() uint64 {
+	return s.reaped.Load()
+}
+
+// Len returns the number of live sessions.
+func (s *Repository) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.internalIpToPeer)
+}
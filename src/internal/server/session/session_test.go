@@ -218,7 +218,107 @@ func TestRepository_RevocationAndIdleReaping(t *testing.T) {
 	if count := repo.ReapIdle(time.Minute); count != 1 || !idle.IsClosed() {
 		t.Fatalf("reaped=%d idleClosed=%v", count, idle.IsClosed())
 	}
+	if repo.Reaped() != 1 || repo.Len() != 1 {
+		t.Fatalf("Reaped=%d Len=%d, want 1 and 1", repo.Reaped(), repo.Len())
+	}
 	if count := repo.TerminateByPubKey(pubKey); count != 1 || !active.IsClosed() {
 		t.Fatalf("revoked=%d activeClosed=%v", count, active.IsClosed())
 	}
 }
+
+type epochCrypto struct {
+	testCrypto
+	epoch uint16
+}
+
+func (c *epochCrypto) SendEpoch() uint16 { return c.epoch }
+
+func TestPeer_ExposesKeyAndSendEpoch(t *testing.T) {
+	pubKey := []byte("client-key")
+	peer := NewPeerWithAuth(
+		&epochCrypto{epoch: 3}, nil, netip.MustParseAddr("10.0.0.2"), netip.AddrPort{}, pubKey, nil, nil,
+	)
+
+	got := peer.ClientPubKey()
+	got[0] = 'X'
+	if string(peer.ClientPubKey()) != string(pubKey) {
+		t.Fatal("ClientPubKey() must return a copy")
+	}
+	if peer.SendEpoch() != 3 {
+		t.Fatalf("SendEpoch() = %d, want 3", peer.SendEpoch())
+	}
+
+	peer.markClosed()
+	if peer.SendEpoch() != 0 {
+		t.Fatalf("SendEpoch() on closed peer = %d, want 0", peer.SendEpoch())
+	}
+	if NewPeer(&testCrypto{}, nil, netip.Addr{}, netip.AddrPort{}, nil).SendEpoch() != 0 {
+		t.Fatal("expected zero epoch for crypto without epochs")
+	}
+}
+
+func TestRepository_PeersAndTerminateByInternalAddr(t *testing.T) {
+	repo := NewRepository()
+	v4 := NewPeerWithAuth(
+		nil, nil, netip.MustParseAddr("10.0.0.2"), netip.MustParseAddrPort("192.0.2.1:1"), nil,
+		[]netip.Prefix{netip.MustParsePrefix("fd00::2/128")}, nil,
+	)
+	other := NewPeerWithAuth(nil, nil, netip.MustParseAddr("10.0.0.3"), netip.MustParseAddrPort("192.0.2.2:2"), nil, nil, nil)
+	repo.Add(v4)
+	repo.Add(other)
+
+	if peers := repo.Peers(); len(peers) != 2 {
+		t.Fatalf("Peers() = %d, want 2", len(peers))
+	}
+	if count := repo.TerminateByInternalAddr(netip.MustParseAddr("10.0.0.9")); count != 0 {
+		t.Fatalf("terminated unknown address: %d", count)
+	}
+	if count := repo.TerminateByInternalAddr(netip.MustParseAddr("fd00::2")); count != 1 || !v4.IsClosed() {
+		t.Fatalf("terminated=%d closed=%v", count, v4.IsClosed())
+	}
+	if count := repo.TerminateByInternalAddr(netip.MustParseAddr("::ffff:10.0.0.3")); count != 1 || !other.IsClosed() {
+		t.Fatalf("terminated=%d closed=%v", count, other.IsClosed())
+	}
+	if peers := repo.Peers(); len(peers) != 0 {
+		t.Fatalf("Peers() after termination = %d, want 0", len(peers))
+	}
+}
+
+func TestPeer_TrafficAggregatesByPublicKey(t *testing.T) {
+	ledger := NewLedger()
+	ledger.Seed(map[string]TrafficSnapshot{"client-key": {RXBytes: 1000, RXPackets: 10}})
+	tcpRepo := NewRepositoryWithLedger(ledger)
+	udpRepo := NewRepositoryWithLedger(ledger)
+	key := []byte("client-key")
+	tcpPeer := NewPeerWithAuth(nil, nil, netip.MustParseAddr("10.0.0.2"), netip.AddrPort{}, key, nil, nil)
+	udpPeer := NewPeerWithAuth(nil, nil, netip.MustParseAddr("10.0.1.2"), netip.AddrPort{}, key, nil, nil)
+	tcpRepo.Add(tcpPeer)
+	udpRepo.Add(udpPeer)
+
+	tcpPeer.CountRX(100)
+	tcpPeer.CountTX(40)
+	udpPeer.CountRX(50)
+	tcpRepo.Delete(tcpPeer)
+
+	if got := tcpPeer.Traffic(); got != (TrafficSnapshot{RXBytes: 100, RXPackets: 1, TXBytes: 40, TXPackets: 1}) {
+		t.Fatalf("session traffic = %+v", got)
+	}
+	want := TrafficSnapshot{RXBytes: 1150, RXPackets: 12, TXBytes: 40, TXPackets: 1}
+	if got := ledger.Snapshot()["client-key"]; got != want {
+		t.Fatalf("aggregate traffic = %+v, want %+v", got, want)
+	}
+}
+
+func TestPeer_CountingDoesNotAllocate(t *testing.T) {
+	repo := NewRepositoryWithLedger(NewLedger())
+	peer := NewPeerWithAuth(nil, nil, netip.MustParseAddr("10.0.0.2"), netip.AddrPort{}, []byte("key"), nil, nil)
+	repo.Add(peer)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		peer.CountRX(1400)
+		peer.CountTX(1400)
+	})
+	if allocs != 0 {
+		t.Fatalf("allocs per packet = %v, want 0", allocs)
+	}
+}
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Traffic counts plaintext IP packets crossing the server TUN on behalf of a
+// client. RX is client → server, TX is server → client. Counting is
+// allocation-free and safe for concurrent use.
+type Traffic struct {
+	rxBytes   atomic.Uint64
+	rxPackets atomic.Uint64
+	txBytes   atomic.Uint64
+	txPackets atomic.Uint64
+}
+
+type TrafficSnapshot struct {
+	RXBytes   uint64
+	RXPackets uint64
+	TXBytes   uint64
+	TXPackets uint64
+}
+
+func (t *Traffic) addRX(bytes int) {
+	t.rxBytes.Add(uint64(bytes))
+	t.rxPackets.Add(1)
+}
+
+func (t *Traffic) addTX(bytes int) {
+	t.txBytes.Add(uint64(bytes))
+	t.txPackets.Add(1)
+}
+
+func (t *Traffic) Snapshot() TrafficSnapshot {
+	return TrafficSnapshot{
+		RXBytes:   t.rxBytes.Load(),
+		RXPackets: t.rxPackets.Load(),
+		TXBytes:   t.txBytes.Load(),
+		TXPackets: t.txPackets.Load(),
+	}
+}
+
+// Ledger aggregates traffic of every session that shares a client public key,
+// across protocols and reconnects. Repositories sharing a Ledger attach the
+// per-key counters to peers as they are added.
+type Ledger struct {
+	mu     sync.Mutex
+	totals map[string]*Traffic
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{totals: make(map[string]*Traffic)}
+}
+
+// Seed adds previously persisted totals, keyed by public key.
+func (l *Ledger) Seed(totals map[string]TrafficSnapshot) {
+	for key, snapshot := range totals {
+		traffic := l.traffic(key)
+		traffic.rxBytes.Add(snapshot.RXBytes)
+		traffic.rxPackets.Add(snapshot.RXPackets)
+		traffic.txBytes.Add(snapshot.TXBytes)
+		traffic.txPackets.Add(snapshot.TXPackets)
+	}
+}
+
+// Snapshot returns the cumulative totals keyed by public key.
+func (l *Ledger) Snapshot() map[string]TrafficSnapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	totals := make(map[string]TrafficSnapshot, len(l.totals))
+	for key, traffic := range l.totals {
+		totals[key] = traffic.Snapshot()
+	}
+	return totals
+}
+
+func (l *Ledger) traffic(key string) *Traffic {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	traffic, ok := l.totals[key]
+	if !ok {
+		traffic = &Traffic{}
+		l.totals[key] = traffic
+	}
+	return traffic
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"io"
+	"net/netip"
 	"sync"
 	"sync/atomic"
 
@@ -9,12 +10,14 @@ import (
 	serverconfig "tungo/internal/config/server"
 	"tungo/internal/config/settings"
 	"tungo/internal/protocol/noise"
+	"tungo/internal/server/admin"
 	"tungo/internal/server/session"
 )
 
 type tunManager interface {
 	CreateDevice(settings.Settings) (io.ReadWriteCloser, error)
 	DisposeDevices(settings.Settings) error
+	routeTable
 }
 
 // Server owns the shared state of all server tunnels.
@@ -26,33 +29,95 @@ type Server struct {
 	allowedPeers  *allowedPeers
 	cookieManager *noise.CookieManager
 	loadMonitor   *noise.LoadMonitor
+	replayGuard   *noise.ReplayGuard
+	adminSocket   string
+	ledger        *session.Ledger
 
 	repositoriesMu sync.RWMutex
-	repositories   []*session.Repository
+	repositories   []protocolRepository
 }
 
-func (r *Server) register(repository *session.Repository) {
+// protocolRepository tags a session repository with the tunnel that owns it.
+type protocolRepository struct {
+	protocol   settings.Protocol
+	repository *session.Repository
+	tunnel     protocolTunnel
+}
+
+func (r *Server) register(protocol settings.Protocol, repository *session.Repository, tunnel protocolTunnel) {
 	r.repositoriesMu.Lock()
-	r.repositories = append(r.repositories, repository)
+	r.repositories = append(r.repositories, protocolRepository{
+		protocol:   protocol,
+		repository: repository,
+		tunnel:     tunnel,
+	})
 	r.repositoriesMu.Unlock()
 }
 
+func (r *Server) registered() []protocolRepository {
+	r.repositoriesMu.RLock()
+	defer r.repositoriesMu.RUnlock()
+	return append([]protocolRepository(nil), r.repositories...)
+}
+
 // RevokeByPubKey terminates matching sessions across every active protocol.
 func (r *Server) RevokeByPubKey(publicKey []byte) int {
-	r.repositoriesMu.RLock()
-	repositories := append([]*session.Repository(nil), r.repositories...)
-	r.repositoriesMu.RUnlock()
+	total := 0
+	for _, registered := range r.registered() {
+		total += registered.repository.TerminateByPubKey(publicKey)
+	}
+	return total
+}
 
+// TerminateByInternalIP terminates the session owning addr in every protocol.
+func (r *Server) TerminateByInternalIP(addr netip.Addr) int {
 	total := 0
-	for _, repository := range repositories {
-		total += repository.TerminateByPubKey(publicKey)
+	for _, registered := range r.registered() {
+		total += registered.repository.TerminateByInternalAddr(addr)
 	}
 	return total
 }
 
-// Update replaces the peers accepted by new handshakes.
+// Sessions lists the live sessions of every protocol for the admin socket.
+func (r *Server) Sessions() []admin.Session {
+	var sessions []admin.Session
+	for _, registered := range r.registered() {
+		for _, peer := range registered.repository.Peers() {
+			publicKey := peer.ClientPubKey()
+			var name string
+			if r.allowedPeers != nil {
+				name, _ = r.allowedPeers.Name(publicKey)
+			}
+			sessions = append(sessions, admin.Session{
+				Name:         name,
+				PublicKey:    publicKey,
+				InternalIP:   peer.InternalAddr(),
+				ExternalAddr: peer.ExternalAddrPort(),
+				LastActivity: peer.LastActivity(),
+				Protocol:     registered.protocol.String(),
+				SendEpoch:    peer.SendEpoch(),
+				Traffic:      admin.Traffic(peer.Traffic()),
+			})
+		}
+	}
+	return sessions
+}
+
+// Update replaces the peers accepted by new handshakes and re-routes live
+// sessions whose AllowedIPs changed without terminating them.
 func (r *Server) Update(peers []serverconfig.AllowedPeer) {
-	if r.allowedPeers != nil {
-		r.allowedPeers.Update(peers)
+	if r.allowedPeers == nil {
+		return
+	}
+	rerouted := r.allowedPeers.Update(peers)
+	if len(rerouted) == 0 {
+		return
+	}
+	registered := r.registered()
+	for _, publicKey := range rerouted {
+		access, _ := r.allowedPeers.Lookup(publicKey)
+		for _, tunnel := range registered {
+			tunnel.repository.UpdateRoutes(publicKey, access.AllowedIPs)
+		}
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	serverconfig "tungo/internal/config/server"
+	"tungo/internal/config/settings"
 	"tungo/internal/server/session"
 )
 
@@ -15,15 +16,54 @@ func TestServerUpdateReplacesAllowedPeers(t *testing.T) {
 	}
 	r.Update([]serverconfig.AllowedPeer{{PublicKey: publicKey, ClientID: 7, Enabled: true}})
 
-	clientID, enabled, found := r.allowedPeers.Lookup(publicKey)
-	if !found || !enabled || clientID != 7 {
-		t.Fatalf("Lookup() = (%d, %v, %v), want (7, true, true)", clientID, enabled, found)
+	access, found := r.allowedPeers.Lookup(publicKey)
+	if !found || !access.Enabled || access.ClientID != 7 {
+		t.Fatalf("Lookup() = (%+v, %v), want ClientID 7 enabled", access, found)
 	}
 }
 
+func TestServerUpdateReroutesLiveSessions(t *testing.T) {
+	publicKey := []byte("site")
+	r := Server{allowedPeers: newAllowedPeers([]serverconfig.AllowedPeer{{
+		PublicKey: publicKey, ClientID: 1, Enabled: true,
+		AllowedIPs: []netip.Prefix{netip.MustParsePrefix("192.168.10.0/24")},
+	}})}
+	repository := session.NewRepository()
+	router := &recordingRouter{}
+	repository.SetRouter(router)
+	peer := session.NewPeerWithAuth(nil, nil, netip.MustParseAddr("10.0.0.2"), netip.AddrPort{}, publicKey, nil, nil)
+	peer.SetRoutes([]netip.Prefix{netip.MustParsePrefix("192.168.10.0/24")})
+	repository.Add(peer)
+	r.register(settings.UDP, repository, nil)
+
+	moved := netip.MustParsePrefix("192.168.20.0/24")
+	r.Update([]serverconfig.AllowedPeer{{
+		PublicKey: publicKey, ClientID: 1, Enabled: true,
+		AllowedIPs: []netip.Prefix{moved},
+	}})
+
+	if peer.IsClosed() {
+		t.Fatal("re-routing terminated the session")
+	}
+	if !peer.IsSourceAllowed(netip.MustParseAddr("192.168.20.5")) || peer.IsSourceAllowed(netip.MustParseAddr("192.168.10.5")) {
+		t.Fatalf("peer routes = %v, want only %s", peer.Routes(), moved)
+	}
+	if len(router.synced) != 1 || router.synced[0] != moved {
+		t.Fatalf("router synced %v, want [%s]", router.synced, moved)
+	}
+}
+
+type recordingRouter struct {
+	synced []netip.Prefix
+}
+
+func (r *recordingRouter) Sync(prefixes []netip.Prefix) {
+	r.synced = prefixes
+}
+
 func TestRuntimeRegistersRepositories(t *testing.T) {
 	r := Server{}
-	r.register(session.NewRepository())
+	r.register(settings.UDP, session.NewRepository(), nil)
 
 	if len(r.repositories) != 1 {
 		t.Fatalf("repositories = %d, want 1", len(r.repositories))
@@ -41,10 +81,49 @@ func TestServerRevokesSessionsAcrossProtocols(t *testing.T) {
 		nil, nil, netip.MustParseAddr("10.0.0.3"), netip.MustParseAddrPort("192.0.2.2:2"), key, nil, nil,
 	))
 	server := Server{}
-	server.register(first)
-	server.register(second)
+	server.register(settings.TCP, first, nil)
+	server.register(settings.UDP, second, nil)
 
 	if revoked := server.RevokeByPubKey(key); revoked != 2 {
 		t.Fatalf("RevokeByPubKey() = %d, want 2", revoked)
 	}
 }
+
+func TestServerListsAndTerminatesSessions(t *testing.T) {
+	key := []byte("client-key")
+	tcpRepo := session.NewRepository()
+	udpRepo := session.NewRepository()
+	tcpRepo.Add(session.NewPeerWithAuth(
+		nil, nil, netip.MustParseAddr("10.0.0.2"), netip.MustParseAddrPort("192.0.2.1:1"), key, nil, nil,
+	))
+	udpRepo.Add(session.NewPeerWithAuth(
+		nil, nil, netip.MustParseAddr("10.0.1.2"), netip.MustParseAddrPort("192.0.2.1:2"), key, nil, nil,
+	))
+	server := Server{
+		allowedPeers: newAllowedPeers([]serverconfig.AllowedPeer{{Name: "laptop", PublicKey: key, ClientID: 1, Enabled: true}}),
+	}
+	server.register(settings.TCP, tcpRepo, nil)
+	server.register(settings.UDP, udpRepo, nil)
+
+	sessions := server.Sessions()
+	if len(sessions) != 2 {
+		t.Fatalf("Sessions() = %d, want 2", len(sessions))
+	}
+	protocols := map[string]string{}
+	for _, s := range sessions {
+		if s.Name != "laptop" || string(s.PublicKey) != string(key) {
+			t.Fatalf("unexpected session identity %+v", s)
+		}
+		protocols[s.Protocol] = s.InternalIP.String()
+	}
+	if protocols["TCP"] != "10.0.0.2" || protocols["UDP"] != "10.0.1.2" {
+		t.Fatalf("unexpected protocol mapping %v", protocols)
+	}
+
+	if terminated := server.TerminateByInternalIP(netip.MustParseAddr("10.0.1.2")); terminated != 1 {
+		t.Fatalf("TerminateByInternalIP() = %d, want 1", terminated)
+	}
+	if sessions := server.Sessions(); len(sessions) != 1 || sessions[0].Protocol != "TCP" {
+		t.Fatalf("Sessions() after termination = %+v", sessions)
+	}
+}
@@ -171,13 +171,14 @@ func (r *registrar) registerClient(conn net.Conn) (*session.Peer, io.ReadWriteCl
 
 	// Extract authentication info from IK handshake result if available
 	var clientPubKey []byte
-	var allowedIPs []netip.Prefix
+	var routes []netip.Prefix
 	if authenticated, ok := h.(authenticatedHandshake); ok {
 		clientPubKey = authenticated.ClientPubKey()
-		allowedIPs = authenticated.AllowedIPs()
+		routes = authenticated.AllowedIPs()
 	}
 
 	// Add IPv6 address to allowedIPs for dual-stack support
+	var allowedIPs []netip.Prefix
 	if r.ipv6Subnet.IsValid() {
 		ipv6Addr, ipv6Err := addressing.AllocateClientIP(r.ipv6Subnet, clientID)
 		if ipv6Err == nil {
@@ -188,6 +189,7 @@ func (r *registrar) registerClient(conn net.Conn) (*session.Peer, io.ReadWriteCl
 	peer := session.NewPeerWithAuth(
 		cryptographyService, rekeyCoordinator, internalIP, tcpAddr.AddrPort(), clientPubKey, allowedIPs, framingAdapter,
 	)
+	peer.SetRoutes(routes)
 	r.sessionManager.Add(peer)
 
 	return peer, framingAdapter, nil
@@ -24,6 +24,7 @@ type tcpRegHandshake struct {
 func (h *tcpRegHandshake) ID() [32]byte              { return h.id }
 func (h *tcpRegHandshake) KeyClientToServer() []byte { return h.c2s }
 func (h *tcpRegHandshake) KeyServerToClient() []byte { return h.s2c }
+func (h *tcpRegHandshake) Cipher() chacha20.Cipher   { return chacha20.ChaCha20Poly1305 }
 func (*tcpRegHandshake) ClientSideHandshake(_ io.ReadWriter) error {
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"net"
 	"net/netip"
+	"sync/atomic"
 
 	"golang.org/x/crypto/chacha20poly1305"
 
@@ -29,6 +30,9 @@ type Server struct {
 	peers     *session.Repository
 	registrar *registrar
 	deriver   keys.DefaultKeyDeriver
+
+	rekeys         atomic.Uint64
+	epochExhausted atomic.Uint64
 }
 
 func New(
@@ -55,6 +59,16 @@ func New(
 	}
 }
 
+// RekeysCompleted returns the number of rekeys answered by this tunnel.
+func (s *Server) RekeysCompleted() uint64 {
+	return s.rekeys.Load()
+}
+
+// EpochsExhausted returns how often a peer ran out of key epochs.
+func (s *Server) EpochsExhausted() uint64 {
+	return s.epochExhausted.Load()
+}
+
 // Run moves packets in both directions until the context is cancelled or one
 // direction fails.
 func (s *Server) Run() error {
@@ -147,6 +161,7 @@ func (s *Server) handleFrame(peer *session.Peer, frame []byte) error {
 	if _, err := s.tun.Write(plaintext); err != nil {
 		return err
 	}
+	peer.CountRX(len(plaintext))
 	return nil
 }
 
@@ -181,6 +196,7 @@ func (s *Server) runTun() error {
 			s.peers.Delete(peer)
 			continue
 		}
+		peer.CountTX(n)
 	}
 }
 
@@ -188,11 +204,13 @@ func (s *Server) handleService(peer *session.Peer, carrierEpoch uint16, plaintex
 	response, epoch, rekeyed, err := peer.HandleRekey(carrierEpoch, &s.deriver, plaintext)
 	if err != nil {
 		if errors.Is(err, chacha20.ErrEpochExhausted) {
+			s.epochExhausted.Add(1)
 			return true, s.sendService(peer, servicepacket.EpochExhausted, nil)
 		}
 		return true, nil
 	}
 	if rekeyed {
+		s.rekeys.Add(1)
 		if err := s.sendPlaintext(peer, response); err != nil {
 			return true, err
 		}
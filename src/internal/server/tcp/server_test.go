@@ -97,6 +97,9 @@ func TestServer_RunTunRoutesPacketDirectlyToPeer(t *testing.T) {
 	if string(writer.packet[tcp.EpochPrefixSize:]) != string(tun.packet) {
 		t.Fatal("sent payload differs from TUN packet")
 	}
+	if got := peer.Traffic(); got.TXPackets != 1 || got.TXBytes != uint64(len(tun.packet)) {
+		t.Fatalf("peer traffic = %+v", got)
+	}
 }
 
 func TestServer_PingDoesNotRequireRekey(t *testing.T) {
@@ -134,6 +137,9 @@ func TestServer_HandleFrameDecryptsAuthorizesAndWritesTun(t *testing.T) {
 	if len(tun.writes) != 1 || string(tun.writes[0]) != string(plaintext) {
 		t.Fatalf("TUN writes=%q", tun.writes)
 	}
+	if got := peer.Traffic(); got.RXPackets != 1 || got.RXBytes != uint64(len(plaintext)) {
+		t.Fatalf("peer traffic = %+v", got)
+	}
 
 	peer = session.NewPeer(
 		&plaintextCrypto{plaintext: ipv4Packet(netip.MustParseAddr("10.0.0.99"), netip.MustParseAddr("1.1.1.1"))},
@@ -142,6 +148,9 @@ func TestServer_HandleFrameDecryptsAuthorizesAndWritesTun(t *testing.T) {
 	if err := server.handleFrame(peer, frame); err != nil || len(tun.writes) != 1 {
 		t.Fatalf("disallowed frame: writes=%d err=%v", len(tun.writes), err)
 	}
+	if got := peer.Traffic(); got.RXPackets != 0 {
+		t.Fatalf("disallowed frame counted: %+v", got)
+	}
 }
 
 func TestServer_RekeySendsAckAndActivatesTCP(t *testing.T) {
@@ -172,6 +181,9 @@ func TestServer_RekeySendsAckAndActivatesTCP(t *testing.T) {
 	if got := fsm.SendEpoch(); got != 1 {
 		t.Fatalf("send epoch=%d, want 1", got)
 	}
+	if got := server.RekeysCompleted(); got != 1 {
+		t.Fatalf("RekeysCompleted=%d, want 1", got)
+	}
 }
 
 func TestServer_RekeyV2SendsNoiseAckAndActivatesTCP(t *testing.T) {
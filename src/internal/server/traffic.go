@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	serverconfig "tungo/internal/config/server"
+	"tungo/internal/server/session"
+)
+
+// trafficFlushInterval bounds how much usage is lost on an unclean exit and
+// how stale ListPeers may be while the server runs.
+const trafficFlushInterval = 30 * time.Second
+
+// runTrafficAccounting seeds the ledger from persisted totals, then flushes
+// it periodically and once more when ctx is cancelled. Nothing is flushed
+// until the totals load, so that a store that cannot be read is not
+// overwritten with the usage of this run alone; the load is retried before
+// every flush.
+func (s *Server) runTrafficAccounting(ctx context.Context) {
+	if s.control == nil || s.ledger == nil {
+		return
+	}
+	loaded := s.loadTraffic()
+
+	ticker := time.NewTicker(trafficFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if loaded || s.loadTraffic() {
+				s.flushTraffic()
+			}
+			return
+		case <-ticker.C:
+			if loaded = loaded || s.loadTraffic(); loaded {
+				s.flushTraffic()
+			}
+		}
+	}
+}
+
+// loadTraffic adds the persisted totals to the ledger and reports whether
+// they loaded.
+func (s *Server) loadTraffic() bool {
+	persisted, err := s.control.LoadPeerTraffic()
+	if err != nil {
+		slog.Warn("failed to load peer traffic; not persisting it until it loads", "err", err)
+		return false
+	}
+	seed := make(map[string]session.TrafficSnapshot, len(persisted))
+	for key, usage := range persisted {
+		seed[key] = session.TrafficSnapshot(usage)
+	}
+	s.ledger.Seed(seed)
+	return true
+}
+
+// flushTraffic persists totals of peers that are still configured; usage of
+// removed peers is dropped.
+func (s *Server) flushTraffic() {
+	totals := s.ledger.Snapshot()
+	traffic := make(map[string]serverconfig.PeerTraffic, len(totals))
+	for key, usage := range totals {
+		if _, configured := s.allowedPeers.Name([]byte(key)); !configured {
+			continue
+		}
+		traffic[key] = serverconfig.PeerTraffic(usage)
+	}
+	if err := s.control.SavePeerTraffic(traffic); err != nil {
+		slog.Warn("failed to persist peer traffic", "err", err)
+	}
+}
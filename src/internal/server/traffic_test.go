@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"net/netip"
+	"testing"
+
+	"tungo/internal/config"
+	serverconfig "tungo/internal/config/server"
+	"tungo/internal/server/session"
+)
+
+type trafficControl struct {
+	config.ServerRuntimeControl
+	persisted map[string]serverconfig.PeerTraffic
+	// loadErrs fail the next loads, one each.
+	loadErrs []error
+	saved    map[string]serverconfig.PeerTraffic
+}
+
+func (c *trafficControl) LoadPeerTraffic() (map[string]serverconfig.PeerTraffic, error) {
+	if len(c.loadErrs) > 0 {
+		err := c.loadErrs[0]
+		c.loadErrs = c.loadErrs[1:]
+		return nil, err
+	}
+	return c.persisted, nil
+}
+
+func (c *trafficControl) SavePeerTraffic(traffic map[string]serverconfig.PeerTraffic) error {
+	c.saved = traffic
+	return nil
+}
+
+func TestServerTrafficAccountingSeedsAndFlushesConfiguredPeers(t *testing.T) {
+	key := []byte("configured")
+	control := &trafficControl{persisted: map[string]serverconfig.PeerTraffic{
+		string(key): {RXBytes: 100, RXPackets: 1},
+		"removed":   {RXBytes: 7},
+	}}
+	s := &Server{
+		control:      control,
+		ledger:       session.NewLedger(),
+		allowedPeers: newAllowedPeers([]serverconfig.AllowedPeer{{PublicKey: key, ClientID: 1, Enabled: true}}),
+	}
+	repository := session.NewRepositoryWithLedger(s.ledger)
+	peer := session.NewPeerWithAuth(nil, nil, netip.MustParseAddr("10.0.0.2"), netip.AddrPort{}, key, nil, nil)
+	repository.Add(peer)
+	peer.CountRX(50)
+	peer.CountTX(20)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.runTrafficAccounting(ctx)
+
+	want := serverconfig.PeerTraffic{RXBytes: 150, RXPackets: 2, TXBytes: 20, TXPackets: 1}
+	if len(control.saved) != 1 || control.saved[string(key)] != want {
+		t.Fatalf("saved traffic = %+v, want only %+v", control.saved, want)
+	}
+}
+
+func TestServerTrafficAccountingKeepsUnreadableStore(t *testing.T) {
+	key := []byte("configured")
+	corrupt := errors.New("traffic file is invalid")
+	for name, tc := range map[string]struct {
+		loadErrs []error
+		want     map[string]serverconfig.PeerTraffic
+	}{
+		"corrupt": {loadErrs: []error{corrupt, corrupt}},
+		"recovers": {
+			loadErrs: []error{corrupt},
+			want:     map[string]serverconfig.PeerTraffic{string(key): {RXBytes: 150, RXPackets: 2}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			control := &trafficControl{
+				persisted: map[string]serverconfig.PeerTraffic{string(key): {RXBytes: 100, RXPackets: 1}},
+				loadErrs:  tc.loadErrs,
+			}
+			s := &Server{
+				control:      control,
+				ledger:       session.NewLedger(),
+				allowedPeers: newAllowedPeers([]serverconfig.AllowedPeer{{PublicKey: key, ClientID: 1, Enabled: true}}),
+			}
+			repository := session.NewRepositoryWithLedger(s.ledger)
+			peer := session.NewPeerWithAuth(nil, nil, netip.MustParseAddr("10.0.0.2"), netip.AddrPort{}, key, nil, nil)
+			repository.Add(peer)
+			peer.CountRX(50)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			s.runTrafficAccounting(ctx)
+
+			if !maps.Equal(control.saved, tc.want) {
+				t.Fatalf("saved traffic = %+v, want %+v", control.saved, tc.want)
+			}
+		})
+	}
+}
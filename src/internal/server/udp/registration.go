@@ -131,6 +131,12 @@ func (r *registrar) closeAll() {
 	}
 }
 
+func (r *registrar) inFlight() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.registrations)
+}
+
 func (r *registrar) getOrCreateRegistrationQueue(addrPort netip.AddrPort) (*registrationQueue, bool) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -219,13 +225,14 @@ func (r *registrar) registerClient(addrPort netip.AddrPort, queue *registrationQ
 
 	// Extract authentication info from IK handshake result if available
 	var clientPubKey []byte
-	var allowedIPs []netip.Prefix
+	var routes []netip.Prefix
 	if authenticated, ok := h.(authenticatedHandshake); ok {
 		clientPubKey = authenticated.ClientPubKey()
-		allowedIPs = authenticated.AllowedIPs()
+		routes = authenticated.AllowedIPs()
 	}
 
 	// Add IPv6 address to allowedIPs for dual-stack support
+	var allowedIPs []netip.Prefix
 	if r.ipv6Subnet.IsValid() {
 		ipv6Addr, ipv6Err := addressing.AllocateClientIP(r.ipv6Subnet, clientID)
 		if ipv6Err == nil {
@@ -243,6 +250,7 @@ func (r *registrar) registerClient(addrPort netip.AddrPort, queue *registrationQ
 	peer := session.NewPeerWithAuth(
 		cryptoSession, rekeyCoordinator, internalIP, addrPort, clientPubKey, allowedIPs, regTransport,
 	)
+	peer.SetRoutes(routes)
 	r.sessionRepo.Add(peer)
 	slog.Info("UDP client registered", "client", addrPort.Addr(), "internal_ip", internalIP)
 }
@@ -39,6 +39,7 @@ type udpRegHandshake struct {
 func (h *udpRegHandshake) ID() [32]byte              { return h.id }
 func (h *udpRegHandshake) KeyClientToServer() []byte { return h.c2s }
 func (h *udpRegHandshake) KeyServerToClient() []byte { return h.s2c }
+func (h *udpRegHandshake) Cipher() chacha20.Cipher   { return chacha20.ChaCha20Poly1305 }
 func (h *udpRegHandshake) ClientSideHandshake(_ io.ReadWriter) error {
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"net/netip"
+	"sync/atomic"
 	"time"
 
 	"tungo/internal/config/settings"
@@ -31,6 +32,9 @@ type Server struct {
 	peers     *session.Repository
 	registrar *registrar
 	deriver   keys.DefaultKeyDeriver
+
+	rekeys         atomic.Uint64
+	epochExhausted atomic.Uint64
 }
 
 func New(
@@ -59,6 +63,24 @@ func New(
 	}
 }
 
+// RekeysCompleted returns the number of rekeys answered by this tunnel.
+func (s *Server) RekeysCompleted() uint64 {
+	return s.rekeys.Load()
+}
+
+// EpochsExhausted returns how often a peer ran out of key epochs.
+func (s *Server) EpochsExhausted() uint64 {
+	return s.epochExhausted.Load()
+}
+
+// RegistrationsInFlight returns the number of handshakes being processed.
+func (s *Server) RegistrationsInFlight() int {
+	if s.registrar == nil {
+		return 0
+	}
+	return s.registrar.inFlight()
+}
+
 // Run moves packets in both directions until the context is cancelled or one
 // direction fails.
 func (s *Server) Run() error {
@@ -179,6 +201,7 @@ func (s *Server) handleDecrypted(
 	if _, err := s.tun.Write(plaintext); err != nil {
 		return fmt.Errorf("write to TUN: %w", err)
 	}
+	peer.CountRX(len(plaintext))
 	return nil
 }
 
@@ -213,6 +236,7 @@ func (s *Server) runTun() error {
 			s.peers.Delete(peer)
 			continue
 		}
+		peer.CountTX(n)
 	}
 }
 
@@ -233,11 +257,13 @@ func (s *Server) handleRekey(peer *session.Peer, carrierEpoch uint16, plaintext
 	response, _, rekeyed, err := peer.HandleRekey(carrierEpoch, &s.deriver, plaintext)
 	if err != nil {
 		if errors.Is(err, chacha20.ErrEpochExhausted) {
+			s.epochExhausted.Add(1)
 			_ = s.sendService(peer, servicepacket.EpochExhausted, nil)
 		}
 		return nil
 	}
 	if rekeyed {
+		s.rekeys.Add(1)
 		return s.sendPlaintext(peer, response)
 	}
 	return nil
@@ -102,6 +102,9 @@ func TestServer_HandleEstablishedWritesPlaintextToTun(t *testing.T) {
 	if len(tun.writes) != 1 || string(tun.writes[0]) != string(plaintext) {
 		t.Fatalf("TUN writes = %q", tun.writes)
 	}
+	if got := peer.Traffic(); got.RXPackets != 1 || got.RXBytes != uint64(len(plaintext)) {
+		t.Fatalf("peer traffic = %+v", got)
+	}
 }
 
 func TestServer_HandleDatagramLooksUpPeerByRouteID(t *testing.T) {
@@ -157,6 +160,9 @@ func TestServer_RunTunRoutesPacketDirectlyToPeer(t *testing.T) {
 	if string(writer.packet[udpPayloadOffset:]) != string(tun.packet) {
 		t.Fatal("sent payload differs from TUN packet")
 	}
+	if got := peer.Traffic(); got.TXPackets != 1 || got.TXBytes != uint64(len(tun.packet)) {
+		t.Fatalf("peer traffic = %+v", got)
+	}
 }
 
 func TestServer_PingDoesNotRequireRekey(t *testing.T) {
@@ -206,6 +212,9 @@ func TestServer_RekeySendsAckWithoutPrematureUDPActivation(t *testing.T) {
 	if got := fsm.SendEpoch(); got != 0 {
 		t.Fatalf("send epoch=%d, want old epoch 0 until authenticated peer traffic", got)
 	}
+	if got := server.RekeysCompleted(); got != 1 {
+		t.Fatalf("RekeysCompleted=%d, want 1", got)
+	}
 }
 
 func TestServer_RekeyV2SendsNoiseAckWithoutPrematureUDPActivation(t *testing.T) {
@@ -5,16 +5,20 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/netip"
 	"os"
+	"slices"
 	"strings"
 	clientconfig "tungo/internal/config/client"
 	"tungo/internal/config/settings"
 	"tungo/internal/platform/command"
 	"tungo/internal/transport/host"
+	"tungo/internal/tun/internal/linux/dns"
 	"tungo/internal/tun/internal/linux/epoll"
 	"tungo/internal/tun/internal/linux/ioctl"
 	"tungo/internal/tun/internal/linux/ip"
+	"tungo/internal/tun/internal/linux/killswitch"
 	"tungo/internal/tun/internal/linux/mssclamp"
 )
 
@@ -28,8 +32,14 @@ type Manager struct {
 	ip                 ip.Contract
 	ioctl              ioctl.Contract
 	mss                mssclamp.Contract
+	dns                dns.Contract
+	killSwitch         killswitch.Contract
 	wrapper            tunWrapper
 	routeEndpoint      netip.AddrPort
+	// uplinkRoutes are the routes around the tunnel that this manager
+	// added, by target. Only these are deleted: the host may have had a
+	// route to the same target before.
+	uplinkRoutes map[string]route
 }
 
 func New(conf *clientconfig.Configuration) (*Manager, error) {
@@ -43,6 +53,8 @@ func New(conf *clientconfig.Configuration) (*Manager, error) {
 		ip:                 ip.NewWrapper(command.New()),
 		ioctl:              ioctl.NewWrapper(ioctl.NewLinuxIoctlCommander(), "/dev/net/tun"),
 		mss:                mssclamp.NewManager(command.New()),
+		dns:                dns.NewManager(command.New()),
+		killSwitch:         killswitch.NewManager(command.New()),
 		wrapper:            epoll.NewWrapper(),
 	}, nil
 }
@@ -68,6 +80,12 @@ func (t *Manager) SetRouteEndpoint(addr netip.AddrPort) {
 	t.routeEndpoint = addr
 }
 
+// UseSettings selects the profile of the endpoint the next device is
+// created for.
+func (t *Manager) UseSettings(s settings.Settings) {
+	t.connectionSettings = s
+}
+
 // configureTUN Configures client's TUN device (creates the TUN device, assigns an IP to it, etc)
 func (t *Manager) configureTUN(connSettings settings.Settings) error {
 	err := t.ip.TunTapAddDevTun(connSettings.TunName)
@@ -126,32 +144,19 @@ func (t *Manager) configureTUN(connSettings settings.Settings) error {
 	if err != nil {
 		return err
 	}
-	var viaGateway, devInterface string
-	fields := strings.Fields(routeInfo)
-	for i, field := range fields {
-		if field == "via" && i+1 < len(fields) {
-			viaGateway = fields[i+1]
-		}
-		if field == "dev" && i+1 < len(fields) {
-			devInterface = fields[i+1]
-		}
-	}
-	if devInterface == "" {
+	uplink := parseRoute(routeInfo)
+	if uplink.dev == "" {
 		return fmt.Errorf("failed to parse route to server IP")
 	}
 
 	// Add route to server IP
-	if viaGateway == "" {
-		err = t.ip.RouteAddDev(serverIP, devInterface)
-	} else {
-		err = t.ip.RouteAddViaDev(serverIP, devInterface, viaGateway)
-	}
-	if err != nil {
+	if err := t.addUplinkRoute(serverIP, uplink); err != nil {
 		return fmt.Errorf("failed to add route to server IP: %v", err)
 	}
-	slog.Info("added route to server", "server_ip", serverIP, "via", viaGateway, "device", devInterface)
+	slog.Info("added route to server", "server_ip", serverIP, "via", uplink.via, "device", uplink.dev)
 
 	// Add route for IPv6 server address (if available)
+	var uplink6 route
 	if connSettings.Server.IPv6 != "" || (t.routeEndpoint.IsValid() && !t.routeEndpoint.Addr().Unmap().Is4()) {
 		serverIPv6 := ""
 		if t.routeEndpoint.IsValid() && !t.routeEndpoint.Addr().Unmap().Is4() {
@@ -163,33 +168,110 @@ func (t *Manager) configureTUN(connSettings settings.Settings) error {
 		if serverIPv6 != "" {
 			routeInfo6, routeErr6 := t.ip.RouteGet(serverIPv6)
 			if routeErr6 == nil {
-				var via6, dev6 string
-				fields6 := strings.Fields(routeInfo6)
-				for i, field := range fields6 {
-					if field == "via" && i+1 < len(fields6) {
-						via6 = fields6[i+1]
-					}
-					if field == "dev" && i+1 < len(fields6) {
-						dev6 = fields6[i+1]
-					}
-				}
-				if dev6 != "" {
-					if via6 == "" {
-						_ = t.ip.RouteAddDev(serverIPv6, dev6)
-					} else {
-						_ = t.ip.RouteAddViaDev(serverIPv6, dev6, via6)
-					}
-					slog.Info("added route to IPv6 server", "server_ip", serverIPv6, "via", via6, "device", dev6)
+				uplink6 = parseRoute(routeInfo6)
+				if uplink6.dev != "" {
+					_ = t.addUplinkRoute(serverIPv6, uplink6)
+					slog.Info("added route to IPv6 server", "server_ip", serverIPv6, "via", uplink6.via, "device", uplink6.dev)
 				}
 			}
 		}
 	}
 
+	// Route excluded prefixes around the tunnel before the tunnel routes
+	// exist, so that route lookups still see the physical uplink.
+	t.addBypassRoutes(connSettings, uplink, uplink6)
+
+	if connSettings.FullTunnel() {
+		if err := t.addFullTunnelRoutes(connSettings); err != nil {
+			return err
+		}
+	} else if err := t.addIncludeRoutes(connSettings); err != nil {
+		return err
+	}
+
+	// sets client's TUN device maximum transmission unit (MTU)
+	if setMtuErr := t.ip.LinkSetDevMTU(connSettings.TunName, connSettings.MTU); setMtuErr != nil {
+		return fmt.Errorf(
+			"failed to set %d MTU for %s: %s", connSettings.MTU, connSettings.TunName, setMtuErr,
+		)
+	}
+
+	// install MSS clamping to prevent PMTU blackholes when forwarding traffic
+	if err := t.mss.Install(connSettings.TunName); err != nil {
+		return fmt.Errorf("failed to install MSS clamping for %s: %v", connSettings.TunName, err)
+	}
+
+	// point system DNS at the tunnel resolvers so queries do not leak to the ISP
+	resolvers := connSettings.DNSv4Resolvers()
+	if connSettings.IPv6.IsValid() {
+		resolvers = append(resolvers, connSettings.DNSv6Resolvers()...)
+	}
+	if err := t.dns.Apply(connSettings.TunName, resolvers); err != nil {
+		return fmt.Errorf("failed to configure DNS for %s: %v", connSettings.TunName, err)
+	}
+	slog.Info("configured DNS resolvers", "name", connSettings.TunName, "resolvers", resolvers)
+
+	return nil
+}
+
+// route is the next hop parsed from `ip route get` output.
+type route struct {
+	via string
+	dev string
+}
+
+func parseRoute(routeInfo string) route {
+	var r route
+	fields := strings.Fields(routeInfo)
+	for i, field := range fields {
+		if field == "via" && i+1 < len(fields) {
+			r.via = fields[i+1]
+		}
+		if field == "dev" && i+1 < len(fields) {
+			r.dev = fields[i+1]
+		}
+	}
+	return r
+}
+
+func (t *Manager) addUplinkRoute(target string, uplink route) error {
+	var err error
+	if uplink.via == "" {
+		err = t.ip.RouteAddDev(target, uplink.dev)
+	} else {
+		err = t.ip.RouteAddViaDev(target, uplink.dev, uplink.via)
+	}
+	if err != nil {
+		return err
+	}
+	if t.uplinkRoutes == nil {
+		t.uplinkRoutes = make(map[string]route)
+	}
+	t.uplinkRoutes[target] = uplink
+	return nil
+}
+
+// delUplinkRoute deletes the route to target that addUplinkRoute added, with
+// its next hop, so that another route to target stays.
+func (t *Manager) delUplinkRoute(target string) {
+	uplink, ok := t.uplinkRoutes[target]
+	if !ok {
+		return
+	}
+	delete(t.uplinkRoutes, target)
+	if uplink.via == "" {
+		_ = t.ip.RouteDelDev(target, uplink.dev)
+		return
+	}
+	_ = t.ip.RouteDelViaDev(target, uplink.dev, uplink.via)
+}
+
+// addFullTunnelRoutes sends all traffic into the tunnel.
+func (t *Manager) addFullTunnelRoutes(connSettings settings.Settings) error {
 	// Set split default routes — more specific than 0.0.0.0/0 so they take
 	// priority without destroying the original default route. On crash or
 	// device deletion the kernel removes them automatically.
-	err = t.ip.RouteAddSplitDefaultDev(connSettings.TunName)
-	if err != nil {
+	if err := t.ip.RouteAddSplitDefaultDev(connSettings.TunName); err != nil {
 		return err
 	}
 	slog.Info("set interface as default gateway for split routes", "name", connSettings.TunName)
@@ -201,19 +283,178 @@ func (t *Manager) configureTUN(connSettings settings.Settings) error {
 		}
 		slog.Info("set interface as IPv6 default gateway for split routes", "name", connSettings.TunName)
 	}
+	return nil
+}
 
-	// sets client's TUN device maximum transmission unit (MTU)
-	if setMtuErr := t.ip.LinkSetDevMTU(connSettings.TunName, connSettings.MTU); setMtuErr != nil {
-		return fmt.Errorf(
-			"failed to set %d MTU for %s: %s", connSettings.MTU, connSettings.TunName, setMtuErr,
-		)
+// addIncludeRoutes sends only IncludeRoutes into the tunnel. The routes go
+// away with the device.
+func (t *Manager) addIncludeRoutes(connSettings settings.Settings) error {
+	for _, prefix := range connSettings.IncludeRoutes {
+		if prefix.Addr().Is6() && !connSettings.IPv6.IsValid() {
+			slog.Warn("skipping IPv6 include route, tunnel has no IPv6 address", "prefix", prefix)
+			continue
+		}
+		if err := t.ip.RouteAddDev(prefix.String(), connSettings.TunName); err != nil {
+			return fmt.Errorf("failed to add include route %s: %v", prefix, err)
+		}
+		slog.Info("added include route", "prefix", prefix, "name", connSettings.TunName)
 	}
+	return nil
+}
 
-	// install MSS clamping to prevent PMTU blackholes when forwarding traffic
-	if err := t.mss.Install(connSettings.TunName); err != nil {
-		return fmt.Errorf("failed to install MSS clamping for %s: %v", connSettings.TunName, err)
+// addBypassRoutes routes ExcludeRoutes and, with AllowLAN, the LAN prefixes
+// through the same uplink as the server. A prefix that cannot be routed is
+// logged and left to the tunnel.
+func (t *Manager) addBypassRoutes(connSettings settings.Settings, uplink, uplink6 route) {
+	for _, prefix := range connSettings.BypassRoutes() {
+		next := uplink
+		if prefix.Addr().Is6() {
+			next = uplink6
+			if next.dev == "" {
+				if routeInfo, err := t.ip.RouteGet(prefix.Addr().String()); err == nil {
+					next = parseRoute(routeInfo)
+				}
+			}
+			if next.dev == "" || next.dev == connSettings.TunName {
+				slog.Warn("skipping IPv6 exclude route, no IPv6 uplink", "prefix", prefix)
+				continue
+			}
+		}
+		if err := t.addUplinkRoute(prefix.String(), next); err != nil {
+			slog.Warn("failed to add exclude route", "prefix", prefix, "err", err)
+			continue
+		}
+		slog.Info("added exclude route", "prefix", prefix, "via", next.via, "device", next.dev)
 	}
+}
 
+// EnableKillSwitch blocks all egress except the tunnel, loopback, the servers
+// of every endpoint and bypass routes. Server hosts are resolved now, while
+// DNS still works; for a domain host plain DNS stays allowed so it can be
+// re-resolved.
+func (t *Manager) EnableKillSwitch() error {
+	candidates, err := t.configuration.CandidateSettings()
+	if err != nil {
+		return err
+	}
+	if t.configuration.UDPFallbackAfter > 0 {
+		for _, candidate := range candidates {
+			if candidate.Protocol != settings.UDP {
+				continue
+			}
+			if fallback, err := t.configuration.UDPFallbackSettings(candidate); err == nil {
+				candidates = append(candidates, fallback)
+			}
+		}
+	}
+	policy := killswitch.Policy{Bypass: t.connectionSettings.BypassRoutes()}
+	for _, candidate := range candidates {
+		if !slices.Contains(policy.TunNames, candidate.TunName) {
+			policy.TunNames = append(policy.TunNames, candidate.TunName)
+		}
+		if candidate.Server.Domain != "" {
+			policy.AllowDNS = true
+		}
+		endpoints, err := killSwitchEndpoints(candidate.Server)
+		if err != nil {
+			return fmt.Errorf("failed to resolve server address for the kill switch: %w", err)
+		}
+		for _, endpoint := range endpoints {
+			if !slices.Contains(policy.Endpoints, endpoint) {
+				policy.Endpoints = append(policy.Endpoints, endpoint)
+			}
+		}
+	}
+	if err := t.killSwitch.Enable(policy); err != nil {
+		return err
+	}
+	slog.Info("kill switch enabled", "names", policy.TunNames, "endpoints", policy.Endpoints)
+	return nil
+}
+
+// killSwitchEndpoints returns every address the server host may be dialed at.
+func killSwitchEndpoints(server settings.Host) ([]netip.Addr, error) {
+	if server.Domain != "" {
+		addrs, err := net.DefaultResolver.LookupNetIP(context.Background(), "ip", server.Domain)
+		if err != nil {
+			return nil, err
+		}
+		for i := range addrs {
+			addrs[i] = addrs[i].Unmap()
+		}
+		return addrs, nil
+	}
+	var endpoints []netip.Addr
+	for _, raw := range []string{server.IPv4, server.IPv6} {
+		if raw == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(raw)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, addr.Unmap())
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("server host is empty")
+	}
+	return endpoints, nil
+}
+
+// DisableKillSwitch removes the kill switch rules.
+func (t *Manager) DisableKillSwitch() error {
+	return t.killSwitch.Disable()
+}
+
+// RefreshUplink re-points the routes that bypass the tunnel, to the server
+// and to excluded prefixes, at the current default route after the host
+// changed networks. Only routes this manager added are replaced; the tunnel
+// routes stay as they are.
+func (t *Manager) RefreshUplink() error {
+	if !t.routeEndpoint.IsValid() {
+		return nil
+	}
+	uplinks := make(map[int]route, 2)
+	uplink := func(addr netip.Addr) route {
+		ipV := 4
+		if addr.Is6() {
+			ipV = 6
+		}
+		if next, ok := uplinks[ipV]; ok {
+			return next
+		}
+		var next route
+		if routeInfo, err := t.ip.RouteShowDefault(ipV); err == nil {
+			next = parseRoute(routeInfo)
+		}
+		if next.dev == t.connectionSettings.TunName {
+			next = route{}
+		}
+		uplinks[ipV] = next
+		return next
+	}
+
+	server := t.routeEndpoint.Addr().Unmap()
+	next := uplink(server)
+	if next.dev == "" {
+		return fmt.Errorf("no default route to reach server %s", server)
+	}
+	t.delUplinkRoute(server.String())
+	if err := t.addUplinkRoute(server.String(), next); err != nil {
+		return fmt.Errorf("failed to move route to server: %w", err)
+	}
+	slog.Info("moved route to server", "server_ip", server, "via", next.via, "device", next.dev)
+
+	for _, prefix := range t.connectionSettings.BypassRoutes() {
+		next := uplink(prefix.Addr())
+		if next.dev == "" {
+			continue
+		}
+		t.delUplinkRoute(prefix.String())
+		if err := t.addUplinkRoute(prefix.String(), next); err != nil {
+			slog.Warn("failed to move exclude route", "prefix", prefix, "err", err)
+		}
+	}
 	return nil
 }
 
@@ -221,6 +462,11 @@ func (t *Manager) DisposeDevices() error {
 	t.disposeDevice(t.configuration.TCPSettings)
 	t.disposeDevice(t.configuration.UDPSettings)
 	t.disposeDevice(t.configuration.WSSettings)
+	// The endpoint may override the server of its profile.
+	if t.routeEndpoint.IsValid() {
+		_ = t.ip.RouteDel(t.routeEndpoint.Addr().Unmap().String())
+	}
+	t.uplinkRoutes = nil
 	return nil
 }
 
@@ -231,6 +477,14 @@ func (t *Manager) disposeDevice(s settings.Settings) {
 	if err := t.mss.Remove(s.TunName); err != nil {
 		slog.Warn("failed to remove MSS clamping", "name", s.TunName, "err", err)
 	}
+	if err := t.dns.Restore(s.TunName); err != nil {
+		slog.Warn("failed to restore DNS configuration", "name", s.TunName, "err", err)
+	}
+	// Bypass routes live on the uplink and outlive the device. Prefixes the
+	// host already routed were never added and are left alone.
+	for _, prefix := range s.BypassRoutes() {
+		t.delUplinkRoute(prefix.String())
+	}
 	// Remove split routes before deleting the device
 	_ = t.ip.RouteDelSplitDefault(s.TunName)
 	_ = t.ip.Route6DelSplitDefault(s.TunName)
@@ -7,11 +7,14 @@ import (
 	"io"
 	"net/netip"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
 	clientconfig "tungo/internal/config/client"
 	"tungo/internal/config/settings"
+	"tungo/internal/tun/internal/linux/killswitch"
 )
 
 // clienttunManagerPlainDev is a minimal device over *os.File.
@@ -38,7 +41,13 @@ func (w clienttunManagerPlainWrapper) Wrap(f *os.File) (io.ReadWriteCloser, erro
 type clienttunManagerIPMock struct {
 	log        bytes.Buffer
 	routeReply string
-	failStep   string
+	// defaultReply is the IPv4 default route; there is no IPv6 one.
+	defaultReply string
+	failStep     string
+	// existingRoutes are targets the host routed before; adding them fails.
+	existingRoutes []string
+	// deletedRoutes are the targets of deletions with a next hop.
+	deletedRoutes []string
 }
 
 func (m *clienttunManagerIPMock) mark(s string) error {
@@ -59,8 +68,22 @@ func (m *clienttunManagerIPMock) RouteDefault() (string, error)           { retu
 func (m *clienttunManagerIPMock) RouteAddDefaultDev(string) error         { return m.mark("def") }
 func (m *clienttunManagerIPMock) Route6AddDefaultDev(string) error        { return m.mark("def6") }
 func (m *clienttunManagerIPMock) RouteGet(string) (string, error)         { return m.routeReply, nil }
-func (m *clienttunManagerIPMock) RouteAddDev(string, string) error        { return m.mark("radd") }
-func (m *clienttunManagerIPMock) RouteAddViaDev(string, string, string) error {
+func (m *clienttunManagerIPMock) RouteShowDefault(ipV int) (string, error) {
+	if ipV != 4 || m.defaultReply == "" {
+		return "", errors.New("no default route")
+	}
+	return m.defaultReply, nil
+}
+func (m *clienttunManagerIPMock) RouteAddDev(target, _ string) error {
+	if slices.Contains(m.existingRoutes, target) {
+		return errors.New("RTNETLINK answers: File exists")
+	}
+	return m.mark("radd")
+}
+func (m *clienttunManagerIPMock) RouteAddViaDev(target, _, _ string) error {
+	if slices.Contains(m.existingRoutes, target) {
+		return errors.New("RTNETLINK answers: File exists")
+	}
 	return m.mark("raddvia")
 }
 func (m *clienttunManagerIPMock) RouteAddSplitDefaultDev(string) error  { return m.mark("splitdef") }
@@ -74,6 +97,16 @@ func (m *clienttunManagerIPMock) Route6DelSplitDefault(string) error {
 	return nil
 }
 func (m *clienttunManagerIPMock) RouteDel(string) error { m.log.WriteString("rdel;"); return nil }
+func (m *clienttunManagerIPMock) RouteDelDev(target, _ string) error {
+	m.log.WriteString("rdeldev;")
+	m.deletedRoutes = append(m.deletedRoutes, target)
+	return nil
+}
+func (m *clienttunManagerIPMock) RouteDelViaDev(target, _, _ string) error {
+	m.log.WriteString("rdelvia;")
+	m.deletedRoutes = append(m.deletedRoutes, target)
+	return nil
+}
 
 // clienttunManagerIPGetErr forces RouteGet to error (code ignores err, falls to parse error).
 type clienttunManagerIPGetErr struct{ clienttunManagerIPMock }
@@ -93,6 +126,44 @@ type clienttunManagerMSSMock struct {
 	removeErr  error
 }
 
+// clienttunManagerDNSMock simulates dns.Contract and records applied servers.
+type clienttunManagerDNSMock struct {
+	applyErr  error
+	applied   []string
+	appliedTo []string
+	restored  []string
+}
+
+func (m *clienttunManagerDNSMock) Apply(tunName string, servers []string) error {
+	if m.applyErr != nil {
+		return m.applyErr
+	}
+	m.applied = append(m.applied, servers...)
+	m.appliedTo = append(m.appliedTo, tunName)
+	return nil
+}
+
+func (m *clienttunManagerDNSMock) Restore(tunName string) error {
+	m.restored = append(m.restored, tunName)
+	return nil
+}
+
+// clienttunManagerKillSwitchMock records kill switch policies.
+type clienttunManagerKillSwitchMock struct {
+	enabled  []killswitch.Policy
+	disabled int
+}
+
+func (m *clienttunManagerKillSwitchMock) Enable(policy killswitch.Policy) error {
+	m.enabled = append(m.enabled, policy)
+	return nil
+}
+
+func (m *clienttunManagerKillSwitchMock) Disable() error {
+	m.disabled++
+	return nil
+}
+
 func mustHost(raw string) settings.Host {
 	ip, err := netip.ParseAddr(raw)
 	if err != nil {
@@ -141,9 +212,12 @@ func newMgr(
 		RouteDelSplitDefault(string) error
 		Route6DelSplitDefault(string) error
 		RouteGet(string) (string, error)
+		RouteShowDefault(int) (string, error)
 		RouteAddDev(string, string) error
 		RouteAddViaDev(string, string, string) error
 		RouteDel(string) error
+		RouteDelDev(string, string) error
+		RouteDelViaDev(string, string, string) error
 	},
 	ioctlMock interface {
 		DetectTunNameFromFd(*os.File) (string, error)
@@ -199,6 +273,8 @@ func newMgr(
 		ip:                 ipMock,
 		ioctl:              ioctlMock,
 		mss:                mssMock,
+		dns:                &clienttunManagerDNSMock{},
+		killSwitch:         &clienttunManagerKillSwitchMock{},
 		wrapper:            wrap,
 	}
 }
@@ -475,3 +551,282 @@ func TestDisposeDevices_MSSRemoveError_Logged(t *testing.T) {
 		t.Fatalf("expected no error (MSS remove only logged), got %v", err)
 	}
 }
+
+func TestCreateDevice_ConfiguresDNS(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{routeReply: "198.51.100.1 dev eth0"}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	dnsMock := &clienttunManagerDNSMock{}
+	m.dns = dnsMock
+	m.connectionSettings.DNSv4 = []string{"9.9.9.9"}
+	m.connectionSettings.DNSv6 = []string{"2620:fe::9"}
+
+	dev, err := m.CreateDevice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = dev.Close()
+	if !reflect.DeepEqual(dnsMock.applied, []string{"9.9.9.9"}) {
+		t.Fatalf("expected only IPv4 resolvers without tunnel IPv6, got %v", dnsMock.applied)
+	}
+
+	dnsMock.applied = nil
+	m.connectionSettings.IPv6 = mustAddr("fd00::2")
+	m.connectionSettings.IPv6Subnet = mustPrefix("fd00::/64")
+	dev, err = m.CreateDevice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = dev.Close()
+	if !reflect.DeepEqual(dnsMock.applied, []string{"9.9.9.9", "2620:fe::9"}) {
+		t.Fatalf("expected IPv4 and IPv6 resolvers, got %v", dnsMock.applied)
+	}
+}
+
+func TestCreateDevice_DNSApplyError(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{routeReply: "198.51.100.1 dev eth0"}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.dns = &clienttunManagerDNSMock{applyErr: errors.New("resolvectl fail")}
+
+	_, err := m.CreateDevice()
+	if err == nil || !strings.Contains(err.Error(), "failed to configure DNS") {
+		t.Fatalf("expected DNS configuration error, got %v", err)
+	}
+}
+
+func TestDisposeDevices_RestoresDNS(t *testing.T) {
+	m := newMgr(settings.UDP, &clienttunManagerIPMock{}, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	dnsMock := &clienttunManagerDNSMock{}
+	m.dns = dnsMock
+
+	if err := m.DisposeDevices(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(dnsMock.restored, []string{"tun1", "tun0", "tun2"}) {
+		t.Fatalf("expected DNS restore for every profile, got %v", dnsMock.restored)
+	}
+}
+
+func TestCreateDevice_IncludeAndExcludeRoutes(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{routeReply: "198.51.100.1 via 192.0.2.1 dev eth0"}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.connectionSettings.IncludeRoutes = []netip.Prefix{mustPrefix("10.20.0.0/16"), mustPrefix("2001:db8::/32")}
+	m.connectionSettings.ExcludeRoutes = []netip.Prefix{mustPrefix("10.20.99.0/24")}
+
+	dev, err := m.CreateDevice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = dev.Close()
+
+	// server route, exclude route via the uplink, then the IPv4 include route;
+	// the IPv6 include is skipped because the tunnel has no IPv6 address.
+	got := ipMock.log.String()
+	if !strings.Contains(got, "raddvia;raddvia;radd;mtu;") {
+		t.Fatalf("unexpected route steps: %s", got)
+	}
+	if strings.Contains(got, "splitdef") {
+		t.Fatalf("split tunnel must not install default routes: %s", got)
+	}
+}
+
+func TestCreateDevice_AllowLANKeepsFullTunnel(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{routeReply: "198.51.100.1 dev eth0"}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.connectionSettings.AllowLAN = true
+
+	dev, err := m.CreateDevice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = dev.Close()
+
+	// one server route plus one route per LAN prefix, all through the uplink
+	got := ipMock.log.String()
+	if n := strings.Count(got, "radd;"); n != 1+len(settings.LANPrefixes) {
+		t.Fatalf("expected %d uplink routes, got %d: %s", 1+len(settings.LANPrefixes), n, got)
+	}
+	if !strings.Contains(got, "splitdef;") {
+		t.Fatalf("AllowLAN must keep the full tunnel: %s", got)
+	}
+}
+
+func TestCreateDevice_IncludeRouteError(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{routeReply: "198.51.100.1 via 192.0.2.1 dev eth0", failStep: "radd"}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.connectionSettings.IncludeRoutes = []netip.Prefix{mustPrefix("10.20.0.0/16")}
+
+	_, err := m.CreateDevice()
+	if err == nil || !strings.Contains(err.Error(), "failed to add include route 10.20.0.0/16") {
+		t.Fatalf("expected include route error, got %v", err)
+	}
+}
+
+func TestDisposeDevices_RemovesBypassRoutes(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{routeReply: "198.51.100.1 via 192.0.2.1 dev eth0", existingRoutes: []string{"10.0.0.0/8"}}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.configuration.UDPSettings.ExcludeRoutes = []netip.Prefix{mustPrefix("203.0.113.0/24")}
+	m.configuration.UDPSettings.AllowLAN = true
+	m.connectionSettings = m.configuration.UDPSettings
+
+	dev, err := m.CreateDevice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = dev.Close()
+	if err := m.DisposeDevices(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the exclude and LAN routes tungo added, but not the host's 10.0.0.0/8
+	want := []string{"203.0.113.0/24"}
+	for _, prefix := range settings.LANPrefixes {
+		if prefix.String() != "10.0.0.0/8" {
+			want = append(want, prefix.String())
+		}
+	}
+	if !slices.Equal(ipMock.deletedRoutes, want) {
+		t.Fatalf("deleted routes %v, want %v", ipMock.deletedRoutes, want)
+	}
+	if got := strings.Count(ipMock.log.String(), "rdel;"); got != 3 {
+		t.Fatalf("expected 3 server host route deletions, got %d: %s", got, ipMock.log.String())
+	}
+
+	// a later cleanup, as at startup, deletes no bypass route
+	ipMock.deletedRoutes = nil
+	if err := m.DisposeDevices(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ipMock.deletedRoutes) != 0 {
+		t.Fatalf("cleanup without added routes deleted %v", ipMock.deletedRoutes)
+	}
+}
+
+func TestRefreshUplink_MovesServerAndExcludeRoutes(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{
+		routeReply:     "198.51.100.1 via 192.0.2.1 dev eth0",
+		defaultReply:   "default via 192.168.7.1 dev wlan0 proto dhcp",
+		existingRoutes: []string{"10.0.0.0/8"},
+	}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.connectionSettings.ExcludeRoutes = []netip.Prefix{mustPrefix("10.20.99.0/24"), mustPrefix("10.0.0.0/8"), mustPrefix("2001:db8::/32")}
+	m.SetRouteEndpoint(netip.MustParseAddrPort("198.51.100.1:9090"))
+	dev, err := m.CreateDevice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = dev.Close()
+	ipMock.log.Reset()
+
+	if err := m.RefreshUplink(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// server and IPv4 exclude route move; the host's 10.0.0.0/8 stays, and
+	// the IPv6 route has no default to move to
+	if got := ipMock.log.String(); got != "rdelvia;raddvia;rdelvia;raddvia;" {
+		t.Fatalf("unexpected route steps: %s", got)
+	}
+	if want := []string{"198.51.100.1", "10.20.99.0/24"}; !slices.Equal(ipMock.deletedRoutes, want) {
+		t.Fatalf("deleted routes %v, want %v", ipMock.deletedRoutes, want)
+	}
+}
+
+func TestRefreshUplink_NoDefaultRoute(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.SetRouteEndpoint(netip.MustParseAddrPort("198.51.100.1:9090"))
+
+	if err := m.RefreshUplink(); err == nil {
+		t.Fatal("expected an error without a default route")
+	}
+	if got := ipMock.log.String(); got != "" {
+		t.Fatalf("routes must stay while offline: %s", got)
+	}
+}
+
+func TestEnableKillSwitch_Policy(t *testing.T) {
+	m := newMgr(settings.UDP, &clienttunManagerIPMock{}, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.configuration.UDPSettings.Server = settings.Host{IPv4: "198.51.100.1", IPv6: "2001:db8::1"}
+	m.connectionSettings.AllowLAN = true
+	ks := &clienttunManagerKillSwitchMock{}
+	m.killSwitch = ks
+
+	if err := m.EnableKillSwitch(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ks.enabled) != 1 {
+		t.Fatalf("expected one Enable call, got %d", len(ks.enabled))
+	}
+	policy := ks.enabled[0]
+	if len(policy.TunNames) != 1 || policy.TunNames[0] != "tun0" {
+		t.Fatalf("unexpected tun names: %v", policy.TunNames)
+	}
+	want := []netip.Addr{mustAddr("198.51.100.1"), mustAddr("2001:db8::1")}
+	if !slices.Equal(policy.Endpoints, want) {
+		t.Fatalf("endpoints = %v, want %v", policy.Endpoints, want)
+	}
+	if len(policy.Bypass) != len(settings.LANPrefixes) {
+		t.Fatalf("expected LAN bypass prefixes, got %v", policy.Bypass)
+	}
+	if policy.AllowDNS {
+		t.Fatal("DNS must not be allowed for an IP server host")
+	}
+
+	if err := m.DisableKillSwitch(); err != nil || ks.disabled != 1 {
+		t.Fatalf("DisableKillSwitch() err=%v calls=%d", err, ks.disabled)
+	}
+}
+
+func TestEnableKillSwitch_EmptyServerHost(t *testing.T) {
+	m := newMgr(settings.UDP, &clienttunManagerIPMock{}, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.configuration.UDPSettings.Server = settings.Host{}
+	ks := &clienttunManagerKillSwitchMock{}
+	m.killSwitch = ks
+
+	if err := m.EnableKillSwitch(); err == nil {
+		t.Fatal("expected error for empty server host")
+	}
+	if len(ks.enabled) != 0 {
+		t.Fatal("kill switch must not be enabled without endpoints")
+	}
+}
+
+func TestEnableKillSwitch_CoversEndpointsAndFallback(t *testing.T) {
+	m := newMgr(settings.UDP, &clienttunManagerIPMock{}, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	m.configuration.Endpoints = []clientconfig.Endpoint{
+		{Protocol: settings.UDP},
+		{Protocol: settings.TCP, Server: settings.Host{IPv4: "192.0.2.7"}},
+	}
+	m.configuration.UDPFallbackAfter = 2
+	ks := &clienttunManagerKillSwitchMock{}
+	m.killSwitch = ks
+
+	if err := m.EnableKillSwitch(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	policy := ks.enabled[0]
+	if want := []string{"tun0", "tun1", "tun2"}; !slices.Equal(policy.TunNames, want) {
+		t.Fatalf("tun names = %v, want %v", policy.TunNames, want)
+	}
+	// the WS fallback keeps the UDP server, so it adds no address
+	if want := []netip.Addr{mustAddr("198.51.100.1"), mustAddr("192.0.2.7")}; !slices.Equal(policy.Endpoints, want) {
+		t.Fatalf("endpoints = %v, want %v", policy.Endpoints, want)
+	}
+}
+
+func TestUseSettings_SwitchesDeviceProfile(t *testing.T) {
+	ipMock := &clienttunManagerIPMock{routeReply: "192.0.2.7 via 192.0.2.1 dev eth0"}
+	m := newMgr(settings.UDP, ipMock, clienttunManagerIOCTLMock{}, clienttunManagerMSSMock{}, clienttunManagerPlainWrapper{})
+	dnsMock := &clienttunManagerDNSMock{}
+	m.dns = dnsMock
+	ws := m.configuration.WSSettings
+	ws.Protocol = settings.WS
+	m.UseSettings(ws)
+
+	dev, err := m.CreateDevice()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = dev.Close()
+	if !slices.Equal(dnsMock.appliedTo, []string{"tun2"}) {
+		t.Fatalf("expected the WS device to be configured, got %v", dnsMock.appliedTo)
+	}
+}
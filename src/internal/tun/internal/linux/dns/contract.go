@@ -0,0 +1,7 @@
+package dns
+
+// Contract defines per-link DNS configuration for the TunGo TUN interface.
+type Contract interface {
+	Apply(tunName string, servers []string) error
+	Restore(tunName string) error
+}
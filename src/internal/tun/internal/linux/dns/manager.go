@@ -0,0 +1,217 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"tungo/internal/platform/command"
+)
+
+type backend int
+
+const (
+	backendUnknown backend = iota
+	backendResolved
+	backendResolvConf
+
+	defaultResolvConfPath = "/etc/resolv.conf"
+	backupSuffix          = ".tungo-backup"
+	ownerSuffix           = ".tungo-owner"
+)
+
+// Manager points system DNS at the tunnel resolvers while the tunnel is up.
+//
+// With systemd-resolved running, the servers are set on the TUN link together
+// with the catch-all "~." routing domain, so every query goes through the
+// tunnel. The kernel drops that link state when the TUN is deleted, so a
+// crash cannot leave it behind.
+//
+// Otherwise /etc/resolv.conf is moved aside to a backup next to it and
+// replaced. The backup survives a crash and Restore puts it back on the next
+// cleanup. An owner file next to it names the TUN whose tunnel replaced the
+// file: while that tunnel runs, other tunnels can neither take the file over
+// nor restore it.
+type Manager struct {
+	commander      command.Runner
+	backend        backend
+	resolvConfPath string
+	// attached reports whether a running tunnel holds the named TUN open.
+	attached func(tunName string) bool
+}
+
+func NewManager(commander command.Runner) *Manager {
+	return &Manager{
+		commander:      commander,
+		resolvConfPath: defaultResolvConfPath,
+		attached:       tunAttached,
+	}
+}
+
+// tunAttached reads the carrier of the TUN, which is on only while a process
+// has the device open. A device left by a crashed run has none.
+func tunAttached(tunName string) bool {
+	carrier, err := os.ReadFile(filepath.Join("/sys/class/net", tunName, "carrier"))
+	return err == nil && strings.TrimSpace(string(carrier)) == "1"
+}
+
+// Apply makes servers the only DNS resolvers while tunName is up.
+func (m *Manager) Apply(tunName string, servers []string) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	switch m.detectBackend() {
+	case backendResolved:
+		return m.applyResolved(tunName, servers)
+	default:
+		return m.applyResolvConf(tunName, servers)
+	}
+}
+
+// Restore reverts any DNS changes made for tunName, including ones left over
+// by a process that did not exit cleanly.
+func (m *Manager) Restore(tunName string) error {
+	if m.detectBackend() == backendResolved {
+		// Fails when the link is already gone, which also drops its DNS state.
+		_, _ = m.commander.CombinedOutput("resolvectl", "revert", tunName)
+	}
+	return m.restoreResolvConf(tunName)
+}
+
+func (m *Manager) detectBackend() backend {
+	if m.backend != backendUnknown {
+		return m.backend
+	}
+	if _, err := m.commander.Output("resolvectl", "status"); err == nil {
+		m.backend = backendResolved
+	} else {
+		m.backend = backendResolvConf
+	}
+	return m.backend
+}
+
+func (m *Manager) applyResolved(tunName string, servers []string) error {
+	commands := [][]string{
+		append([]string{"dns", tunName}, servers...),
+		{"domain", tunName, "~."},
+	}
+	for _, args := range commands {
+		if output, err := m.commander.CombinedOutput("resolvectl", args...); err != nil {
+			return fmt.Errorf("failed to run resolvectl %s: %v, output: %s", strings.Join(args, " "), err, output)
+		}
+	}
+	// Older systemd lacks default-route; "~." alone already routes all queries.
+	_, _ = m.commander.CombinedOutput("resolvectl", "default-route", tunName, "yes")
+	return nil
+}
+
+func (m *Manager) backupPath() string {
+	return m.resolvConfPath + backupSuffix
+}
+
+func (m *Manager) ownerPath() string {
+	return m.resolvConfPath + ownerSuffix
+}
+
+// otherOwner returns the TUN of another running tunnel that replaced
+// resolv.conf, or "" when tunName may change it.
+func (m *Manager) otherOwner(tunName string) (string, error) {
+	data, err := os.ReadFile(m.ownerPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", m.ownerPath(), err)
+	}
+	owner := strings.TrimSpace(string(data))
+	if owner == "" || owner == tunName || !m.attached(owner) {
+		return "", nil
+	}
+	return owner, nil
+}
+
+func (m *Manager) applyResolvConf(tunName string, servers []string) error {
+	owner, err := m.otherOwner(tunName)
+	if err != nil {
+		return err
+	}
+	if owner != "" {
+		return fmt.Errorf("%s is managed by the tunnel on %s; DNS of more than one tunnel needs systemd-resolved", m.resolvConfPath, owner)
+	}
+	// An existing backup is the original left by a crashed run; keep it.
+	if _, err := os.Lstat(m.backupPath()); errors.Is(err, fs.ErrNotExist) {
+		if err := m.backupResolvConf(); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", m.resolvConfPath, err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", m.backupPath(), err)
+	}
+	if err := os.WriteFile(m.ownerPath(), []byte(tunName+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", m.ownerPath(), err)
+	}
+
+	var content strings.Builder
+	fmt.Fprintf(&content, "# Generated by tungo. The original is saved as %s.\n", m.backupPath())
+	for _, server := range servers {
+		fmt.Fprintf(&content, "nameserver %s\n", server)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(m.resolvConfPath), ".resolv.conf.tungo-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", m.resolvConfPath, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.WriteString(content.String()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", m.resolvConfPath, err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", m.resolvConfPath, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", m.resolvConfPath, err)
+	}
+	if err := os.Rename(tmp.Name(), m.resolvConfPath); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", m.resolvConfPath, err)
+	}
+	return nil
+}
+
+// backupResolvConf moves the original aside, keeping a symlink as a symlink.
+// A missing original is backed up as an empty file, which resolvers treat
+// the same way.
+func (m *Manager) backupResolvConf() error {
+	err := os.Rename(m.resolvConfPath, m.backupPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.WriteFile(m.backupPath(), nil, 0o644)
+	}
+	return err
+}
+
+// restoreResolvConf puts the original back unless another running tunnel
+// replaced it.
+func (m *Manager) restoreResolvConf(tunName string) error {
+	owner, err := m.otherOwner(tunName)
+	if err != nil || owner != "" {
+		return err
+	}
+	if _, err := os.Lstat(m.backupPath()); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return m.removeOwner()
+		}
+		return fmt.Errorf("failed to inspect %s: %w", m.backupPath(), err)
+	}
+	if err := os.Rename(m.backupPath(), m.resolvConfPath); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", m.resolvConfPath, err)
+	}
+	return m.removeOwner()
+}
+
+func (m *Manager) removeOwner() error {
+	if err := os.Remove(m.ownerPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", m.ownerPath(), err)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package dns
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type recordingCommander struct {
+	calls  []string
+	errMap map[string]error
+}
+
+func (m *recordingCommander) record(name string, args ...string) string {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	m.calls = append(m.calls, cmd)
+	return cmd
+}
+
+func (m *recordingCommander) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return nil, m.errMap[m.record(name, args...)]
+}
+
+func (m *recordingCommander) Output(name string, args ...string) ([]byte, error) {
+	return nil, m.errMap[m.record(name, args...)]
+}
+
+func (m *recordingCommander) Run(name string, args ...string) error {
+	return m.errMap[m.record(name, args...)]
+}
+
+func newTestManager(t *testing.T, cmd *recordingCommander) *Manager {
+	t.Helper()
+	m := NewManager(cmd)
+	m.resolvConfPath = filepath.Join(t.TempDir(), "resolv.conf")
+	m.attached = func(string) bool { return false }
+	return m
+}
+
+func TestManager_ApplyAndRestore_Resolved(t *testing.T) {
+	cmd := &recordingCommander{errMap: map[string]error{}}
+	m := newTestManager(t, cmd)
+
+	if err := m.Apply("tun0", []string{"1.1.1.1", "2606:4700:4700::1111"}); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if err := m.Restore("tun0"); err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+
+	expected := []string{
+		"resolvectl status",
+		"resolvectl dns tun0 1.1.1.1 2606:4700:4700::1111",
+		"resolvectl domain tun0 ~.",
+		"resolvectl default-route tun0 yes",
+		"resolvectl revert tun0",
+	}
+	if !reflect.DeepEqual(expected, cmd.calls) {
+		t.Fatalf("unexpected commands.\nwant: %v\n got: %v", expected, cmd.calls)
+	}
+}
+
+func TestManager_Apply_ResolvedError(t *testing.T) {
+	cmd := &recordingCommander{errMap: map[string]error{
+		"resolvectl domain tun0 ~.": errors.New("boom"),
+	}}
+	m := newTestManager(t, cmd)
+
+	err := m.Apply("tun0", []string{"1.1.1.1"})
+	if err == nil || !strings.Contains(err.Error(), "resolvectl domain tun0 ~.") {
+		t.Fatalf("expected resolvectl domain error, got %v", err)
+	}
+}
+
+func TestManager_Apply_NoServers(t *testing.T) {
+	cmd := &recordingCommander{errMap: map[string]error{}}
+	m := newTestManager(t, cmd)
+
+	if err := m.Apply("tun0", nil); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	if len(cmd.calls) != 0 {
+		t.Fatalf("expected no commands, got %v", cmd.calls)
+	}
+}
+
+func resolvConfFallback() *recordingCommander {
+	return &recordingCommander{errMap: map[string]error{
+		"resolvectl status": errors.New("not running"),
+	}}
+}
+
+func TestManager_ApplyAndRestore_ResolvConf(t *testing.T) {
+	m := newTestManager(t, resolvConfFallback())
+	original := "nameserver 192.168.1.1\n"
+	if err := os.WriteFile(m.resolvConfPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Apply("tun0", []string{"1.1.1.1", "8.8.8.8"}); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+	written, err := os.ReadFile(m.resolvConfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(written), "nameserver 1.1.1.1\nnameserver 8.8.8.8\n") ||
+		strings.Contains(string(written), "192.168.1.1") {
+		t.Fatalf("unexpected resolv.conf:\n%s", written)
+	}
+
+	if err := m.Restore("tun0"); err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+	restored, err := os.ReadFile(m.resolvConfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(restored) != original {
+		t.Fatalf("expected original resolv.conf, got:\n%s", restored)
+	}
+	if _, err := os.Lstat(m.backupPath()); !os.IsNotExist(err) {
+		t.Fatalf("expected backup to be removed, got %v", err)
+	}
+}
+
+func TestManager_ResolvConf_KeepsBackupFromCrashedRun(t *testing.T) {
+	m := newTestManager(t, resolvConfFallback())
+	original := "nameserver 192.168.1.1\n"
+	if err := os.WriteFile(m.resolvConfPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Apply("tun0", []string{"1.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second run after a crash must not back up the generated file.
+	restarted := &Manager{commander: resolvConfFallback(), resolvConfPath: m.resolvConfPath}
+	if err := restarted.Apply("tun0", []string{"9.9.9.9"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.Restore("tun0"); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := os.ReadFile(m.resolvConfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(restored) != original {
+		t.Fatalf("expected original resolv.conf, got:\n%s", restored)
+	}
+}
+
+func TestManager_ResolvConf_SecondTunnel(t *testing.T) {
+	first := newTestManager(t, resolvConfFallback())
+	original := "nameserver 192.168.1.1\n"
+	if err := os.WriteFile(first.resolvConfPath, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Apply("tun0", []string{"1.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance must neither take over nor restore the file while the
+	// first tunnel runs.
+	second := newTestManager(t, resolvConfFallback())
+	second.resolvConfPath = first.resolvConfPath
+	second.attached = func(tunName string) bool { return tunName == "tun0" }
+	if err := second.Apply("tun1", []string{"9.9.9.9"}); err == nil || !strings.Contains(err.Error(), "tun0") {
+		t.Fatalf("expected an error naming tun0, got %v", err)
+	}
+	if err := second.Restore("tun1"); err != nil {
+		t.Fatal(err)
+	}
+	if written, _ := os.ReadFile(first.resolvConfPath); !strings.Contains(string(written), "nameserver 1.1.1.1") {
+		t.Fatalf("resolv.conf of the running tunnel changed:\n%s", written)
+	}
+
+	// Once the first tunnel is gone without cleaning up, its state is a
+	// leftover that the second may restore.
+	second.attached = func(string) bool { return false }
+	if err := second.Restore("tun1"); err != nil {
+		t.Fatal(err)
+	}
+	if restored, _ := os.ReadFile(first.resolvConfPath); string(restored) != original {
+		t.Fatalf("expected original resolv.conf, got:\n%s", restored)
+	}
+	if _, err := os.Lstat(first.ownerPath()); !os.IsNotExist(err) {
+		t.Fatalf("expected owner file to be removed, got %v", err)
+	}
+}
+
+func TestManager_ResolvConf_PreservesSymlink(t *testing.T) {
+	m := newTestManager(t, resolvConfFallback())
+	target := filepath.Join(filepath.Dir(m.resolvConfPath), "stub-resolv.conf")
+	if err := os.WriteFile(target, []byte("nameserver 127.0.0.53\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, m.resolvConfPath); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Apply("tun0", []string{"1.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if stub, _ := os.ReadFile(target); string(stub) != "nameserver 127.0.0.53\n" {
+		t.Fatalf("symlink target must not be modified, got:\n%s", stub)
+	}
+	if err := m.Restore("tun0"); err != nil {
+		t.Fatal(err)
+	}
+	if link, err := os.Readlink(m.resolvConfPath); err != nil || link != target {
+		t.Fatalf("expected symlink to %s to be restored, got %q, %v", target, link, err)
+	}
+}
+
+func TestManager_ResolvConf_MissingOriginal(t *testing.T) {
+	m := newTestManager(t, resolvConfFallback())
+
+	if err := m.Apply("tun0", []string{"1.1.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Restore("tun0"); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := os.ReadFile(m.resolvConfPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != 0 {
+		t.Fatalf("expected empty resolv.conf, got:\n%s", restored)
+	}
+}
+
+func TestManager_Restore_NothingToDo(t *testing.T) {
+	m := newTestManager(t, resolvConfFallback())
+	if err := m.Restore("tun0"); err != nil {
+		t.Fatalf("Restore returned error: %v", err)
+	}
+}
@@ -16,7 +16,10 @@ type Contract interface {
 	RouteDelSplitDefault(devName string) error
 	Route6DelSplitDefault(devName string) error
 	RouteGet(hostIp string) (string, error)
+	RouteShowDefault(ipV int) (string, error)
 	RouteAddDev(hostIp string, ifName string) error
 	RouteAddViaDev(hostIp string, ifName string, gateway string) error
 	RouteDel(hostIp string) error
+	RouteDelDev(hostIp string, ifName string) error
+	RouteDelViaDev(hostIp string, ifName string, gateway string) error
 }
@@ -166,6 +166,22 @@ func (i *Wrapper) RouteGet(hostIp string) (string, error) {
 	return string(routeBytes), nil
 }
 
+// RouteShowDefault returns the first IPv4 or IPv6 default route of the main
+// table, for example "default via 192.168.1.1 dev wlan0 proto dhcp". Unlike
+// RouteGet it is not shadowed by more specific tunnel routes.
+func (i *Wrapper) RouteShowDefault(ipV int) (string, error) {
+	output, err := i.commander.Output("ip", fmt.Sprintf("-%d", ipV), "route", "show", "default")
+	if err != nil {
+		return "", fmt.Errorf("failed to get IPv%d default route: %v", ipV, err)
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "default") {
+			return line, nil
+		}
+	}
+	return "", fmt.Errorf("no IPv%d default route found", ipV)
+}
+
 // RouteAddDev adds a route to host via device
 func (i *Wrapper) RouteAddDev(hostIp string, ifName string) error {
 	output, err := i.commander.CombinedOutput("ip", "route", "add", hostIp, "dev", ifName)
@@ -193,6 +209,25 @@ func (i *Wrapper) RouteDel(hostIp string) error {
 	return err
 }
 
+// RouteDelDev deletes the route to host via device, leaving routes to the
+// same host on other devices
+func (i *Wrapper) RouteDelDev(hostIp string, ifName string) error {
+	output, err := i.commander.CombinedOutput("ip", "route", "del", hostIp, "dev", ifName)
+	if err != nil {
+		return fmt.Errorf("failed to del route: %s, output: %s", err, output)
+	}
+	return err
+}
+
+// RouteDelViaDev deletes the route to host via device via gateway
+func (i *Wrapper) RouteDelViaDev(hostIp string, ifName string, gateway string) error {
+	output, err := i.commander.CombinedOutput("ip", "route", "del", hostIp, "via", gateway, "dev", ifName)
+	if err != nil {
+		return fmt.Errorf("failed to del route: %s, output: %s", err, output)
+	}
+	return err
+}
+
 // LinkSetDevMTU sets device MTU
 func (i *Wrapper) LinkSetDevMTU(devName string, mtu int) error {
 	output, err := i.commander.CombinedOutput("ip", "link",
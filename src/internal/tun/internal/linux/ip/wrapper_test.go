@@ -274,6 +274,25 @@ func TestRouteGet(t *testing.T) {
 	})
 }
 
+func TestRouteShowDefault(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		route, err := newWrapper(true, "default via 10.0.0.1 dev eth0 proto dhcp\n", nil).RouteShowDefault(4)
+		if err != nil || route != "default via 10.0.0.1 dev eth0 proto dhcp" {
+			t.Fatal("unexpected result", err, route)
+		}
+	})
+	t.Run("no default", func(t *testing.T) {
+		if _, err := newWrapper(true, "", nil).RouteShowDefault(6); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		if _, err := newWrapper(false, "output", errors.New("fail")).RouteShowDefault(4); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
 func TestRouteAddDev(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		err := newWrapper(true, "", nil).RouteAddDev("1.1.1.1", "tun0")
@@ -319,6 +338,36 @@ func TestRouteDel(t *testing.T) {
 	})
 }
 
+func TestRouteDelDev(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		err := newWrapper(true, "", nil).RouteDelDev("1.1.1.1", "tun0")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		err := newWrapper(false, "output", errors.New("fail")).RouteDelDev("1.1.1.1", "tun0")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
+func TestRouteDelViaDev(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		err := newWrapper(true, "", nil).RouteDelViaDev("1.1.1.1", "tun0", "10.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		err := newWrapper(false, "output", errors.New("fail")).RouteDelViaDev("1.1.1.1", "tun0", "10.0.0.1")
+		if err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
+
 func TestLinkSetDevMTU(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		err := newWrapper(true, "", nil).LinkSetDevMTU("tun0", 1400)
@@ -0,0 +1,23 @@
+package killswitch
+
+import "net/netip"
+
+// Policy lists the egress the kill switch still allows. Everything else
+// leaving the host is dropped.
+type Policy struct {
+	// TunNames are the tunnel interfaces.
+	TunNames []string
+	// Endpoints are the server addresses the transport connects to.
+	Endpoints []netip.Addr
+	// Bypass are destinations routed around the tunnel on purpose.
+	Bypass []netip.Prefix
+	// AllowDNS permits plain DNS so that a server domain can be re-resolved
+	// between sessions.
+	AllowDNS bool
+}
+
+// Contract defines a firewall that blocks non-tunnel egress.
+type Contract interface {
+	Enable(policy Policy) error
+	Disable() error
+}
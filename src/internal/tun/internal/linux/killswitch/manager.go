@@ -0,0 +1,265 @@
+package killswitch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"os"
+	"strings"
+	"tungo/internal/platform/command"
+)
+
+type backend int
+
+const (
+	backendUnknown backend = iota
+	backendNft
+	backendIptables
+
+	nftTable       = "tungo_killswitch"
+	nftOutputChain = "output"
+	iptablesChain  = "TUNGO_KILLSWITCH"
+	maxStaleHooks  = 4
+
+	disableIPv6Path = "/proc/sys/net/ipv6/conf/all/disable_ipv6"
+)
+
+// readFile is replaced in tests.
+var readFile = os.ReadFile
+
+// Manager installs an output filter that drops every packet not bound for
+// the tunnel, loopback, the server or an explicitly bypassed destination.
+// DHCP and IPv6 neighbour discovery stay allowed so the uplink keeps working.
+// The rules do not depend on the TUN device existing, so they keep blocking
+// while the device is torn down between sessions.
+type Manager struct {
+	commander command.Runner
+	backend   backend
+	ipv6      int8 // 0=unknown, 1=available, -1=unavailable
+}
+
+func NewManager(commander command.Runner) *Manager {
+	return &Manager{commander: commander}
+}
+
+// Enable replaces any existing kill switch rules with ones for policy.
+func (m *Manager) Enable(policy Policy) error {
+	backend, err := m.detectBackend()
+	if err != nil {
+		return err
+	}
+	switch backend {
+	case backendNft:
+		m.removeNft()
+		return m.installNft(policy)
+	case backendIptables:
+		m.removeIptables()
+		return m.installIptables(policy)
+	default:
+		return fmt.Errorf("unsupported kill switch backend")
+	}
+}
+
+// Disable removes the kill switch rules. Missing rules are not an error.
+func (m *Manager) Disable() error {
+	backend, err := m.detectBackend()
+	if err != nil {
+		return err
+	}
+	switch backend {
+	case backendNft:
+		m.removeNft()
+	case backendIptables:
+		m.removeIptables()
+	}
+	return nil
+}
+
+func (m *Manager) detectBackend() (backend, error) {
+	if m.backend != backendUnknown {
+		return m.backend, nil
+	}
+
+	if _, err := m.commander.Output("nft", "--version"); err == nil {
+		m.backend = backendNft
+		return m.backend, nil
+	}
+
+	if _, err := m.commander.Output("iptables", "--version"); err == nil {
+		m.backend = backendIptables
+		return m.backend, nil
+	}
+
+	return backendUnknown, fmt.Errorf("neither nftables nor iptables is available for the kill switch")
+}
+
+type describedCommand struct {
+	name string
+	args []string
+	desc string
+}
+
+func (m *Manager) run(commands []describedCommand) error {
+	for _, cmd := range commands {
+		output, err := m.commander.CombinedOutput(cmd.name, cmd.args...)
+		if err != nil {
+			return fmt.Errorf("failed to %s: %v, output: %s", cmd.desc, err, output)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) installNft(policy Policy) error {
+	rule := func(desc string, match ...string) describedCommand {
+		args := append([]string{"add", "rule", "inet", nftTable, nftOutputChain}, match...)
+		return describedCommand{name: "nft", args: append(args, "accept"), desc: "allow " + desc}
+	}
+	commands := []describedCommand{
+		{
+			name: "nft",
+			args: []string{"add", "table", "inet", nftTable},
+			desc: "create nftable table for the kill switch",
+		},
+		{
+			name: "nft",
+			args: []string{"add", "chain", "inet", nftTable, nftOutputChain, "{", "type", "filter", "hook", "output", "priority", "0", ";", "policy", "drop", ";", "}"},
+			desc: "create kill switch output chain",
+		},
+		rule("loopback", "oifname", "lo"),
+	}
+	for _, tunName := range policy.TunNames {
+		commands = append(commands, rule("tunnel interface "+tunName, "oifname", tunName))
+	}
+	for _, endpoint := range policy.Endpoints {
+		commands = append(commands, rule("server "+endpoint.String(), nftFamily(endpoint), "daddr", endpoint.String()))
+	}
+	for _, prefix := range policy.Bypass {
+		commands = append(commands, rule("bypass route "+prefix.String(), nftFamily(prefix.Addr()), "daddr", prefix.String()))
+	}
+	commands = append(commands,
+		rule("DHCP", "udp", "sport", "68", "udp", "dport", "67"),
+		rule("DHCPv6", "udp", "sport", "546", "udp", "dport", "547"),
+		rule("IPv6 neighbour discovery", "icmpv6", "type", "{", "nd-router-solicit,", "nd-neighbor-solicit,", "nd-neighbor-advert", "}"),
+	)
+	if policy.AllowDNS {
+		commands = append(commands,
+			rule("DNS over UDP", "udp", "dport", "53"),
+			rule("DNS over TCP", "tcp", "dport", "53"),
+		)
+	}
+	return m.run(commands)
+}
+
+func nftFamily(addr netip.Addr) string {
+	if addr.Is4() {
+		return "ip"
+	}
+	return "ip6"
+}
+
+func (m *Manager) removeNft() {
+	_, _ = m.commander.CombinedOutput("nft", "delete", "table", "inet", nftTable)
+}
+
+func (m *Manager) installIptables(policy Policy) error {
+	if err := m.run(m.iptablesChain("iptables", policy, func(addr netip.Addr) bool { return addr.Is4() })); err != nil {
+		return err
+	}
+	if !m.ip6tablesUsable() {
+		// IPv6 egress cannot be filtered, so it must not exist at all.
+		if ipv6Enabled() {
+			return fmt.Errorf("ip6tables is not usable but IPv6 is enabled: IPv6 traffic would bypass the kill switch")
+		}
+		return nil
+	}
+	return m.run(m.iptablesChain("ip6tables", policy, func(addr netip.Addr) bool { return addr.Is6() }))
+}
+
+// ipv6Enabled reports whether the kernel may send IPv6 packets. It errs on
+// the side of yes: only a kernel without IPv6 or with it disabled is no.
+func ipv6Enabled() bool {
+	data, err := readFile(disableIPv6Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+	return err != nil || strings.TrimSpace(string(data)) != "1"
+}
+
+func (m *Manager) iptablesChain(tool string, policy Policy, inFamily func(netip.Addr) bool) []describedCommand {
+	rule := func(desc string, match ...string) describedCommand {
+		args := append([]string{"-A", iptablesChain}, match...)
+		return describedCommand{name: tool, args: append(args, "-j", "ACCEPT"), desc: tool + " allow " + desc}
+	}
+	commands := []describedCommand{
+		{name: tool, args: []string{"-N", iptablesChain}, desc: tool + " create kill switch chain"},
+		rule("loopback", "-o", "lo"),
+	}
+	for _, tunName := range policy.TunNames {
+		commands = append(commands, rule("tunnel interface "+tunName, "-o", tunName))
+	}
+	for _, endpoint := range policy.Endpoints {
+		if inFamily(endpoint) {
+			commands = append(commands, rule("server "+endpoint.String(), "-d", endpoint.String()))
+		}
+	}
+	for _, prefix := range policy.Bypass {
+		if inFamily(prefix.Addr()) {
+			commands = append(commands, rule("bypass route "+prefix.String(), "-d", prefix.String()))
+		}
+	}
+	if tool == "iptables" {
+		commands = append(commands, rule("DHCP", "-p", "udp", "--sport", "68", "--dport", "67"))
+	} else {
+		commands = append(commands,
+			rule("DHCPv6", "-p", "udp", "--sport", "546", "--dport", "547"),
+			rule("router solicitation", "-p", "ipv6-icmp", "--icmpv6-type", "router-solicitation"),
+			rule("neighbour solicitation", "-p", "ipv6-icmp", "--icmpv6-type", "neighbour-solicitation"),
+			rule("neighbour advertisement", "-p", "ipv6-icmp", "--icmpv6-type", "neighbour-advertisement"),
+		)
+	}
+	if policy.AllowDNS {
+		commands = append(commands,
+			rule("DNS over UDP", "-p", "udp", "--dport", "53"),
+			rule("DNS over TCP", "-p", "tcp", "--dport", "53"),
+		)
+	}
+	return append(commands,
+		describedCommand{name: tool, args: []string{"-A", iptablesChain, "-j", "DROP"}, desc: tool + " drop other egress"},
+		describedCommand{name: tool, args: []string{"-I", "OUTPUT", "-j", iptablesChain}, desc: tool + " hook kill switch into OUTPUT"},
+	)
+}
+
+// removeIptables unhooks, flushes and deletes the chain, ignoring errors
+// for pieces that do not exist.
+func (m *Manager) removeIptables() {
+	tools := []string{"iptables"}
+	if m.ip6tablesUsable() {
+		tools = append(tools, "ip6tables")
+	}
+	for _, tool := range tools {
+		// Enable hooks the chain once; repeat in case a crashed run left more.
+		for range maxStaleHooks {
+			if _, err := m.commander.CombinedOutput(tool, "-D", "OUTPUT", "-j", iptablesChain); err != nil {
+				break
+			}
+		}
+		_, _ = m.commander.CombinedOutput(tool, "-F", iptablesChain)
+		_, _ = m.commander.CombinedOutput(tool, "-X", iptablesChain)
+	}
+}
+
+// ip6tablesUsable reports whether ip6tables can manage filter rules.
+// The result is cached for the Manager lifetime.
+func (m *Manager) ip6tablesUsable() bool {
+	if m.ipv6 != 0 {
+		return m.ipv6 > 0
+	}
+	_, err := m.commander.CombinedOutput("ip6tables", "-t", "filter", "-L", "-n")
+	if err == nil {
+		m.ipv6 = 1
+	} else {
+		m.ipv6 = -1
+	}
+	return m.ipv6 > 0
+}
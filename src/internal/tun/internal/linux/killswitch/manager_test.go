@@ -0,0 +1,209 @@
+package killswitch
+
+import (
+	"errors"
+	"io/fs"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type recordingCommander struct {
+	calls  []string
+	errMap map[string]error
+}
+
+func (m *recordingCommander) record(name string, args ...string) string {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	m.calls = append(m.calls, cmd)
+	return cmd
+}
+
+func (m *recordingCommander) CombinedOutput(name string, args ...string) ([]byte, error) {
+	return nil, m.errMap[m.record(name, args...)]
+}
+
+func (m *recordingCommander) Output(name string, args ...string) ([]byte, error) {
+	return nil, m.errMap[m.record(name, args...)]
+}
+
+func (m *recordingCommander) Run(name string, args ...string) error {
+	return m.errMap[m.record(name, args...)]
+}
+
+func testPolicy() Policy {
+	return Policy{
+		TunNames:  []string{"tungo0"},
+		Endpoints: []netip.Addr{netip.MustParseAddr("198.51.100.1"), netip.MustParseAddr("2001:db8::1")},
+		Bypass:    []netip.Prefix{netip.MustParsePrefix("192.168.0.0/16")},
+		AllowDNS:  true,
+	}
+}
+
+func TestManager_EnableAndDisable_Nft(t *testing.T) {
+	cmd := &recordingCommander{errMap: map[string]error{}}
+	m := NewManager(cmd)
+
+	if err := m.Enable(testPolicy()); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	if err := m.Disable(); err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+
+	rule := "nft add rule inet tungo_killswitch output "
+	expected := []string{
+		"nft --version",
+		"nft delete table inet tungo_killswitch",
+		"nft add table inet tungo_killswitch",
+		"nft add chain inet tungo_killswitch output { type filter hook output priority 0 ; policy drop ; }",
+		rule + "oifname lo accept",
+		rule + "oifname tungo0 accept",
+		rule + "ip daddr 198.51.100.1 accept",
+		rule + "ip6 daddr 2001:db8::1 accept",
+		rule + "ip daddr 192.168.0.0/16 accept",
+		rule + "udp sport 68 udp dport 67 accept",
+		rule + "udp sport 546 udp dport 547 accept",
+		rule + "icmpv6 type { nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert } accept",
+		rule + "udp dport 53 accept",
+		rule + "tcp dport 53 accept",
+		"nft delete table inet tungo_killswitch",
+	}
+	if !reflect.DeepEqual(expected, cmd.calls) {
+		t.Fatalf("unexpected commands.\nwant: %v\n got: %v", expected, cmd.calls)
+	}
+}
+
+// withIPv6Sysctl makes disable_ipv6 read as value, or fail with err.
+func withIPv6Sysctl(t *testing.T, value string, err error) {
+	t.Helper()
+	prev := readFile
+	readFile = func(name string) ([]byte, error) {
+		if name != disableIPv6Path {
+			t.Fatalf("unexpected read of %s", name)
+		}
+		return []byte(value), err
+	}
+	t.Cleanup(func() { readFile = prev })
+}
+
+func TestManager_Enable_Iptables_IPv4Only(t *testing.T) {
+	withIPv6Sysctl(t, "1\n", nil)
+	cmd := &recordingCommander{errMap: map[string]error{
+		"nft --version":                          errors.New("missing"),
+		"ip6tables -t filter -L -n":              errors.New("ipv6 disabled"),
+		"iptables -D OUTPUT -j TUNGO_KILLSWITCH": errors.New("no chain"),
+	}}
+	m := NewManager(cmd)
+	policy := testPolicy()
+	policy.AllowDNS = false
+
+	if err := m.Enable(policy); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+
+	expected := []string{
+		"nft --version",
+		"iptables --version",
+		"ip6tables -t filter -L -n",
+		"iptables -D OUTPUT -j TUNGO_KILLSWITCH",
+		"iptables -F TUNGO_KILLSWITCH",
+		"iptables -X TUNGO_KILLSWITCH",
+		"iptables -N TUNGO_KILLSWITCH",
+		"iptables -A TUNGO_KILLSWITCH -o lo -j ACCEPT",
+		"iptables -A TUNGO_KILLSWITCH -o tungo0 -j ACCEPT",
+		"iptables -A TUNGO_KILLSWITCH -d 198.51.100.1 -j ACCEPT",
+		"iptables -A TUNGO_KILLSWITCH -d 192.168.0.0/16 -j ACCEPT",
+		"iptables -A TUNGO_KILLSWITCH -p udp --sport 68 --dport 67 -j ACCEPT",
+		"iptables -A TUNGO_KILLSWITCH -j DROP",
+		"iptables -I OUTPUT -j TUNGO_KILLSWITCH",
+	}
+	if !reflect.DeepEqual(expected, cmd.calls) {
+		t.Fatalf("unexpected commands.\nwant: %v\n got: %v", expected, cmd.calls)
+	}
+}
+
+func TestManager_Enable_IptablesWithoutIp6tablesFailsClosed(t *testing.T) {
+	for name, tc := range map[string]struct {
+		value   string
+		err     error
+		wantErr bool
+	}{
+		"ipv6 enabled":      {value: "0\n", wantErr: true},
+		"unreadable":        {err: fs.ErrPermission, wantErr: true},
+		"no ipv6 in kernel": {err: fs.ErrNotExist},
+	} {
+		t.Run(name, func(t *testing.T) {
+			withIPv6Sysctl(t, tc.value, tc.err)
+			cmd := &recordingCommander{errMap: map[string]error{
+				"nft --version":             errors.New("missing"),
+				"ip6tables -t filter -L -n": errors.New("ip6tables missing"),
+			}}
+			m := NewManager(cmd)
+
+			err := m.Enable(testPolicy())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Enable error = %v, want error %v", err, tc.wantErr)
+			}
+			if !slices.Contains(cmd.calls, "iptables -I OUTPUT -j TUNGO_KILLSWITCH") {
+				t.Fatalf("IPv4 kill switch was not installed:\n%s", strings.Join(cmd.calls, "\n"))
+			}
+		})
+	}
+}
+
+func TestManager_Enable_IptablesDualStack(t *testing.T) {
+	cmd := &recordingCommander{errMap: map[string]error{
+		"nft --version":                           errors.New("missing"),
+		"iptables -D OUTPUT -j TUNGO_KILLSWITCH":  errors.New("no chain"),
+		"ip6tables -D OUTPUT -j TUNGO_KILLSWITCH": errors.New("no chain"),
+	}}
+	m := NewManager(cmd)
+
+	if err := m.Enable(testPolicy()); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	calls := strings.Join(cmd.calls, "\n")
+	for _, want := range []string{
+		"ip6tables -A TUNGO_KILLSWITCH -d 2001:db8::1 -j ACCEPT",
+		"ip6tables -A TUNGO_KILLSWITCH -p ipv6-icmp --icmpv6-type neighbour-solicitation -j ACCEPT",
+		"ip6tables -A TUNGO_KILLSWITCH -p udp --dport 53 -j ACCEPT",
+		"ip6tables -I OUTPUT -j TUNGO_KILLSWITCH",
+	} {
+		if !strings.Contains(calls, want) {
+			t.Fatalf("missing %q in:\n%s", want, calls)
+		}
+	}
+	if strings.Contains(calls, "ip6tables -A TUNGO_KILLSWITCH -d 198.51.100.1") {
+		t.Fatalf("IPv4 endpoint must not be added to ip6tables:\n%s", calls)
+	}
+}
+
+func TestManager_Enable_RuleError(t *testing.T) {
+	cmd := &recordingCommander{errMap: map[string]error{
+		"nft add table inet tungo_killswitch": errors.New("permission denied"),
+	}}
+	m := NewManager(cmd)
+
+	err := m.Enable(testPolicy())
+	if err == nil || !strings.Contains(err.Error(), "create nftable table for the kill switch") {
+		t.Fatalf("expected table creation error, got %v", err)
+	}
+}
+
+func TestManager_NoBackend(t *testing.T) {
+	cmd := &recordingCommander{errMap: map[string]error{
+		"nft --version":      errors.New("missing"),
+		"iptables --version": errors.New("missing"),
+	}}
+	m := NewManager(cmd)
+
+	if err := m.Enable(testPolicy()); err == nil {
+		t.Fatal("expected error without a firewall backend")
+	}
+	if err := m.Disable(); err == nil {
+		t.Fatal("expected error without a firewall backend")
+	}
+}
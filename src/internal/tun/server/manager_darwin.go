@@ -3,6 +3,7 @@ package server
 import (
 	"errors"
 	"io"
+	"net/netip"
 	"tungo/internal/config/settings"
 )
 
@@ -22,3 +23,11 @@ func (s Manager) CreateDevice(_ settings.Settings) (io.ReadWriteCloser, error) {
 func (s Manager) DisposeDevices(_ settings.Settings) error {
 	return nil
 }
+
+func (s Manager) AddRoute(_ settings.Settings, _ netip.Prefix) error {
+	return errServerNotSupported
+}
+
+func (s Manager) DeleteRoute(_ settings.Settings, _ netip.Prefix) error {
+	return errServerNotSupported
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"net"
+	"net/netip"
 	"os"
 	"strings"
 	"syscall"
@@ -113,6 +114,17 @@ func (s Manager) DisposeDevices(connSettings settings.Settings) error {
 	return nil
 }
 
+// AddRoute routes prefix into the TUN of connSettings.
+func (s Manager) AddRoute(connSettings settings.Settings, prefix netip.Prefix) error {
+	return s.device.ip.RouteAddDev(prefix.String(), connSettings.TunName)
+}
+
+// DeleteRoute removes a route added by AddRoute, leaving routes to prefix on
+// other devices.
+func (s Manager) DeleteRoute(connSettings settings.Settings, prefix netip.Prefix) error {
+	return s.device.ip.RouteDelDev(prefix.String(), connSettings.TunName)
+}
+
 func (s Manager) Unconfigure(tunFile *os.File) error {
 	tunName, err := s.device.detectName(tunFile)
 	if err != nil {
@@ -59,9 +59,15 @@ func (m *TunFactoryMockIP) Route6AddSplitDefaultDev(_ string) error     { return
 func (m *TunFactoryMockIP) RouteDelSplitDefault(_ string) error         { return nil }
 func (m *TunFactoryMockIP) Route6DelSplitDefault(_ string) error        { return nil }
 func (m *TunFactoryMockIP) RouteGet(_ string) (string, error)           { return "", nil }
+func (m *TunFactoryMockIP) RouteShowDefault(_ int) (string, error)      { return "", nil }
 func (m *TunFactoryMockIP) RouteAddDev(_, _ string) error               { return nil }
 func (m *TunFactoryMockIP) RouteAddViaDev(_, _, _ string) error         { return nil }
 func (m *TunFactoryMockIP) RouteDel(_ string) error                     { return nil }
+func (m *TunFactoryMockIP) RouteDelViaDev(_, _, _ string) error         { return nil }
+func (m *TunFactoryMockIP) RouteDelDev(prefix, dev string) error {
+	m.add("rdel " + prefix + " dev " + dev)
+	return nil
+}
 
 // Variant: RouteDefault returns empty iface (to hit "skipping iptables forwarding disable").
 type TunFactoryMockIPRouteEmpty struct{ TunFactoryMockIP }
@@ -1320,3 +1326,16 @@ func TestClearForwarding_DisableForwardingTunToTun_Error(t *testing.T) {
 		t.Errorf("expected iptables error, got %v", err)
 	}
 }
+
+func TestDeleteRoute_UsesTunDevice(t *testing.T) {
+	ipMock := &TunFactoryMockIP{}
+	f := newFactory(ipMock, nil, nil, nil, nil)
+	s := settings.Settings{Addressing: settings.Addressing{TunName: "tcptun0"}}
+
+	if err := f.DeleteRoute(s, netip.MustParsePrefix("192.168.50.0/24")); err != nil {
+		t.Fatalf("DeleteRoute: %v", err)
+	}
+	if got := ipMock.log.String(); got != "rdel 192.168.50.0/24 dev tcptun0;" {
+		t.Fatalf("unexpected route steps: %q", got)
+	}
+}
@@ -0,0 +1,85 @@
+package userspace
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+
+	"tungo/internal/config/settings"
+)
+
+var errTunnelDown = errors.New("tunnel is not connected")
+
+// Manager hands the client a Stack in place of a TUN device and lets the
+// proxies open connections from whichever stack is current. It has no
+// system state to set up or dispose.
+type Manager struct {
+	mu       sync.Mutex
+	settings settings.Settings
+	current  *Stack
+}
+
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// UseSettings selects the profile of the endpoint the next stack is created
+// for.
+func (m *Manager) UseSettings(s settings.Settings) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.settings = s
+}
+
+func (m *Manager) CreateDevice() (io.ReadWriteCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	st, err := NewStack(m.settings)
+	if err != nil {
+		return nil, err
+	}
+	m.current = st
+	return st, nil
+}
+
+func (m *Manager) DisposeDevices() error {
+	return nil
+}
+
+func (m *Manager) SetRouteEndpoint(netip.AddrPort) {}
+
+func (m *Manager) stack() (*Stack, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil || m.current.ctx.Err() != nil {
+		return nil, errTunnelDown
+	}
+	return m.current, nil
+}
+
+func (m *Manager) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	st, err := m.stack()
+	if err != nil {
+		return nil, err
+	}
+	return st.DialContext(ctx, network, address)
+}
+
+func (m *Manager) ListenPacket(network string) (net.PacketConn, error) {
+	st, err := m.stack()
+	if err != nil {
+		return nil, err
+	}
+	return st.ListenPacket(network)
+}
+
+func (m *Manager) LookupNetIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	st, err := m.stack()
+	if err != nil {
+		return nil, err
+	}
+	return st.LookupNetIP(ctx, host)
+}
@@ -0,0 +1,246 @@
+// Package userspace terminates the tunnel in a userspace TCP/IP stack instead
+// of a TUN device, so that the client runs without privileges. Local
+// programs reach the tunnel through the proxies of internal/client/proxy.
+package userspace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"sync/atomic"
+
+	"tungo/internal/config/settings"
+
+	"gvisor.dev/gvisor/pkg/buffer"
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/channel"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+)
+
+const (
+	nicID = 1
+	// queueSize bounds the packets the stack sent that the tunnel did not
+	// read yet.
+	queueSize = 1024
+)
+
+var errNoIPv6 = errors.New("tunnel has no IPv6 address")
+
+// Stack is a TCP/IP stack with the tunnel addresses of one settings profile.
+// As an io.ReadWriteCloser it stands in for the TUN device: Read returns the
+// IP packets the stack sends and Write delivers IP packets from the tunnel.
+type Stack struct {
+	stack    *stack.Stack
+	endpoint *channel.Endpoint
+	ipv6     bool
+	dns      []netip.AddrPort
+	next     atomic.Uint32
+	resolver *net.Resolver
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewStack builds a stack with the addresses, MTU and DNS resolvers of s.
+func NewStack(s settings.Settings) (*Stack, error) {
+	if !s.IPv4.IsValid() {
+		return nil, errors.New("tunnel has no IPv4 address")
+	}
+	mtu := s.MTU
+	if mtu <= 0 {
+		mtu = settings.DefaultEthernetMTU
+	}
+	st := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol},
+		HandleLocal:        true,
+	})
+	sack := tcpip.TCPSACKEnabled(true)
+	st.SetTransportProtocolOption(tcp.ProtocolNumber, &sack)
+
+	endpoint := channel.New(queueSize, uint32(mtu), "")
+	if err := st.CreateNIC(nicID, endpoint); err != nil {
+		st.Close()
+		return nil, fmt.Errorf("failed to create userspace NIC: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u := &Stack{stack: st, endpoint: endpoint, ctx: ctx, cancel: cancel}
+	if err := u.addAddress(s.IPv4, header.IPv4EmptySubnet); err != nil {
+		_ = u.Close()
+		return nil, err
+	}
+	if s.IPv6.IsValid() {
+		if err := u.addAddress(s.IPv6, header.IPv6EmptySubnet); err != nil {
+			_ = u.Close()
+			return nil, err
+		}
+		u.ipv6 = true
+	}
+
+	resolvers := s.DNSv4Resolvers()
+	if u.ipv6 {
+		resolvers = append(resolvers, s.DNSv6Resolvers()...)
+	}
+	for _, raw := range resolvers {
+		if addr, err := netip.ParseAddr(raw); err == nil {
+			u.dns = append(u.dns, netip.AddrPortFrom(addr.Unmap(), 53))
+		}
+	}
+	u.resolver = &net.Resolver{PreferGo: true, Dial: u.dialDNS}
+	return u, nil
+}
+
+func (u *Stack) addAddress(addr netip.Addr, subnet tcpip.Subnet) error {
+	protocol := tcpip.ProtocolAddress{
+		Protocol:          protocolNumber(addr),
+		AddressWithPrefix: tcpip.AddrFromSlice(addr.Unmap().AsSlice()).WithPrefix(),
+	}
+	if err := u.stack.AddProtocolAddress(nicID, protocol, stack.AddressProperties{}); err != nil {
+		return fmt.Errorf("failed to assign %s to the userspace NIC: %s", addr, err)
+	}
+	u.stack.AddRoute(tcpip.Route{Destination: subnet, NIC: nicID})
+	return nil
+}
+
+// Read blocks until the stack sends a packet.
+func (u *Stack) Read(p []byte) (int, error) {
+	pkt := u.endpoint.ReadContext(u.ctx)
+	if pkt == nil {
+		return 0, io.EOF
+	}
+	defer pkt.DecRef()
+	view := pkt.ToView()
+	defer view.Release()
+	return view.Read(p)
+}
+
+// Write delivers one IP packet to the stack. Packets that are not IP are
+// dropped.
+func (u *Stack) Write(p []byte) (int, error) {
+	if u.ctx.Err() != nil {
+		return 0, io.ErrClosedPipe
+	}
+	var protocol tcpip.NetworkProtocolNumber
+	switch header.IPVersion(p) {
+	case header.IPv4Version:
+		protocol = ipv4.ProtocolNumber
+	case header.IPv6Version:
+		protocol = ipv6.ProtocolNumber
+	default:
+		return len(p), nil
+	}
+	pkt := stack.NewPacketBuffer(stack.PacketBufferOptions{Payload: buffer.MakeWithData(bytes.Clone(p))})
+	u.endpoint.InjectInbound(protocol, pkt)
+	pkt.DecRef()
+	return len(p), nil
+}
+
+// Close resets every connection of the stack.
+func (u *Stack) Close() error {
+	u.cancel()
+	u.endpoint.Close()
+	u.stack.Close()
+	return nil
+}
+
+// DialContext opens a TCP or UDP connection through the tunnel. Host names
+// are resolved with the tunnel DNS resolvers.
+func (u *Stack) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, rawPort, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(rawPort, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port %q", rawPort)
+	}
+	addrs, err := u.LookupNetIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, addr := range addrs {
+		conn, err := u.dial(ctx, network, netip.AddrPortFrom(addr, uint16(port)))
+		if err == nil {
+			return conn, nil
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (u *Stack) dial(ctx context.Context, network string, target netip.AddrPort) (net.Conn, error) {
+	if target.Addr().Is6() && !u.ipv6 {
+		return nil, errNoIPv6
+	}
+	full := tcpip.FullAddress{NIC: nicID, Addr: tcpip.AddrFromSlice(target.Addr().AsSlice()), Port: target.Port()}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return gonet.DialContextTCP(ctx, u.stack, full, protocolNumber(target.Addr()))
+	case "udp", "udp4", "udp6":
+		return gonet.DialUDP(u.stack, nil, &full, protocolNumber(target.Addr()))
+	default:
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+}
+
+// ListenPacket opens an unconnected UDP socket of the given family, "udp4"
+// or "udp6", that sends through the tunnel.
+func (u *Stack) ListenPacket(network string) (net.PacketConn, error) {
+	protocol := ipv4.ProtocolNumber
+	if network == "udp6" {
+		if !u.ipv6 {
+			return nil, errNoIPv6
+		}
+		protocol = ipv6.ProtocolNumber
+	}
+	return gonet.DialUDP(u.stack, &tcpip.FullAddress{NIC: nicID}, nil, protocol)
+}
+
+// LookupNetIP resolves host with the tunnel DNS resolvers. Addresses the
+// tunnel cannot reach are left out.
+func (u *Stack) LookupNetIP(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr.Unmap()}, nil
+	}
+	network := "ip4"
+	if u.ipv6 {
+		network = "ip"
+	}
+	addrs, err := u.resolver.LookupNetIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+	for i := range addrs {
+		addrs[i] = addrs[i].Unmap()
+	}
+	return addrs, nil
+}
+
+// dialDNS sends resolver queries to the tunnel resolvers in turn, whatever
+// the system resolver configuration names.
+func (u *Stack) dialDNS(ctx context.Context, network, _ string) (net.Conn, error) {
+	if len(u.dns) == 0 {
+		return nil, errors.New("no tunnel DNS resolvers")
+	}
+	server := u.dns[int(u.next.Add(1)-1)%len(u.dns)]
+	return u.dial(ctx, network, server)
+}
+
+func protocolNumber(addr netip.Addr) tcpip.NetworkProtocolNumber {
+	if addr.Unmap().Is4() {
+		return ipv4.ProtocolNumber
+	}
+	return ipv6.ProtocolNumber
+}
@@ -0,0 +1,122 @@
+package userspace
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/netip"
+	"testing"
+	"time"
+
+	"tungo/internal/config/settings"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/adapters/gonet"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+)
+
+func newTestStack(t *testing.T, addr string) *Stack {
+	t.Helper()
+	st, err := NewStack(settings.Settings{Addressing: settings.Addressing{IPv4: netip.MustParseAddr(addr)}, MTU: 1400})
+	if err != nil {
+		t.Fatalf("NewStack() error = %v", err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+// link carries the packets one stack sends to the other, as the tunnel
+// would.
+func link(from, to *Stack) {
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, err := from.Read(buf)
+			if err != nil {
+				return
+			}
+			_, _ = to.Write(buf[:n])
+		}
+	}()
+}
+
+func TestStackDialsThroughPeer(t *testing.T) {
+	client := newTestStack(t, "10.0.0.2")
+	server := newTestStack(t, "10.0.0.1")
+	link(client, server)
+	link(server, client)
+
+	listener, err := gonet.ListenTCP(server.stack, tcpip.FullAddress{NIC: nicID, Addr: tcpip.AddrFrom4([4]byte{10, 0, 0, 1}), Port: 80}, ipv4.ProtocolNumber)
+	if err != nil {
+		t.Fatalf("ListenTCP() error = %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := client.DialContext(ctx, "tcp", "10.0.0.1:80")
+	if err != nil {
+		t.Fatalf("DialContext() error = %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	message := make([]byte, 64*1024)
+	for i := range message {
+		message[i] = byte(i)
+	}
+	go func() { _, _ = conn.Write(message) }()
+	echoed := make([]byte, len(message))
+	if _, err := io.ReadFull(conn, echoed); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(echoed) != string(message) {
+		t.Fatal("echoed bytes differ")
+	}
+}
+
+func TestStackRejectsWithoutIPv6(t *testing.T) {
+	st := newTestStack(t, "10.0.0.2")
+	if _, err := st.DialContext(context.Background(), "tcp", "[2001:db8::1]:80"); !errors.Is(err, errNoIPv6) {
+		t.Fatalf("DialContext() error = %v, want %v", err, errNoIPv6)
+	}
+	if _, err := st.ListenPacket("udp6"); !errors.Is(err, errNoIPv6) {
+		t.Fatalf("ListenPacket() error = %v, want %v", err, errNoIPv6)
+	}
+	if n, err := st.Write([]byte{0x00, 0x01}); err != nil || n != 2 {
+		t.Fatalf("Write(non-IP) = %d, %v; want the packet dropped", n, err)
+	}
+}
+
+func TestManagerFollowsCurrentStack(t *testing.T) {
+	m := NewManager()
+	if _, err := m.DialContext(context.Background(), "tcp", "10.0.0.1:80"); !errors.Is(err, errTunnelDown) {
+		t.Fatalf("DialContext() before a device = %v, want %v", err, errTunnelDown)
+	}
+
+	m.UseSettings(settings.Settings{Addressing: settings.Addressing{IPv4: netip.MustParseAddr("10.0.0.2")}})
+	device, err := m.CreateDevice()
+	if err != nil {
+		t.Fatalf("CreateDevice() error = %v", err)
+	}
+	addrs, err := m.LookupNetIP(context.Background(), "10.0.0.1")
+	if err != nil || len(addrs) != 1 || addrs[0] != netip.MustParseAddr("10.0.0.1") {
+		t.Fatalf("LookupNetIP() = %v, %v", addrs, err)
+	}
+
+	_ = device.Close()
+	if _, err := m.ListenPacket("udp4"); !errors.Is(err, errTunnelDown) {
+		t.Fatalf("ListenPacket() after close = %v, want %v", err, errTunnelDown)
+	}
+	if _, err := device.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("Read() after close = %v, want EOF", err)
+	}
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"tungo/internal/check"
+)
+
+// Check prints report and fails when it has errors, so that scripts can
+// gate a start on it.
+func Check(out io.Writer, report check.Report, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else if err := writeReport(out, report); err != nil {
+		return err
+	}
+	if n := report.Errors(); n > 0 {
+		return fmt.Errorf("check found %d error(s) in %s", n, report.Path)
+	}
+	return nil
+}
+
+func writeReport(out io.Writer, report check.Report) error {
+	_, _ = fmt.Fprintf(out, "Configuration: %s\n\n", report.Path)
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "STATUS\tCHECK\tMESSAGE")
+	for _, f := range report.Findings {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\n", f.Status, f.Check, f.Message)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	if len(report.Plan) == 0 {
+		return nil
+	}
+	_, _ = fmt.Fprintln(out, "\nStarting would apply these changes; none were made:")
+	for i, step := range report.Plan {
+		_, _ = fmt.Fprintf(out, "  %d. %s\n", i+1, step)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"tungo/internal/check"
+)
+
+func TestCheck_Table(t *testing.T) {
+	report := check.Report{
+		Path: "/etc/tungo/server_configuration.json",
+		Findings: []check.Finding{
+			{Status: check.StatusOK, Check: "keys", Message: "server key pair matches"},
+			{Status: check.StatusWarning, Check: "subnets", Message: "overlaps route"},
+		},
+		Plan: []string{"create TUN tun0", "clamp TCP MSS on tun0"},
+	}
+	var out bytes.Buffer
+	if err := Check(&out, report, false); err != nil {
+		t.Fatalf("Check error: %v", err)
+	}
+	got := out.String()
+	for _, want := range []string{"Configuration: /etc/tungo/server_configuration.json", "ok       keys", "warning  subnets", "none were made", "  2. clamp TCP MSS on tun0"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("output lacks %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCheck_ErrorsFailAfterPrinting(t *testing.T) {
+	report := check.Report{
+		Path:     "client.json",
+		Findings: []check.Finding{{Status: check.StatusError, Check: "configuration", Message: "invalid"}},
+	}
+	var out bytes.Buffer
+	err := Check(&out, report, true)
+	if err == nil || !strings.Contains(err.Error(), "1 error(s) in client.json") {
+		t.Fatalf("expected check error, got %v", err)
+	}
+	var decoded check.Report
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil || len(decoded.Findings) != 1 {
+		t.Fatalf("JSON output = %s, %v", out.String(), err)
+	}
+}
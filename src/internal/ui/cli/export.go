@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"tungo/internal/config"
+)
+
+type exportKind uint8
+
+const (
+	exportFile exportKind = iota
+	exportDirectory
+	exportArchive
+)
+
+// Export writes generated client configurations to a file, a directory or a
+// .zip archive. Existing files are never overwritten: they may hold the
+// private key of another peer.
+type Export struct {
+	path string
+	kind exportKind
+}
+
+// NewExport checks that path can take count configurations, so that a
+// batch fails before its peers are added. A path ending in .zip is an
+// archive, an existing directory or a path ending in a separator is a
+// directory, and any other path is a file for a single configuration.
+func NewExport(path string, count int) (*Export, error) {
+	e := &Export{path: path}
+	info, statErr := os.Stat(path)
+	isDir := statErr == nil && info.IsDir() ||
+		strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator))
+	switch {
+	case strings.EqualFold(filepath.Ext(path), ".zip"):
+		e.kind = exportArchive
+	case isDir:
+		e.kind = exportDirectory
+		if err := os.MkdirAll(path, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %w", err)
+		}
+		return e, nil
+	default:
+		if count > 1 {
+			return nil, fmt.Errorf("%s takes one configuration; write %d to a directory or a .zip archive", path, count)
+		}
+	}
+	if statErr == nil {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("invalid output path: %w", err)
+	}
+	return e, nil
+}
+
+// Write saves generated and prints where each configuration went to out.
+func (e *Export) Write(out io.Writer, generated []config.GeneratedClientConfiguration) error {
+	files := make([]string, len(generated))
+	var err error
+	switch e.kind {
+	case exportArchive:
+		err = e.writeArchive(generated, files)
+	case exportDirectory:
+		for i, g := range generated {
+			files[i] = filepath.Join(e.path, configurationFileName(g))
+			if err = writeNewFile(files[i], []byte(g.JSON)); err != nil {
+				break
+			}
+		}
+	default:
+		if len(generated) > 0 {
+			files[0] = e.path
+			err = writeNewFile(e.path, []byte(generated[0].JSON))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := WriteGenerated(out, generated, files); err != nil {
+		return err
+	}
+	if e.kind == exportArchive {
+		_, err = fmt.Fprintf(out, "Archive: %s\n", e.path)
+	}
+	return err
+}
+
+// writeArchive stores <name>.json and <name>.uri, the shareable tungo:// URI,
+// for every configuration.
+func (e *Export) writeArchive(generated []config.GeneratedClientConfiguration, files []string) (err error) {
+	f, err := os.OpenFile(e.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	archive := zip.NewWriter(f)
+	for i, g := range generated {
+		base := strings.TrimSuffix(configurationFileName(g), ".json")
+		files[i] = base + ".json"
+		entries := []struct{ name, data string }{
+			{base + ".json", g.JSON},
+			{base + ".uri", g.URI + "\n"},
+		}
+		for _, entry := range entries {
+			header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+			header.SetMode(0600)
+			w, err := archive.CreateHeader(header)
+			if err != nil {
+				return fmt.Errorf("failed to write archive: %w", err)
+			}
+			if _, err := io.WriteString(w, entry.data); err != nil {
+				return fmt.Errorf("failed to write archive: %w", err)
+			}
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// WriteGenerated prints the ID, name and file of each configuration.
+func WriteGenerated(out io.Writer, generated []config.GeneratedClientConfiguration, files []string) error {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "ID\tNAME\tFILE")
+	for i, g := range generated {
+		_, _ = fmt.Fprintf(table, "%d\t%s\t%s\n", g.ClientID, g.Name, files[i])
+	}
+	return table.Flush()
+}
+
+// PrintConfigurations writes the configuration JSON to out: one object, or
+// an array for a batch.
+func PrintConfigurations(out io.Writer, generated []config.GeneratedClientConfiguration) error {
+	if len(generated) == 1 {
+		_, err := fmt.Fprintln(out, generated[0].JSON)
+		return err
+	}
+	batch := make([]json.RawMessage, 0, len(generated))
+	for _, g := range generated {
+		batch = append(batch, json.RawMessage(g.JSON))
+	}
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// configurationFileName names the file of a configuration after its peer,
+// replacing characters that do not belong in a file name.
+func configurationFileName(g config.GeneratedClientConfiguration) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, g.Name)
+	if strings.Trim(name, "._") == "" {
+		name = fmt.Sprintf("client-%d", g.ClientID)
+	}
+	return name + ".json"
+}
+
+func writeNewFile(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%s already exists", path)
+		}
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tungo/internal/config"
+)
+
+func generatedBatch() []config.GeneratedClientConfiguration {
+	return []config.GeneratedClientConfiguration{
+		{ClientID: 4, Name: "team-1", JSON: `{"ClientID": 4}`, URI: "tungo://1/a"},
+		{ClientID: 5, Name: "team/2", JSON: `{"ClientID": 5}`, URI: "tungo://1/b"},
+	}
+}
+
+func TestExportDirectory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "onboarding") + string(filepath.Separator)
+	export, err := NewExport(dir, 2)
+	if err != nil {
+		t.Fatalf("NewExport() error = %v", err)
+	}
+	var out bytes.Buffer
+	if err := export.Write(&out, generatedBatch()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	for name, want := range map[string]string{"team-1.json": `{"ClientID": 4}`, "team_2.json": `{"ClientID": 5}`} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Fatalf("%s = %q, %v; want %q", name, data, err, want)
+		}
+	}
+	if !strings.Contains(out.String(), "team_2.json") {
+		t.Fatalf("summary lacks the files:\n%s", out.String())
+	}
+	if err := export.Write(io.Discard, generatedBatch()); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected existing files to be kept, got %v", err)
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.zip")
+	export, err := NewExport(path, 2)
+	if err != nil {
+		t.Fatalf("NewExport() error = %v", err)
+	}
+	if err := export.Write(io.Discard, generatedBatch()); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	defer func() { _ = archive.Close() }()
+	var names []string
+	for _, f := range archive.File {
+		names = append(names, f.Name)
+	}
+	if got, want := strings.Join(names, " "), "team-1.json team-1.uri team_2.json team_2.uri"; got != want {
+		t.Fatalf("archive entries = %q, want %q", got, want)
+	}
+	if _, err := NewExport(path, 2); err == nil {
+		t.Fatal("expected an existing archive to be kept")
+	}
+}
+
+func TestExportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alice.json")
+	if _, err := NewExport(path, 3); err == nil || !strings.Contains(err.Error(), "takes one configuration") {
+		t.Fatalf("expected a batch to be rejected, got %v", err)
+	}
+	export, err := NewExport(path, 1)
+	if err != nil {
+		t.Fatalf("NewExport() error = %v", err)
+	}
+	if err := export.Write(io.Discard, generatedBatch()[:1]); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != `{"ClientID": 4}` {
+		t.Fatalf("file = %q, %v", data, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("file mode = %v, %v; want 0600", info.Mode(), err)
+	}
+}
+
+func TestPrintConfigurations(t *testing.T) {
+	var out bytes.Buffer
+	if err := PrintConfigurations(&out, generatedBatch()); err != nil {
+		t.Fatalf("PrintConfigurations() error = %v", err)
+	}
+	var batch []map[string]int
+	if err := json.Unmarshal(out.Bytes(), &batch); err != nil || len(batch) != 2 || batch[1]["ClientID"] != 5 {
+		t.Fatalf("batch = %v, %v", batch, err)
+	}
+
+	out.Reset()
+	if err := PrintConfigurations(&out, generatedBatch()[:1]); err != nil || strings.TrimSpace(out.String()) != `{"ClientID": 4}` {
+		t.Fatalf("single output = %q, %v", out.String(), err)
+	}
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+
+	"tungo/internal/protocol/keys"
+)
+
+// maxKeyInput bounds what Pubkey reads: a base64 key and some whitespace.
+const maxKeyInput = 1024
+
+// Keygen prints a new X25519 private key.
+func Keygen(out io.Writer) error {
+	_, private, err := (&keys.DefaultKeyDeriver{}).GenerateX25519KeyPair()
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+	_, err = fmt.Fprintln(out, keys.EncodeKey(private[:]))
+	return err
+}
+
+// Pubkey prints the public key of the private key read from in.
+func Pubkey(in io.Reader, out io.Writer) error {
+	data, err := io.ReadAll(io.LimitReader(in, maxKeyInput))
+	if err != nil {
+		return fmt.Errorf("failed to read private key: %w", err)
+	}
+	private, err := keys.ParseKey(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+	public, err := keys.PublicKey(private)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, keys.EncodeKey(public))
+	return err
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"tungo/internal/protocol/keys"
+)
+
+func TestKeygenPubkey(t *testing.T) {
+	var private, public bytes.Buffer
+	if err := Keygen(&private); err != nil {
+		t.Fatalf("Keygen() error = %v", err)
+	}
+	if err := Pubkey(strings.NewReader(private.String()), &public); err != nil {
+		t.Fatalf("Pubkey() error = %v", err)
+	}
+	privateKey, err := keys.ParseKey(private.String())
+	if err != nil {
+		t.Fatalf("Keygen() printed %q: %v", private.String(), err)
+	}
+	want, _ := keys.PublicKey(privateKey)
+	if strings.TrimSpace(public.String()) != keys.EncodeKey(want) {
+		t.Fatalf("Pubkey() = %q, want %q", public.String(), keys.EncodeKey(want))
+	}
+	if err := Pubkey(strings.NewReader("not a key"), &public); err == nil {
+		t.Fatal("expected error for an invalid private key")
+	}
+}
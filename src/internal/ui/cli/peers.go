@@ -0,0 +1,244 @@
+// Package cli prints the reports of non-interactive commands.
+package cli
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tungo/internal/config"
+	serverconfig "tungo/internal/config/server"
+	"tungo/internal/trafficstats"
+)
+
+// PeerControl is the part of the server configuration control that manages
+// peers. Changes land in the configuration file, where the running server's
+// watcher picks them up and closes the sessions of revoked peers.
+type PeerControl interface {
+	ListPeers() ([]config.ServerPeer, error)
+	SetPeerEnabled(clientID int, enabled bool) error
+	RenamePeer(clientID int, name string) error
+	RemovePeer(clientID int) error
+}
+
+// Peers runs the "s peers" commands.
+type Peers struct {
+	control PeerControl
+	out     io.Writer
+	json    bool
+}
+
+// NewPeers prints to out, as JSON when asJSON is set and as a table
+// otherwise.
+func NewPeers(control PeerControl, out io.Writer, asJSON bool) *Peers {
+	return &Peers{control: control, out: out, json: asJSON}
+}
+
+// peerView is the JSON form of a peer.
+type peerView struct {
+	ClientID   int            `json:"ClientID"`
+	Name       string         `json:"Name"`
+	Enabled    bool           `json:"Enabled"`
+	PublicKey  []byte         `json:"PublicKey"`
+	AllowedIPs []netip.Prefix `json:"AllowedIPs"`
+	ExpiresAt  time.Time      `json:"ExpiresAt,omitzero"`
+	// PresharedKey tells whether the peer has one, never the key.
+	PresharedKey bool                     `json:"PresharedKey"`
+	Traffic      serverconfig.PeerTraffic `json:"Traffic"`
+}
+
+func newPeerView(peer config.ServerPeer) peerView {
+	allowedIPs := peer.AllowedIPs
+	if allowedIPs == nil {
+		allowedIPs = []netip.Prefix{}
+	}
+	return peerView{
+		ClientID:     peer.ClientID,
+		Name:         peer.Name,
+		Enabled:      peer.Enabled,
+		PublicKey:    peer.PublicKey,
+		AllowedIPs:   allowedIPs,
+		ExpiresAt:    peer.ExpiresAt,
+		PresharedKey: len(peer.PresharedKey) > 0,
+		Traffic:      peer.Traffic,
+	}
+}
+
+func (p *Peers) List() error {
+	peers, err := p.control.ListPeers()
+	if err != nil {
+		return err
+	}
+	if p.json {
+		views := make([]peerView, 0, len(peers))
+		for _, peer := range peers {
+			views = append(views, newPeerView(peer))
+		}
+		return p.writeJSON(views)
+	}
+	table := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "ID\tNAME\tSTATUS\tRX\tTX")
+	for _, peer := range peers {
+		_, _ = fmt.Fprintf(table, "%d\t%s\t%s\t%s\t%s\n",
+			peer.ClientID, displayName(peer), peerStatus(peer),
+			trafficstats.FormatTotal(peer.Traffic.RXBytes), trafficstats.FormatTotal(peer.Traffic.TXBytes))
+	}
+	return table.Flush()
+}
+
+func (p *Peers) Show(id string) error {
+	peer, err := p.find(id)
+	if err != nil {
+		return err
+	}
+	if p.json {
+		return p.writeJSON(newPeerView(peer))
+	}
+	allowedIPs := "-"
+	if len(peer.AllowedIPs) > 0 {
+		prefixes := make([]string, 0, len(peer.AllowedIPs))
+		for _, prefix := range peer.AllowedIPs {
+			prefixes = append(prefixes, prefix.String())
+		}
+		allowedIPs = strings.Join(prefixes, ", ")
+	}
+	table := tabwriter.NewWriter(p.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(table, "ID:\t%d\n", peer.ClientID)
+	_, _ = fmt.Fprintf(table, "Name:\t%s\n", displayName(peer))
+	_, _ = fmt.Fprintf(table, "Status:\t%s\n", peerStatus(peer))
+	if !peer.ExpiresAt.IsZero() {
+		_, _ = fmt.Fprintf(table, "Expires:\t%s\n", peer.ExpiresAt.Local().Format(time.DateTime))
+	}
+	_, _ = fmt.Fprintf(table, "Public key:\t%s\n", base64.StdEncoding.EncodeToString(peer.PublicKey))
+	_, _ = fmt.Fprintf(table, "Allowed IPs:\t%s\n", allowedIPs)
+	if len(peer.PresharedKey) > 0 {
+		_, _ = fmt.Fprintln(table, "Pre-shared key:\tyes")
+	}
+	_, _ = fmt.Fprintf(table, "Received:\t%s (%d packets)\n", trafficstats.FormatTotal(peer.Traffic.RXBytes), peer.Traffic.RXPackets)
+	_, _ = fmt.Fprintf(table, "Sent:\t%s (%d packets)\n", trafficstats.FormatTotal(peer.Traffic.TXBytes), peer.Traffic.TXPackets)
+	return table.Flush()
+}
+
+// Enable and Disable succeed when the peer is already in the wanted state,
+// so that provisioning can run them repeatedly.
+func (p *Peers) Enable(id string) error {
+	return p.setEnabled(id, true)
+}
+
+func (p *Peers) Disable(id string) error {
+	return p.setEnabled(id, false)
+}
+
+func (p *Peers) setEnabled(id string, enabled bool) error {
+	clientID, err := parseClientID(id)
+	if err != nil {
+		return err
+	}
+	if err := p.control.SetPeerEnabled(clientID, enabled); err != nil {
+		return err
+	}
+	return p.report(clientID, "Peer #%d %s.", clientID, status(enabled))
+}
+
+func (p *Peers) Rename(id, name string) error {
+	clientID, err := parseClientID(id)
+	if err != nil {
+		return err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("peer name cannot be empty")
+	}
+	if err := p.control.RenamePeer(clientID, name); err != nil {
+		return err
+	}
+	return p.report(clientID, "Peer #%d renamed to %s.", clientID, name)
+}
+
+func (p *Peers) Remove(id string) error {
+	peer, err := p.find(id)
+	if err != nil {
+		return err
+	}
+	if err := p.control.RemovePeer(peer.ClientID); err != nil {
+		return err
+	}
+	if p.json {
+		return p.writeJSON(newPeerView(peer))
+	}
+	_, err = fmt.Fprintf(p.out, "Peer #%d removed.\n", peer.ClientID)
+	return err
+}
+
+// report prints the peer after a change: as JSON, or as the message.
+func (p *Peers) report(clientID int, format string, args ...any) error {
+	if !p.json {
+		_, err := fmt.Fprintf(p.out, format+"\n", args...)
+		return err
+	}
+	peer, err := p.find(strconv.Itoa(clientID))
+	if err != nil {
+		return err
+	}
+	return p.writeJSON(newPeerView(peer))
+}
+
+func (p *Peers) find(id string) (config.ServerPeer, error) {
+	clientID, err := parseClientID(id)
+	if err != nil {
+		return config.ServerPeer{}, err
+	}
+	peers, err := p.control.ListPeers()
+	if err != nil {
+		return config.ServerPeer{}, err
+	}
+	for _, peer := range peers {
+		if peer.ClientID == clientID {
+			return peer, nil
+		}
+	}
+	return config.ServerPeer{}, fmt.Errorf("peer #%d not found", clientID)
+}
+
+func (p *Peers) writeJSON(v any) error {
+	encoder := json.NewEncoder(p.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func parseClientID(id string) (int, error) {
+	clientID, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(id), "#"))
+	if err != nil || clientID <= 0 {
+		return 0, fmt.Errorf("invalid peer id %q: use the numeric client ID", id)
+	}
+	return clientID, nil
+}
+
+func displayName(peer config.ServerPeer) string {
+	if name := strings.TrimSpace(peer.Name); name != "" {
+		return name
+	}
+	return fmt.Sprintf("client-%d", peer.ClientID)
+}
+
+// peerStatus reports an enabled peer whose expiry has passed as expired.
+func peerStatus(peer config.ServerPeer) string {
+	if peer.Enabled && peer.Expired(time.Now()) {
+		return "expired"
+	}
+	return status(peer.Enabled)
+}
+
+func status(enabled bool) string {
+	if enabled {
+		return "enabled"
+	}
+	return "disabled"
+}
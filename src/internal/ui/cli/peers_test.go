@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"tungo/internal/config"
+	serverconfig "tungo/internal/config/server"
+)
+
+type fakePeerControl struct {
+	peers []config.ServerPeer
+	calls []string
+}
+
+func (f *fakePeerControl) ListPeers() ([]config.ServerPeer, error) {
+	return append([]config.ServerPeer(nil), f.peers...), nil
+}
+
+func (f *fakePeerControl) index(clientID int) (int, error) {
+	for i := range f.peers {
+		if f.peers[i].ClientID == clientID {
+			return i, nil
+		}
+	}
+	return 0, errors.New("not found")
+}
+
+func (f *fakePeerControl) SetPeerEnabled(clientID int, enabled bool) error {
+	f.calls = append(f.calls, "enabled")
+	i, err := f.index(clientID)
+	if err == nil {
+		f.peers[i].Enabled = enabled
+	}
+	return err
+}
+
+func (f *fakePeerControl) RenamePeer(clientID int, name string) error {
+	f.calls = append(f.calls, "rename")
+	i, err := f.index(clientID)
+	if err == nil {
+		f.peers[i].Name = name
+	}
+	return err
+}
+
+func (f *fakePeerControl) RemovePeer(clientID int) error {
+	f.calls = append(f.calls, "remove")
+	i, err := f.index(clientID)
+	if err == nil {
+		f.peers = append(f.peers[:i], f.peers[i+1:]...)
+	}
+	return err
+}
+
+func newFakePeerControl() *fakePeerControl {
+	return &fakePeerControl{peers: []config.ServerPeer{
+		{
+			ClientID:     1,
+			Name:         "alice",
+			Enabled:      true,
+			PublicKey:    bytes.Repeat([]byte{1}, 32),
+			AllowedIPs:   []netip.Prefix{netip.MustParsePrefix("192.168.10.0/24")},
+			Traffic:      serverconfig.PeerTraffic{RXBytes: 2048, RXPackets: 3, TXBytes: 1024, TXPackets: 2},
+			PresharedKey: bytes.Repeat([]byte{9}, 32),
+		},
+		{ClientID: 2, PublicKey: bytes.Repeat([]byte{2}, 32)},
+	}}
+}
+
+func TestPeersListTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewPeers(newFakePeerControl(), &out, false).List(); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") ||
+		!strings.Contains(lines[1], "alice") || !strings.Contains(lines[1], "enabled") ||
+		!strings.Contains(lines[2], "client-2") || !strings.Contains(lines[2], "disabled") {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+}
+
+func TestPeersListJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewPeers(newFakePeerControl(), &out, true).List(); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var views []peerView
+	if err := json.Unmarshal(out.Bytes(), &views); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if len(views) != 2 || views[0].Name != "alice" || views[0].Traffic.RXBytes != 2048 ||
+		len(views[0].AllowedIPs) != 1 || views[1].AllowedIPs == nil {
+		t.Fatalf("unexpected peers %+v", views)
+	}
+	if !views[0].PresharedKey || views[1].PresharedKey || strings.Contains(out.String(), "CQkJ") {
+		t.Fatalf("pre-shared key must show as a flag only, got %s", out.String())
+	}
+	if !strings.Contains(out.String(), `"AllowedIPs": []`) {
+		t.Fatalf("expected an empty AllowedIPs list, got %s", out.String())
+	}
+}
+
+func TestPeersShow(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewPeers(newFakePeerControl(), &out, false).Show("#1"); err != nil {
+		t.Fatalf("Show() error = %v", err)
+	}
+	for _, want := range []string{"alice", "192.168.10.0/24", "AQEBAQ", "3 packets", "Pre-shared key:  yes"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("Show() output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if err := NewPeers(newFakePeerControl(), &out, false).Show("9"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestPeersShowExpired(t *testing.T) {
+	control := newFakePeerControl()
+	control.peers[0].ExpiresAt = time.Now().Add(-time.Hour)
+	var out bytes.Buffer
+	if err := NewPeers(control, &out, false).Show("1"); err != nil {
+		t.Fatalf("Show() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "expired") || !strings.Contains(out.String(), "Expires:") {
+		t.Fatalf("Show() output lacks the expiry:\n%s", out.String())
+	}
+}
+
+func TestPeersChanges(t *testing.T) {
+	control := newFakePeerControl()
+	var out bytes.Buffer
+	peers := NewPeers(control, &out, false)
+
+	if err := peers.Disable("1"); err != nil || control.peers[0].Enabled {
+		t.Fatalf("Disable() error = %v, peer %+v", err, control.peers[0])
+	}
+	// Repeating a change is not an error.
+	if err := peers.Disable("1"); err != nil {
+		t.Fatalf("second Disable() error = %v", err)
+	}
+	if err := peers.Enable("2"); err != nil || !control.peers[1].Enabled {
+		t.Fatalf("Enable() error = %v, peer %+v", err, control.peers[1])
+	}
+	if err := peers.Rename("2", " bob "); err != nil || control.peers[1].Name != "bob" {
+		t.Fatalf("Rename() error = %v, peer %+v", err, control.peers[1])
+	}
+	if err := peers.Remove("1"); err != nil || len(control.peers) != 1 {
+		t.Fatalf("Remove() error = %v, peers %+v", err, control.peers)
+	}
+	want := "Peer #1 disabled.\nPeer #1 disabled.\nPeer #2 enabled.\nPeer #2 renamed to bob.\nPeer #1 removed.\n"
+	if out.String() != want {
+		t.Fatalf("output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := NewPeers(control, &out, true).Disable("2"); err != nil {
+		t.Fatalf("Disable() error = %v", err)
+	}
+	var view peerView
+	if err := json.Unmarshal(out.Bytes(), &view); err != nil || view.ClientID != 2 || view.Enabled {
+		t.Fatalf("unexpected JSON report %q: %v", out.String(), err)
+	}
+}
+
+func TestPeersRejectBadInput(t *testing.T) {
+	control := newFakePeerControl()
+	peers := NewPeers(control, &bytes.Buffer{}, false)
+	for _, id := range []string{"", "abc", "0", "-1"} {
+		if err := peers.Enable(id); err == nil {
+			t.Fatalf("expected error for id %q", id)
+		}
+	}
+	if err := peers.Rename("1", " "); err == nil {
+		t.Fatal("expected error for an empty name")
+	}
+	if err := peers.Remove("9"); err == nil {
+		t.Fatal("expected error for an unknown peer")
+	}
+	if len(control.calls) != 0 {
+		t.Fatalf("unexpected control calls %v", control.calls)
+	}
+}
@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tungo/internal/server/admin"
+	"tungo/internal/trafficstats"
+)
+
+// SessionCall sends one request to the running server's admin socket, as
+// admin.Call does.
+type SessionCall func(ctx context.Context, request admin.Request) (admin.Response, error)
+
+// Sessions runs the "s admin" commands against a running server.
+type Sessions struct {
+	call SessionCall
+	out  io.Writer
+	json bool
+}
+
+// NewSessions prints to out, as JSON when asJSON is set and as a table
+// otherwise.
+func NewSessions(call SessionCall, out io.Writer, asJSON bool) *Sessions {
+	return &Sessions{call: call, out: out, json: asJSON}
+}
+
+func (s *Sessions) List(ctx context.Context) error {
+	response, err := s.call(ctx, admin.Request{Command: admin.CommandList})
+	if err != nil {
+		return err
+	}
+	if s.json {
+		sessions := response.Sessions
+		if sessions == nil {
+			sessions = []admin.Session{}
+		}
+		return s.writeJSON(sessions)
+	}
+	table := tabwriter.NewWriter(s.out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "NAME\tPROTOCOL\tINTERNAL IP\tEXTERNAL ADDRESS\tEPOCH\tRX\tTX\tLAST ACTIVITY")
+	for _, session := range response.Sessions {
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			sessionName(session), session.Protocol, session.InternalIP, session.ExternalAddr, session.SendEpoch,
+			trafficstats.FormatTotal(session.Traffic.RXBytes), trafficstats.FormatTotal(session.Traffic.TXBytes),
+			lastActivity(session.LastActivity))
+	}
+	return table.Flush()
+}
+
+// Terminate closes the sessions of target, a tunnel address or a base64
+// public key. Closing no session is not an error: the client may have left.
+func (s *Sessions) Terminate(ctx context.Context, target string) error {
+	request, err := terminateRequest(target)
+	if err != nil {
+		return err
+	}
+	response, err := s.call(ctx, request)
+	if err != nil {
+		return err
+	}
+	if s.json {
+		return s.writeJSON(struct {
+			Terminated int `json:"terminated"`
+		}{response.Terminated})
+	}
+	_, err = fmt.Fprintf(s.out, "Terminated %d session(s).\n", response.Terminated)
+	return err
+}
+
+func (s *Sessions) writeJSON(v any) error {
+	encoder := json.NewEncoder(s.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func terminateRequest(target string) (admin.Request, error) {
+	target = strings.TrimSpace(target)
+	if addr, err := netip.ParseAddr(target); err == nil {
+		return admin.Request{Command: admin.CommandTerminate, InternalIP: addr.String()}, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(target)
+	if err != nil || len(key) != 32 {
+		return admin.Request{}, fmt.Errorf("invalid session %q: use the tunnel address or the base64 public key", target)
+	}
+	return admin.Request{Command: admin.CommandTerminate, PublicKey: key}, nil
+}
+
+func sessionName(session admin.Session) string {
+	if name := strings.TrimSpace(session.Name); name != "" {
+		return name
+	}
+	return base64.StdEncoding.EncodeToString(session.PublicKey)
+}
+
+func lastActivity(at time.Time) string {
+	if at.IsZero() {
+		return "-"
+	}
+	return at.Local().Format(time.DateTime)
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"tungo/internal/server/admin"
+)
+
+// fakeAdmin answers admin requests the way a server with sessions does.
+type fakeAdmin struct {
+	sessions []admin.Session
+	requests []admin.Request
+}
+
+func (f *fakeAdmin) call(_ context.Context, request admin.Request) (admin.Response, error) {
+	f.requests = append(f.requests, request)
+	switch request.Command {
+	case admin.CommandList:
+		return admin.Response{Sessions: f.sessions}, nil
+	case admin.CommandTerminate:
+		return admin.Response{Terminated: 1}, nil
+	default:
+		return admin.Response{}, errors.New("unknown command")
+	}
+}
+
+func newFakeAdmin() *fakeAdmin {
+	return &fakeAdmin{sessions: []admin.Session{
+		{
+			Name:         "alice",
+			PublicKey:    bytes.Repeat([]byte{1}, 32),
+			InternalIP:   netip.MustParseAddr("10.0.0.2"),
+			ExternalAddr: netip.MustParseAddrPort("203.0.113.7:51820"),
+			LastActivity: time.Now(),
+			Protocol:     "udp",
+			SendEpoch:    3,
+			Traffic:      admin.Traffic{RXBytes: 2048, TXBytes: 1024},
+		},
+		{
+			PublicKey:  bytes.Repeat([]byte{2}, 32),
+			InternalIP: netip.MustParseAddr("10.0.0.3"),
+			Protocol:   "tcp",
+		},
+	}}
+}
+
+func TestSessionsListTable(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewSessions(newFakeAdmin().call, &out, false).List(context.Background()); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "NAME") ||
+		!strings.Contains(lines[1], "alice") || !strings.Contains(lines[1], "203.0.113.7:51820") ||
+		!strings.Contains(lines[2], base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))) {
+		t.Fatalf("unexpected table:\n%s", out.String())
+	}
+}
+
+func TestSessionsListJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := NewSessions(newFakeAdmin().call, &out, true).List(context.Background()); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	var sessions []admin.Session
+	if err := json.Unmarshal(out.Bytes(), &sessions); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if len(sessions) != 2 || sessions[0].Name != "alice" || sessions[0].SendEpoch != 3 {
+		t.Fatalf("unexpected sessions %+v", sessions)
+	}
+
+	out.Reset()
+	if err := NewSessions((&fakeAdmin{}).call, &out, true).List(context.Background()); err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Fatalf("expected an empty list, got %s", out.String())
+	}
+}
+
+func TestSessionsTerminate(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	fake := newFakeAdmin()
+	var out bytes.Buffer
+	sessions := NewSessions(fake.call, &out, false)
+	if err := sessions.Terminate(context.Background(), "10.0.0.2"); err != nil {
+		t.Fatalf("Terminate(ip) error = %v", err)
+	}
+	if err := sessions.Terminate(context.Background(), base64.StdEncoding.EncodeToString(key)); err != nil {
+		t.Fatalf("Terminate(key) error = %v", err)
+	}
+	if len(fake.requests) != 2 || fake.requests[0].InternalIP != "10.0.0.2" || !bytes.Equal(fake.requests[1].PublicKey, key) {
+		t.Fatalf("unexpected requests %+v", fake.requests)
+	}
+	if !strings.Contains(out.String(), "Terminated 1 session(s).") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	for _, target := range []string{"client-1", base64.StdEncoding.EncodeToString(key[:16])} {
+		if err := sessions.Terminate(context.Background(), target); err == nil {
+			t.Fatalf("Terminate(%q) succeeded", target)
+		}
+	}
+	if len(fake.requests) != 2 {
+		t.Fatalf("invalid targets reached the server: %+v", fake.requests)
+	}
+}
@@ -0,0 +1,19 @@
+// Package qr renders QR codes as terminal text.
+package qr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Render draws text as a QR code with half-block characters, two modules
+// per line. Dark modules are blank, so it scans on a dark terminal.
+func Render(text string) (string, error) {
+	code, err := qrcode.New(text, qrcode.Low)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return strings.TrimSuffix(code.ToSmallString(false), "\n"), nil
+}
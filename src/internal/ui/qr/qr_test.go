@@ -0,0 +1,31 @@
+package qr
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRender(t *testing.T) {
+	got, err := Render("tungo://1/payload")
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	lines := strings.Split(got, "\n")
+	width := utf8.RuneCountInString(lines[0])
+	// a version 1 code is 21 modules plus a 4 module quiet zone each side
+	if width < 29 || len(lines) != (width+1)/2 {
+		t.Fatalf("got %d lines of %d columns", len(lines), width)
+	}
+	for i, line := range lines {
+		if utf8.RuneCountInString(line) != width {
+			t.Fatalf("line %d has %d columns, want %d", i, utf8.RuneCountInString(line), width)
+		}
+	}
+}
+
+func TestRenderTooLong(t *testing.T) {
+	if _, err := Render(strings.Repeat("x", 4000)); err == nil {
+		t.Fatal("expected error for content beyond QR capacity")
+	}
+}
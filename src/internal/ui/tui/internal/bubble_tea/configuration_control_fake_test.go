@@ -1,6 +1,10 @@
 package bubble_tea
 
-import "tungo/internal/config"
+import (
+	"errors"
+
+	"tungo/internal/config"
+)
 
 type testConfigurationControl struct {
 	clientConfigs        []string
@@ -21,6 +25,8 @@ type testConfigurationControl struct {
 	removeErr            error
 	removeCalls          int
 	lastRemoved          int
+	shareURI             string
+	shareErr             error
 }
 
 func newTestConfigurationControl() *testConfigurationControl {
@@ -93,6 +99,18 @@ func (c *testConfigurationControl) GenerateClientConfiguration() (config.Generat
 	return config.GeneratedClientConfiguration{Path: c.generatePath}, nil
 }
 
+func (c *testConfigurationControl) GenerateClientConfigurations(config.GenerateOptions) ([]config.GeneratedClientConfiguration, error) {
+	generated, err := c.GenerateClientConfiguration()
+	if err != nil {
+		return nil, err
+	}
+	return []config.GeneratedClientConfiguration{generated}, nil
+}
+
+func (c *testConfigurationControl) ClientConfigurationURI(int) (string, error) {
+	return c.shareURI, c.shareErr
+}
+
 func (c *testConfigurationControl) ListPeers() ([]config.ServerPeer, error) {
 	if c.listPeersErr != nil {
 		return nil, c.listPeersErr
@@ -118,6 +136,16 @@ func (c *testConfigurationControl) SetPeerEnabled(clientID int, enabled bool) er
 	return nil
 }
 
+func (c *testConfigurationControl) RenamePeer(clientID int, name string) error {
+	for i := range c.peers {
+		if c.peers[i].ClientID == clientID {
+			c.peers[i].Name = name
+			return nil
+		}
+	}
+	return errors.New("peer not found")
+}
+
 func (c *testConfigurationControl) RemovePeer(clientID int) error {
 	c.removeCalls++
 	c.lastRemoved = clientID
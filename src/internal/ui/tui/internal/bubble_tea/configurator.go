@@ -48,6 +48,7 @@ const (
 	configuratorScreenServerSelect
 	configuratorScreenServerManage
 	configuratorScreenServerDeleteConfirm
+	configuratorScreenServerPeerQR
 	configuratorScreenDaemonManage
 	configuratorScreenDaemonReconfigureConfirm
 	configuratorScreenDaemonActiveConfirm
@@ -107,6 +108,10 @@ type serverState struct {
 	manageLabels []string
 	deletePeer   config.ServerPeer
 	deleteCursor int
+	qrPeer       config.ServerPeer
+	qrCursor     int
+	qrURI        string
+	qrCode       string
 }
 
 type daemonState struct {
@@ -332,6 +337,8 @@ func (m Configurator) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.updateServerManageScreen(msg)
 		case configuratorScreenServerDeleteConfirm:
 			return m.updateServerDeleteConfirmScreen(msg)
+		case configuratorScreenServerPeerQR:
+			return m.updateServerPeerQRScreen(msg)
 		case configuratorScreenDaemonManage:
 			return m.updateDaemonManageScreen(msg)
 		case configuratorScreenDaemonReconfigureConfirm:
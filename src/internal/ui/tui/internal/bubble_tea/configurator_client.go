@@ -7,14 +7,25 @@ import (
 	"time"
 
 	"tungo/internal/config"
+	clientconfig "tungo/internal/config/client"
 
 	"charm.land/bubbles/v2/textarea"
 	"charm.land/bubbles/v2/textinput"
 	tea "charm.land/bubbletea/v2"
 )
 
+// instanceDaemon is implemented by daemon controls that can run named
+// client configurations as background instances.
+type instanceDaemon interface {
+	SetupInstance(name string) error
+	RemoveInstance(name string) error
+	IsInstanceActive(name string) (bool, error)
+}
+
 func (m Configurator) updateClientSelectScreen(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
+	case "b":
+		return m.toggleClientInstance(), nil
 	case "esc":
 		m.notice = ""
 		m.cursor = 0
@@ -257,6 +268,39 @@ func (m Configurator) persistAutoSelectClientConfig(selected string) Configurato
 	return m
 }
 
+// toggleClientInstance starts the highlighted configuration as a background
+// instance, or stops it when it is already running.
+func (m Configurator) toggleClientInstance() Configurator {
+	instances, ok := m.options.Daemon.(instanceDaemon)
+	if !ok || m.cursor >= len(m.client.configs) {
+		return m
+	}
+	name, ok := clientconfig.InstanceName(clientconfig.NewResolver(), m.client.configs[m.cursor])
+	if !ok {
+		m.notice = "This configuration name cannot run in the background; use letters, digits, '-' or '_'."
+		return m
+	}
+	active, err := instances.IsInstanceActive(name)
+	if err != nil {
+		m.notice = "Failed to check instance: " + err.Error()
+		return m
+	}
+	if active {
+		if err := instances.RemoveInstance(name); err != nil {
+			m.notice = "Failed to stop instance: " + err.Error()
+			return m
+		}
+		m.notice = "Instance " + name + " stopped."
+		return m
+	}
+	if err := instances.SetupInstance(name); err != nil {
+		m.notice = "Failed to start instance: " + err.Error()
+		return m
+	}
+	m.notice = "Instance " + name + " running in the background."
+	return m
+}
+
 func (m *Configurator) reloadClientConfigs() error {
 	configs, err := m.options.ClientConfigurationControl.List()
 	if err != nil {
@@ -304,7 +348,7 @@ func (m *Configurator) tryFormatJSON() {
 func (m *Configurator) initJSONInput() {
 	ta := textarea.New()
 	ta.Prompt = "> "
-	ta.Placeholder = "Paste it here"
+	ta.Placeholder = "Paste JSON or a tungo:// link here"
 	ta.SetWidth(80)
 	ta.SetHeight(10)
 	ta.ShowLineNumbers = true
@@ -23,6 +23,9 @@ func (m Configurator) mainTabView() string {
 		if len(m.modeOptions) == 1 {
 			clientSelectHint = "up/k down/j move | Enter select | Tab switch tabs | Esc exit | ctrl+c exit"
 		}
+		if _, ok := m.options.Daemon.(instanceDaemon); ok {
+			clientSelectHint = strings.Replace(clientSelectHint, "Enter select |", "Enter select | b background |", 1)
+		}
 		return m.renderSelectionScreen(
 			"Select configuration - or add/remove one:",
 			m.notice,
@@ -104,10 +107,10 @@ func (m Configurator) mainTabView() string {
 	case configuratorScreenServerManage:
 		return m.renderSelectionScreen(
 			"Select client to enable/disable or delete",
-			"",
+			m.notice,
 			m.server.manageLabels,
 			m.cursor,
-			"up/k down/j move | Enter toggle | d delete | Tab switch tabs | Esc back | ctrl+c exit",
+			"up/k down/j move | Enter toggle | q share | d delete | Tab switch tabs | Esc back | ctrl+c exit",
 		)
 	case configuratorScreenServerDeleteConfirm:
 		return m.renderSelectionScreen(
@@ -121,6 +124,8 @@ func (m Configurator) mainTabView() string {
 			m.cursor,
 			"up/k down/j move | Enter confirm | Tab switch tabs | Esc back | ctrl+c exit",
 		)
+	case configuratorScreenServerPeerQR:
+		return m.renderServerPeerQRScreen()
 	case configuratorScreenDaemonManage:
 		return m.renderDaemonManageScreen()
 	case configuratorScreenDaemonReconfigureConfirm:
@@ -174,6 +179,55 @@ func (m Configurator) mainTabView() string {
 	}
 }
 
+// renderServerPeerQRScreen shows the configuration URI of a client and, when
+// the terminal is large enough to scan it, its QR code.
+func (m Configurator) renderServerPeerQRScreen() string {
+	styles := resolveUIStyles(m.preferences)
+	contentWidth := 0
+	if m.width > 0 {
+		contentWidth = contentWidthForTerminal(m.width)
+	}
+	body := make([]string, 0, 64)
+	if code := m.server.qrCode; code != "" {
+		lines := strings.Split(code, "\n")
+		fits := contentWidth == 0 || utf8.RuneCountInString(lines[0]) <= contentWidth
+		if m.height > 0 {
+			// header, subtitle and footer take about ten lines of the card
+			fits = fits && len(lines)+10 <= computeCardHeight(m.height)-frameVertSize
+		}
+		if fits {
+			body = append(body, lines...)
+		} else {
+			body = append(body, "Enlarge the terminal to show the QR code.")
+		}
+		body = append(body, "")
+	}
+	body = append(body, chunkString(m.server.qrURI, contentWidth)...)
+	return renderScreenRaw(
+		m.width,
+		m.height,
+		m.tabsLine(styles),
+		fmt.Sprintf("Client #%d %s", m.server.qrPeer.ClientID, serverPeerDisplayName(m.server.qrPeer)),
+		body,
+		"Esc back | ctrl+c exit",
+		m.preferences,
+		styles,
+	)
+}
+
+// chunkString splits s into lines of at most width characters.
+func chunkString(s string, width int) []string {
+	if width <= 0 || len(s) <= width {
+		return []string{s}
+	}
+	lines := make([]string, 0, len(s)/width+1)
+	for len(s) > width {
+		lines = append(lines, s[:width])
+		s = s[width:]
+	}
+	return append(lines, s)
+}
+
 func (m Configurator) renderSelectionScreen(
 	screenTitle string,
 	notice string,
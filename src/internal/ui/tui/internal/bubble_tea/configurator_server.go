@@ -3,8 +3,11 @@ package bubble_tea
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"tungo/internal/config"
+	"tungo/internal/trafficstats"
+	"tungo/internal/ui/qr"
 
 	tea "charm.land/bubbletea/v2"
 )
@@ -76,6 +79,27 @@ func (m Configurator) updateServerManageScreen(msg tea.KeyPressMsg) (tea.Model,
 		m.cursor = 0
 		m.screen = configuratorScreenServerDeleteConfirm
 		return m, nil
+	case "q", "Q":
+		if len(m.server.managePeers) == 0 {
+			return m, nil
+		}
+		peer := m.server.managePeers[m.cursor]
+		uri, err := m.options.ServerConfigurationControl.ClientConfigurationURI(peer.ClientID)
+		if err != nil {
+			m.notice = fmt.Sprintf("Failed to share client #%d: %v", peer.ClientID, err)
+			return m, nil
+		}
+		code, err := qr.Render(uri)
+		if err != nil {
+			code = ""
+		}
+		m.notice = ""
+		m.server.qrPeer = peer
+		m.server.qrCursor = m.cursor
+		m.server.qrURI = uri
+		m.server.qrCode = code
+		m.screen = configuratorScreenServerPeerQR
+		return m, nil
 	}
 
 	m.updateCursor(msg, len(m.server.managePeers))
@@ -105,6 +129,7 @@ func (m Configurator) updateServerManageScreen(msg tea.KeyPressMsg) (tea.Model,
 		return m, nil
 	}
 
+	m.notice = ""
 	m.server.managePeers = peers
 	m.server.manageLabels = buildServerManageLabels(peers)
 	if m.cursor >= len(m.server.managePeers) {
@@ -174,6 +199,17 @@ func (m Configurator) updateServerDeleteConfirmScreen(msg tea.KeyPressMsg) (tea.
 	return m, nil
 }
 
+func (m Configurator) updateServerPeerQRScreen(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc", "enter":
+		m.cursor = minInt(m.server.qrCursor, maxInt(0, len(m.server.managePeers)-1))
+		m.server.qrURI = ""
+		m.server.qrCode = ""
+		m.screen = configuratorScreenServerManage
+	}
+	return m, nil
+}
+
 func buildServerManageLabels(peers []config.ServerPeer) []string {
 	labels := make([]string, 0, len(peers))
 	for _, peer := range peers {
@@ -195,6 +231,17 @@ func serverPeerOptionLabel(peer config.ServerPeer) string {
 	if peer.Enabled {
 		status = "enabled"
 	}
+	if peer.Enabled && peer.Expired(time.Now()) {
+		status = "expired"
+	}
 	name := serverPeerDisplayName(peer)
-	return fmt.Sprintf("#%d %s [%s]", peer.ClientID, name, status)
+	label := fmt.Sprintf("#%d %s [%s]", peer.ClientID, name, status)
+	if traffic := peer.Traffic; traffic.RXBytes > 0 || traffic.TXBytes > 0 {
+		label += fmt.Sprintf(
+			" RX %s / TX %s",
+			trafficstats.FormatTotal(traffic.RXBytes),
+			trafficstats.FormatTotal(traffic.TXBytes),
+		)
+	}
+	return label
 }
@@ -267,3 +267,48 @@ func TestServerDeleteConfirm_CancelWithPeers_RestoresCursor(t *testing.T) {
 		t.Fatalf("expected cursor restored to 1, got %d", state.cursor)
 	}
 }
+
+func TestServerManage_ShareShowsQRCode(t *testing.T) {
+	manager := &testConfigurationControl{
+		peers: []config.ServerPeer{
+			{Name: "alpha", ClientID: 1, Enabled: true},
+			{Name: "beta", ClientID: 2, Enabled: true},
+		},
+		shareURI: "tungo://1/payload",
+	}
+	model := newSessionModelForServerManageTests(t, manager)
+	model.cursor = 1
+
+	nextModel, _ := model.updateServerManageScreen(keyRunes('q'))
+	state := nextModel.(Configurator)
+	if state.screen != configuratorScreenServerPeerQR {
+		t.Fatalf("expected QR screen, got %v", state.screen)
+	}
+	if state.server.qrPeer.ClientID != 2 || state.server.qrCode == "" {
+		t.Fatalf("unexpected QR state: %+v", state.server)
+	}
+	view := state.mainTabView()
+	if !strings.Contains(view, "tungo://1/payload") || !strings.Contains(view, "█") {
+		t.Fatalf("expected URI and QR code in view, got %q", view)
+	}
+
+	nextModel, _ = state.updateServerPeerQRScreen(keyNamed(tea.KeyEscape))
+	state = nextModel.(Configurator)
+	if state.screen != configuratorScreenServerManage || state.cursor != 1 {
+		t.Fatalf("expected return to manage screen at cursor 1, got screen=%v cursor=%d", state.screen, state.cursor)
+	}
+}
+
+func TestServerManage_ShareError_ShowsNotice(t *testing.T) {
+	manager := &testConfigurationControl{
+		peers:    []config.ServerPeer{{Name: "alpha", ClientID: 1, Enabled: true}},
+		shareErr: errors.New("not generated here"),
+	}
+	model := newSessionModelForServerManageTests(t, manager)
+
+	nextModel, _ := model.updateServerManageScreen(keyRunes('q'))
+	state := nextModel.(Configurator)
+	if state.screen != configuratorScreenServerManage || !strings.Contains(state.notice, "not generated here") {
+		t.Fatalf("expected notice on manage screen, got screen=%v notice=%q", state.screen, state.notice)
+	}
+}
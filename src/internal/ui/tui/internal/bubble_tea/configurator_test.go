@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"tungo/internal/config"
+	clientconfig "tungo/internal/config/client"
 	"tungo/internal/daemon/systemd"
 
 	tea "charm.land/bubbletea/v2"
@@ -2963,3 +2964,45 @@ func TestNewConfiguratorSessionModel_AutoSelectConfig_NilClientManager_UsesDaemo
 		t.Fatalf("expected pending client config cfg.json, got %q", model.pendingClientConfig)
 	}
 }
+
+func TestUpdateClientSelectScreen_BTogglesBackgroundInstance(t *testing.T) {
+	active, err := clientconfig.NewResolver().Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	daemon := &instanceDaemonStub{daemonControlStub: newDaemonControlStub(), active: map[string]bool{}}
+	m := newTestConfigurator(t)
+	m.options.Daemon = daemon
+	m.screen = configuratorScreenClientSelect
+	m.client.configs = []string{active + ".prod", active + ".my prod"}
+	m.client.menuOptions = append(append([]string(nil), m.client.configs...), clientRemoveLabel, clientAddLabel)
+
+	if view := m.View().Content; !strings.Contains(view, "b background") {
+		t.Fatalf("expected background hint, got: %s", view)
+	}
+
+	result, _ := m.updateClientSelectScreen(keyRunes('b'))
+	m = result.(Configurator)
+	if !daemon.active["prod"] || !strings.Contains(m.notice, "running in the background") {
+		t.Fatalf("expected instance started, notice=%q calls=%v", m.notice, daemon.calls)
+	}
+	result, _ = m.updateClientSelectScreen(keyRunes('b'))
+	m = result.(Configurator)
+	if daemon.active["prod"] || m.notice != "Instance prod stopped." {
+		t.Fatalf("expected instance stopped, notice=%q calls=%v", m.notice, daemon.calls)
+	}
+
+	m.cursor = 1
+	result, _ = m.updateClientSelectScreen(keyRunes('b'))
+	m = result.(Configurator)
+	if len(daemon.calls) != 2 || !strings.Contains(m.notice, "cannot run in the background") {
+		t.Fatalf("expected invalid name notice, notice=%q calls=%v", m.notice, daemon.calls)
+	}
+
+	m.cursor = 2
+	m.notice = ""
+	result, _ = m.updateClientSelectScreen(keyRunes('b'))
+	if result.(Configurator).notice != "" || len(daemon.calls) != 2 {
+		t.Fatal("expected menu actions to ignore the background key")
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"tungo/internal/config"
 	clientconfig "tungo/internal/config/client"
+	serverconfig "tungo/internal/config/server"
 	"tungo/internal/config/settings"
 )
 
@@ -190,6 +191,18 @@ func TestServerPeerDisplayName_WhitespaceOnlyName(t *testing.T) {
 	}
 }
 
+func TestServerPeerOptionLabel_ShowsTrafficWhenPresent(t *testing.T) {
+	peer := config.ServerPeer{Name: "alpha", ClientID: 1, Enabled: true}
+	if got := serverPeerOptionLabel(peer); got != "#1 alpha [enabled]" {
+		t.Fatalf("label without traffic = %q", got)
+	}
+
+	peer.Traffic = serverconfig.PeerTraffic{RXBytes: 2048, TXBytes: 512}
+	if got := serverPeerOptionLabel(peer); got != "#1 alpha [enabled] RX 2.0 KiB / TX 512 B" {
+		t.Fatalf("label with traffic = %q", got)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // NewConfigurator
 // ---------------------------------------------------------------------------
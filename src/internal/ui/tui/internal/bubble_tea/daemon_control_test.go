@@ -96,3 +96,30 @@ func (o *ConfiguratorOptions) testDaemon() *daemonControlStub {
 	o.Daemon = daemon
 	return daemon
 }
+
+// instanceDaemonStub adds background client instances to the daemon stub.
+type instanceDaemonStub struct {
+	*daemonControlStub
+	active  map[string]bool
+	calls   []string
+	failErr error
+}
+
+func (s *instanceDaemonStub) SetupInstance(name string) error {
+	s.calls = append(s.calls, "setup "+name)
+	if s.failErr != nil {
+		return s.failErr
+	}
+	s.active[name] = true
+	return nil
+}
+
+func (s *instanceDaemonStub) RemoveInstance(name string) error {
+	s.calls = append(s.calls, "remove "+name)
+	delete(s.active, name)
+	return nil
+}
+
+func (s *instanceDaemonStub) IsInstanceActive(name string) (bool, error) {
+	return s.active[name], nil
+}
@@ -2,9 +2,11 @@ package bubble_tea
 
 import (
 	"context"
+	"fmt"
 	"net/netip"
 	"strings"
 	"time"
+	"tungo/internal/client/state"
 	"tungo/internal/config"
 	"tungo/internal/config/settings"
 	"tungo/internal/trafficstats"
@@ -19,6 +21,8 @@ type RuntimeDashboardOptions struct {
 	Ready           func() bool
 	Protocol        settings.Protocol
 	Endpoints       []config.EndpointInfo
+	// States streams client connection states; nil keeps the Ready polling.
+	States <-chan state.Status
 }
 
 type runtimeTickMsg struct {
@@ -27,6 +31,11 @@ type runtimeTickMsg struct {
 
 type runtimeContextDoneMsg struct{}
 
+type runtimeStateMsg struct {
+	status state.Status
+	ok     bool
+}
+
 type runtimeDashboardScreen int
 
 const (
@@ -72,6 +81,9 @@ type RuntimeDashboard struct {
 	connected            bool
 	protocol             settings.Protocol
 	endpoints            []config.EndpointInfo
+	states               <-chan state.Status
+	status               state.Status
+	hasStatus            bool
 }
 
 func NewRuntimeDashboard(ctx context.Context, options RuntimeDashboardOptions, settings *Preferences) RuntimeDashboard {
@@ -101,6 +113,7 @@ func NewRuntimeDashboard(ctx context.Context, options RuntimeDashboardOptions, s
 		connected:       connected,
 		protocol:        options.Protocol,
 		endpoints:       options.Endpoints,
+		states:          options.States,
 	}
 	if model.preferences.ShowDataplaneGraph {
 		model.recordTrafficSample(trafficstats.SnapshotGlobal())
@@ -112,6 +125,7 @@ func (m RuntimeDashboard) Init() tea.Cmd {
 	return tea.Batch(
 		runtimeTickCmd(m.tickSeq),
 		waitForRuntimeContextDone(m.ctx),
+		waitForRuntimeState(m.ctx, m.states),
 	)
 }
 
@@ -148,6 +162,16 @@ func (m RuntimeDashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case runtimeContextDoneMsg:
 		m.logs.stopWait()
 		return m, tea.Quit
+	case runtimeStateMsg:
+		if !msg.ok {
+			return m, nil
+		}
+		m.status = msg.status
+		m.hasStatus = true
+		if m.status.State == state.Connected {
+			m.connected = true
+		}
+		return m, waitForRuntimeState(m.ctx, m.states)
 	case tea.KeyPressMsg:
 		if m.confirmOpen {
 			return m.updateConfirm(msg)
@@ -302,6 +326,9 @@ func (m RuntimeDashboard) mainView() string {
 	if m.connected {
 		status = "Status: Connected"
 	}
+	if m.hasStatus {
+		status = "Status: " + formatRuntimeStatus(m.status, time.Now())
+	}
 	if m.mode == config.ModeServer {
 		modeLine = "Mode: Server"
 		status = "Status: Running"
@@ -498,6 +525,33 @@ func sharedServerAddress(endpoints []config.EndpointInfo) (settings.Host, bool)
 	return sharedAddress, true
 }
 
+// formatRuntimeStatus describes a client connection state for the status
+// line.
+func formatRuntimeStatus(status state.Status, now time.Time) string {
+	endpoint := "server"
+	if status.Endpoint != "" {
+		endpoint = status.Endpoint
+	}
+	switch status.State {
+	case state.Resolving:
+		return "Resolving " + endpoint + "..."
+	case state.Dialing:
+		return "Connecting to " + endpoint + "..."
+	case state.Handshaking:
+		return "Handshaking with " + endpoint + "..."
+	case state.Connected:
+		return "Connected"
+	case state.Backoff:
+		remaining := max(status.Since.Add(status.RetryIn).Sub(now), 0)
+		return fmt.Sprintf("Reconnecting in %s (%s)", remaining.Round(time.Second), status.Reason)
+	default:
+		if status.Err != nil {
+			return fmt.Sprintf("Disconnected (%s)", status.Reason)
+		}
+		return "Connecting to server..."
+	}
+}
+
 func (m RuntimeDashboard) stopActionLabel() string {
 	if m.mode == config.ModeClient && m.preferences.AutoConnect {
 		return "Stop (AutoConnect will be disabled)"
@@ -603,6 +657,20 @@ func runtimeLogUpdateCmd(
 	return logViewportTickCmd(logSeq)
 }
 
+func waitForRuntimeState(ctx context.Context, states <-chan state.Status) tea.Cmd {
+	if states == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		select {
+		case <-ctx.Done():
+			return runtimeContextDoneMsg{}
+		case status, ok := <-states:
+			return runtimeStateMsg{status: status, ok: ok}
+		}
+	}
+}
+
 func waitForRuntimeContextDone(ctx context.Context) tea.Cmd {
 	return func() tea.Msg {
 		<-ctx.Done()
@@ -2,11 +2,13 @@ package bubble_tea
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/netip"
 	"strings"
 	"testing"
 	"time"
+	"tungo/internal/client/state"
 	"tungo/internal/config"
 	"tungo/internal/config/settings"
 	"tungo/internal/trafficstats"
@@ -1707,3 +1709,62 @@ func TestSharedServerAddress_RequiresExactMatch(t *testing.T) {
 		t.Fatalf("unexpected shared server address: %+v", shared)
 	}
 }
+
+func TestRuntimeDashboard_StateStreamUpdatesStatus(t *testing.T) {
+	states := make(chan state.Status, 1)
+	m := NewRuntimeDashboard(context.Background(), RuntimeDashboardOptions{
+		Mode:   config.ModeClient,
+		Ready:  func() bool { return false },
+		States: states,
+	}, testSettings())
+	m.width = 80
+	m.height = 24
+
+	states <- state.Status{State: state.Handshaking, Endpoint: "198.51.100.1:9090"}
+	msg := waitForRuntimeState(context.Background(), states)()
+	updated, cmd := m.Update(msg)
+	m = updated.(RuntimeDashboard)
+	if cmd == nil {
+		t.Fatal("expected the dashboard to keep waiting for states")
+	}
+	if view := m.mainView(); !strings.Contains(view, "Handshaking with 198.51.100.1:9090...") {
+		t.Fatalf("expected handshaking status, got:\n%s", view)
+	}
+
+	updated, _ = m.Update(runtimeStateMsg{status: state.Status{State: state.Connected}, ok: true})
+	m = updated.(RuntimeDashboard)
+	if !m.connected {
+		t.Fatal("Connected state must mark the dashboard connected")
+	}
+
+	updated, cmd = m.Update(runtimeStateMsg{})
+	if cmd != nil {
+		t.Fatal("closed state stream must stop waiting")
+	}
+	_ = updated
+}
+
+func TestFormatRuntimeStatus(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		status state.Status
+		want   string
+	}{
+		{state.Status{}, "Connecting to server..."},
+		{state.Status{State: state.Resolving, Endpoint: "vpn.example.com:443"}, "Resolving vpn.example.com:443..."},
+		{state.Status{State: state.Dialing}, "Connecting to server..."},
+		{
+			state.Status{State: state.Backoff, Reason: state.ReasonNetwork, RetryIn: 4 * time.Second, Since: now.Add(-time.Second)},
+			"Reconnecting in 3s (network unreachable)",
+		},
+		{
+			state.Status{State: state.Disconnected, Reason: state.ReasonAuthRejected, Err: errors.New("rejected")},
+			"Disconnected (auth rejected)",
+		},
+	}
+	for _, tt := range tests {
+		if got := formatRuntimeStatus(tt.status, now); got != tt.want {
+			t.Fatalf("formatRuntimeStatus(%+v) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 
 	"tungo/internal/client"
+	"tungo/internal/client/state"
 	"tungo/internal/config"
 	"tungo/internal/server"
 	bubbleTea "tungo/internal/ui/tui/internal/bubble_tea"
@@ -69,6 +70,15 @@ func (t *TUI) runRuntime(
 	runtimeCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	var states <-chan state.Status
+	if subscriber, ok := runtimeInstance.(interface {
+		Subscribe() (<-chan state.Status, func())
+	}); ok {
+		var unsubscribe func()
+		states, unsubscribe = subscriber.Subscribe()
+		defer unsubscribe()
+	}
+
 	uiErrCh := make(chan error, 1)
 	go func() {
 		reconfigure, err := t.runRuntimePhase(runtimeCtx, bubbleTea.RuntimeDashboardOptions{
@@ -78,6 +88,7 @@ func (t *TUI) runRuntime(
 			Ready:           runtimeInstance.Ready,
 			Protocol:        info.Protocol,
 			Endpoints:       info.Endpoints,
+			States:          states,
 		})
 		if err == nil && reconfigure {
 			err = errReconfigureRequested
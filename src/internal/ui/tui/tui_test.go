@@ -255,6 +255,14 @@ func (configurationControlMock) GenerateClientConfiguration() (config.GeneratedC
 	return config.GeneratedClientConfiguration{}, nil
 }
 
+func (configurationControlMock) GenerateClientConfigurations(config.GenerateOptions) ([]config.GeneratedClientConfiguration, error) {
+	return nil, nil
+}
+
+func (configurationControlMock) ClientConfigurationURI(int) (string, error) {
+	return "", nil
+}
+
 func (configurationControlMock) ListPeers() ([]config.ServerPeer, error) {
 	return nil, nil
 }
@@ -263,6 +271,10 @@ func (configurationControlMock) SetPeerEnabled(int, bool) error {
 	return nil
 }
 
+func (configurationControlMock) RenamePeer(int, string) error {
+	return nil
+}
+
 func (configurationControlMock) RemovePeer(int) error {
 	return nil
 }
@@ -277,3 +289,11 @@ func (configurationControlMock) WatchServerConfiguration(
 	config.ServerAllowedPeersUpdater,
 ) {
 }
+
+func (configurationControlMock) LoadPeerTraffic() (map[string]serverconfig.PeerTraffic, error) {
+	return nil, nil
+}
+
+func (configurationControlMock) SavePeerTraffic(map[string]serverconfig.PeerTraffic) error {
+	return nil
+}
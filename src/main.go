@@ -3,21 +3,34 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
 	"time"
 
+	"tungo/internal/check"
 	"tungo/internal/client"
+	"tungo/internal/client/instance"
 	"tungo/internal/commandline"
 	"tungo/internal/config"
+	clientconfig "tungo/internal/config/client"
+	"tungo/internal/config/keyfile"
+	serverconfig "tungo/internal/config/server"
 	"tungo/internal/daemon/systemd"
 	"tungo/internal/elevation"
 	"tungo/internal/logging"
 	"tungo/internal/platform/command"
 	"tungo/internal/product"
+	"tungo/internal/protocol/keys"
 	"tungo/internal/server"
+	"tungo/internal/server/admin"
 	"tungo/internal/shutdown"
 	"tungo/internal/trafficstats"
+	"tungo/internal/ui/cli"
+	"tungo/internal/ui/qr"
 	"tungo/internal/ui/tui"
 )
 
@@ -47,11 +60,19 @@ func main() {
 }
 
 func runCLI(ctx context.Context) error {
-	command, err := commandline.ParseCommand(os.Args[1:])
+	invocation, err := commandline.Parse(os.Args[1:])
 	if err != nil {
 		fmt.Print(commandline.CommandUsage(product.Name))
 		return fmt.Errorf("configuration error: %w", err)
 	}
+	if invocation.Help {
+		fmt.Print(commandline.CommandHelp(product.Name, invocation))
+		return nil
+	}
+	options := invocation.Options
+	slog.SetDefault(logging.New(options.LogLevel, options.LogFormat))
+
+	command := invocation.Command
 	if command.RequiresElevation {
 		if err := requireElevation(); err != nil {
 			return err
@@ -61,27 +82,70 @@ func runCLI(ctx context.Context) error {
 	case commandline.CommandVersion:
 		fmt.Printf("%s %s\n", product.Name, product.Version)
 		return nil
-	case commandline.CommandServerConfigGenerate:
-		serverControl := config.NewServerControl()
-		if serverControl == nil {
-			return fmt.Errorf("server configuration is not supported")
+	case commandline.CommandKeygen:
+		return cli.Keygen(os.Stdout)
+	case commandline.CommandPubkey:
+		return cli.Pubkey(os.Stdin, os.Stdout)
+	case commandline.CommandClientKeygen:
+		return enrollClientKey(clientResolver(options))
+	case commandline.CommandServerCheck:
+		report := check.NewChecker().Server(serverConfigurationPath(options))
+		return cli.Check(os.Stdout, report, options.Output == commandline.OutputJSON)
+	case commandline.CommandClientCheck:
+		path, err := clientconfig.InstancePath(clientResolver(options), command.Operands[0])
+		if err != nil {
+			return err
 		}
-		generated, err := serverControl.GenerateClientConfiguration()
+		return cli.Check(os.Stdout, check.NewChecker().Client(path), options.Output == commandline.OutputJSON)
+	case commandline.CommandServerKeyFile:
+		path := serverConfigurationPath(options)
+		keyFile := keyfile.DefaultName(path)
+		if err := serverconfig.NewX25519KeyManager(serverconfig.NewManager(path)).MoveToFile(keyFile); err != nil {
+			return err
+		}
+		return printKeyFile(keyfile.Resolve(path, keyFile))
+	case commandline.CommandClientKeyFile:
+		path, err := clientconfig.InstancePath(clientResolver(options), command.Operands[0])
 		if err != nil {
-			return fmt.Errorf("configuration generation failed: %w", err)
+			return err
 		}
-		fmt.Println(generated.JSON)
-		return nil
+		keyFile := keyfile.DefaultName(path)
+		if err := clientconfig.MoveKeyToFile(path, keyFile); err != nil {
+			return err
+		}
+		return printKeyFile(keyfile.Resolve(path, keyFile))
+	case commandline.CommandServerConfigGenerate:
+		return generateClientConfigurations(invocation.Generate, options)
+	case commandline.CommandServerPeerList,
+		commandline.CommandServerPeerShow,
+		commandline.CommandServerPeerEnable,
+		commandline.CommandServerPeerDisable,
+		commandline.CommandServerPeerRemove,
+		commandline.CommandServerPeerRename:
+		return runPeerCommand(command, options)
+	case commandline.CommandServerSessionList,
+		commandline.CommandServerSessionTerminate:
+		return runSessionCommand(ctx, command, options)
+	case commandline.CommandClientProxy:
+		runningClient, err := client.NewUserspace(userClientResolver(options))
+		if err != nil {
+			return err
+		}
+		return runningClient.Run(ctx)
+	case commandline.CommandClientDown:
+		return stopClientInstance(command.Operands[0])
+	case commandline.CommandClientList:
+		return listClientInstances(os.Stdout, clientResolver(options))
 	case commandline.CommandRuntime:
 		switch command.RuntimeMode {
 		case config.ModeClient:
-			runningClient, err := client.New()
+			runningClient, err := client.NewInstance(clientResolver(options), command.Operands[0])
 			if err != nil {
 				return err
 			}
 			return runningClient.Run(ctx)
 		case config.ModeServer:
-			runningServer, err := server.New()
+			runningServer, err := newServer(options)
 			if err != nil {
 				return err
 			}
@@ -94,6 +158,234 @@ func runCLI(ctx context.Context) error {
 	}
 }
 
+// enrollClientKey makes the client key pair and prints the public key, which
+// the server operator enrolls with "s gen --public-key". The private key
+// stays in the client key store.
+func enrollClientKey(resolver clientconfig.Resolver) error {
+	public, err := clientconfig.NewKeyStore(resolver).Generate()
+	if err != nil {
+		return err
+	}
+	key := keys.EncodeKey(public)
+	fmt.Println(key)
+	_, _ = fmt.Fprintf(os.Stderr,
+		"\nSend this public key to the server operator, who enrolls it with:\n  %s s gen --public-key %s\n",
+		product.Name, key)
+	return nil
+}
+
+// printKeyFile reports where "keyfile" moved the private key. An encrypted
+// key file needs the same passphrase whenever the configuration loads.
+func printKeyFile(path string) error {
+	passphrase, err := keyfile.Passphrase()
+	if err != nil {
+		return err
+	}
+	if len(passphrase) == 0 {
+		fmt.Printf("Moved the private key to %s, unencrypted; set %s to encrypt it\n", path, keyfile.PassphraseEnv)
+		return nil
+	}
+	fmt.Printf("Moved the private key to %s, encrypted; tungo needs the same passphrase to read it\n", path)
+	return nil
+}
+
+// generateClientConfigurations runs "s gen". Configurations generated before
+// an error are still written out, since the server already allows their peers.
+func generateClientConfigurations(generate commandline.Generate, options commandline.Options) error {
+	serverControl := newServerControl(options)
+	if serverControl == nil {
+		return fmt.Errorf("server configuration is not supported")
+	}
+	var export *cli.Export
+	if generate.Out != "" && generate.Out != commandline.OutStdout {
+		var err error
+		if export, err = cli.NewExport(generate.Out, max(generate.Options.Count, 1)); err != nil {
+			return err
+		}
+	}
+	generated, genErr := serverControl.GenerateClientConfigurations(generate.Options)
+	if len(generated) == 0 {
+		return fmt.Errorf("configuration generation failed: %w", genErr)
+	}
+	var err error
+	switch {
+	case export != nil:
+		err = export.Write(os.Stdout, generated)
+	case generate.Out == commandline.OutStdout:
+		err = cli.PrintConfigurations(os.Stdout, generated)
+	case len(generated) == 1:
+		fmt.Println(generated[0].JSON)
+		printShareableConfiguration(generated[0].URI)
+	default:
+		paths := make([]string, len(generated))
+		for i, g := range generated {
+			paths[i] = g.Path
+		}
+		err = cli.WriteGenerated(os.Stdout, generated, paths)
+	}
+	if genErr != nil {
+		return fmt.Errorf("configuration generation stopped after %d configurations: %w", len(generated), genErr)
+	}
+	return err
+}
+
+func runPeerCommand(command commandline.Command, options commandline.Options) error {
+	serverControl := newServerControl(options)
+	if serverControl == nil {
+		return fmt.Errorf("server configuration is not supported")
+	}
+	peers := cli.NewPeers(serverControl, os.Stdout, options.Output == commandline.OutputJSON)
+	id := command.Operands[0]
+	switch command.Kind {
+	case commandline.CommandServerPeerList:
+		return peers.List()
+	case commandline.CommandServerPeerShow:
+		return peers.Show(id)
+	case commandline.CommandServerPeerEnable:
+		return peers.Enable(id)
+	case commandline.CommandServerPeerDisable:
+		return peers.Disable(id)
+	case commandline.CommandServerPeerRemove:
+		return peers.Remove(id)
+	case commandline.CommandServerPeerRename:
+		return peers.Rename(id, command.Operands[1])
+	default:
+		return fmt.Errorf("unhandled peer command: %v", command.Kind)
+	}
+}
+
+// runSessionCommand asks the running server over its admin socket.
+func runSessionCommand(ctx context.Context, command commandline.Command, options commandline.Options) error {
+	call := func(ctx context.Context, request admin.Request) (admin.Response, error) {
+		return admin.Call(ctx, admin.DefaultSocketPath, request)
+	}
+	sessions := cli.NewSessions(call, os.Stdout, options.Output == commandline.OutputJSON)
+	switch command.Kind {
+	case commandline.CommandServerSessionList:
+		return sessions.List(ctx)
+	case commandline.CommandServerSessionTerminate:
+		return sessions.Terminate(ctx, command.Operands[0])
+	default:
+		return fmt.Errorf("unhandled session command: %v", command.Kind)
+	}
+}
+
+// clientResolver points at the --config file, or at the default client
+// configuration.
+func clientResolver(options commandline.Options) clientconfig.Resolver {
+	if options.ConfigPath != "" {
+		return clientconfig.NewPathResolver(options.ConfigPath)
+	}
+	return clientconfig.NewResolver()
+}
+
+// userClientResolver resolves the configuration of commands that run as the
+// invoking user.
+func userClientResolver(options commandline.Options) clientconfig.Resolver {
+	if options.ConfigPath != "" {
+		return clientconfig.NewPathResolver(options.ConfigPath)
+	}
+	return clientconfig.NewUserResolver()
+}
+
+func serverConfigurationPath(options commandline.Options) string {
+	if options.ConfigPath != "" {
+		return options.ConfigPath
+	}
+	return config.DefaultServerConfigurationPath
+}
+
+func newServerControl(options commandline.Options) config.ServerControl {
+	if options.ConfigPath != "" {
+		return config.NewServerControlAt(options.ConfigPath)
+	}
+	return config.NewServerControl()
+}
+
+func newServer(options commandline.Options) (*server.Server, error) {
+	if options.ConfigPath != "" {
+		return server.NewAt(options.ConfigPath)
+	}
+	return server.New()
+}
+
+// printShareableConfiguration writes the configuration URI and its QR code to
+// stderr, so that stdout stays plain JSON.
+func printShareableConfiguration(uri string) {
+	code, err := qr.Render(uri)
+	if err != nil {
+		slog.Warn("configuration does not fit a QR code", "err", err)
+		_, _ = fmt.Fprintf(os.Stderr, "\n%s\n", uri)
+		return
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "\n%s\n\n%s\n", code, uri)
+}
+
+// stopClientInstance stops a named client instance. An instance run by
+// systemd is disabled as well, so that systemd does not restart it.
+func stopClientInstance(name string) error {
+	if err := clientconfig.ValidateInstanceName(name); err != nil {
+		return err
+	}
+	units := systemd.NewUnitInstaller(command.New())
+	if units.Available() {
+		active, err := units.IsInstanceActive(name)
+		if err != nil {
+			return err
+		}
+		if active {
+			return units.RemoveInstance(name)
+		}
+	}
+	return instance.NewRegistry().Stop(name)
+}
+
+// listClientInstances prints the configured and the running client
+// instances.
+func listClientInstances(w io.Writer, resolver clientconfig.Resolver) error {
+	paths, err := clientconfig.NewObserver(resolver).Observe()
+	if err != nil {
+		return fmt.Errorf("failed to list client configurations: %w", err)
+	}
+	running, err := instance.NewRegistry().Running()
+	if err != nil {
+		return fmt.Errorf("failed to list running instances: %w", err)
+	}
+	pids := make(map[string]int, len(running))
+	names := []string{""}
+	for _, r := range running {
+		pids[r.Name] = r.PID
+		names = append(names, r.Name)
+	}
+	for _, path := range paths {
+		if name, ok := clientconfig.InstanceName(resolver, path); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "NAME\tSTATE\tPID\tTUN")
+	for _, name := range slices.Compact(names) {
+		label, state, pid, tun := name, "stopped", "-", "-"
+		if name == "" {
+			label = "(active)"
+		}
+		if p, ok := pids[name]; ok {
+			state, pid = "running", strconv.Itoa(p)
+		}
+		if conf, err := clientconfig.NewInstanceManager(resolver, name).Configuration(); err == nil {
+			if profiles, err := conf.CandidateSettings(); err == nil && len(profiles) > 0 {
+				tun = profiles[0].TunName
+			}
+		} else if name == "" && state == "stopped" {
+			continue
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", label, state, pid, tun)
+	}
+	return table.Flush()
+}
+
 func runTUI(ctx context.Context) error {
 	if err := requireElevation(); err != nil {
 		return err
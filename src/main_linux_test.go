@@ -11,6 +11,10 @@ import (
 	"strings"
 	"syscall"
 	"testing"
+
+	clientconfig "tungo/internal/config/client"
+	"tungo/internal/config/keyfile"
+	serverconfig "tungo/internal/config/server"
 )
 
 const (
@@ -91,3 +95,144 @@ func TestRunCLI_GenerateServerConfigurationOnCleanSystem(t *testing.T) {
 		t.Fatalf("stat generated client configuration: %v", err)
 	}
 }
+
+func TestRunCLI_ListClientInstancesOnCleanSystem(t *testing.T) {
+	requireCleanDefaultConfigDirectory(t)
+	setCommandLine(t, "c", "list")
+
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runCLI(context.Background())
+	})
+	if runErr != nil {
+		t.Fatalf("runCLI() error = %v", runErr)
+	}
+	if strings.TrimSpace(output) != "NAME  STATE  PID  TUN" {
+		t.Fatalf("instance list output = %q", output)
+	}
+}
+
+func TestRunCLI_PeersOnCleanSystem(t *testing.T) {
+	requireCleanDefaultConfigDirectory(t)
+	t.Setenv("ServerIP", "127.0.0.1")
+	setCommandLine(t, "s", "gen")
+	_ = captureStdout(t, func() {
+		if err := runCLI(context.Background()); err != nil {
+			t.Fatalf("generate: %v", err)
+		}
+	})
+
+	run := func(args ...string) string {
+		t.Helper()
+		setCommandLine(t, args...)
+		var runErr error
+		output := captureStdout(t, func() {
+			runErr = runCLI(context.Background())
+		})
+		if runErr != nil {
+			t.Fatalf("runCLI(%q) error = %v", args, runErr)
+		}
+		return output
+	}
+	if output := run("s", "peers", "disable", "1"); output != "Peer #1 disabled.\n" {
+		t.Fatalf("disable output = %q", output)
+	}
+	if output := run("s", "peers", "list", "--output", "json"); !strings.Contains(output, `"Enabled": false`) {
+		t.Fatalf("list output = %q", output)
+	}
+}
+
+func TestRunCLI_GenerateBatchArchive(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("generating configurations requires root access")
+	}
+	directory := t.TempDir()
+	configPath := filepath.Join(directory, "server_configuration.json")
+	archive := filepath.Join(directory, "team.zip")
+	t.Setenv("ServerIP", "127.0.0.1")
+
+	setCommandLine(t, "s", "gen", "--config", configPath, "--count", "2", "--out", filepath.Join(directory, "one.json"))
+	if err := runCLI(context.Background()); err == nil || !strings.Contains(err.Error(), "takes one configuration") {
+		t.Fatalf("runCLI() error = %v, want a rejected output file", err)
+	}
+
+	setCommandLine(t, "s", "gen", "--config", configPath, "--name", "team", "--count", "2", "--out", archive)
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runCLI(context.Background())
+	})
+	if runErr != nil {
+		t.Fatalf("runCLI() error = %v", runErr)
+	}
+	if !strings.Contains(output, "team-2.json") || !strings.Contains(output, "Archive: "+archive) {
+		t.Fatalf("generate output = %q", output)
+	}
+	// The rejected batch added no peers: the archive holds clients 1 and 2.
+	if !strings.Contains(output, "1  team-1") || !strings.Contains(output, "2  team-2") {
+		t.Fatalf("generate output = %q, want clients 1 and 2", output)
+	}
+}
+
+func TestRunCLI_EnrollClientKey(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("enrollment commands require root access")
+	}
+	directory := t.TempDir()
+	clientPath := filepath.Join(directory, "client", "client_configuration.json")
+	serverPath := filepath.Join(directory, "server", "server_configuration.json")
+	t.Setenv("ServerIP", "127.0.0.1")
+	run := func(args ...string) string {
+		t.Helper()
+		setCommandLine(t, args...)
+		var runErr error
+		output := captureStdout(t, func() {
+			runErr = runCLI(context.Background())
+		})
+		if runErr != nil {
+			t.Fatalf("runCLI(%q) error = %v", args, runErr)
+		}
+		return output
+	}
+
+	publicKey := strings.TrimSpace(run("c", "keygen", "--config", clientPath))
+	configuration := run("s", "gen", "--config", serverPath, "--public-key", publicKey, "--out", "-")
+	if strings.Contains(configuration, "ClientPrivateKey") || !strings.Contains(configuration, publicKey) {
+		t.Fatalf("enrolled configuration = %s", configuration)
+	}
+	if err := os.WriteFile(clientPath, []byte(configuration), 0600); err != nil {
+		t.Fatalf("write client configuration: %v", err)
+	}
+	if _, err := clientconfig.NewInstanceManager(clientconfig.NewPathResolver(clientPath), "").Configuration(); err != nil {
+		t.Fatalf("enrolled configuration does not load: %v", err)
+	}
+}
+
+func TestRunCLI_ServerKeyFile(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("keyfile commands require root access")
+	}
+	configPath := filepath.Join(t.TempDir(), "server_configuration.json")
+	t.Setenv("ServerIP", "127.0.0.1")
+	t.Setenv(keyfile.PassphraseEnv, "secret")
+
+	setCommandLine(t, "s", "keyfile", "--config", configPath)
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runCLI(context.Background())
+	})
+	if runErr != nil {
+		t.Fatalf("runCLI() error = %v", runErr)
+	}
+	keyPath := strings.TrimSuffix(configPath, ".json") + ".key"
+	if !strings.Contains(output, keyPath+", encrypted") {
+		t.Fatalf("keyfile output = %q", output)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil || strings.Contains(string(data), "X25519PrivateKey") {
+		t.Fatalf("server configuration = %s, %v", data, err)
+	}
+	conf, err := serverconfig.Load(configPath)
+	if err != nil || len(conf.X25519PrivateKey) != 32 {
+		t.Fatalf("server configuration does not load its key file: %v", err)
+	}
+}
@@ -62,6 +62,21 @@ func TestRunCLI_InvalidArguments(t *testing.T) {
 	}
 }
 
+func TestRunCLI_CommandHelp(t *testing.T) {
+	setCommandLine(t, "s", "gen", "--help")
+	var runErr error
+	output := captureStdout(t, func() {
+		runErr = runCLI(context.Background())
+	})
+
+	if runErr != nil {
+		t.Fatalf("runCLI() error = %v", runErr)
+	}
+	if !strings.Contains(output, "Usage: tungo s gen [flags]") || !strings.Contains(output, "--log-format <format>") {
+		t.Fatalf("help output = %q", output)
+	}
+}
+
 func TestMain_Version(t *testing.T) {
 	if os.Getenv(runMainVersionEnv) == "1" {
 		os.Args = []string{"tungo", "version"}